@@ -26,7 +26,10 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/aggregator"
 	"github.com/rabbitstack/fibratus/pkg/api"
 	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter"
+	"github.com/rabbitstack/fibratus/pkg/filter/action"
 	"github.com/rabbitstack/fibratus/pkg/handle"
+	"github.com/rabbitstack/fibratus/pkg/kcap"
 	"github.com/rabbitstack/fibratus/pkg/kstream"
 	"github.com/rabbitstack/fibratus/pkg/ps"
 	ver "github.com/rabbitstack/fibratus/pkg/util/version"
@@ -229,6 +232,9 @@ func (s *fsvc) run() error {
 	hsnap := handle.NewSnapshotter(svcConfig, nil)
 	psnap := ps.NewSnapshotter(hsnap, svcConfig)
 	consumer = kstream.NewConsumer(ctrl, psnap, hsnap, svcConfig)
+	rec := kcap.NewRecorder(psnap, hsnap)
+	action.SetRecorder(rec)
+	consumer.SetEventTap(rec.Tap)
 	// open the kernel event stream, start processing events and forwarding to outputs
 	err = consumer.OpenKstream(ctrl.Traces())
 	if err != nil {
@@ -239,14 +245,15 @@ func (s *fsvc) run() error {
 		consumer.Events(),
 		consumer.Errors(),
 		svcConfig.Aggregator,
-		svcConfig.Output,
+		svcConfig.Outputs,
 		svcConfig.Transformers,
 		svcConfig.Alertsenders,
+		filter.NewOutputCompiler(svcConfig),
 	)
 	if err != nil {
 		return err
 	}
-	if err := api.StartServer(svcConfig); err != nil {
+	if err := api.StartServer(svcConfig, api.WithRulesReloader(consumer), api.WithRulesProfiler(consumer)); err != nil {
 		return err
 	}
 
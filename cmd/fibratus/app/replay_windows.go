@@ -115,9 +115,10 @@ func replay(cmd *cobra.Command, args []string) error {
 			kevents,
 			errs,
 			replayConfig.Aggregator,
-			replayConfig.Output,
+			replayConfig.Outputs,
 			replayConfig.Transformers,
 			replayConfig.Alertsenders,
+			filter.NewOutputCompiler(replayConfig),
 		)
 		if err != nil {
 			return err
@@ -0,0 +1,324 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/rabbitstack/fibratus/cmd/fibratus/common"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	kerrors "github.com/rabbitstack/fibratus/pkg/errors"
+	"github.com/rabbitstack/fibratus/pkg/filter"
+	"github.com/rabbitstack/fibratus/pkg/filter/sigma"
+	"github.com/rabbitstack/fibratus/pkg/util/rest"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Test and manage detection rules",
+}
+
+var testRulesCmd = &cobra.Command{
+	Use:   "test [tests-file]...",
+	Short: "Run rule tests against fixture events",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  testRules,
+	Example: `
+	# Run rule tests with rules loaded from the configuration file
+	fibratus rules test rules/tests/credential_access.yml
+
+	# Run rule tests with rules loaded from the specified path
+	fibratus rules test --filters.rules.from-paths=rules/*.yml rules/tests/*.yml
+	`,
+}
+
+var validateRulesCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate rules and macros and report all problems found",
+	RunE:  validateRules,
+	Example: `
+	# Validate rules loaded from the configuration file
+	fibratus rules validate
+
+	# Validate rules loaded from the specified path and print issues as JSON
+	fibratus rules validate --filters.rules.from-paths=rules/*.yml --output=json
+	`,
+}
+
+var sigmaRulesCmd = &cobra.Command{
+	Use:   "sigma [sigma-rule-file]...",
+	Short: "Translate Sigma rules into rule groups",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  translateSigmaRules,
+	Example: `
+	# Translate Sigma rules and print rule groups to the standard output
+	fibratus rules sigma sigma/rules/windows/process_creation/*.yml
+
+	# Translate Sigma rules and write rule groups to the file
+	fibratus rules sigma --output-file=rules/sigma.yml sigma/rules/windows/process_creation/*.yml
+	`,
+}
+
+var profileRulesCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Show the most expensive rules in the running instance",
+	RunE:  profileRules,
+	Example: `
+	# Show the 20 rules with the highest cumulative evaluation time
+	fibratus rules profile
+
+	# Show the 10 rules with the highest 99th percentile evaluation time
+	fibratus rules profile --sort=p99 --limit=10
+	`,
+}
+
+var (
+	rulesConfig = config.NewWithOpts(config.WithRules())
+	// validateOutput is the output format of the validate command
+	validateOutput string
+	// sigmaOutputFile is the file where translated Sigma rules are written
+	sigmaOutputFile string
+	// profileSort is the rule profile attribute used for ranking rules
+	profileSort string
+	// profileLimit is the maximum number of displayed rule profiles
+	profileLimit int
+)
+
+func init() {
+	rulesConfig.MustViperize(rulesCmd)
+
+	validateRulesCmd.Flags().StringVar(&validateOutput, "output", "text", "Output format of the validation issues (text or json)")
+
+	sigmaRulesCmd.Flags().StringVar(&sigmaOutputFile, "output-file", "", "File where translated rule groups are written. Rule groups are printed to the standard output if not specified")
+
+	profileRulesCmd.Flags().StringVar(&profileSort, "sort", "total", "Rule profile attribute used for ranking rules (total, avg, p99, accessors, evaluations, or matches)")
+	profileRulesCmd.Flags().IntVar(&profileLimit, "limit", 20, "Maximum number of displayed rules. All rules are displayed if zero")
+
+	rulesCmd.AddCommand(testRulesCmd)
+	rulesCmd.AddCommand(validateRulesCmd)
+	rulesCmd.AddCommand(sigmaRulesCmd)
+	rulesCmd.AddCommand(profileRulesCmd)
+
+	RootCmd.AddCommand(rulesCmd)
+}
+
+// testRules runs the rule test cases and prints the outcome of each
+// test case. An error is returned if any of the test cases fails, so
+// the command can be used as a CI gate.
+func testRules(cmd *cobra.Command, args []string) error {
+	if err := common.Init(rulesConfig, false); err != nil {
+		return err
+	}
+
+	var passed, failed int
+	for _, path := range args {
+		tests, err := filter.LoadRuleTests(path)
+		if err != nil {
+			return err
+		}
+		for _, res := range filter.RunRuleTests(rulesConfig, tests) {
+			if res.Passed() {
+				passed++
+				fmt.Fprintf(os.Stdout, "PASS  %s\n", res.Test.Name)
+				continue
+			}
+			failed++
+			fmt.Fprintf(os.Stdout, "FAIL  %s\n", res.Test.Name)
+			if res.Err != nil {
+				fmt.Fprintf(os.Stdout, "      error: %v\n", res.Err)
+				continue
+			}
+			if len(res.Missing) > 0 {
+				fmt.Fprintf(os.Stdout, "      expected rules didn't fire: %s\n", strings.Join(res.Missing, ", "))
+			}
+			if len(res.Unexpected) > 0 {
+				fmt.Fprintf(os.Stdout, "      unexpected rules fired: %s\n", strings.Join(res.Unexpected, ", "))
+			}
+		}
+	}
+
+	fmt.Fprintf(os.Stdout, "\n%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d rule test(s) failed", failed)
+	}
+	return nil
+}
+
+// validateRules lints all rule groups and macros and prints the issues
+// either in text or JSON format. An error is returned if any issue with
+// the error severity is found.
+func validateRules(cmd *cobra.Command, args []string) error {
+	if validateOutput != "text" && validateOutput != "json" {
+		return fmt.Errorf("unsupported output format %q. Use text or json", validateOutput)
+	}
+	if err := common.Init(rulesConfig, false); err != nil {
+		return err
+	}
+
+	issues, err := filter.Lint(rulesConfig)
+	if err != nil {
+		return err
+	}
+
+	var errs, warns int
+	for _, issue := range issues {
+		switch issue.Severity {
+		case filter.LintError:
+			errs++
+		case filter.LintWarning:
+			warns++
+		}
+	}
+
+	switch validateOutput {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			return err
+		}
+	default:
+		for _, issue := range issues {
+			fmt.Fprintln(os.Stdout, issue)
+		}
+		fmt.Fprintf(os.Stdout, "\n%d error(s), %d warning(s)\n", errs, warns)
+	}
+
+	if errs > 0 {
+		return fmt.Errorf("rule validation failed with %d error(s)", errs)
+	}
+	return nil
+}
+
+// translateSigmaRules translates Sigma rules into rule groups. Rule groups
+// are written in YAML format, while the report listing translated rules and
+// constructs that couldn't be translated is printed to the standard error.
+func translateSigmaRules(cmd *cobra.Command, args []string) error {
+	translations := make([]sigma.Translation, 0)
+	for _, path := range args {
+		t, err := sigma.TranslateFile(path)
+		if err != nil {
+			return err
+		}
+		translations = append(translations, t...)
+	}
+
+	groups := make([]*config.FilterGroup, 0)
+	for _, t := range translations {
+		if t.Translated() {
+			groups = append(groups, t.Group)
+		}
+	}
+
+	fmt.Fprint(os.Stderr, sigma.Report(translations))
+	if len(groups) == 0 {
+		return fmt.Errorf("none of the Sigma rules could be translated")
+	}
+
+	b, err := yaml.Marshal(groups)
+	if err != nil {
+		return err
+	}
+	if sigmaOutputFile == "" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(sigmaOutputFile, b, 0644)
+}
+
+// profileRules fetches rule profiles from the API server of the running
+// instance and renders the table with the most expensive rules.
+func profileRules(cmd *cobra.Command, args []string) error {
+	rank, ok := profileRankers[profileSort]
+	if !ok {
+		return fmt.Errorf("unsupported sort attribute %q", profileSort)
+	}
+	if err := common.Init(rulesConfig, false); err != nil {
+		return err
+	}
+
+	c := rulesConfig.API
+	body, err := rest.Get(rest.WithTransport(c.Transport), rest.WithURI("rules/profile"))
+	if err != nil {
+		return kerrors.ErrHTTPServerUnavailable(c.Transport, err)
+	}
+	var profiles []filter.RuleProfile
+	if err := json.Unmarshal(body, &profiles); err != nil {
+		// the server responds with the plain
+		// error message if profiles are not
+		// available
+		return fmt.Errorf("%s", strings.TrimSpace(string(body)))
+	}
+	sort.SliceStable(profiles, func(i, j int) bool { return rank(profiles[i]) > rank(profiles[j]) })
+	if profileLimit > 0 && len(profiles) > profileLimit {
+		profiles = profiles[:profileLimit]
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Rule", "Group", "Evaluations", "Matches", "Total", "Avg", "P99", "Accessors", "Functions"})
+	t.SetStyle(table.StyleLight)
+	for _, p := range profiles {
+		t.AppendRow(table.Row{
+			p.Rule,
+			p.Group,
+			p.Evaluations,
+			p.Matches,
+			p.TotalTime,
+			p.AvgTime,
+			p.P99Time,
+			p.AccessorTime,
+			formatFunctionTime(p.FunctionTime),
+		})
+	}
+	t.Render()
+
+	return nil
+}
+
+// profileRankers maps the sort attributes to the rule profile values.
+var profileRankers = map[string]func(filter.RuleProfile) float64{
+	"total":       func(p filter.RuleProfile) float64 { return float64(p.TotalTime) },
+	"avg":         func(p filter.RuleProfile) float64 { return float64(p.AvgTime) },
+	"p99":         func(p filter.RuleProfile) float64 { return float64(p.P99Time) },
+	"accessors":   func(p filter.RuleProfile) float64 { return float64(p.AccessorTime) },
+	"evaluations": func(p filter.RuleProfile) float64 { return float64(p.Evaluations) },
+	"matches":     func(p filter.RuleProfile) float64 { return float64(p.Matches) },
+}
+
+// formatFunctionTime renders function call costs starting with the most expensive function.
+func formatFunctionTime(fns map[string]time.Duration) string {
+	names := make([]string, 0, len(fns))
+	for name := range fns {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return fns[names[i]] > fns[names[j]] })
+	costs := make([]string, len(names))
+	for i, name := range names {
+		costs[i] = fmt.Sprintf("%s %v", name, fns[name])
+	}
+	return strings.Join(costs, ", ")
+}
@@ -30,7 +30,9 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/config"
 	"github.com/rabbitstack/fibratus/pkg/filament"
 	"github.com/rabbitstack/fibratus/pkg/filter"
+	"github.com/rabbitstack/fibratus/pkg/filter/action"
 	"github.com/rabbitstack/fibratus/pkg/handle"
+	"github.com/rabbitstack/fibratus/pkg/kcap"
 	"github.com/rabbitstack/fibratus/pkg/kstream"
 	"github.com/rabbitstack/fibratus/pkg/ps"
 	"github.com/rabbitstack/fibratus/pkg/util/multierror"
@@ -89,6 +91,11 @@ func run(cmd *cobra.Command, args []string) error {
 	hsnap := handle.NewSnapshotter(cfg, nil)
 	psnap := ps.NewSnapshotter(hsnap, cfg)
 	kstreamc := kstream.NewConsumer(ktracec, psnap, hsnap, cfg)
+	// the recorder backs the capture rule action
+	// by taping into the live event stream
+	rec := kcap.NewRecorder(psnap, hsnap)
+	action.SetRecorder(rec)
+	kstreamc.SetEventTap(rec.Tap)
 	// build the filter from the CLI argument. If we got a valid expression the filter
 	// is linked to the kernel stream consumer so it can drop any events that don't match
 	// the filter criteria
@@ -143,9 +150,10 @@ func run(cmd *cobra.Command, args []string) error {
 			kstreamc.Events(),
 			kstreamc.Errors(),
 			cfg.Aggregator,
-			cfg.Output,
+			cfg.Outputs,
 			cfg.Transformers,
 			cfg.Alertsenders,
+			filter.NewOutputCompiler(cfg),
 		)
 		if err != nil {
 			return err
@@ -158,7 +166,7 @@ func run(cmd *cobra.Command, args []string) error {
 	}
 
 	// start the HTTP server
-	if err := api.StartServer(cfg); err != nil {
+	if err := api.StartServer(cfg, api.WithRulesReloader(kstreamc), api.WithRulesProfiler(kstreamc)); err != nil {
 		return err
 	}
 
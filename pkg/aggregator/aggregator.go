@@ -27,6 +27,7 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/alertsender"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/rabbitstack/fibratus/pkg/util/multierror"
 	log "github.com/sirupsen/logrus"
 
 	// initialize outputs
@@ -34,8 +35,11 @@ import (
 	_ "github.com/rabbitstack/fibratus/pkg/outputs/console"
 	_ "github.com/rabbitstack/fibratus/pkg/outputs/elasticsearch"
 	_ "github.com/rabbitstack/fibratus/pkg/outputs/eventlog"
+	_ "github.com/rabbitstack/fibratus/pkg/outputs/file"
 	_ "github.com/rabbitstack/fibratus/pkg/outputs/http"
+	_ "github.com/rabbitstack/fibratus/pkg/outputs/kafka"
 	_ "github.com/rabbitstack/fibratus/pkg/outputs/null"
+	_ "github.com/rabbitstack/fibratus/pkg/outputs/syslog"
 
 	// initialize alert senders
 	_ "github.com/rabbitstack/fibratus/pkg/alertsender/mail"
@@ -60,10 +64,15 @@ var (
 	transformerErrors = expvar.NewMap("aggregator.transformer.errors")
 	/// keventErrors is the number of kernel event errors
 	keventErrors = expvar.NewInt("aggregator.kevent.errors")
+	// outputEvents counts the number of events routed to each output
+	outputEvents = expvar.NewMap("aggregator.output.events")
+	// unroutedEvents counts the number of events that didn't match any of the output filters
+	unroutedEvents = expvar.NewInt("aggregator.unrouted.events")
 )
 
 // BufferedAggregator collects events from the inbound channel and produces batches on regular intervals. The batches
-// are pushed to the work queue from which load-balanced configured workers consume the batches and publish to the outputs.
+// are routed to the work queues of all outputs from which load-balanced configured workers consume the batches and
+// publish to the outputs.
 type BufferedAggregator struct {
 	kevtsc  chan *kevent.Kevent
 	errsc   chan error
@@ -71,41 +80,43 @@ type BufferedAggregator struct {
 	flusher *time.Ticker
 	// queue of inbound kernel events
 	kevts []*kevent.Kevent
-	// work queue that forwarder passes to outputs
-	wq         queue
-	submitter  *submitter
+	// submitters contains a submitter for each output
+	submitters []*submitter
 	transforms []transformers.Transformer
 	c          Config
 }
 
-// NewBuffered creates a new instance of the event aggregator.
+// NewBuffered creates a new instance of the event aggregator. The filter
+// compiler is used to compile filter expressions of the outputs.
 func NewBuffered(
 	kevents chan *kevent.Kevent,
 	errs chan error,
 	config Config,
-	outputConfig outputs.Config,
+	outputConfigs []outputs.Config,
 	transformerConfigs []transformers.Config,
 	alertsenderConfigs []alertsender.Config,
+	compiler FilterCompiler,
 ) (*BufferedAggregator, error) {
 	flushInterval := config.FlushPeriod
 	if flushInterval < time.Millisecond*250 {
 		flushInterval = time.Millisecond * 250
 	}
 	agg := &BufferedAggregator{
-		kevtsc:  kevents,
-		kevts:   make([]*kevent.Kevent, 0),
-		errsc:   errs,
-		stop:    make(chan struct{}, 1),
-		flusher: time.NewTicker(flushInterval),
-		wq:      make(chan *kevent.Batch),
-		c:       config,
+		kevtsc: kevents,
+		kevts:  make([]*kevent.Kevent, 0),
+		errsc:  errs,
+		stop:   make(chan struct{}, 1),
+		c:      config,
 	}
 
-	var err error
-	agg.submitter, err = newSubmitter(agg.wq, outputConfig)
-	if err != nil {
-		return nil, err
+	if len(outputConfigs) == 0 {
+		return nil, errors.New("no outputs configured")
 	}
+
+	// transformers and alert senders are loaded before
+	// output clients are initialized, so nothing has to
+	// be torn down if they fail to load
+	var err error
 	agg.transforms, err = transformers.LoadAll(transformerConfigs)
 	if err != nil {
 		return nil, err
@@ -116,6 +127,20 @@ func NewBuffered(
 		return nil, err
 	}
 
+	agg.submitters = make([]*submitter, 0, len(outputConfigs))
+	for _, outputConfig := range outputConfigs {
+		s, err := newSubmitter(outputConfig, config, compiler)
+		if err != nil {
+			// stop workers of already initialized outputs
+			agg.closeQueues()
+			return nil, multierror.Wrap(err, agg.shutdown())
+		}
+		// dropping batches only spares other outputs
+		s.drop = config.DropOnFull && len(outputConfigs) > 1
+		agg.submitters = append(agg.submitters, s)
+	}
+
+	agg.flusher = time.NewTicker(flushInterval)
 	go agg.run()
 
 	return agg, nil
@@ -126,31 +151,81 @@ func (agg *BufferedAggregator) Stop() error {
 	agg.stop <- struct{}{}
 
 	// flush enqueued events
-	b := kevent.NewBatch(agg.kevts...)
-	if b.Len() > 0 {
-		done := make(chan struct{}, 1)
-		go func() {
-			agg.wq <- b
-			done <- struct{}{}
-		}()
+	done := make(chan struct{}, 1)
+	go func() {
+		agg.dispatch(agg.kevts)
+		done <- struct{}{}
+	}()
 
-		select {
-		case <-done:
-			close(agg.wq)
-		case <-time.After(agg.c.FlushTimeout):
-			return errors.New("fail to flush events after stop timed out")
-		}
+	select {
+	case <-done:
+		agg.closeQueues()
+	case <-time.After(agg.c.FlushTimeout):
+		return errors.New("fail to flush events after stop timed out")
 	}
 
 	// sleep a bit before closing the clients
 	time.Sleep(time.Millisecond * 150)
 
-	err := agg.submitter.shutdown()
-	if err != nil {
-		return err
+	return agg.shutdown()
+}
+
+// closeQueues closes the work queues of all outputs.
+func (agg *BufferedAggregator) closeQueues() {
+	for _, s := range agg.submitters {
+		close(s.wq)
+	}
+}
+
+// shutdown closes the clients of all outputs.
+func (agg *BufferedAggregator) shutdown() error {
+	errs := make([]error, 0)
+	for _, s := range agg.submitters {
+		if err := s.shutdown(); err != nil {
+			errs = append(errs, err)
+		}
 	}
+	return multierror.Wrap(errs...)
+}
 
-	return nil
+// dispatch routes events to outputs. Each output receives the batch of events
+// that match the output filter. Events routed to several outputs are copied, so
+// every output can transform and release the events independently. Events that
+// are not routed to any output are returned to the pool.
+func (agg *BufferedAggregator) dispatch(kevts []*kevent.Kevent) {
+	batches := make([][]*kevent.Kevent, len(agg.submitters))
+	targets := make([]int, 0, len(agg.submitters))
+	for _, kevt := range kevts {
+		targets = targets[:0]
+		for i, s := range agg.submitters {
+			if s.accepts(kevt) {
+				targets = append(targets, i)
+			}
+		}
+		if len(targets) == 0 {
+			unroutedEvents.Add(1)
+			kevt.Release()
+			continue
+		}
+		for n, i := range targets {
+			// the last output takes over the original
+			// event once all the copies have been made
+			e := kevt
+			if n < len(targets)-1 {
+				e = kevt.Copy()
+			}
+			agg.submitters[i].transform(e)
+			batches[i] = append(batches[i], e)
+		}
+	}
+	for i, s := range agg.submitters {
+		if len(batches[i]) == 0 {
+			continue
+		}
+		b := kevent.NewBatch(batches[i]...)
+		outputEvents.Add(s.name, b.Len())
+		s.submit(b)
+	}
 }
 
 // run starts the aggregator loop. The aggregator receives kernel event stream from the upstream channel, buffers
@@ -165,27 +240,14 @@ func (agg *BufferedAggregator) run() {
 			if len(agg.kevts) == 0 {
 				continue
 			}
-			b := kevent.NewBatch(agg.kevts...)
-			l := b.Len()
-			batchEvents.Add(l)
-			// push the batch to the work queue
-			if l > 0 {
-				agg.wq <- b
-			}
+			batchEvents.Add(int64(len(agg.kevts)))
+			// route the batch to output work queues
+			agg.dispatch(agg.kevts)
 			flushesCount.Add(1)
 			// clear the queue
 			agg.kevts = nil
 		case kevt := <-agg.kevtsc:
-			for _, transformer := range agg.transforms {
-				if transformer == nil {
-					continue
-				}
-				err := transformer.Transform(kevt)
-				if err != nil {
-					log.Warnf("transformer error occurred: %v", err)
-					transformerErrors.Add(err.Error(), 1)
-				}
-			}
+			transform(agg.transforms, kevt)
 			// push the event to the queue
 			agg.kevts = append(agg.kevts, kevt)
 			keventsDequeued.Add(1)
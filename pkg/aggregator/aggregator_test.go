@@ -19,6 +19,12 @@
 package aggregator
 
 import (
+	"errors"
+	"expvar"
+	"sync"
+
+	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers/remove"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
 	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
@@ -38,7 +44,8 @@ func TestNewBufferedAggregator(t *testing.T) {
 		keventsc,
 		errsc,
 		Config{FlushPeriod: time.Millisecond * 200},
-		outputs.Config{Type: outputs.Console, Output: console.Config{Format: "pretty"}},
+		[]outputs.Config{{Type: outputs.Console, Output: console.Config{Format: "pretty"}, Name: "console"}},
+		nil,
 		nil,
 		nil,
 	)
@@ -81,3 +88,190 @@ func TestNewBufferedAggregator(t *testing.T) {
 	assert.Equal(t, int64(6), batchEvents.Value())
 	assert.Equal(t, int64(2), flushesCount.Value())
 }
+
+// sink is the output type that collects published events
+const sink outputs.Type = 200
+
+type sinkClient struct {
+	mu    sync.Mutex
+	kevts []*kevent.Kevent
+}
+
+func (c *sinkClient) Connect() error { return nil }
+func (c *sinkClient) Close() error   { return nil }
+func (c *sinkClient) Publish(b *kevent.Batch) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.kevts = append(c.kevts, b.Events...)
+	return nil
+}
+
+func (c *sinkClient) events() []*kevent.Kevent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.kevts
+}
+
+type categoryFilter ktypes.Category
+
+func (f categoryFilter) Run(kevt *kevent.Kevent) bool { return kevt.Category == ktypes.Category(f) }
+
+func TestOutputRouting(t *testing.T) {
+	clients := map[string]*sinkClient{"all": {}, "net": {}, "file": {}}
+	outputs.Register(sink, func(config outputs.Config) (outputs.OutputGroup, error) {
+		return outputs.Success(clients[config.Name]), nil
+	})
+
+	compiler := func(expr string) (Filter, error) { return categoryFilter(expr), nil }
+
+	keventsc := make(chan *kevent.Kevent, 20)
+	errsc := make(chan error, 1)
+	agg, err := NewBuffered(
+		keventsc,
+		errsc,
+		Config{FlushPeriod: time.Millisecond * 250, FlushTimeout: time.Second},
+		[]outputs.Config{
+			{Type: sink, Name: "all"},
+			{Type: sink, Name: "net", Filter: string(ktypes.Net), Transformers: []transformers.Config{
+				{Type: transformers.Remove, Transformer: remove.Config{Kparams: []string{kparams.NetSIP}}},
+			}},
+			{Type: sink, Name: "file", Filter: string(ktypes.File)},
+		},
+		nil,
+		nil,
+		compiler,
+	)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		keventsc <- &kevent.Kevent{
+			Type:     ktypes.SendTCPv4,
+			Category: ktypes.Net,
+			Seq:      uint64(i),
+			Kparams: kevent.Kparams{
+				kparams.NetDport: {Name: kparams.NetDport, Type: kparams.Uint16, Value: uint16(443)},
+				kparams.NetSIP:   {Name: kparams.NetSIP, Type: kparams.IPv4, Value: net.ParseIP("127.0.0.1")},
+			},
+			Metadata: make(map[kevent.MetadataKey]any),
+		}
+	}
+	keventsc <- &kevent.Kevent{
+		Type:     ktypes.CreateThread,
+		Category: ktypes.Thread,
+		Seq:      3,
+		Kparams:  kevent.Kparams{},
+		Metadata: make(map[kevent.MetadataKey]any),
+	}
+
+	<-time.After(time.Millisecond * 400)
+	require.NoError(t, agg.Stop())
+
+	assert.Len(t, clients["all"].events(), 4)
+	assert.Len(t, clients["net"].events(), 3)
+	assert.Len(t, clients["file"].events(), 0)
+
+	// the transformer of the net output doesn't
+	// alter events published to other outputs
+	for _, kevt := range clients["net"].events() {
+		assert.False(t, kevt.Kparams.Contains(kparams.NetSIP))
+	}
+	for _, kevt := range clients["all"].events()[:3] {
+		assert.True(t, kevt.Kparams.Contains(kparams.NetSIP))
+	}
+}
+
+func TestInvalidOutputFilter(t *testing.T) {
+	_, err := NewBuffered(
+		make(chan *kevent.Kevent),
+		make(chan error),
+		Config{},
+		[]outputs.Config{{Type: outputs.Null, Name: "alerts", Filter: "kevt.name ="}},
+		nil,
+		nil,
+		func(expr string) (Filter, error) { return nil, errors.New("bad filter") },
+	)
+	require.EqualError(t, err, `invalid "alerts" output filter: bad filter`)
+}
+
+// closer is the output type that records whether its client is closed
+const closer outputs.Type = 201
+
+type closerClient struct {
+	sinkClient
+	closed chan struct{}
+}
+
+func (c *closerClient) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestNewBufferedCleanupOnError(t *testing.T) {
+	client := &closerClient{closed: make(chan struct{})}
+	outputs.Register(closer, func(config outputs.Config) (outputs.OutputGroup, error) {
+		return outputs.Success(client), nil
+	})
+
+	_, err := NewBuffered(
+		make(chan *kevent.Kevent),
+		make(chan error),
+		Config{},
+		[]outputs.Config{
+			{Type: closer, Name: "all"},
+			{Type: outputs.Null, Name: "alerts", Filter: "kevt.name ="},
+		},
+		nil,
+		nil,
+		func(expr string) (Filter, error) { return nil, errors.New("bad filter") },
+	)
+	require.EqualError(t, err, `invalid "alerts" output filter: bad filter`)
+
+	// clients of the outputs initialized before
+	// the failing output are closed
+	select {
+	case <-client.closed:
+	case <-time.After(time.Second):
+		t.Fatal("output client should be closed")
+	}
+}
+
+func TestSubmitFullQueue(t *testing.T) {
+	s := &submitter{name: "slow", wq: make(queue, 1), drop: true}
+	before := droppedEvents.Get("slow")
+
+	s.submit(kevent.NewBatch(&kevent.Kevent{Seq: 1}))
+	// the queue is full, so the batch is dropped instead of
+	// blocking the dispatch to other outputs
+	s.submit(kevent.NewBatch(&kevent.Kevent{Seq: 2}, &kevent.Kevent{Seq: 3}))
+
+	require.Len(t, s.wq, 1)
+	assert.Equal(t, uint64(1), (<-s.wq).Events[0].Seq)
+	require.NotNil(t, droppedEvents.Get("slow"))
+	var n int64
+	if before != nil {
+		n = before.(*expvar.Int).Value()
+	}
+	assert.Equal(t, n+2, droppedEvents.Get("slow").(*expvar.Int).Value())
+}
+
+func TestSubmitBlocksOnFullQueue(t *testing.T) {
+	s := &submitter{name: "sole", wq: make(queue, 1)}
+
+	s.submit(kevent.NewBatch(&kevent.Kevent{Seq: 1}))
+	done := make(chan struct{})
+	go func() {
+		s.submit(kevent.NewBatch(&kevent.Kevent{Seq: 2}))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("submit should block while the queue is full")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	assert.Equal(t, uint64(1), (<-s.wq).Events[0].Seq)
+	<-done
+	assert.Equal(t, uint64(2), (<-s.wq).Events[0].Seq)
+	assert.Nil(t, droppedEvents.Get("sole"))
+}
@@ -21,12 +21,24 @@ package aggregator
 import (
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
 	"time"
 )
 
+// defaultQueueSize is the default capacity of the output work queue
+const defaultQueueSize = 100
+
 const (
 	flushPeriod  = "aggregator.flush-period"
 	flushTimeout = "aggregator.flush-timeout"
+	queueSize    = "aggregator.queue-size"
+	dropOnFull   = "aggregator.drop-on-full"
+
+	spoolEnabled = "aggregator.spool.enabled"
+	spoolDir     = "aggregator.spool.dir"
+	spoolMaxSize = "aggregator.spool.max-size"
+	spoolSync    = "aggregator.spool.sync-interval"
 )
 
 // Config contains aggregator-specific configuration tweaks.
@@ -35,16 +47,54 @@ type Config struct {
 	FlushPeriod time.Duration `json:"aggregator.flush-period" yaml:"aggregator.flush-period"`
 	// FlushTimeout represents the max time to wait before announcing failed flushing of enqueued events
 	FlushTimeout time.Duration `json:"aggregator.flush-timeout" yaml:"aggregator.flush-timeout"`
+	// QueueSize is the maximum number of batches buffered in the work queue of each output.
+	QueueSize int `json:"aggregator.queue-size" yaml:"aggregator.queue-size"`
+	// DropOnFull indicates if batches are dropped when the work queue of the output is full, so
+	// the slow output doesn't stall other outputs. By default, the dispatch blocks until the work
+	// queue has room for the batch. It has no effect if only one output is configured.
+	DropOnFull bool `json:"aggregator.drop-on-full" yaml:"aggregator.drop-on-full"`
+	// Spool contains the settings of the disk-backed output queue.
+	Spool SpoolConfig `json:"aggregator.spool" yaml:"aggregator.spool"`
+}
+
+// SpoolConfig determines the behaviour of the disk spool. When enabled,
+// batches are written to the spool before they are published to the
+// output, so they aren't lost if the output sink is unavailable or the
+// agent is restarted.
+type SpoolConfig struct {
+	// Enabled indicates if batches are spooled on disk.
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Dir is the directory where spool files are stored. Each output client
+	// gets a dedicated subdirectory.
+	Dir string `json:"dir" yaml:"dir"`
+	// MaxSize is the maximum size of the spool in megabytes for each output
+	// client. The oldest batches are evicted when the spool exceeds this size.
+	MaxSize int `json:"max-size" yaml:"max-size"`
+	// SyncInterval is the period for flushing appended batches to disk. If zero,
+	// each batch is flushed to disk as soon as it is appended to the spool.
+	SyncInterval time.Duration `json:"sync-interval" yaml:"sync-interval"`
 }
 
 // AddFlags registers persistent aggregator flags.
 func AddFlags(flags *pflag.FlagSet) {
 	flags.Duration(flushPeriod, time.Millisecond*200, "Determines the period for flushing batches to outputs")
 	flags.Duration(flushTimeout, time.Second*4, "Represents the max time to wait before announcing failed flushing of enqueued events on aggregator shutdown")
+	flags.Int(queueSize, defaultQueueSize, "Specifies the maximum number of batches buffered in the work queue of each output")
+	flags.Bool(dropOnFull, false, "Indicates if batches are dropped when the output work queue is full instead of blocking the dispatch to other outputs")
+	flags.Bool(spoolEnabled, false, "Indicates if batches are spooled on disk before they are published to outputs")
+	flags.String(spoolDir, filepath.Join(os.Getenv("PROGRAMFILES"), "fibratus", "spool"), "Specifies the directory where output spool files are stored")
+	flags.Int(spoolMaxSize, 1024, "Specifies the maximum size in megabytes of the spool for each output client")
+	flags.Duration(spoolSync, time.Second, "Specifies the period for flushing spooled batches to disk. If zero, batches are flushed as soon as they are spooled")
 }
 
 // InitFromViper initializes aggregator flags from viper.
 func (c *Config) InitFromViper(v *viper.Viper) {
 	c.FlushPeriod = v.GetDuration(flushPeriod)
 	c.FlushTimeout = v.GetDuration(flushTimeout)
+	c.QueueSize = v.GetInt(queueSize)
+	c.DropOnFull = v.GetBool(dropOnFull)
+	c.Spool.Enabled = v.GetBool(spoolEnabled)
+	c.Spool.Dir = v.GetString(spoolDir)
+	c.Spool.MaxSize = v.GetInt(spoolMaxSize)
+	c.Spool.SyncInterval = v.GetDuration(spoolSync)
 }
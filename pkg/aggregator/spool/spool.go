@@ -0,0 +1,601 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package spool implements a disk-backed FIFO queue of opaque records. The
+// spool is laid out as a sequence of append-only segment files. Records are
+// consumed from the oldest segment, and the position of the consumer is
+// persisted in the cursor file, so the unconsumed records survive restarts.
+// When the spool outgrows its size cap, the oldest segments are evicted.
+package spool
+
+import (
+	"encoding/binary"
+	"errors"
+	"expvar"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/util/multierror"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// segmentExt is the extension of segment files
+	segmentExt = ".spool"
+	// cursorFile stores the sequence and offset of the next unconsumed record
+	cursorFile = "cursor"
+	// headerSize is the size of the record header: length, checksum and timestamp
+	headerSize = 16
+	// maxSegmentSize is the upper bound of the segment file size
+	maxSegmentSize = 16 * 1024 * 1024
+)
+
+// ErrRecordTooLarge is returned when the record can't fit in the spool
+var ErrRecordTooLarge = errors.New("record exceeds the spool size")
+
+var (
+	evictedRecords   = expvar.NewInt("aggregator.spool.evicted.records")
+	corruptedRecords = expvar.NewInt("aggregator.spool.corrupted.records")
+	appendErrors     = expvar.NewInt("aggregator.spool.append.errors")
+
+	mu     sync.Mutex
+	spools = make(map[string]*Spool)
+)
+
+func init() {
+	stat := func(fn func(s *Spool) any) expvar.Func {
+		return func() any {
+			mu.Lock()
+			defer mu.Unlock()
+			m := make(map[string]any, len(spools))
+			for name, s := range spools {
+				m[name] = fn(s)
+			}
+			return m
+		}
+	}
+	expvar.Publish("aggregator.spool.depth", stat(func(s *Spool) any { return s.Len() }))
+	expvar.Publish("aggregator.spool.bytes", stat(func(s *Spool) any { return s.Size() }))
+	expvar.Publish("aggregator.spool.age.seconds", stat(func(s *Spool) any { return s.Age().Seconds() }))
+}
+
+// segment represents a single segment file
+type segment struct {
+	seq     uint64
+	size    int64
+	records int // number of unconsumed records
+}
+
+type record struct {
+	data []byte
+	ts   time.Time
+	size int64
+}
+
+// Spool is the disk-backed queue of records.
+type Spool struct {
+	sync.Mutex
+	name    string
+	dir     string
+	maxSize int64
+	segSize int64
+
+	segs  []*segment
+	w     *os.File // writer of the newest segment
+	r     *os.File // reader of the oldest segment
+	off   int64    // offset of the next record in the oldest segment
+	next  *record  // peeked record waiting for acknowledgment
+	size  int64
+	count int
+	// oldest is the append time of the oldest record
+	oldest time.Time
+
+	// syncInterval is the period for syncing the newest segment to disk
+	syncInterval time.Duration
+	dirty        bool // the newest segment has unsynced records
+	quit         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Open opens the spool in the given directory, creating the directory if it
+// doesn't exist. The records left in the spool by the previous run are kept
+// for consumption. The name identifies the spool in the metrics. Appended
+// records are synced to disk every sync interval, or after each append if
+// the sync interval is zero.
+func Open(name, dir string, maxSize int64, syncInterval time.Duration) (*Spool, error) {
+	if maxSize <= headerSize {
+		return nil, fmt.Errorf("invalid %q spool size: %d", name, maxSize)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	s := &Spool{
+		name:    name,
+		dir:     dir,
+		maxSize: maxSize,
+		segSize: maxSize / 4,
+
+		syncInterval: syncInterval,
+		quit:         make(chan struct{}),
+	}
+	if s.segSize > maxSegmentSize {
+		s.segSize = maxSegmentSize
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	spools[name] = s
+	mu.Unlock()
+	if syncInterval > 0 {
+		s.wg.Add(1)
+		go s.syncer()
+	}
+	return s, nil
+}
+
+// syncer periodically syncs the records appended since the last sync.
+func (s *Spool) syncer() {
+	defer s.wg.Done()
+	tick := time.NewTicker(s.syncInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			s.Lock()
+			if err := s.sync(); err != nil {
+				log.Warnf("unable to sync %q spool: %v", s.name, err)
+			}
+			s.Unlock()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// sync flushes the newest segment to disk if it has unsynced records.
+func (s *Spool) sync() error {
+	if s.w == nil || !s.dirty {
+		return nil
+	}
+	s.dirty = false
+	return s.w.Sync()
+}
+
+// load scans the segment files and restores the consumer position.
+func (s *Spool) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	seqs := make([]uint64, 0)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	cseq, coff := s.readCursor()
+	for _, seq := range seqs {
+		// segments behind the cursor were fully consumed
+		if seq < cseq {
+			_ = os.Remove(s.segmentPath(seq))
+			continue
+		}
+		var off int64
+		if seq == cseq {
+			off = coff
+		}
+		seg, off, err := s.scan(seq, off)
+		if err != nil {
+			return err
+		}
+		if len(s.segs) == 0 {
+			s.off = off
+		}
+		s.segs = append(s.segs, seg)
+		s.size += seg.size
+		s.count += seg.records
+	}
+	if len(s.segs) == 0 {
+		s.segs = append(s.segs, &segment{seq: cseq})
+		s.w, err = os.OpenFile(s.segmentPath(cseq), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	} else {
+		s.size -= s.off
+		tail := s.segs[len(s.segs)-1]
+		s.w, err = os.OpenFile(s.segmentPath(tail.seq), os.O_WRONLY|os.O_APPEND, 0o600)
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.writeCursor(); err != nil {
+		return err
+	}
+	s.readOldest()
+	return nil
+}
+
+// scan counts the valid records of the segment starting at the given offset.
+// The segment is truncated at the first torn or corrupted record.
+func (s *Spool) scan(seq uint64, start int64) (*segment, int64, error) {
+	f, err := os.OpenFile(s.segmentPath(seq), os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	seg := &segment{seq: seq, size: fi.Size()}
+	if start > seg.size {
+		start = seg.size
+	}
+	off := start
+	for off < seg.size {
+		rec, err := s.readRecord(f, off)
+		if err != nil {
+			log.Warnf("truncating %s spool segment at offset %d: %v", s.segmentPath(seq), off, err)
+			corruptedRecords.Add(1)
+			if err := f.Truncate(off); err != nil {
+				return nil, 0, err
+			}
+			seg.size = off
+			break
+		}
+		off += rec.size
+		seg.records++
+	}
+	return seg, start, nil
+}
+
+// Append writes the record at the end of the spool. If the size cap is
+// exceeded, the oldest segments are evicted along with their records.
+func (s *Spool) Append(b []byte) error {
+	size := int64(headerSize + len(b))
+	if size > s.maxSize {
+		return ErrRecordTooLarge
+	}
+	buf := make([]byte, size)
+	binary.LittleEndian.PutUint32(buf[0:], uint32(len(b)))
+	binary.LittleEndian.PutUint32(buf[4:], crc32.ChecksumIEEE(b))
+	ts := time.Now()
+	binary.LittleEndian.PutUint64(buf[8:], uint64(ts.UnixNano()))
+	copy(buf[headerSize:], b)
+
+	s.Lock()
+	defer s.Unlock()
+	if s.w == nil {
+		return os.ErrClosed
+	}
+	tail := s.segs[len(s.segs)-1]
+	if tail.size > 0 && tail.size+size > s.segSize {
+		if err := s.rotate(); err != nil {
+			appendErrors.Add(1)
+			return err
+		}
+		tail = s.segs[len(s.segs)-1]
+	}
+	if _, err := s.w.Write(buf); err != nil {
+		appendErrors.Add(1)
+		return err
+	}
+	s.dirty = true
+	if s.syncInterval <= 0 {
+		if err := s.sync(); err != nil {
+			appendErrors.Add(1)
+			return err
+		}
+	}
+	if s.count == 0 {
+		s.oldest = ts
+	}
+	tail.size += size
+	tail.records++
+	s.size += size
+	s.count++
+
+	var evicted bool
+	for s.size > s.maxSize && len(s.segs) > 1 {
+		evictedRecords.Add(int64(s.segs[0].records))
+		s.count -= s.segs[0].records
+		s.removeHead()
+		evicted = true
+	}
+	if evicted {
+		s.readOldest()
+	}
+	return nil
+}
+
+// Peek returns the oldest record along with the time it was appended
+// without removing it from the spool. io.EOF is returned if the spool
+// is empty. The record is removed from the spool by calling Ack.
+func (s *Spool) Peek() ([]byte, time.Time, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.peek(); err != nil {
+		return nil, time.Time{}, err
+	}
+	return s.next.data, s.next.ts, nil
+}
+
+func (s *Spool) peek() error {
+	if s.next != nil {
+		return nil
+	}
+	if s.count == 0 {
+		return io.EOF
+	}
+	head := s.segs[0]
+	if s.r == nil {
+		f, err := os.Open(s.segmentPath(head.seq))
+		if err != nil {
+			return err
+		}
+		s.r = f
+	}
+	rec, err := s.readRecord(s.r, s.off)
+	if err != nil {
+		// the rest of the segment can't be trusted, so skip
+		// its records and carry on with the next segment
+		log.Warnf("discarding %d records from %s spool segment: %v", head.records, s.segmentPath(head.seq), err)
+		corruptedRecords.Add(int64(head.records))
+		s.count -= head.records
+		head.records = 0
+		if len(s.segs) > 1 {
+			s.removeHead()
+		} else {
+			s.size -= head.size - s.off
+			s.off = head.size
+		}
+		return s.peek()
+	}
+	s.next = rec
+	s.oldest = rec.ts
+	return nil
+}
+
+// readOldest reads the append time of the oldest record. The record
+// isn't peeked, so the record that was peeked before it got evicted
+// is never mistaken for the oldest record when it is acknowledged.
+func (s *Spool) readOldest() {
+	s.oldest = time.Time{}
+	if s.next != nil {
+		s.oldest = s.next.ts
+		return
+	}
+	if s.count == 0 {
+		return
+	}
+	if s.r == nil {
+		f, err := os.Open(s.segmentPath(s.segs[0].seq))
+		if err != nil {
+			return
+		}
+		s.r = f
+	}
+	hdr := make([]byte, headerSize)
+	if _, err := s.r.ReadAt(hdr, s.off); err != nil {
+		return
+	}
+	s.oldest = time.Unix(0, int64(binary.LittleEndian.Uint64(hdr[8:])))
+}
+
+// Ack removes the record returned by the last Peek call from the spool.
+// It is a no-op if the record was evicted in the meantime.
+func (s *Spool) Ack() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.next == nil {
+		return nil
+	}
+	head := s.segs[0]
+	s.off += s.next.size
+	s.size -= s.next.size
+	head.records--
+	s.count--
+	s.next = nil
+	if head.records == 0 && len(s.segs) > 1 {
+		s.removeHead()
+	}
+	if err := s.writeCursor(); err != nil {
+		return err
+	}
+	s.readOldest()
+	return nil
+}
+
+// Len returns the number of records in the spool.
+func (s *Spool) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.count
+}
+
+// Size returns the number of bytes occupied by the records in the spool.
+func (s *Spool) Size() int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.size
+}
+
+// Age returns the age of the oldest record in the spool. The append time
+// of the oldest record is tracked as records are appended and consumed,
+// so Age never touches the segment files.
+func (s *Spool) Age() time.Duration {
+	s.Lock()
+	defer s.Unlock()
+	if s.count == 0 || s.oldest.IsZero() {
+		return 0
+	}
+	return time.Since(s.oldest)
+}
+
+// Close closes the spool. The records remain on disk and are available
+// the next time the spool is opened.
+func (s *Spool) Close() error {
+	mu.Lock()
+	if spools[s.name] == s {
+		delete(spools, s.name)
+	}
+	mu.Unlock()
+	s.Lock()
+	if s.w != nil {
+		close(s.quit)
+	}
+	s.Unlock()
+	s.wg.Wait()
+
+	s.Lock()
+	defer s.Unlock()
+	if s.r != nil {
+		_ = s.r.Close()
+		s.r = nil
+	}
+	if s.w == nil {
+		return nil
+	}
+	errs := make([]error, 0)
+	if err := s.sync(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.w.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	s.w = nil
+	return multierror.Wrap(errs...)
+}
+
+// rotate starts a new segment. The current segment is synced to disk
+// before it is closed, and removed if all of its records were consumed.
+func (s *Spool) rotate() error {
+	if err := s.sync(); err != nil {
+		return err
+	}
+	seq := s.segs[len(s.segs)-1].seq + 1
+	f, err := os.OpenFile(s.segmentPath(seq), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if s.w != nil {
+		_ = s.w.Close()
+	}
+	s.w = f
+	s.segs = append(s.segs, &segment{seq: seq})
+	if len(s.segs) > 1 && s.segs[0].records == 0 {
+		s.removeHead()
+	}
+	return nil
+}
+
+// removeHead deletes the oldest segment and moves the reader to the next one.
+func (s *Spool) removeHead() {
+	head := s.segs[0]
+	if s.r != nil {
+		_ = s.r.Close()
+		s.r = nil
+	}
+	s.size -= head.size - s.off
+	s.segs = s.segs[1:]
+	s.off = 0
+	s.next = nil
+	if err := os.Remove(s.segmentPath(head.seq)); err != nil {
+		log.Warnf("unable to remove spool segment: %v", err)
+	}
+	if err := s.writeCursor(); err != nil {
+		log.Warnf("unable to write spool cursor: %v", err)
+	}
+}
+
+func (s *Spool) segmentPath(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+func (s *Spool) readCursor() (uint64, int64) {
+	b, err := os.ReadFile(filepath.Join(s.dir, cursorFile))
+	if err != nil || len(b) != 16 {
+		return 0, 0
+	}
+	return binary.LittleEndian.Uint64(b), int64(binary.LittleEndian.Uint64(b[8:]))
+}
+
+// writeCursor persists the consumer position. The cursor is first written
+// and synced to the temporary file which then replaces the cursor file, so
+// the cursor is never left torn if the agent crashes in the middle of writing.
+func (s *Spool) writeCursor() error {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint64(b, s.segs[0].seq)
+	binary.LittleEndian.PutUint64(b[8:], uint64(s.off))
+	path := filepath.Join(s.dir, cursorFile)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readRecord reads and verifies the record at the given offset.
+func (s *Spool) readRecord(f *os.File, off int64) (*record, error) {
+	hdr := make([]byte, headerSize)
+	if _, err := f.ReadAt(hdr, off); err != nil {
+		return nil, fmt.Errorf("unable to read record header: %v", err)
+	}
+	l := binary.LittleEndian.Uint32(hdr)
+	if int64(l)+headerSize > s.maxSize {
+		return nil, fmt.Errorf("invalid record length: %d", l)
+	}
+	data := make([]byte, l)
+	if _, err := f.ReadAt(data, off+headerSize); err != nil {
+		return nil, fmt.Errorf("unable to read record: %v", err)
+	}
+	if crc32.ChecksumIEEE(data) != binary.LittleEndian.Uint32(hdr[4:]) {
+		return nil, errors.New("record checksum mismatch")
+	}
+	return &record{
+		data: data,
+		ts:   time.Unix(0, int64(binary.LittleEndian.Uint64(hdr[8:]))),
+		size: int64(headerSize) + int64(l),
+	}, nil
+}
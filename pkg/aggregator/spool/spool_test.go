@@ -0,0 +1,230 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spool
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendPeekAck(t *testing.T) {
+	s, err := Open("test", t.TempDir(), 1024*1024, 0)
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, _, err = s.Peek()
+	require.Equal(t, io.EOF, err)
+	assert.Equal(t, time.Duration(0), s.Age())
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.Append([]byte(fmt.Sprintf("record-%d", i))))
+	}
+	assert.Equal(t, 10, s.Len())
+	assert.True(t, s.Size() > 0)
+
+	for i := 0; i < 10; i++ {
+		b, ts, err := s.Peek()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("record-%d", i), string(b))
+		assert.False(t, ts.IsZero())
+		// peeking again yields the same record until it is acknowledged
+		b, _, err = s.Peek()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("record-%d", i), string(b))
+		require.NoError(t, s.Ack())
+	}
+
+	assert.Equal(t, 0, s.Len())
+	assert.Equal(t, int64(0), s.Size())
+	_, _, err = s.Peek()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open("test", dir, 2048, 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, s.Append([]byte(fmt.Sprintf("record-%d", i))))
+	}
+	for i := 0; i < 5; i++ {
+		_, _, err := s.Peek()
+		require.NoError(t, err)
+		require.NoError(t, s.Ack())
+	}
+	require.NoError(t, s.Close())
+
+	s, err = Open("test", dir, 2048, 0)
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t, 15, s.Len())
+
+	for i := 5; i < 20; i++ {
+		b, _, err := s.Peek()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("record-%d", i), string(b))
+		require.NoError(t, s.Ack())
+	}
+	require.NoError(t, s.Append([]byte("record-20")))
+	b, _, err := s.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, "record-20", string(b))
+}
+
+func TestEviction(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open("test", dir, 1024, 0)
+	require.NoError(t, err)
+	defer s.Close()
+
+	rec := make([]byte, 48)
+	for i := 0; i < 100; i++ {
+		copy(rec, fmt.Sprintf("record-%03d", i))
+		require.NoError(t, s.Append(rec))
+	}
+	assert.True(t, s.Size() <= 1024)
+	assert.True(t, s.Len() < 100)
+
+	// the newest records are retained in order
+	first := 100 - s.Len()
+	for i := first; i < 100; i++ {
+		b, _, err := s.Peek()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("record-%03d", i), string(b[:10]))
+		require.NoError(t, s.Ack())
+	}
+
+	require.Equal(t, ErrRecordTooLarge, s.Append(make([]byte, 2048)))
+}
+
+func TestTornTail(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open("test", dir, 1024*1024, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Append([]byte("record-0")))
+	require.NoError(t, s.Append([]byte("record-1")))
+	require.NoError(t, s.Close())
+
+	// simulate the crash in the middle of writing the record
+	f, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("%020d%s", 0, segmentExt)), os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0x20, 0x0, 0x0, 0x0, 0xa, 0xb})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err = Open("test", dir, 1024*1024, 0)
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t, 2, s.Len())
+
+	require.NoError(t, s.Append([]byte("record-2")))
+	for i := 0; i < 3; i++ {
+		b, _, err := s.Peek()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("record-%d", i), string(b))
+		require.NoError(t, s.Ack())
+	}
+}
+
+func TestPeriodicSync(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open("test", dir, 1024*1024, time.Millisecond*10)
+	require.NoError(t, err)
+	require.NoError(t, s.Append([]byte("record-0")))
+
+	// the syncer flushes the appended record
+	require.Eventually(t, func() bool {
+		s.Lock()
+		defer s.Unlock()
+		return !s.dirty
+	}, time.Second, time.Millisecond*5)
+
+	require.NoError(t, s.Append([]byte("record-1")))
+	require.NoError(t, s.Close())
+	// closing the spool twice is safe
+	require.NoError(t, s.Close())
+
+	s, err = Open("test", dir, 1024*1024, time.Millisecond*10)
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t, 2, s.Len())
+}
+
+func TestAge(t *testing.T) {
+	s, err := Open("test", t.TempDir(), 1024*1024, 0)
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Append([]byte("record-0")))
+	time.Sleep(time.Millisecond * 50)
+	require.NoError(t, s.Append([]byte("record-1")))
+
+	// the age doesn't require reading the segment
+	assert.True(t, s.Age() >= time.Millisecond*50)
+	assert.Nil(t, s.r)
+	assert.Nil(t, s.next)
+
+	_, _, err = s.Peek()
+	require.NoError(t, err)
+	require.NoError(t, s.Ack())
+	assert.True(t, s.Age() < time.Millisecond*50)
+
+	_, _, err = s.Peek()
+	require.NoError(t, err)
+	require.NoError(t, s.Ack())
+	assert.Equal(t, time.Duration(0), s.Age())
+}
+
+func TestAckEvicted(t *testing.T) {
+	s, err := Open("test", t.TempDir(), 1024, 0)
+	require.NoError(t, err)
+	defer s.Close()
+
+	rec := make([]byte, 48)
+	copy(rec, "record-000")
+	require.NoError(t, s.Append(rec))
+	b, _, err := s.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, "record-000", string(b[:10]))
+
+	// the peeked record is evicted while it is being published
+	n := 1
+	for ; s.Len() == n; n++ {
+		copy(rec, fmt.Sprintf("record-%03d", n))
+		require.NoError(t, s.Append(rec))
+	}
+	l := s.Len()
+	first := n - l
+
+	// acknowledging the evicted record doesn't
+	// remove the new head from the spool
+	require.NoError(t, s.Ack())
+	require.Equal(t, l, s.Len())
+	b, _, err = s.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("record-%03d", first), string(b[:10]))
+}
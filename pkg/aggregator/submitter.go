@@ -19,39 +19,141 @@
 package aggregator
 
 import (
+	"expvar"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/rabbitstack/fibratus/pkg/aggregator/spool"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/rabbitstack/fibratus/pkg/util/multierror"
+	log "github.com/sirupsen/logrus"
 )
 
 // queue defines the type alias for the batch worker queue
 type queue chan *kevent.Batch
 
-// submitter initializes a group of load balanced output producers.
+// droppedEvents counts the number of events dropped because the work queue of the output was full
+var droppedEvents = expvar.NewMap("aggregator.output.dropped.events")
+
+// Filter decides whether the event is routed to the output.
+type Filter interface {
+	Run(kevt *kevent.Kevent) bool
+}
+
+// FilterCompiler compiles the filter expression of the output.
+type FilterCompiler func(expr string) (Filter, error)
+
+// submitter initializes a group of load balanced output producers. Each
+// submitter has a dedicated bounded work queue and selects the events that
+// are routed to the output through the optional filter.
 type submitter struct {
-	wq      queue
-	workers []*worker
+	name       string
+	wq         queue
+	drop       bool
+	workers    []*worker
+	filter     Filter
+	transforms []transformers.Transformer
 }
 
-func newSubmitter(wq queue, outputConfig outputs.Config) (*submitter, error) {
+func newSubmitter(outputConfig outputs.Config, config Config, compiler FilterCompiler) (*submitter, error) {
+	size := config.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	s := &submitter{name: outputConfig.Name, wq: make(queue, size)}
+	if outputConfig.Filter != "" {
+		if compiler == nil {
+			return nil, fmt.Errorf("unable to compile %q output filter", outputConfig.Name)
+		}
+		var err error
+		s.filter, err = compiler(outputConfig.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q output filter: %v", outputConfig.Name, err)
+		}
+	}
+	var err error
+	s.transforms, err = transformers.LoadAll(outputConfig.Transformers)
+	if err != nil {
+		return nil, err
+	}
+
 	output, err := outputs.Load(outputConfig.Type, outputConfig)
 	if err != nil {
 		return nil, err
 	}
 	clients := output.Clients
-	workers := make([]*worker, len(clients))
+	s.workers = make([]*worker, len(clients))
 
 	for i, client := range clients {
-		workers[i] = initWorker(wq, client)
+		if !config.Spool.Enabled {
+			s.workers[i] = initWorker(s.wq, client)
+			continue
+		}
+		// each client consumes batches from its own spool
+		name := fmt.Sprintf("%s/%d", outputConfig.Name, i)
+		dir := filepath.Join(config.Spool.Dir, outputConfig.Name, strconv.Itoa(i))
+		sp, err := spool.Open(name, dir, int64(config.Spool.MaxSize)*1024*1024, config.Spool.SyncInterval)
+		if err != nil {
+			s.workers = s.workers[:i]
+			return nil, multierror.Wrap(fmt.Errorf("unable to open %q output spool: %v", outputConfig.Name, err), s.shutdown())
+		}
+		s.workers[i] = initSpoolWorker(s.wq, client, sp)
+	}
+
+	return s, nil
+}
+
+// accepts determines if the event is routed to the output.
+func (s *submitter) accepts(kevt *kevent.Kevent) bool {
+	return s.filter == nil || s.filter.Run(kevt)
+}
+
+// submit enqueues the batch to the work queue. The call blocks if the work
+// queue is full, unless dropping is enabled. In that case the batch is dropped,
+// so the output that can't keep up with the event flow doesn't stall other
+// outputs. With the spool enabled, the work queue is drained by the spool writer.
+func (s *submitter) submit(b *kevent.Batch) {
+	if !s.drop {
+		s.wq <- b
+		return
 	}
+	select {
+	case s.wq <- b:
+	default:
+		log.Warnf("%q output work queue is full. Dropping batch of %d events", s.name, b.Len())
+		droppedEvents.Add(s.name, b.Len())
+		b.Release()
+	}
+}
 
-	return &submitter{wq: wq, workers: workers}, nil
+// transform applies the output transformers to the event.
+func (s *submitter) transform(kevt *kevent.Kevent) {
+	transform(s.transforms, kevt)
 }
 
 func (s *submitter) shutdown() error {
+	errs := make([]error, 0)
 	for _, w := range s.workers {
 		if err := w.close(); err != nil {
-			return err
+			errs = append(errs, err)
+		}
+	}
+	return multierror.Wrap(errs...)
+}
+
+// transform runs the chain of transformers on the event.
+func transform(transforms []transformers.Transformer, kevt *kevent.Kevent) {
+	for _, transformer := range transforms {
+		if transformer == nil {
+			continue
+		}
+		err := transformer.Transform(kevt)
+		if err != nil {
+			log.Warnf("transformer error occurred: %v", err)
+			transformerErrors.Add(err.Error(), 1)
 		}
 	}
-	return nil
 }
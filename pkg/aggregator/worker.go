@@ -20,20 +20,35 @@ package aggregator
 
 import (
 	"expvar"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/aggregator/spool"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/rabbitstack/fibratus/pkg/util/multierror"
 	log "github.com/sirupsen/logrus"
-	"time"
 )
 
 // maxBackoff determines the maximum exponential backoff wait time before reconnecting the client
 const maxBackoff = time.Minute
 
-var clientPublishErrors = expvar.NewInt("aggregator.worker.client.publish.errors")
+var (
+	clientPublishErrors = expvar.NewInt("aggregator.worker.client.publish.errors")
+	spoolErrors         = expvar.NewInt("aggregator.worker.spool.errors")
+)
 
 type worker struct {
 	qu      queue
 	client  outputs.Client
 	backoff time.Duration
+
+	// spool holds the batches that are yet to be published
+	spool  *spool.Spool
+	signal chan struct{}
+	quit   chan struct{}
+	wg     sync.WaitGroup
 }
 
 func initWorker(q queue, client outputs.Client) *worker {
@@ -42,7 +57,37 @@ func initWorker(q queue, client outputs.Client) *worker {
 	return w
 }
 
+// initSpoolWorker creates a worker that writes batches to the spool and
+// publishes them from the spool in order, so the batches are retained
+// while the client is unable to publish them.
+func initSpoolWorker(q queue, client outputs.Client, s *spool.Spool) *worker {
+	w := &worker{
+		qu:      q,
+		client:  client,
+		backoff: time.Second * 2,
+		spool:   s,
+		signal:  make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+	}
+	w.wg.Add(2)
+	go w.enqueue()
+	go w.drain()
+	return w
+}
+
 func (w *worker) run() {
+	w.connect()
+	for batch := range w.qu {
+		if err := w.client.Publish(batch); err != nil {
+			clientPublishErrors.Add(1)
+			log.Warnf("couldn't publish batch to client: %v", err)
+		}
+	}
+}
+
+// connect establishes the client connection. It returns false if the
+// worker was closed before the client could connect.
+func (w *worker) connect() bool {
 	for {
 		err := w.client.Connect()
 		if err != nil {
@@ -52,19 +97,118 @@ func (w *worker) run() {
 			if w.backoff > maxBackoff {
 				w.backoff = maxBackoff
 			}
-			<-time.After(w.backoff)
+			select {
+			case <-time.After(w.backoff):
+			case <-w.quit:
+				return false
+			}
 			continue
 		}
-		break
+		return true
 	}
-	for batch := range w.qu {
+}
+
+// enqueue writes batches from the queue to the spool.
+func (w *worker) enqueue() {
+	defer w.wg.Done()
+	for {
+		select {
+		case batch, ok := <-w.qu:
+			if !ok {
+				return
+			}
+			err := w.spool.Append(batch.MarshalRaw())
+			batch.Release()
+			if err != nil {
+				spoolErrors.Add(1)
+				log.Warnf("couldn't write batch to spool: %v", err)
+				continue
+			}
+			select {
+			case w.signal <- struct{}{}:
+			default:
+			}
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// drain publishes batches from the spool. A batch is removed from
+// the spool only after it is successfully published. Otherwise, the
+// publishing is retried with exponential backoff.
+func (w *worker) drain() {
+	defer w.wg.Done()
+	if !w.connect() {
+		return
+	}
+	backoff := time.Second
+	wait := func() bool {
+		select {
+		case <-time.After(backoff):
+		case <-w.quit:
+			return false
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		return true
+	}
+	for {
+		b, _, err := w.spool.Peek()
+		if err == io.EOF {
+			select {
+			case <-w.signal:
+				continue
+			case <-w.quit:
+				return
+			}
+		}
+		if err != nil {
+			spoolErrors.Add(1)
+			log.Warnf("couldn't read batch from spool: %v", err)
+			if !wait() {
+				return
+			}
+			continue
+		}
+		batch, err := kevent.NewBatchFromRaw(b)
+		if err != nil {
+			spoolErrors.Add(1)
+			log.Warnf("discarding malformed batch from spool: %v", err)
+			if err := w.spool.Ack(); err != nil {
+				log.Warnf("couldn't acknowledge spooled batch: %v", err)
+			}
+			continue
+		}
 		if err := w.client.Publish(batch); err != nil {
 			clientPublishErrors.Add(1)
-			log.Warnf("couldn't publish batch to client: %v", err)
+			log.Warnf("couldn't publish batch to client: %v. Retrying in %v...", err, backoff)
+			if !wait() {
+				return
+			}
+			continue
+		}
+		backoff = time.Second
+		if err := w.spool.Ack(); err != nil {
+			log.Warnf("couldn't acknowledge spooled batch: %v", err)
 		}
 	}
 }
 
 func (w *worker) close() error {
-	return w.client.Close()
+	if w.spool == nil {
+		return w.client.Close()
+	}
+	close(w.quit)
+	w.wg.Wait()
+	errs := make([]error, 0)
+	if err := w.client.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := w.spool.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return multierror.Wrap(errs...)
 }
@@ -19,10 +19,15 @@
 package aggregator
 
 import (
+	"errors"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/spool"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 )
@@ -125,3 +130,88 @@ func TestConnectClientBackoff(t *testing.T) {
 
 	assert.Equal(t, 2, client.published)
 }
+
+// flakyClient fails to publish batches while the sink is down
+type flakyClient struct {
+	sync.Mutex
+	down bool
+	seqs []uint64
+}
+
+func (c *flakyClient) Connect() error { return nil }
+func (c *flakyClient) Close() error   { return nil }
+
+func (c *flakyClient) Publish(b *kevent.Batch) error {
+	c.Lock()
+	defer c.Unlock()
+	defer b.Release()
+	if c.down {
+		return errors.New("sink unavailable")
+	}
+	for _, e := range b.Events {
+		c.seqs = append(c.seqs, e.Seq)
+	}
+	return nil
+}
+
+func (c *flakyClient) setDown(down bool) {
+	c.Lock()
+	defer c.Unlock()
+	c.down = down
+}
+
+func (c *flakyClient) published() []uint64 {
+	c.Lock()
+	defer c.Unlock()
+	return append([]uint64(nil), c.seqs...)
+}
+
+func newSpoolBatch(seqs ...uint64) *kevent.Batch {
+	evts := make([]*kevent.Kevent, len(seqs))
+	for i, seq := range seqs {
+		evts[i] = &kevent.Kevent{
+			Type:      ktypes.CreateFile,
+			Seq:       seq,
+			Name:      "CreateFile",
+			Category:  ktypes.File,
+			Timestamp: time.Now(),
+			Kparams:   kevent.Kparams{},
+			Metadata:  make(kevent.Metadata),
+		}
+	}
+	return kevent.NewBatch(evts...)
+}
+
+func TestSpoolWorker(t *testing.T) {
+	dir := t.TempDir()
+	q := make(queue)
+	client := &flakyClient{down: true}
+
+	sp, err := spool.Open("test", dir, 1024*1024, 0)
+	require.NoError(t, err)
+	w := initSpoolWorker(q, client, sp)
+
+	q <- newSpoolBatch(1, 2)
+	q <- newSpoolBatch(3)
+
+	// batches are retained while the sink is down
+	time.Sleep(time.Millisecond * 200)
+	assert.Empty(t, client.published())
+	assert.Equal(t, 2, sp.Len())
+
+	// restart the worker and recover the batches from disk
+	require.NoError(t, w.close())
+	client = &flakyClient{down: true}
+	sp, err = spool.Open("test", dir, 1024*1024, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, sp.Len())
+	w = initSpoolWorker(q, client, sp)
+	defer w.close()
+
+	q <- newSpoolBatch(4)
+	client.setDown(false)
+
+	assert.Eventually(t, func() bool { return len(client.published()) == 4 }, time.Second*10, time.Millisecond*50)
+	assert.Equal(t, []uint64{1, 2, 3, 4}, client.published())
+	assert.Equal(t, 0, sp.Len())
+}
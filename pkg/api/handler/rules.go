@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/json"
+	"github.com/rabbitstack/fibratus/pkg/filter"
+	"net/http"
+)
+
+// RulesReloader recompiles detection rules and replaces the active rule set.
+type RulesReloader interface {
+	ReloadRules() error
+}
+
+// ReloadRules is the handler that triggers the reload of detection rules. If
+// any of the rules fails to compile, the active rule set is kept and the
+// compilation error is returned in the response body.
+func ReloadRules(reloader RulesReloader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := reloader.ReloadRules(); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// RulesProfiler provides evaluation statistics of detection rules.
+type RulesProfiler interface {
+	ProfileRules() ([]filter.RuleProfile, error)
+}
+
+// ProfileRules is the handler that returns evaluation statistics of
+// detection rules ranked by the cumulative evaluation time.
+func ProfileRules(profiler RulesProfiler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		profiles, err := profiler.ProfileRules()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(profiles); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
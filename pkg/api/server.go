@@ -30,9 +30,37 @@ import (
 	"strings"
 )
 
-func setupServer(lis net.Listener, c *config.Config) {
+// Option customizes the API server.
+type Option func(o *opts)
+
+type opts struct {
+	rulesReloader handler.RulesReloader
+	rulesProfiler handler.RulesProfiler
+}
+
+// WithRulesReloader exposes the endpoint for reloading detection rules.
+func WithRulesReloader(reloader handler.RulesReloader) Option {
+	return func(o *opts) {
+		o.rulesReloader = reloader
+	}
+}
+
+// WithRulesProfiler exposes the endpoint for retrieving rule evaluation statistics.
+func WithRulesProfiler(profiler handler.RulesProfiler) Option {
+	return func(o *opts) {
+		o.rulesProfiler = profiler
+	}
+}
+
+func setupServer(lis net.Listener, c *config.Config, o opts) {
 	mux := http.NewServeMux()
 	mux.Handle("/config", handler.Config(c))
+	if o.rulesReloader != nil {
+		mux.Handle("/rules/reload", handler.ReloadRules(o.rulesReloader))
+	}
+	if o.rulesProfiler != nil {
+		mux.Handle("/rules/profile", handler.ProfileRules(o.rulesProfiler))
+	}
 	mux.Handle("/debug/vars", expvar.Handler())
 
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
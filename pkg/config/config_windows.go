@@ -24,8 +24,11 @@ import (
 	"time"
 
 	"github.com/rabbitstack/fibratus/pkg/outputs/eventlog"
+	"github.com/rabbitstack/fibratus/pkg/outputs/file"
 
 	"github.com/rabbitstack/fibratus/pkg/outputs/http"
+	"github.com/rabbitstack/fibratus/pkg/outputs/kafka"
+	"github.com/rabbitstack/fibratus/pkg/outputs/syslog"
 
 	"github.com/rabbitstack/fibratus/pkg/aggregator"
 	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers"
@@ -79,8 +82,8 @@ type Config struct {
 	Filament FilamentConfig `json:"filament" yaml:"filament"`
 	// PE contains the settings that influences the behaviour of the PE (Portable Executable) reader.
 	PE pe.Config `json:"pe" yaml:"pe"`
-	// Output stores the currently active output config
-	Output outputs.Config
+	// Outputs stores the configs of all active outputs
+	Outputs []outputs.Config
 	// InitHandleSnapshot indicates whether initial handle snapshot is built
 	InitHandleSnapshot bool `json:"init-handle-snapshot" yaml:"init-handle-snapshot"`
 	DebugPrivilege     bool `json:"debug-privilege" yaml:"debug-privilege"`
@@ -115,6 +118,7 @@ type Options struct {
 	run     bool
 	list    bool
 	stats   bool
+	rules   bool
 }
 
 // Option is the type alias for the config option.
@@ -155,6 +159,13 @@ func WithStats() Option {
 	}
 }
 
+// WithRules determines the rules command is executed.
+func WithRules() Option {
+	return func(o *Options) {
+		o.rules = true
+	}
+}
+
 // NewWithOpts builds a new configuration store from a variety of sources such as configuration files,
 // environment variables or command line flags.
 func NewWithOpts(options ...Option) *Config {
@@ -190,6 +201,9 @@ func NewWithOpts(options ...Option) *Config {
 		elasticsearch.AddFlags(flagSet)
 		http.AddFlags(flagSet)
 		eventlog.AddFlags(flagSet)
+		kafka.AddFlags(flagSet)
+		syslog.AddFlags(flagSet)
+		file.AddFlags(flagSet)
 		removet.AddFlags(flagSet)
 		replacet.AddFlags(flagSet)
 		renamet.AddFlags(flagSet)
@@ -309,8 +323,25 @@ func (c *Config) addFlags() {
 	c.flags.String(configFile, filepath.Join(os.Getenv("PROGRAMFILES"), "fibratus", "config", "fibratus.yml"), "Indicates the location of the configuration file")
 	if c.opts.run || c.opts.replay {
 		c.flags.StringP(filamentName, "f", "", "Specifies the filament to execute")
+	}
+	if c.opts.run || c.opts.replay || c.opts.rules {
 		c.flags.StringSlice(rulesFromPaths, []string{}, "Comma-separated list of rules files")
 		c.flags.StringSlice(rulesFromURLs, []string{}, "Comma-separated list of rules URL resources")
+		c.flags.StringSlice(exceptionsFromPaths, []string{}, "Comma-separated list of rule exceptions files")
+		c.flags.StringSlice(lookupsFromPaths, []string{}, "Comma-separated list of lookup table files")
+		c.flags.Bool(strictMode, false, "Indicates if comparisons with missing field values evaluate to unknown instead of false")
+	}
+	if c.opts.run {
+		c.flags.String(sequencesStateFile, "", "Specifies the file where the state of in-flight sequence rules is persisted across restarts")
+		c.flags.Duration(sequencesSnapshotInterval, time.Minute, "Determines how often the state of sequence rules is snapshotted")
+		c.flags.Bool(profilingEnabled, false, "Indicates if the evaluation cost of each rule is recorded. Rule profiles are exposed through the API server")
+		c.flags.Int(actionsRateLimitMax, 10, "Specifies how many times each rule action can be executed by the rule within the rate limit interval. Zero disables the limit")
+		c.flags.Duration(actionsRateLimitInterval, time.Minute, "Specifies the duration of the rule action rate limit window")
+		c.flags.String(actionsAuditLog, "", "Specifies the file where the outcome of rule action executions is recorded")
+		c.flags.String(actionsQuarantineDir, filepath.Join(os.Getenv("PROGRAMFILES"), "fibratus", "quarantine"), "Specifies the directory where files are moved by the quarantine rule action")
+		c.flags.String(actionsCaptureDir, filepath.Join(os.Getenv("PROGRAMFILES"), "fibratus", "captures"), "Specifies the directory where capture files are written by the capture rule action")
+		c.flags.Duration(actionsMaxCaptureDuration, time.Minute*5, "Specifies the maximum duration of the capture started by the capture rule action")
+		c.flags.Duration(actionsExecTimeout, time.Second*30, "Specifies the time after which the script run by the exec rule action is killed")
 	}
 	if c.opts.capture {
 		c.flags.StringP(kcapFile, "o", "", "The path of the output kcap file")
@@ -321,7 +352,7 @@ func (c *Config) addFlags() {
 	if c.opts.run || c.opts.replay || c.opts.list {
 		c.flags.String(filamentPath, filepath.Join(os.Getenv("PROGRAMFILES"), "fibratus", "filaments"), "Denotes the directory where filaments are located")
 	}
-	if c.opts.run || c.opts.replay || c.opts.capture || c.opts.stats {
+	if c.opts.run || c.opts.replay || c.opts.capture || c.opts.stats || c.opts.rules {
 		c.flags.String(transport, `localhost:8080`, "Specifies the underlying transport protocol for the API HTTP server")
 		c.flags.Duration(timeout, time.Second*15, "Determines the timeout for the API server responses")
 	}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/util/multierror"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Exceptions contains attributes that describe the location
+// of rule exception resources.
+type Exceptions struct {
+	FromPaths []string `json:"from-paths" yaml:"from-paths"`
+}
+
+// Exception suppresses matches of the referenced rules when the
+// exception condition matches the event that fired the rule. Rules
+// are referenced by name, group name, or by the label selector.
+type Exception struct {
+	Name          string            `json:"exception" yaml:"exception"`
+	Justification string            `json:"justification" yaml:"justification,omitempty"`
+	Rules         []string          `json:"rules" yaml:"rules,omitempty"`
+	Groups        []string          `json:"groups" yaml:"groups,omitempty"`
+	Labels        map[string]string `json:"labels" yaml:"labels,omitempty"`
+	Condition     string            `json:"condition" yaml:"condition"`
+	Expires       *time.Time        `json:"expires" yaml:"expires,omitempty"`
+}
+
+// IsExpired determines if the exception expired at the given time.
+func (e Exception) IsExpired(now time.Time) bool {
+	return e.Expires != nil && !now.Before(*e.Expires)
+}
+
+// Selects determines whether the exception references the rule. The rule
+// is selected if its name or group name is listed in the exception, or if
+// the rule or group labels contain all labels of the exception selector.
+func (e Exception) Selects(group FilterGroup, filter *FilterConfig) bool {
+	for _, rule := range e.Rules {
+		if rule == filter.Name {
+			return true
+		}
+	}
+	for _, g := range e.Groups {
+		if g == group.Name {
+			return true
+		}
+	}
+	if len(e.Labels) == 0 {
+		return false
+	}
+	for k, v := range e.Labels {
+		label, ok := filter.Labels[k]
+		if !ok {
+			label, ok = group.Labels[k]
+		}
+		if !ok || label != v {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadExceptions reads and decodes rule exceptions from all exception files.
+func (f Filters) LoadExceptions() ([]Exception, error) {
+	exceptions := make([]Exception, 0)
+	names := make(map[string]bool)
+	for _, p := range f.Exceptions.FromPaths {
+		paths, err := filepath.Glob(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			if !isValidExt(path) {
+				continue
+			}
+			log.Infof("loading rule exceptions from file %s", path)
+			buf, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't load rule exceptions from file: %v", err)
+			}
+			excs, err := DecodeExceptions(Resource{Name: path, Content: buf})
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range excs {
+				if names[e.Name] {
+					return nil, fmt.Errorf("exception names must be unique. Found duplicate %q exception", e.Name)
+				}
+				names[e.Name] = true
+			}
+			exceptions = append(exceptions, excs...)
+		}
+	}
+	return exceptions, nil
+}
+
+// DecodeExceptions validates the structure of the exceptions file and decodes exceptions.
+func DecodeExceptions(res Resource) ([]Exception, error) {
+	var out interface{}
+	if err := yaml.Unmarshal(res.Content, &out); err != nil {
+		return nil, fmt.Errorf("%q is invalid exceptions yaml file: %v", res.Name, err)
+	}
+	valid, errs := validate(exceptionsSchema, out)
+	if !valid || len(errs) > 0 {
+		return nil, fmt.Errorf("invalid exception definition in %s: %v", res.Name, multierror.Wrap(errs...))
+	}
+	var exceptions []Exception
+	if err := yaml.Unmarshal(res.Content, &exceptions); err != nil {
+		return nil, fmt.Errorf("%q is invalid exceptions yaml file: %v", res.Name, err)
+	}
+	return exceptions, nil
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeExceptions(t *testing.T) {
+	excs, err := DecodeExceptions(Resource{Name: "exceptions.yml", Content: []byte(`
+- exception: Backup agent credential access
+  justification: Backup agent reads browser credential stores
+  rules:
+    - Suspicious access to Windows Credential Manager files
+  labels:
+    tactic.id: TA0006
+  condition: ps.exe imatches 'C:\\Program Files\\Backup\\*'
+  expires: 2030-01-01
+`)})
+	require.NoError(t, err)
+	require.Len(t, excs, 1)
+	e := excs[0]
+	assert.Equal(t, "Backup agent credential access", e.Name)
+	assert.Equal(t, []string{"Suspicious access to Windows Credential Manager files"}, e.Rules)
+	require.NotNil(t, e.Expires)
+	assert.Equal(t, time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC), *e.Expires)
+	assert.False(t, e.IsExpired(time.Date(2029, time.December, 31, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, e.IsExpired(time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)))
+
+	// exceptions must reference rules by name, group, or labels
+	_, err = DecodeExceptions(Resource{Name: "exceptions.yml", Content: []byte(`
+- exception: Orphaned exception
+  condition: ps.name = 'svchost.exe'
+`)})
+	require.Error(t, err)
+}
+
+func TestExceptionSelects(t *testing.T) {
+	group := FilterGroup{Name: "Credential access", Labels: map[string]string{"tactic.id": "TA0006"}}
+	rule := &FilterConfig{Name: "Credential discovery", Labels: map[string]string{"technique.id": "T1555"}}
+
+	var tests = []struct {
+		exc     Exception
+		selects bool
+	}{
+		{Exception{Rules: []string{"Credential discovery"}}, true},
+		{Exception{Rules: []string{"LSASS memory dumping"}}, false},
+		{Exception{Groups: []string{"Credential access"}}, true},
+		{Exception{Labels: map[string]string{"tactic.id": "TA0006", "technique.id": "T1555"}}, true},
+		{Exception{Labels: map[string]string{"tactic.id": "TA0006", "technique.id": "T1003"}}, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.selects, tt.exc.Selects(group, rule))
+	}
+}
@@ -36,6 +36,7 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 )
 
 // FilterGroupPolicy is the type alias for the filter group policy
@@ -113,6 +114,16 @@ func (r *FilterGroupRelation) UnmarshalYAML(unmarshal func(interface{}) error) e
 	return nil
 }
 
+// MarshalYAML converts the policy enum type to string.
+func (p FilterGroupPolicy) MarshalYAML() (interface{}, error) {
+	return p.String(), nil
+}
+
+// MarshalYAML converts the relation enum type to string.
+func (r FilterGroupRelation) MarshalYAML() (interface{}, error) {
+	return r.String(), nil
+}
+
 func filterGroupPolicyFromString(s string) FilterGroupPolicy {
 	switch s {
 	case "include", "INCLUDE":
@@ -138,17 +149,41 @@ func filterGroupRelationFromString(s string) FilterGroupRelation {
 // FilterConfig is the descriptor of a single filter.
 type FilterConfig struct {
 	Name        string            `json:"name" yaml:"name"`
-	Description string            `json:"description" yaml:"description"`
-	Def         string            `json:"def" yaml:"def"` // deprecated in favor of `Condition`
-	Condition   string            `json:"condition" yaml:"condition"`
-	Action      string            `json:"action" yaml:"action"`
-	Labels      map[string]string `json:"labels" yaml:"labels"`
+	Description string            `json:"description" yaml:"description,omitempty"`
+	Def         string            `json:"def" yaml:"def,omitempty"` // deprecated in favor of `Condition`
+	Condition   string            `json:"condition" yaml:"condition,omitempty"`
+	Action      string            `json:"action" yaml:"action,omitempty"`
+	Labels      map[string]string `json:"labels" yaml:"labels,omitempty"`
+	Suppress    *Suppression      `json:"suppress" yaml:"suppress,omitempty"`
+	// RateLimit overrides the default rate limit of rule actions
+	RateLimit *RateLimit `json:"rate-limit" yaml:"rate-limit,omitempty"`
+}
+
+// Suppression describes how repeated rule matches are collapsed into
+// a single alert. Matches with the same values of the dedup fields that
+// happen within the window share the alert budget. Once the budget is
+// exhausted, further matches only bump the occurrence count.
+type Suppression struct {
+	// By is the list of fields whose values make up the dedup key
+	By []string `json:"by" yaml:"by,omitempty"`
+	// Window is the duration of the suppression window
+	Window time.Duration `json:"window" yaml:"window"`
+	// MaxAlerts is the number of rule actions executed per window
+	MaxAlerts int `json:"max-alerts" yaml:"max-alerts,omitempty"`
 }
 
-// parseTmpl ensures the correctness of the rule
+// Alerts returns the number of alerts allowed per window.
+func (s Suppression) Alerts() int {
+	if s.MaxAlerts <= 0 {
+		return 1
+	}
+	return s.MaxAlerts
+}
+
+// ParseAction ensures the correctness of the rule
 // action template by trying to parse the template
 // string from the base64 payload.
-func (f FilterConfig) parseTmpl(resource string) error {
+func (f FilterConfig) ParseAction(resource string) error {
 	if f.Action == "" {
 		return nil
 	}
@@ -167,14 +202,24 @@ func (f FilterConfig) parseTmpl(resource string) error {
 // FilterGroup represents the container for filters.
 type FilterGroup struct {
 	Name        string              `json:"group" yaml:"group"`
-	Description string              `json:"description" yaml:"description"`
-	Enabled     *bool               `json:"enabled" yaml:"enabled"`
+	Description string              `json:"description" yaml:"description,omitempty"`
+	Enabled     *bool               `json:"enabled" yaml:"enabled,omitempty"`
 	Policy      FilterGroupPolicy   `json:"policy" yaml:"policy"`
 	Relation    FilterGroupRelation `json:"relation" yaml:"relation"`
-	Rules       []*FilterConfig     `json:"rules" yaml:"rules"`
-	FromStrings []*FilterConfig     `json:"from-strings" yaml:"from-strings"` // deprecated in favor or `Rules`
-	Tags        []string            `json:"tags" yaml:"tags"`
-	Labels      map[string]string   `json:"labels" yaml:"labels"`
+	Rules       []*FilterConfig     `json:"rules" yaml:"rules,omitempty"`
+	FromStrings []*FilterConfig     `json:"from-strings" yaml:"from-strings,omitempty"` // deprecated in favor or `Rules`
+	Tags        []string            `json:"tags" yaml:"tags,omitempty"`
+	Labels      map[string]string   `json:"labels" yaml:"labels,omitempty"`
+	Suppress    *Suppression        `json:"suppress" yaml:"suppress,omitempty"`
+}
+
+// SuppressionFor returns the suppression settings of the rule. Rule
+// settings take precedence over the settings declared in the group.
+func (g FilterGroup) SuppressionFor(filter *FilterConfig) *Suppression {
+	if filter.Suppress != nil {
+		return filter.Suppress
+	}
+	return g.Suppress
 }
 
 // IsDisabled determines if this group is disabled.
@@ -187,7 +232,7 @@ func (g FilterGroup) validate(resource string) error {
 			return fmt.Errorf("%q rule found in %q group with exclude policy. "+
 				"Only groups with include policies can have rule actions", filter.Name, g.Name)
 		}
-		if err := filter.parseTmpl(resource); err != nil {
+		if err := filter.ParseAction(resource); err != nil {
 			return fmt.Errorf("invalid %q rule action: %v", filter.Name, err)
 		}
 	}
@@ -203,13 +248,26 @@ func (g FilterGroup) Hash() uint32 {
 // Each filter group can contain multiple filter expressions which
 // represent the rules.
 type Filters struct {
-	Rules  Rules  `json:"rules" yaml:"rules"`
-	Macros Macros `json:"macros" yaml:"macros"`
-	macros map[string]*Macro
+	Rules      Rules      `json:"rules" yaml:"rules"`
+	Macros     Macros     `json:"macros" yaml:"macros"`
+	Lookups    Lookups    `json:"lookups" yaml:"lookups"`
+	Exceptions Exceptions `json:"exceptions" yaml:"exceptions"`
+	Sequences  Sequences  `json:"sequences" yaml:"sequences"`
+	Profiling  Profiling  `json:"profiling" yaml:"profiling"`
+	// Strict determines if comparisons with missing field values
+	// evaluate to unknown instead of false. Negating the unknown
+	// value yields unknown, so the rule doesn't match the event
+	// when the field the rule depends on can't be resolved.
+	Strict bool `json:"strict" yaml:"strict"`
+	// Actions controls the execution of rule actions
+	Actions Actions `json:"actions" yaml:"actions"`
+
+	macros  map[string]*Macro
+	lookups map[string]*LookupTable
 }
 
 // FiltersWithMacros builds the filter config with the map of
-// predefined macros. Only used for testing purposes.
+// predefined macros.
 func FiltersWithMacros(macros map[string]*Macro) *Filters {
 	return &Filters{macros: macros}
 }
@@ -227,6 +285,50 @@ type Macros struct {
 	FromPaths []string `json:"from-paths" yaml:"from-paths"`
 }
 
+// Sequences contains attributes that control the
+// persistence of the in-flight sequence rule state.
+type Sequences struct {
+	// StateFile is the path of the file where the sequence state is
+	// snapshotted. The state is not persisted if the path is empty.
+	StateFile string `json:"state-file" yaml:"state-file"`
+	// SnapshotInterval determines how often the sequence state is snapshotted
+	SnapshotInterval time.Duration `json:"snapshot-interval" yaml:"snapshot-interval"`
+}
+
+// Profiling controls the collection of rule evaluation statistics.
+type Profiling struct {
+	// Enabled indicates if the evaluation cost is recorded for each rule
+	Enabled bool `json:"enabled" yaml:"enabled"`
+}
+
+// Actions contains attributes that control the execution of rule actions.
+type Actions struct {
+	// RateLimit is the default rate limit applied to each action of the rule
+	RateLimit RateLimit `json:"rate-limit" yaml:"rate-limit"`
+	// AuditLog is the path of the file where the outcome of each action
+	// execution is appended. Executions are only logged if the path is empty
+	AuditLog string `json:"audit-log" yaml:"audit-log"`
+	// QuarantineDir is the directory where quarantined files are moved
+	QuarantineDir string `json:"quarantine-dir" yaml:"quarantine-dir"`
+	// CaptureDir is the directory where capture files are written
+	CaptureDir string `json:"capture-dir" yaml:"capture-dir"`
+	// MaxCaptureDuration caps the duration of the capture
+	MaxCaptureDuration time.Duration `json:"max-capture-duration" yaml:"max-capture-duration"`
+	// Scripts maps script names to paths of local scripts run by the exec action
+	Scripts map[string]string `json:"scripts" yaml:"scripts"`
+	// ExecTimeout is the time after which the script run by the exec action is killed
+	ExecTimeout time.Duration `json:"exec-timeout" yaml:"exec-timeout"`
+}
+
+// RateLimit restricts the number of times the action is executed by
+// the rule within the interval. The limit is disabled if Max is zero.
+type RateLimit struct {
+	// Max is the number of executions allowed within the interval
+	Max int `json:"max" yaml:"max"`
+	// Interval is the duration of the rate limit window
+	Interval time.Duration `json:"interval" yaml:"interval"`
+}
+
 // Macro represents the state of the rule macro. Macros
 // either expand to expressions or lists.
 type Macro struct {
@@ -240,12 +342,46 @@ const (
 	rulesFromPaths  = "filters.rules.from-paths"
 	rulesFromURLs   = "filters.rules.from-urls"
 	macrosFromPaths = "filters.macros.from-paths"
+
+	lookupsFromPaths = "filters.lookups.from-paths"
+
+	exceptionsFromPaths = "filters.exceptions.from-paths"
+
+	sequencesStateFile        = "filters.sequences.state-file"
+	sequencesSnapshotInterval = "filters.sequences.snapshot-interval"
+
+	profilingEnabled = "filters.profiling.enabled"
+
+	strictMode = "filters.strict"
+
+	actionsRateLimitMax       = "filters.actions.rate-limit.max"
+	actionsRateLimitInterval  = "filters.actions.rate-limit.interval"
+	actionsAuditLog           = "filters.actions.audit-log"
+	actionsQuarantineDir      = "filters.actions.quarantine-dir"
+	actionsCaptureDir         = "filters.actions.capture-dir"
+	actionsMaxCaptureDuration = "filters.actions.max-capture-duration"
+	actionsScripts            = "filters.actions.scripts"
+	actionsExecTimeout        = "filters.actions.exec-timeout"
 )
 
 func (f *Filters) initFromViper(v *viper.Viper) {
 	f.Rules.FromPaths = v.GetStringSlice(rulesFromPaths)
 	f.Rules.FromURLs = v.GetStringSlice(rulesFromURLs)
 	f.Macros.FromPaths = v.GetStringSlice(macrosFromPaths)
+	f.Lookups.FromPaths = v.GetStringSlice(lookupsFromPaths)
+	f.Exceptions.FromPaths = v.GetStringSlice(exceptionsFromPaths)
+	f.Sequences.StateFile = v.GetString(sequencesStateFile)
+	f.Sequences.SnapshotInterval = v.GetDuration(sequencesSnapshotInterval)
+	f.Profiling.Enabled = v.GetBool(profilingEnabled)
+	f.Strict = v.GetBool(strictMode)
+	f.Actions.RateLimit.Max = v.GetInt(actionsRateLimitMax)
+	f.Actions.RateLimit.Interval = v.GetDuration(actionsRateLimitInterval)
+	f.Actions.AuditLog = v.GetString(actionsAuditLog)
+	f.Actions.QuarantineDir = v.GetString(actionsQuarantineDir)
+	f.Actions.CaptureDir = v.GetString(actionsCaptureDir)
+	f.Actions.MaxCaptureDuration = v.GetDuration(actionsMaxCaptureDuration)
+	f.Actions.Scripts = v.GetStringMapString(actionsScripts)
+	f.Actions.ExecTimeout = v.GetDuration(actionsExecTimeout)
 }
 
 func (f Filters) HasMacros() bool           { return len(f.macros) > 0 }
@@ -258,69 +394,99 @@ func (f Filters) IsMacroList(id string) bool {
 	return macro.List != nil
 }
 
-// LoadMacros from the macro library. The Go templates are applied
-// on each macro file before running the YAML decoder on them.
-func (f *Filters) LoadMacros() error {
-	f.macros = make(map[string]*Macro)
+// Resource contains the raw content of the rule or
+// macro file along with the path or URL the file was
+// loaded from.
+type Resource struct {
+	Name    string
+	Content []byte
+}
+
+// LoadMacroResources reads all macro files without decoding them.
+func (f Filters) LoadMacroResources() ([]Resource, error) {
+	resources := make([]Resource, 0)
 	for _, p := range f.Macros.FromPaths {
 		paths, err := filepath.Glob(p)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		for _, path := range paths {
 			if !isValidExt(path) {
 				continue
 			}
-			log.Infof("loading macros from file %s", path)
 			buf, err := os.ReadFile(path)
 			if err != nil {
-				return fmt.Errorf("couldn't load macros from file: %v", err)
-			}
-			// validate macro yaml structure
-			var out interface{}
-			err = yaml.Unmarshal(buf, &out)
-			if err != nil {
-				return fmt.Errorf("%q is invalid macro yaml file: %v", path, err)
+				return nil, fmt.Errorf("couldn't load macros from file: %v", err)
 			}
-			valid, errs := validate(macrosSchema, out)
-			if !valid || len(errs) > 0 {
-				b, err := yaml.Marshal(&out)
-				if err == nil {
-					out = string(b)
-				}
-				return fmt.Errorf("invalid macro definition: \n\n"+
-					"%v in %s: %v", out, path, multierror.Wrap(errs...))
-			}
-			buf, err = renderTmpl(path, buf)
-			if err != nil {
-				return err
-			}
-			// unmarshal macros and transform to map
-			var macros []Macro
-			if err := yaml.Unmarshal(buf, &macros); err != nil {
-				return err
-			}
-			for _, m := range macros {
-				f.macros[m.ID] = &Macro{
-					ID:          m.ID,
-					Description: m.Description,
-					Expr:        m.Expr,
-					List:        m.List,
-				}
+			resources = append(resources, Resource{Name: path, Content: buf})
+		}
+	}
+	return resources, nil
+}
+
+// LoadMacros from the macro library. The Go templates are applied
+// on each macro file before running the YAML decoder on them.
+func (f *Filters) LoadMacros() error {
+	f.macros = make(map[string]*Macro)
+	resources, err := f.LoadMacroResources()
+	if err != nil {
+		return err
+	}
+	for _, res := range resources {
+		log.Infof("loading macros from file %s", res.Name)
+		macros, err := DecodeMacros(res)
+		if err != nil {
+			return err
+		}
+		for _, m := range macros {
+			f.macros[m.ID] = &Macro{
+				ID:          m.ID,
+				Description: m.Description,
+				Expr:        m.Expr,
+				List:        m.List,
 			}
 		}
 	}
 	return nil
 }
 
+// DecodeMacros validates the macro file structure and decodes
+// macros after expanding the template directives.
+func DecodeMacros(res Resource) ([]Macro, error) {
+	// validate macro yaml structure
+	var out interface{}
+	err := yaml.Unmarshal(res.Content, &out)
+	if err != nil {
+		return nil, fmt.Errorf("%q is invalid macro yaml file: %v", res.Name, err)
+	}
+	valid, errs := validate(macrosSchema, out)
+	if !valid || len(errs) > 0 {
+		b, err := yaml.Marshal(&out)
+		if err == nil {
+			out = string(b)
+		}
+		return nil, fmt.Errorf("invalid macro definition: \n\n"+
+			"%v in %s: %v", out, res.Name, multierror.Wrap(errs...))
+	}
+	buf, err := renderTmpl(res.Name, res.Content)
+	if err != nil {
+		return nil, err
+	}
+	var macros []Macro
+	if err := yaml.Unmarshal(buf, &macros); err != nil {
+		return nil, err
+	}
+	return macros, nil
+}
+
 func isValidExt(path string) bool {
 	return filepath.Ext(path) == ".yml" || filepath.Ext(path) == ".yaml"
 }
 
-// LoadGroups for each rule group file it decodes the
-// groups and ensures the correctness of the yaml file.
-func (f Filters) LoadGroups() ([]FilterGroup, error) {
-	allGroups := make([]FilterGroup, 0)
+// LoadRuleResources reads all rule files from the
+// file system paths and URLs without decoding them.
+func (f Filters) LoadRuleResources() ([]Resource, error) {
+	resources := make([]Resource, 0)
 	for _, p := range f.Rules.FromPaths {
 		paths, err := filepath.Glob(p)
 		if err != nil {
@@ -331,17 +497,11 @@ func (f Filters) LoadGroups() ([]FilterGroup, error) {
 				continue
 			}
 			log.Infof("loading rules from file %s", path)
-			// read the file group yaml file and produce
-			// the corresponding filter groups from it
 			rawConfig, err := os.ReadFile(path)
 			if err != nil {
 				return nil, fmt.Errorf("couldn't load rule file: %v", err)
 			}
-			groups, err := decodeFilterGroups(path, rawConfig)
-			if err != nil {
-				return nil, err
-			}
-			allGroups = append(allGroups, groups...)
+			resources = append(resources, Resource{Name: path, Content: rawConfig})
 		}
 	}
 	for _, url := range f.Rules.FromURLs {
@@ -366,7 +526,23 @@ func (f Filters) LoadGroups() ([]FilterGroup, error) {
 		if err != nil {
 			return nil, fmt.Errorf("cannot copy rule file from %q: %v", url, err)
 		}
-		groups, err := decodeFilterGroups(url, rawConfig.Bytes())
+		resources = append(resources, Resource{Name: url, Content: rawConfig.Bytes()})
+	}
+	return resources, nil
+}
+
+// LoadGroups for each rule group file it decodes the
+// groups and ensures the correctness of the yaml file.
+func (f Filters) LoadGroups() ([]FilterGroup, error) {
+	resources, err := f.LoadRuleResources()
+	if err != nil {
+		return nil, err
+	}
+	allGroups := make([]FilterGroup, 0)
+	for _, res := range resources {
+		// produce the corresponding filter
+		// groups from the rule file
+		groups, err := decodeFilterGroups(res.Name, res.Content)
 		if err != nil {
 			return nil, err
 		}
@@ -386,7 +562,11 @@ func (f Filters) LoadGroups() ([]FilterGroup, error) {
 	return allGroups, nil
 }
 
-func decodeFilterGroups(resource string, b []byte) ([]FilterGroup, error) {
+// DecodeFilterGroups validates the structure of the rule file
+// and decodes rule groups after expanding template directives.
+// Rule action templates are not validated.
+func DecodeFilterGroups(res Resource) ([]FilterGroup, error) {
+	resource, b := res.Name, res.Content
 	var out interface{}
 	err := yaml.Unmarshal(b, &out)
 	if err != nil {
@@ -430,6 +610,14 @@ func decodeFilterGroups(resource string, b []byte) ([]FilterGroup, error) {
 	if err := yaml.Unmarshal(b, &groups); err != nil {
 		return nil, err
 	}
+	return groups, nil
+}
+
+func decodeFilterGroups(resource string, b []byte) ([]FilterGroup, error) {
+	groups, err := DecodeFilterGroups(Resource{Name: resource, Content: b})
+	if err != nil {
+		return nil, err
+	}
 	// try to validate filter action template
 	for _, group := range groups {
 		err := group.validate(resource)
@@ -498,8 +686,26 @@ type ActionContext struct {
 	// group policies or a list of ordered matched events
 	// for sequence group policies
 	Events []*kevent.Kevent
-	Filter *FilterConfig
-	Group  FilterGroup
+	// Aggregates contains aggregate values computed over
+	// the window of the aggregation rule indexed by the
+	// aggregate function, e.g. count(distinct file.name)
+	Aggregates map[string]float64
+	// Occurrences contains the number of rule matches
+	// collapsed into the alert and the time span in
+	// which they happened
+	Occurrences Occurrences
+	Filter      *FilterConfig
+	Group       FilterGroup
+}
+
+// Occurrences describes repeated matches of the rule.
+type Occurrences struct {
+	// Count is the number of rule matches
+	Count int
+	// FirstSeen is the timestamp of the first match
+	FirstSeen time.Time
+	// LastSeen is the timestamp of the most recent match
+	LastSeen time.Time
 }
 
 // FilterFuncMap returns the template func map
@@ -513,8 +719,15 @@ func FilterFuncMap() template.FuncMap {
 		// late-bound to a template. By declaring them here, we
 		// can still execute the template associated with the
 		// filter action to ensure template syntax is correct
-		"emit": func(ctx *ActionContext, title string, text string, args ...string) string { return "" },
-		"kill": func(pid uint32) string { return "" },
+		"emit":       func(ctx *ActionContext, title string, text string, args ...string) string { return "" },
+		"kill":       func(pid uint32) string { return "" },
+		"suspend":    func(pid uint32) string { return "" },
+		"resume":     func(pid uint32) string { return "" },
+		"quarantine": func(path string) string { return "" },
+		"block_ip":   func(ip string) string { return "" },
+		"tag":        func(key, value string) string { return "" },
+		"exec":       func(script string, args ...string) string { return "" },
+		"capture":    func(duration string) string { return "" },
 	}
 
 	for k, v := range extra {
@@ -26,6 +26,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 )
 
 func newFilters(paths ...string) Filters {
@@ -34,7 +35,14 @@ func newFilters(paths ...string) Filters {
 			FromPaths: paths,
 		},
 		Macros{FromPaths: nil},
+		Lookups{},
+		Exceptions{},
+		Sequences{},
+		Profiling{},
+		false,
+		Actions{},
 		map[string]*Macro{},
+		map[string]*LookupTable{},
 	}
 }
 
@@ -46,7 +54,14 @@ func TestLoadGroupsFromPaths(t *testing.T) {
 			},
 		},
 		Macros{FromPaths: nil},
+		Lookups{},
+		Exceptions{},
+		Sequences{},
+		Profiling{},
+		false,
+		Actions{},
 		map[string]*Macro{},
+		map[string]*LookupTable{},
 	}
 	groups, err := filters.LoadGroups()
 	require.NoError(t, err)
@@ -80,7 +95,14 @@ func TestLoadGroupsFromPathsNewAttributes(t *testing.T) {
 			},
 		},
 		Macros{FromPaths: nil},
+		Lookups{},
+		Exceptions{},
+		Sequences{},
+		Profiling{},
+		false,
+		Actions{},
 		map[string]*Macro{},
+		map[string]*LookupTable{},
 	}
 	groups, err := filters.LoadGroups()
 	require.NoError(t, err)
@@ -134,7 +156,14 @@ func TestLoadGroupsFromURLs(t *testing.T) {
 			},
 		},
 		Macros{FromPaths: nil},
+		Lookups{},
+		Exceptions{},
+		Sequences{},
+		Profiling{},
+		false,
+		Actions{},
 		map[string]*Macro{},
+		map[string]*LookupTable{},
 	}
 	groups, err := filters.LoadGroups()
 	require.NoError(t, err)
@@ -161,3 +190,24 @@ func TestLoadGroupsInvalidTemplates(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadGroupsSuppression(t *testing.T) {
+	filters := newFilters("_fixtures/filters/suppression.yml")
+	groups, err := filters.LoadGroups()
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	g := groups[0]
+	require.Len(t, g.Rules, 2)
+	s1 := g.SuppressionFor(g.Rules[0])
+	require.NotNil(t, s1)
+	assert.Equal(t, []string{"ps.exe"}, s1.By)
+	assert.Equal(t, time.Minute*10, s1.Window)
+	assert.Equal(t, 1, s1.Alerts())
+
+	s2 := g.SuppressionFor(g.Rules[1])
+	require.NotNil(t, s2)
+	assert.Equal(t, []string{"ps.exe", "file.name"}, s2.By)
+	assert.Equal(t, time.Minute*90, s2.Window)
+	assert.Equal(t, 5, s2.Alerts())
+}
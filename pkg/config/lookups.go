@@ -0,0 +1,290 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lookups contains attributes that describe the location
+// of lookup table resources.
+type Lookups struct {
+	FromPaths []string `json:"from-paths" yaml:"from-paths"`
+}
+
+// LookupTable is the set of values loaded from the external list. The
+// table is referenced in rule conditions by its name, which is the base
+// name of the file without the extension. Values are stored in hash
+// tables. Values that are IP addresses or CIDR ranges are additionally
+// indexed by the prefix length, so the IP address is resolved with as
+// many hash lookups as there are distinct prefix lengths in the table.
+type LookupTable struct {
+	Name string
+	// Resource is the path of the file the table was loaded from
+	Resource string
+
+	values map[string]struct{}
+	folded map[string]struct{}
+	v4     *prefixSet
+	v6     *prefixSet
+}
+
+// prefixSet stores network addresses of the IP family grouped by prefix length.
+type prefixSet struct {
+	bits    int
+	lengths []int
+	masks   map[int]net.IPMask
+	nets    map[int]map[string]struct{}
+}
+
+func newPrefixSet(bits int) *prefixSet {
+	return &prefixSet{bits: bits, masks: make(map[int]net.IPMask), nets: make(map[int]map[string]struct{})}
+}
+
+func (s *prefixSet) add(ip net.IP, ones int) {
+	nets, ok := s.nets[ones]
+	if !ok {
+		nets = make(map[string]struct{})
+		s.nets[ones] = nets
+		s.masks[ones] = net.CIDRMask(ones, s.bits)
+		s.lengths = append(s.lengths, ones)
+		// longer prefixes are more specific, so they're checked first
+		sort.Sort(sort.Reverse(sort.IntSlice(s.lengths)))
+	}
+	nets[string(ip.Mask(s.masks[ones]))] = struct{}{}
+}
+
+func (s *prefixSet) contains(ip net.IP) bool {
+	for _, ones := range s.lengths {
+		if _, ok := s.nets[ones][string(ip.Mask(s.masks[ones]))]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// NewLookupTable builds the lookup table from the list of values.
+func NewLookupTable(name string, values []string) *LookupTable {
+	t := &LookupTable{
+		Name:   name,
+		values: make(map[string]struct{}, len(values)),
+		folded: make(map[string]struct{}, len(values)),
+		v4:     newPrefixSet(net.IPv4len * 8),
+		v6:     newPrefixSet(net.IPv6len * 8),
+	}
+	for _, v := range values {
+		t.add(v)
+	}
+	return t
+}
+
+func (t *LookupTable) add(v string) {
+	t.values[v] = struct{}{}
+	t.folded[strings.ToLower(v)] = struct{}{}
+	if _, n, err := net.ParseCIDR(v); err == nil {
+		ones, _ := n.Mask.Size()
+		if len(n.IP) == net.IPv4len {
+			t.v4.add(n.IP, ones)
+		} else {
+			t.v6.add(n.IP, ones)
+		}
+		return
+	}
+	if ip := net.ParseIP(v); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			t.v4.add(ip4, net.IPv4len*8)
+		} else {
+			t.v6.add(ip, net.IPv6len*8)
+		}
+	}
+}
+
+// Len returns the number of distinct values in the table.
+func (t *LookupTable) Len() int { return len(t.values) }
+
+// Contains determines if the table contains the value.
+func (t *LookupTable) Contains(v string) bool {
+	_, ok := t.values[v]
+	return ok
+}
+
+// ContainsFold determines if the table contains the value under case folding.
+func (t *LookupTable) ContainsFold(v string) bool {
+	_, ok := t.folded[strings.ToLower(v)]
+	return ok
+}
+
+// ContainsIP determines if the IP address is in the table or within any of the CIDR ranges in the table.
+func (t *LookupTable) ContainsIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		return t.v4.contains(ip4)
+	}
+	if len(ip) != net.IPv6len {
+		return false
+	}
+	return t.v6.contains(ip)
+}
+
+// String returns the table reference as it appears in conditions.
+func (t *LookupTable) String() string { return fmt.Sprintf("$lookup('%s')", t.Name) }
+
+// FiltersWithLookups builds the filter config with the given lookup tables.
+func FiltersWithLookups(tables ...*LookupTable) *Filters {
+	lookups := make(map[string]*LookupTable, len(tables))
+	for _, t := range tables {
+		lookups[t.Name] = t
+	}
+	return &Filters{lookups: lookups}
+}
+
+// HasLookups determines if any lookup tables are loaded.
+func (f Filters) HasLookups() bool { return len(f.lookups) > 0 }
+
+// GetLookup returns the lookup table with the given name or nil if the table doesn't exist.
+func (f Filters) GetLookup(name string) *LookupTable { return f.lookups[name] }
+
+// LoadLookups reads all lookup table files. CSV files contribute the first column
+// of each record, JSON files contain the array of values or the object whose keys
+// are table values, and any other file lists one value per line. Empty lines and
+// lines starting with # are skipped.
+func (f *Filters) LoadLookups() error {
+	lookups := make(map[string]*LookupTable)
+	for _, p := range f.Lookups.FromPaths {
+		paths, err := filepath.Glob(p)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			if prev, ok := lookups[name]; ok {
+				return fmt.Errorf("lookup table names must be unique. Found duplicate %q table in %s and %s", name, prev.Resource, path)
+			}
+			buf, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("couldn't load lookup table from file: %v", err)
+			}
+			values, err := DecodeLookupValues(Resource{Name: path, Content: buf})
+			if err != nil {
+				return err
+			}
+			t := NewLookupTable(name, values)
+			t.Resource = path
+			lookups[name] = t
+			log.Infof("loaded %d value(s) into %q lookup table from file %s", t.Len(), name, path)
+		}
+	}
+	f.lookups = lookups
+	return nil
+}
+
+// DecodeLookupValues decodes lookup table values from the resource.
+// The format is determined by the resource file extension.
+func DecodeLookupValues(res Resource) ([]string, error) {
+	var (
+		values []string
+		err    error
+	)
+	switch strings.ToLower(filepath.Ext(res.Name)) {
+	case ".csv":
+		values, err = decodeCSVLookup(res.Content)
+	case ".json":
+		values, err = decodeJSONLookup(res.Content)
+	default:
+		values, err = decodeLineLookup(res.Content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%q is invalid lookup table file: %v", res.Name, err)
+	}
+	return values, nil
+}
+
+func decodeCSVLookup(b []byte) ([]string, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	values := make([]string, 0)
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return values, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if v := strings.TrimSpace(record[0]); v != "" {
+			values = append(values, v)
+		}
+	}
+}
+
+func decodeJSONLookup(b []byte) ([]string, error) {
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	values := make([]string, 0)
+	switch v := out.(type) {
+	case []interface{}:
+		for _, e := range v {
+			switch e := e.(type) {
+			case string:
+				values = append(values, e)
+			case float64:
+				values = append(values, strconv.FormatFloat(e, 'f', -1, 64))
+			default:
+				return nil, fmt.Errorf("expected string or number values, but found %v", e)
+			}
+		}
+	case map[string]interface{}:
+		for k := range v {
+			values = append(values, k)
+		}
+	default:
+		return nil, errors.New("expected array of values or object")
+	}
+	return values, nil
+}
+
+func decodeLineLookup(b []byte) ([]string, error) {
+	values := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		values = append(values, line)
+	}
+	return values, scanner.Err()
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLookups(t *testing.T) {
+	filters := &Filters{Lookups: Lookups{FromPaths: []string{"_fixtures/lookups/*"}}}
+	require.NoError(t, filters.LoadLookups())
+	require.True(t, filters.HasLookups())
+
+	binaries := filters.GetLookup("trusted_binaries")
+	require.NotNil(t, binaries)
+	assert.Equal(t, 3, binaries.Len())
+	assert.True(t, binaries.Contains(`C:\Program Files\Backup\agent.exe`))
+	assert.False(t, binaries.Contains(`c:\windows\explorer.exe`))
+	assert.True(t, binaries.ContainsFold(`c:\windows\explorer.exe`))
+	assert.False(t, binaries.Contains("Microsoft Windows"))
+
+	hashes := filters.GetLookup("malware_hashes")
+	require.NotNil(t, hashes)
+	assert.Equal(t, 2, hashes.Len())
+	assert.True(t, hashes.Contains("44d88612fea8a8f36de82e1278abb02f"))
+	assert.True(t, hashes.ContainsFold("e1105070ba828007508566e28a2b8d4c65d192e9eaf3b7868382b7cae747b397"))
+
+	signers := filters.GetLookup("signers")
+	require.NotNil(t, signers)
+	assert.True(t, signers.Contains("Google LLC"))
+	assert.True(t, signers.Contains("7"))
+
+	ranges := filters.GetLookup("c2_ranges")
+	require.NotNil(t, ranges)
+	assert.Equal(t, 4, ranges.Len())
+	assert.True(t, ranges.Contains("45.153.160.2"))
+
+	assert.Nil(t, filters.GetLookup("unknown"))
+
+	// reloading replaces all tables
+	filters.Lookups.FromPaths = []string{"_fixtures/lookups/*.csv"}
+	require.NoError(t, filters.LoadLookups())
+	assert.NotNil(t, filters.GetLookup("trusted_binaries"))
+	assert.Nil(t, filters.GetLookup("c2_ranges"))
+}
+
+func TestLoadLookupsDuplicateNames(t *testing.T) {
+	filters := &Filters{Lookups: Lookups{FromPaths: []string{"_fixtures/lookups/*.csv", "_fixtures/lookups/trusted_binaries.csv"}}}
+	require.Error(t, filters.LoadLookups())
+}
+
+func TestLookupTableContainsIP(t *testing.T) {
+	table := NewLookupTable("c2_ranges", []string{
+		"185.220.101.0/24",
+		"45.153.160.2",
+		"10.0.0.0/8",
+		"10.20.0.0/16",
+		"2a0b:f4c2::/32",
+		"fe80::1",
+		"not an ip",
+	})
+
+	var tests = []struct {
+		ip       string
+		contains bool
+	}{
+		{"185.220.101.1", true},
+		{"185.220.101.255", true},
+		{"185.220.102.1", false},
+		{"45.153.160.2", true},
+		{"45.153.160.3", false},
+		{"10.1.2.3", true},
+		{"11.1.2.3", false},
+		{"::ffff:185.220.101.7", true},
+		{"2a0b:f4c2:1::5", true},
+		{"2a0b:f4c3::5", false},
+		{"fe80::1", true},
+		{"fe80::2", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.contains, table.ContainsIP(net.ParseIP(tt.ip)), tt.ip)
+	}
+	assert.False(t, table.ContainsIP(nil))
+}
+
+func TestDecodeLookupValues(t *testing.T) {
+	values, err := DecodeLookupValues(Resource{Name: "hashes.txt", Content: []byte("\n# comment\n  a1b2  \r\nc3d4\n")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a1b2", "c3d4"}, values)
+
+	_, err = DecodeLookupValues(Resource{Name: "signers.json", Content: []byte(`[{"name": "Microsoft"}]`)})
+	require.Error(t, err)
+
+	_, err = DecodeLookupValues(Resource{Name: "signers.json", Content: []byte(`"Microsoft"`)})
+	require.Error(t, err)
+
+	_, err = DecodeLookupValues(Resource{Name: "binaries.csv", Content: []byte("\"C:\\Windows\n")})
+	require.Error(t, err)
+}
@@ -22,17 +22,22 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"strconv"
+	"sort"
+	"strings"
 
 	"github.com/rabbitstack/fibratus/pkg/outputs/eventlog"
+	"github.com/rabbitstack/fibratus/pkg/outputs/file"
 
 	"github.com/rabbitstack/fibratus/pkg/outputs"
 	"github.com/rabbitstack/fibratus/pkg/outputs/amqp"
 	"github.com/rabbitstack/fibratus/pkg/outputs/console"
 	"github.com/rabbitstack/fibratus/pkg/outputs/elasticsearch"
 	"github.com/rabbitstack/fibratus/pkg/outputs/http"
+	"github.com/rabbitstack/fibratus/pkg/outputs/kafka"
 	"github.com/rabbitstack/fibratus/pkg/outputs/null"
+	"github.com/rabbitstack/fibratus/pkg/outputs/syslog"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
 	"golang.org/x/sys/windows/svc"
 )
 
@@ -40,123 +45,235 @@ var errNoOutputSection = errors.New("no output section in config")
 
 var errOutputConfig = func(output string, err error) error { return fmt.Errorf("%s output invalid config: %v", output, err) }
 
+// tryLoadOutput loads all enabled outputs. Outputs declared in the output
+// section are named after the output type, while the outputs section
+// contains the list of named outputs. Multiple outputs of the same type
+// can be declared in the outputs section.
 func (c *Config) tryLoadOutput() error {
-	output := c.viper.AllSettings()["output"]
-	if output == nil {
+	settings := c.viper.AllSettings()
+	output, named := settings["output"], settings["outputs"]
+	if output == nil && named == nil {
 		return errNoOutputSection
 	}
-	mapping, ok := output.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("expected map[string]interface{} type for output but found %s", reflect.TypeOf(output))
-	}
-
-	humNum := func(n int) string {
-		switch n {
-		case 2:
-			return "two"
-		case 3:
-			return "three"
-		case 4:
-			return "four"
-		case 5:
-			return "five"
-		case 6:
-			return "six"
-		case 7:
-			return "seven"
-		default:
-			return strconv.Itoa(n)
-		}
-	}
-	// don't permit if there are various outputs enabled at a time
-	activeOutputs := findActiveOutputs(mapping)
-	if len(activeOutputs) > 1 {
-		return fmt.Errorf("expected one but found %s active outputs: %s", humNum(len(activeOutputs)), activeOutputs)
-	}
 
-	for typ, config := range mapping {
-		switch outputs.TypeFromString(typ) {
-		case outputs.Console:
-			var consoleConfig console.Config
-			if err := decode(config, &consoleConfig); err != nil {
-				return errOutputConfig(typ, err)
+	c.Outputs = make([]outputs.Config, 0)
+
+	if output != nil {
+		mapping, ok := output.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected map[string]interface{} type for output but found %s", reflect.TypeOf(output))
+		}
+		for typ, config := range mapping {
+			out, enabled, err := decodeOutput(typ, config)
+			if err != nil {
+				return err
 			}
-			if !consoleConfig.Enabled {
+			if !enabled {
 				continue
 			}
-			c.Output.Type, c.Output.Output = outputs.Console, consoleConfig
+			c.Outputs = append(c.Outputs, outputs.Config{Type: outputs.TypeFromString(typ), Output: out, Name: typ})
+		}
+		sort.Slice(c.Outputs, func(i, j int) bool { return c.Outputs[i].Name < c.Outputs[j].Name })
+	}
 
-		case outputs.AMQP:
-			var amqpConfig amqp.Config
-			if err := decode(config, &amqpConfig); err != nil {
-				return errOutputConfig(typ, err)
+	if named != nil {
+		items, ok := named.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected []interface{} type for outputs but found %s", reflect.TypeOf(named))
+		}
+		for i, item := range items {
+			config, enabled, err := c.decodeNamedOutput(item)
+			if err != nil {
+				return fmt.Errorf("outputs[%d]: %v", i, err)
 			}
-			if !amqpConfig.Enabled {
+			if !enabled {
 				continue
 			}
-			c.Output.Type, c.Output.Output = outputs.AMQP, amqpConfig
+			c.Outputs = append(c.Outputs, config)
+		}
+	}
 
-		case outputs.Elasticsearch:
-			var esConfig elasticsearch.Config
-			if err := decode(config, &esConfig); err != nil {
-				return errOutputConfig(typ, err)
-			}
-			if !esConfig.Enabled {
+	names := make(map[string]bool)
+	for _, config := range c.Outputs {
+		if names[config.Name] {
+			return fmt.Errorf("duplicate %q output", config.Name)
+		}
+		names[config.Name] = true
+	}
+
+	// if it is not an interactive session but the console output is enabled
+	// we drop the console output and warn about that
+	if isWindowsService() {
+		active := c.Outputs[:0]
+		for _, config := range c.Outputs {
+			if config.Type == outputs.Console {
+				log.Warnf("running in non-interactive session with %q console output. "+
+					"Please configure a different output type", config.Name)
 				continue
 			}
-			c.Output.Type, c.Output.Output = outputs.Elasticsearch, esConfig
+			active = append(active, config)
+		}
+		c.Outputs = active
+	}
 
-		case outputs.HTTP:
-			var httpConfig http.Config
-			if err := decode(config, &httpConfig); err != nil {
-				return errOutputConfig(typ, err)
-			}
-			if !httpConfig.Enabled {
+	// default to null output
+	if len(c.Outputs) == 0 {
+		log.Warn("all outputs disabled. Defaulting to null output")
+		c.Outputs = append(c.Outputs, outputs.Config{Type: outputs.Null, Output: &null.Config{}, Name: outputs.Null.String()})
+	}
+
+	return nil
+}
+
+// decodeNamedOutput decodes the output from the outputs section. Apart from
+// the name, filter, and transformers, the output must contain exactly one
+// output type block. Unlike outputs in the output section, named outputs are
+// enabled unless explicitly disabled. Options omitted in the output type block
+// are set to their default values.
+func (c *Config) decodeNamedOutput(item interface{}) (outputs.Config, bool, error) {
+	converted, err := convertToStringKeysRecursive(item, "")
+	if err != nil {
+		return outputs.Config{}, false, err
+	}
+	mapping, ok := converted.(map[string]interface{})
+	if !ok {
+		return outputs.Config{}, false, fmt.Errorf("expected map[string]interface{} type for output but found %s", reflect.TypeOf(item))
+	}
+
+	var config outputs.Config
+	var typ string
+	for key, value := range mapping {
+		switch key {
+		case "name":
+			config.Name, _ = value.(string)
+		case "filter":
+			config.Filter, _ = value.(string)
+		case "transformers":
+			if value == nil {
 				continue
 			}
-			c.Output.Type, c.Output.Output = outputs.HTTP, httpConfig
-
-		case outputs.Eventlog:
-			var eventlogConfig eventlog.Config
-			if err := decode(config, &eventlogConfig); err != nil {
-				return errOutputConfig(typ, err)
+			config.Transformers, err = decodeTransformers(value)
+			if err != nil {
+				return config, false, err
 			}
-			if !eventlogConfig.Enabled {
-				continue
+		default:
+			if outputs.TypeFromString(key) == outputs.Unknown {
+				return config, false, fmt.Errorf("unknown output type %q", key)
+			}
+			if typ != "" {
+				return config, false, fmt.Errorf("expected one output type but found %s and %s", typ, key)
 			}
-			c.Output.Type, c.Output.Output = outputs.Eventlog, eventlogConfig
+			typ = key
 		}
 	}
+	if config.Name == "" {
+		return config, false, errors.New("output name is required")
+	}
+	if typ == "" {
+		return config, false, fmt.Errorf("%s output has no output type", config.Name)
+	}
 
-	// if it is not an interactive session but the console output is enabled
-	// we default to null output and warn about that
-	if isWindowsService() && c.Output.Output != nil {
-		if c.Output.Type == outputs.Console {
-			log.Warn("running in non-interactive session with console output. " +
-				"Please configure a different output type. Defaulting to null output")
-			c.Output.Type, c.Output.Output = outputs.Null, &null.Config{}
-			return nil
+	options := c.outputDefaults(typ)
+	if m, ok := mapping[typ].(map[string]interface{}); ok {
+		for k, v := range m {
+			options[k] = v
 		}
 	}
-
-	// default to null output
-	if c.Output.Output == nil {
-		log.Warn("all outputs disabled. Defaulting to null output")
-		c.Output.Type, c.Output.Output = outputs.Null, &null.Config{}
+	if _, ok := options["enabled"]; !ok {
+		options["enabled"] = true
+	}
+	out, enabled, err := decodeOutput(typ, options)
+	if err != nil {
+		return config, false, fmt.Errorf("%s: %v", config.Name, err)
 	}
+	config.Type, config.Output = outputs.TypeFromString(typ), out
+	return config, enabled, nil
+}
 
-	return nil
+// outputDefaults returns the default values of the output type
+// options as given by the flags registered for the output type.
+func (c *Config) outputDefaults(typ string) map[string]interface{} {
+	defaults := make(map[string]interface{})
+	prefix := "output." + typ + "."
+	c.flags.VisitAll(func(f *pflag.Flag) {
+		if !strings.HasPrefix(f.Name, prefix) {
+			return
+		}
+		val := f.DefValue
+		if strings.HasSuffix(f.Value.Type(), "Slice") {
+			val = strings.TrimSuffix(strings.TrimPrefix(val, "["), "]")
+		}
+		defaults[strings.TrimPrefix(f.Name, prefix)] = val
+	})
+	return defaults
 }
 
-func findActiveOutputs(outputs map[string]interface{}) []string {
-	outputTypes := make([]string, 0)
-	for typ, rawConfig := range outputs {
-		enabled, ok := rawConfig.(map[string]interface{})["enabled"].(bool)
-		if ok && enabled {
-			outputTypes = append(outputTypes, typ)
+// decodeOutput decodes the output type config. It returns
+// the output config and the value of the enabled option.
+func decodeOutput(typ string, config interface{}) (interface{}, bool, error) {
+	switch outputs.TypeFromString(typ) {
+	case outputs.Console:
+		var consoleConfig console.Config
+		if err := decode(config, &consoleConfig); err != nil {
+			return nil, false, errOutputConfig(typ, err)
 		}
+		return consoleConfig, consoleConfig.Enabled, nil
+
+	case outputs.AMQP:
+		var amqpConfig amqp.Config
+		if err := decode(config, &amqpConfig); err != nil {
+			return nil, false, errOutputConfig(typ, err)
+		}
+		return amqpConfig, amqpConfig.Enabled, nil
+
+	case outputs.Elasticsearch:
+		var esConfig elasticsearch.Config
+		if err := decode(config, &esConfig); err != nil {
+			return nil, false, errOutputConfig(typ, err)
+		}
+		return esConfig, esConfig.Enabled, nil
+
+	case outputs.HTTP:
+		var httpConfig http.Config
+		if err := decode(config, &httpConfig); err != nil {
+			return nil, false, errOutputConfig(typ, err)
+		}
+		return httpConfig, httpConfig.Enabled, nil
+
+	case outputs.Eventlog:
+		var eventlogConfig eventlog.Config
+		if err := decode(config, &eventlogConfig); err != nil {
+			return nil, false, errOutputConfig(typ, err)
+		}
+		return eventlogConfig, eventlogConfig.Enabled, nil
+
+	case outputs.Kafka:
+		var kafkaConfig kafka.Config
+		if err := decode(config, &kafkaConfig); err != nil {
+			return nil, false, errOutputConfig(typ, err)
+		}
+		return kafkaConfig, kafkaConfig.Enabled, nil
+
+	case outputs.Syslog:
+		var syslogConfig syslog.Config
+		if err := decode(config, &syslogConfig); err != nil {
+			return nil, false, errOutputConfig(typ, err)
+		}
+		return syslogConfig, syslogConfig.Enabled, nil
+
+	case outputs.File:
+		var fileConfig file.Config
+		if err := decode(config, &fileConfig); err != nil {
+			return nil, false, errOutputConfig(typ, err)
+		}
+		return fileConfig, fileConfig.Enabled, nil
+
+	case outputs.Null:
+		m, _ := config.(map[string]interface{})
+		enabled, ok := m["enabled"].(bool)
+		return &null.Config{}, ok && enabled, nil
 	}
-	return outputTypes
+	return nil, false, nil
 }
 
 // isWindowsService returns true if the process is running inside Windows Service.
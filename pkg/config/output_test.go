@@ -24,8 +24,15 @@ import (
 
 	"github.com/rabbitstack/fibratus/pkg/outputs/eventlog"
 
+	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers/remove"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
 	"github.com/rabbitstack/fibratus/pkg/outputs/amqp"
+	"github.com/rabbitstack/fibratus/pkg/outputs/console"
+	"github.com/rabbitstack/fibratus/pkg/outputs/elasticsearch"
 	"github.com/rabbitstack/fibratus/pkg/outputs/http"
+	"github.com/rabbitstack/fibratus/pkg/outputs/kafka"
+	"github.com/rabbitstack/fibratus/pkg/outputs/syslog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -40,10 +47,10 @@ func TestAMQPOutput(t *testing.T) {
 
 	require.NoError(t, c.Init())
 
-	require.NotNil(t, c.Output)
-	require.IsType(t, amqp.Config{}, c.Output.Output)
+	require.Len(t, c.Outputs, 1)
+	require.IsType(t, amqp.Config{}, c.Outputs[0].Output)
 
-	amqpConfig := c.Output.Output.(amqp.Config)
+	amqpConfig := c.Outputs[0].Output.(amqp.Config)
 	assert.Equal(t, "amqp://localhost:5672", amqpConfig.URL)
 	assert.Equal(t, time.Second*5, amqpConfig.Timeout)
 	assert.Equal(t, "fibratus", amqpConfig.Exchange)
@@ -62,10 +69,10 @@ func TestHTTPOutput(t *testing.T) {
 
 	require.NoError(t, c.Init())
 
-	require.NotNil(t, c.Output)
-	require.IsType(t, http.Config{}, c.Output.Output)
+	require.Len(t, c.Outputs, 1)
+	require.IsType(t, http.Config{}, c.Outputs[0].Output)
 
-	httpConfig := c.Output.Output.(http.Config)
+	httpConfig := c.Outputs[0].Output.(http.Config)
 	assert.True(t, httpConfig.Enabled)
 	assert.Len(t, httpConfig.Endpoints, 2)
 	assert.Contains(t, httpConfig.Endpoints, "http://localhost:8081")
@@ -90,10 +97,83 @@ func TestEventlogOutput(t *testing.T) {
 
 	require.NoError(t, c.Init())
 
-	require.NotNil(t, c.Output)
-	require.IsType(t, eventlog.Config{}, c.Output.Output)
+	require.Len(t, c.Outputs, 1)
+	require.IsType(t, eventlog.Config{}, c.Outputs[0].Output)
 
-	eventlogConfig := c.Output.Output.(eventlog.Config)
+	eventlogConfig := c.Outputs[0].Output.(eventlog.Config)
 	assert.True(t, eventlogConfig.Enabled)
 	assert.Equal(t, "INFO", eventlogConfig.Level)
 }
+
+func TestNamedOutputs(t *testing.T) {
+	c := NewWithOpts(WithRun())
+
+	err := c.flags.Parse([]string{"--config-file=_fixtures/outputs.yml"})
+	require.NoError(t, c.viper.BindPFlags(c.flags))
+	require.NoError(t, err)
+	require.NoError(t, c.TryLoadFile(c.GetConfigFile()))
+
+	require.NoError(t, c.Init())
+
+	require.Len(t, c.Outputs, 6)
+
+	assert.Equal(t, "console", c.Outputs[0].Name)
+	assert.Equal(t, outputs.Console, c.Outputs[0].Type)
+	assert.Empty(t, c.Outputs[0].Filter)
+
+	assert.Equal(t, "hunting", c.Outputs[1].Name)
+	require.IsType(t, elasticsearch.Config{}, c.Outputs[1].Output)
+	esConfig := c.Outputs[1].Output.(elasticsearch.Config)
+	assert.True(t, esConfig.Enabled)
+	assert.Equal(t, []string{"http://localhost:9200"}, esConfig.Servers)
+	assert.Equal(t, "fibratus", esConfig.IndexName)
+	// defaults to the flag value
+	assert.Equal(t, time.Second*5, esConfig.Timeout)
+
+	assert.Equal(t, "soar", c.Outputs[2].Name)
+	assert.Equal(t, "kevt.category = 'process'", c.Outputs[2].Filter)
+	require.IsType(t, amqp.Config{}, c.Outputs[2].Output)
+	assert.Equal(t, "soar", c.Outputs[2].Output.(amqp.Config).Exchange)
+	require.Len(t, c.Outputs[2].Transformers, 1)
+	assert.Equal(t, transformers.Remove, c.Outputs[2].Transformers[0].Type)
+	assert.Equal(t, []string{"irp", "file_object"}, c.Outputs[2].Transformers[0].Transformer.(remove.Config).Kparams)
+
+	assert.Equal(t, "alerts", c.Outputs[3].Name)
+	assert.Equal(t, "kevt.meta in ('rule.name')", c.Outputs[3].Filter)
+	require.IsType(t, console.Config{}, c.Outputs[3].Output)
+	assert.Equal(t, "json", c.Outputs[3].Output.(console.Config).Format)
+
+	assert.Equal(t, "pipeline", c.Outputs[4].Name)
+	assert.Equal(t, outputs.Kafka, c.Outputs[4].Type)
+	require.IsType(t, kafka.Config{}, c.Outputs[4].Output)
+	kafkaConfig := c.Outputs[4].Output.(kafka.Config)
+	assert.Equal(t, []string{"kafka-1:9092", "kafka-2:9092"}, kafkaConfig.Brokers)
+	assert.Equal(t, "fibratus-{{ .Category }}", kafkaConfig.Topic)
+	assert.Equal(t, "{{ .Host }}", kafkaConfig.PartitionKey)
+	assert.Equal(t, "zstd", kafkaConfig.Compression)
+	assert.Equal(t, "leader", kafkaConfig.Acks)
+	assert.Equal(t, 500, kafkaConfig.BatchSize)
+	assert.Equal(t, time.Millisecond*100, kafkaConfig.Linger)
+
+	assert.Equal(t, "siem", c.Outputs[5].Name)
+	assert.Equal(t, outputs.Syslog, c.Outputs[5].Type)
+	require.IsType(t, syslog.Config{}, c.Outputs[5].Output)
+	syslogConfig := c.Outputs[5].Output.(syslog.Config)
+	assert.Equal(t, "tcp", syslogConfig.Network)
+	assert.Equal(t, "siem:6514", syslogConfig.Address)
+	assert.Equal(t, "cef", syslogConfig.Format)
+	assert.Equal(t, "rfc5424", syslogConfig.Protocol)
+	assert.Equal(t, "octet-counting", syslogConfig.Framing)
+	assert.Equal(t, map[string]string{"deviceExternalId": "{{ .Host }}"}, syslogConfig.Extensions)
+}
+
+func TestDuplicateOutputNames(t *testing.T) {
+	c := NewWithOpts(WithRun())
+
+	err := c.flags.Parse([]string{"--config-file=_fixtures/duplicate-outputs.yml"})
+	require.NoError(t, c.viper.BindPFlags(c.flags))
+	require.NoError(t, err)
+	require.NoError(t, c.TryLoadFile(c.GetConfigFile()))
+
+	require.EqualError(t, c.Init(), `duplicate "soar" output`)
+}
@@ -34,7 +34,19 @@ var schema = `
 			"type": "object",
 			"properties": {
 				"flush-period":		{"type": "string", "minLength": 2, "pattern": "[0-9]+ms|s"},
-				"flush-timeout":	{"type": "string", "minLength": 2, "pattern": "[0-9]+s"}
+				"flush-timeout":	{"type": "string", "minLength": 2, "pattern": "[0-9]+s"},
+				"queue-size":		{"type": "integer", "minimum": 1},
+				"drop-on-full":		{"type": "boolean"},
+				"spool": {
+					"type": "object",
+					"properties": {
+						"enabled":	{"type": "boolean"},
+						"dir":		{"type": "string", "minLength": 1},
+						"max-size":	{"type": "integer", "minimum": 1},
+						"sync-interval":	{"type": "string", "minLength": 2, "pattern": "[0-9]+(ms|s|m)"}
+					},
+					"additionalProperties": false
+				}
 			},
 			"additionalProperties": false
 		},
@@ -137,7 +149,60 @@ var schema = `
                         "from-paths": 	{"type": ["array", "null"], "items": [{"type": "string", "minLength": 4}]}
                     },
                     "additionalProperties": false
-                }
+                },
+				"lookups": {
+					"type": "object",
+					"properties": {
+						"from-paths": 	{"type": ["array", "null"], "items": [{"type": "string", "minLength": 4}]}
+					},
+					"additionalProperties": false
+				},
+				"exceptions": {
+					"type": "object",
+					"properties": {
+						"from-paths": 	{"type": ["array", "null"], "items": [{"type": "string", "minLength": 4}]}
+					},
+					"additionalProperties": false
+				},
+				"sequences": {
+					"type": "object",
+					"properties": {
+						"state-file":			{"type": "string"},
+						"snapshot-interval":	{"type": "string", "minLength": 2, "pattern": "[0-9]+s|m|h"}
+					},
+					"additionalProperties": false
+				},
+				"profiling": {
+					"type": "object",
+					"properties": {
+						"enabled":	{"type": "boolean"}
+					},
+					"additionalProperties": false
+				},
+				"strict":	{"type": "boolean"},
+				"actions": {
+					"type": "object",
+					"properties": {
+						"rate-limit": {
+							"type": "object",
+							"properties": {
+								"max":		{"type": "integer", "minimum": 0},
+								"interval":	{"type": "string", "minLength": 2, "pattern": "[0-9]+(ns|us|ms|s|m|h)"}
+							},
+							"additionalProperties": false
+						},
+						"audit-log":			{"type": "string"},
+						"quarantine-dir":		{"type": "string"},
+						"capture-dir":			{"type": "string"},
+						"max-capture-duration":	{"type": "string", "minLength": 2, "pattern": "[0-9]+(ns|us|ms|s|m|h)"},
+						"scripts": {
+							"type": "object",
+							"additionalProperties": {"type": "string", "minLength": 1}
+						},
+						"exec-timeout":			{"type": "string", "minLength": 2, "pattern": "[0-9]+(ns|us|ms|s|m|h)"}
+					},
+					"additionalProperties": false
+				}
 			},
 			"additionalProperties": false
 		},
@@ -277,6 +342,72 @@ var schema = `
 							},
 							"additionalProperties": false
 						},
+						"kafka": {
+							"type": "object",
+							"properties": {
+								"enabled":					{"type": "boolean"},
+								"brokers": 					{"type": "array", "items": [{"type": "string", "minLength": 1}]},
+								"topic": 					{"type": "string", "minLength": 1},
+								"partition-key": 			{"type": "string"},
+								"client-id": 				{"type": "string"},
+								"version": 					{"type": "string", "pattern": "^[0-9]+\\.[0-9]+\\.[0-9]+(\\.[0-9]+)?$"},
+								"timeout": 					{"type": "string", "minLength": 2, "pattern": "[0-9]+s|m}"},
+								"acks": 					{"type": "string", "enum": ["none", "leader", "all"]},
+								"compression": 				{"type": "string", "enum": ["none", "gzip", "snappy", "lz4", "zstd"]},
+								"batch-size": 				{"type": "integer", "minimum": 0},
+								"batch-bytes": 				{"type": "integer", "minimum": 0},
+								"linger": 					{"type": "string", "minLength": 2, "pattern": "[0-9]+ms|s"},
+								"max-retries": 				{"type": "integer", "minimum": 0},
+								"sasl-mechanism": 			{"type": "string", "enum": ["", "plain", "scram-sha-256", "scram-sha-512"]},
+								"username": 				{"type": "string"},
+								"password": 				{"type": "string"},
+								"tls-key": 					{"type": "string"},
+								"tls-cert": 				{"type": "string"},
+								"tls-ca": 					{"type": "string"},
+								"tls-insecure-skip-verify": {"type": "boolean"},
+								"headers":					{"type": "object", "additionalProperties": true}
+							},
+							"additionalProperties": false
+						},
+						"syslog": {
+							"type": "object",
+							"properties": {
+								"enabled":					{"type": "boolean"},
+								"network": 					{"type": "string", "enum": ["udp", "tcp", "tls"]},
+								"address": 					{"type": "string", "minLength": 1},
+								"timeout": 					{"type": "string", "minLength": 2, "pattern": "[0-9]+s|m}"},
+								"protocol": 				{"type": "string", "enum": ["rfc5424", "rfc3164"]},
+								"framing": 					{"type": "string", "enum": ["octet-counting", "non-transparent"]},
+								"format": 					{"type": "string", "enum": ["json", "cef", "leef"]},
+								"facility": 				{"type": "string", "enum": ["kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp", "cron", "authpriv", "ftp", "local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7"]},
+								"app-name": 				{"type": "string"},
+								"hostname": 				{"type": "string"},
+								"severity-key": 			{"type": "string"},
+								"extensions":				{"type": "object", "additionalProperties": {"type": "string"}},
+								"tls-key": 					{"type": "string"},
+								"tls-cert": 				{"type": "string"},
+								"tls-ca": 					{"type": "string"},
+								"tls-insecure-skip-verify": {"type": "boolean"}
+							},
+							"additionalProperties": false
+						},
+						"file": {
+							"type": "object",
+							"properties": {
+								"enabled":					{"type": "boolean"},
+								"path": 					{"type": "string", "minLength": 1},
+								"name": 					{"type": "string", "minLength": 1},
+								"serializer": 				{"type": "string", "enum": ["json"]},
+								"template": 				{"type": "string"},
+								"max-size": 				{"type": "integer", "minimum": 0},
+								"interval": 				{"type": "string", "pattern": "^([0-9]+(ns|us|ms|s|m|h))+$"},
+								"compression": 				{"type": "string", "enum": ["none", "gzip", "zstd"]},
+								"max-age": 					{"type": "integer", "minimum": 0},
+								"max-files": 				{"type": "integer", "minimum": 0},
+								"split-by-category": 		{"type": "boolean"}
+							},
+							"additionalProperties": false
+						},
 						"eventlog": {
 							"type": "object",
 							"properties": {
@@ -292,6 +423,30 @@ var schema = `
 				}
 			]
 		},
+		"outputs": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"name":				{"type": "string", "minLength": 1},
+					"filter":			{"type": "string"},
+					"transformers":		{"$ref": "#/properties/transformers"},
+					"console":			{"$ref": "#/properties/output/anyOf/0/properties/console"},
+					"elasticsearch":	{"$ref": "#/properties/output/anyOf/0/properties/elasticsearch"},
+					"amqp":				{"$ref": "#/properties/output/anyOf/0/properties/amqp"},
+					"http":				{"$ref": "#/properties/output/anyOf/0/properties/http"},
+					"eventlog":			{"$ref": "#/properties/output/anyOf/0/properties/eventlog"},
+					"kafka":			{"$ref": "#/properties/output/anyOf/0/properties/kafka"},
+					"syslog":			{"$ref": "#/properties/output/anyOf/0/properties/syslog"},
+					"file":				{"$ref": "#/properties/output/anyOf/0/properties/file"},
+					"null":				{"type": ["object", "null"]}
+				},
+				"required": ["name"],
+				"minProperties": 2,
+				"maxProperties": 4,
+				"additionalProperties": false
+			}
+		},
 		"pe": {
 			"type": "object",
 			"properties": {
@@ -483,7 +638,22 @@ var schema = `
 var rulesSchema = `
 {
 	"$schema": "http://json-schema.org/draft-07/schema#",
-    "definitions": {"rules": {"$id": "#rules", "type": "object", "type": "array",
+    "definitions": {"suppress": {"$id": "#suppress", "type": "object",
+			"properties": {
+				"by":			{"type": "array", "items": [{"type": "string", "minLength": 1}]},
+				"window":		{"type": "string", "pattern": "^([0-9]+(ns|us|ms|s|m|h))+$"},
+				"max-alerts":	{"type": "integer", "minimum": 1}
+			},
+			"required": ["window"],
+			"additionalProperties": false},
+		"rate-limit": {"$id": "#rate-limit", "type": "object",
+			"properties": {
+				"max":		{"type": "integer", "minimum": 0},
+				"interval":	{"type": "string", "pattern": "^([0-9]+(ns|us|ms|s|m|h))+$"}
+			},
+			"required": ["max"],
+			"additionalProperties": false},
+		"rules": {"$id": "#rules", "type": "object", "type": "array",
 			"items":
 				{
 					"type": "object",
@@ -492,7 +662,13 @@ var rulesSchema = `
                         "description":  {"type": "string"},
 						"def": 			{"type": "string", "minLength": 3},
 						"condition": 	{"type": "string", "minLength": 3},
-						"action": 		{"type": "string"}
+						"action": 		{"type": "string"},
+						"labels": {
+							"type": "object",
+							"additionalProperties": { "type": "string" }
+						},
+						"suppress":		{"$ref": "#suppress"},
+						"rate-limit":	{"$ref": "#rate-limit"}
 					},
 					"oneOf": [
 						{"required": ["def"]},
@@ -517,7 +693,8 @@ var rulesSchema = `
         "labels": {
   			"type": "object",
   			"additionalProperties": { "type": "string" }
-		}
+		},
+		"suppress":		{"$ref": "#suppress"}
 	},
 	"required": ["group"],
 	"oneOf": [
@@ -552,6 +729,36 @@ var macrosSchema = `
 }
 `
 
+var exceptionsSchema = `
+{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"type": "array",
+	"items":
+	{
+		"type": "object",
+		"properties": {
+			"exception": 		{"type": "string", "minLength": 3},
+			"justification":	{"type": "string"},
+			"rules":			{"type": "array", "items": [{"type": "string", "minLength": 1}]},
+			"groups":			{"type": "array", "items": [{"type": "string", "minLength": 1}]},
+			"labels": {
+				"type": "object",
+				"additionalProperties": {"type": "string"}
+			},
+			"condition":		{"type": "string", "minLength": 3},
+			"expires":			{"type": "string", "minLength": 8}
+		},
+		"required": ["exception", "condition"],
+		"anyOf": [
+			{"required": ["rules"]},
+			{"required": ["groups"]},
+			{"required": ["labels"]}
+		],
+		"additionalProperties": false
+	}
+}
+`
+
 type schemaConfig struct {
 	MaxBuffers    uint32
 	MinBuffers    uint32
@@ -36,9 +36,20 @@ func (c *Config) tryLoadTransformers() error {
 	if transforms == nil {
 		return nil
 	}
+	configs, err := decodeTransformers(transforms)
+	if err != nil {
+		return err
+	}
+	c.Transformers = configs
+	return nil
+}
+
+// decodeTransformers decodes the configuration of all enabled transformers
+// declared in the transformers section of the config file or the output.
+func decodeTransformers(transforms interface{}) ([]transformers.Config, error) {
 	mapping, ok := transforms.(map[string]interface{})
 	if !ok {
-		return fmt.Errorf("expected map[string]interface{} type for transformers but found %s", reflect.TypeOf(transforms))
+		return nil, fmt.Errorf("expected map[string]interface{} type for transformers but found %s", reflect.TypeOf(transforms))
 	}
 
 	configs := make([]transformers.Config, 0)
@@ -48,7 +59,7 @@ func (c *Config) tryLoadTransformers() error {
 		case "remove":
 			var removeConfig remove.Config
 			if err := decode(config, &removeConfig); err != nil {
-				return errTransformerConfig(typ, err)
+				return nil, errTransformerConfig(typ, err)
 			}
 			if !removeConfig.Enabled {
 				continue
@@ -62,7 +73,7 @@ func (c *Config) tryLoadTransformers() error {
 		case "rename":
 			var renameConfig rename.Config
 			if err := decode(config, &renameConfig); err != nil {
-				return errTransformerConfig(typ, err)
+				return nil, errTransformerConfig(typ, err)
 			}
 			if !renameConfig.Enabled {
 				continue
@@ -76,7 +87,7 @@ func (c *Config) tryLoadTransformers() error {
 		case "replace":
 			var replaceConfig replace.Config
 			if err := decode(config, &replaceConfig); err != nil {
-				return errTransformerConfig(typ, err)
+				return nil, errTransformerConfig(typ, err)
 			}
 			if !replaceConfig.Enabled {
 				continue
@@ -90,7 +101,7 @@ func (c *Config) tryLoadTransformers() error {
 		case "trim":
 			var trimConfig trim.Config
 			if err := decode(config, &trimConfig); err != nil {
-				return errTransformerConfig(typ, err)
+				return nil, errTransformerConfig(typ, err)
 			}
 			if !trimConfig.Enabled {
 				continue
@@ -104,7 +115,7 @@ func (c *Config) tryLoadTransformers() error {
 		case "tags":
 			var tagsConfig tags.Config
 			if err := decode(config, &tagsConfig); err != nil {
-				return errTransformerConfig(typ, err)
+				return nil, errTransformerConfig(typ, err)
 			}
 			if !tagsConfig.Enabled {
 				continue
@@ -117,7 +128,5 @@ func (c *Config) tryLoadTransformers() error {
 		}
 	}
 
-	c.Transformers = configs
-
-	return nil
+	return configs, nil
 }
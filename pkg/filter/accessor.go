@@ -20,6 +20,7 @@ package filter
 
 import (
 	"errors"
+	"fmt"
 	"github.com/rabbitstack/fibratus/pkg/filter/fields"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
@@ -64,6 +65,8 @@ func (k *kevtAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value,
 		return kevt.Host, nil
 	case fields.KevtTime:
 		return kevt.Timestamp.Format(timeFmt), nil
+	case fields.KevtTimestamp:
+		return kevt.Timestamp, nil
 	case fields.KevtTimeHour:
 		return uint8(kevt.Timestamp.Hour()), nil
 	case fields.KevtTimeMin:
@@ -90,7 +93,25 @@ func (k *kevtAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value,
 		return kevt.Timestamp.Weekday().String(), nil
 	case fields.KevtNparams:
 		return uint64(kevt.Kparams.Len()), nil
+	case fields.KevtMeta:
+		keys := make([]string, 0, len(kevt.Metadata))
+		for k := range kevt.Metadata {
+			keys = append(keys, k.String())
+		}
+		return keys, nil
 	default:
+		if f.IsMetaSequence() {
+			// access the value of the specific metadata key
+			key, _ := captureInBrackets(f.String())
+			v, ok := kevt.Metadata[kevent.MetadataKey(key)]
+			if !ok {
+				return nil, nil
+			}
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return fmt.Sprintf("%v", v), nil
+		}
 		return nil, nil
 	}
 }
@@ -197,6 +197,12 @@ func (ps *psAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value, e
 			return nil, nil
 		}
 		return ps.SessionID, nil
+	case fields.PsStartTime:
+		ps := kevt.PS
+		if ps == nil || ps.StartTime.IsZero() {
+			return nil, nil
+		}
+		return ps.StartTime, nil
 	case fields.PsAccessMask:
 		if kevt.Type != ktypes.OpenProcess {
 			return nil, nil
@@ -816,6 +822,8 @@ func (*peAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value, erro
 		return p.Symbols, nil
 	case fields.PeImports:
 		return p.Imports, nil
+	case fields.PeImphash:
+		return p.Imphash, nil
 	case fields.PeCompany:
 		return p.VersionResources[pe.Company], nil
 	case fields.PeCopyright:
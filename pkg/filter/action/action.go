@@ -0,0 +1,211 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package action
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	executedActions    = expvar.NewMap("action.executed")
+	failedActions      = expvar.NewMap("action.failed")
+	rateLimitedActions = expvar.NewMap("action.rate.limited")
+)
+
+// Outcome is the result of the action execution as recorded in the audit log.
+type Outcome string
+
+const (
+	// Succeeded indicates the action was executed successfully
+	Succeeded Outcome = "succeeded"
+	// Failed indicates the action returned an error
+	Failed Outcome = "failed"
+	// RateLimited indicates the action was skipped because
+	// the rule exhausted the rate limit of the action
+	RateLimited Outcome = "rate-limited"
+)
+
+// AuditRecord describes the execution of the rule action.
+type AuditRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Rule      string    `json:"rule"`
+	Group     string    `json:"group"`
+	Action    string    `json:"action"`
+	Args      []string  `json:"args,omitempty"`
+	Outcome   Outcome   `json:"outcome"`
+	// Detail carries the action specific result, e.g. the path of the quarantined file
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+var (
+	mu       sync.RWMutex
+	cfg      config.Actions
+	auditLog *os.File
+	limits   = newLimiter()
+)
+
+// Configure applies the config of rule actions. It resets rate limits
+// and reopens the audit log file if the path is given in the config.
+func Configure(c config.Actions) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if auditLog != nil {
+		_ = auditLog.Close()
+		auditLog = nil
+	}
+	if c.AuditLog != "" {
+		f, err := os.OpenFile(c.AuditLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("couldn't open action audit log: %v", err)
+		}
+		auditLog = f
+	}
+	cfg = c
+	limits = newLimiter()
+	return nil
+}
+
+func actionsConfig() config.Actions {
+	mu.RLock()
+	defer mu.RUnlock()
+	return cfg
+}
+
+// Execute runs the action on behalf of the rule that produced the match.
+// The action is skipped if the rule has exhausted the rate limit of the
+// action. Otherwise, the action is run and the outcome is recorded in the
+// audit log. Asynchronous actions are run in a separate goroutine, so they
+// don't stall the event processing, and their error is only recorded in the
+// audit log.
+func Execute(ctx *config.ActionContext, name string, args []string, async bool, fn func() (string, error)) error {
+	rec := AuditRecord{
+		Timestamp: time.Now(),
+		Group:     ctx.Group.Name,
+		Action:    name,
+		Args:      args,
+	}
+	limit := actionsConfig().RateLimit
+	if ctx.Filter != nil {
+		rec.Rule = ctx.Filter.Name
+		if ctx.Filter.RateLimit != nil {
+			limit = *ctx.Filter.RateLimit
+		}
+	}
+	if !limits.allow(rec.Group+"/"+rec.Rule+"/"+name, limit, rec.Timestamp) {
+		rec.Outcome = RateLimited
+		rateLimitedActions.Add(name, 1)
+		audit(rec)
+		return nil
+	}
+	run := func() error {
+		detail, err := fn()
+		rec.Detail = detail
+		if err != nil {
+			rec.Outcome, rec.Error = Failed, err.Error()
+			failedActions.Add(name, 1)
+		} else {
+			rec.Outcome = Succeeded
+			executedActions.Add(name, 1)
+		}
+		audit(rec)
+		return err
+	}
+	if async {
+		go func() { _ = run() }()
+		return nil
+	}
+	return run()
+}
+
+// audit logs the action execution and appends the record to the audit log file.
+func audit(rec AuditRecord) {
+	entry := log.WithFields(log.Fields{
+		"rule":    rec.Rule,
+		"group":   rec.Group,
+		"action":  rec.Action,
+		"args":    rec.Args,
+		"outcome": rec.Outcome,
+	})
+	switch rec.Outcome {
+	case Failed:
+		entry.Warnf("rule action failed: %s", rec.Error)
+	case RateLimited:
+		entry.Debug("rule action rate limited")
+	default:
+		entry.Info("rule action executed")
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	if auditLog == nil {
+		return
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	if _, err := auditLog.Write(append(b, '\n')); err != nil {
+		log.Warnf("couldn't write to action audit log: %v", err)
+	}
+}
+
+// limiter tracks action executions in fixed windows.
+type limiter struct {
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+type window struct {
+	start time.Time
+	n     int
+}
+
+func newLimiter() *limiter { return &limiter{windows: make(map[string]*window)} }
+
+// allow determines if the action identified by the key can be executed.
+func (l *limiter) allow(key string, limit config.RateLimit, now time.Time) bool {
+	if limit.Max <= 0 {
+		return true
+	}
+	interval := limit.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= interval {
+		l.windows[key] = &window{start: now, n: 1}
+		return true
+	}
+	if w.n >= limit.Max {
+		return false
+	}
+	w.n++
+	return true
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package action
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeQuarantiner struct {
+	moved  map[string]string
+	locked []string
+}
+
+func (q *fakeQuarantiner) Move(src, dst string) error {
+	if src == "C:\\Windows\\System32\\busy.dll" {
+		return errors.New("sharing violation")
+	}
+	q.moved[src] = dst
+	return nil
+}
+
+func (q *fakeQuarantiner) Lock(path string) error {
+	q.locked = append(q.locked, path)
+	return nil
+}
+
+type fakeFirewall struct{ blocked []string }
+
+func (fw *fakeFirewall) Block(ip net.IP) error {
+	fw.blocked = append(fw.blocked, ip.String())
+	return nil
+}
+
+type fakeRecorder struct {
+	filename string
+	d        time.Duration
+}
+
+func (r *fakeRecorder) Record(filename string, d time.Duration) error {
+	r.filename, r.d = filename, d
+	return nil
+}
+
+func newActionContext(rule string, limit *config.RateLimit) *config.ActionContext {
+	return &config.ActionContext{
+		Filter: &config.FilterConfig{Name: rule, RateLimit: limit},
+		Group:  config.FilterGroup{Name: "Defense evasion"},
+	}
+}
+
+func readAuditLog(t *testing.T, path string) []AuditRecord {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	records := make([]AuditRecord, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec AuditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestExecute(t *testing.T) {
+	auditLog := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, Configure(config.Actions{
+		AuditLog:  auditLog,
+		RateLimit: config.RateLimit{Max: 2, Interval: time.Minute},
+	}))
+	defer Configure(config.Actions{})
+
+	ctx := newActionContext("Suspicious DLL loaded", nil)
+	var n int
+	fn := func() (string, error) { n++; return "", nil }
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, Execute(ctx, "suspend", []string{"1234"}, false, fn))
+	}
+	assert.Equal(t, 2, n)
+
+	// rule overrides the default rate limit
+	unlimited := newActionContext("Credential dumping", &config.RateLimit{Max: 0})
+	for i := 0; i < 3; i++ {
+		require.NoError(t, Execute(unlimited, "suspend", []string{"1234"}, false, fn))
+	}
+	assert.Equal(t, 5, n)
+
+	// the limit is tracked per action
+	require.NoError(t, Execute(ctx, "resume", []string{"1234"}, false, fn))
+	assert.Equal(t, 6, n)
+
+	err := Execute(ctx, "resume", []string{"1234"}, false, func() (string, error) { return "", errors.New("access denied") })
+	require.Error(t, err)
+
+	records := readAuditLog(t, auditLog)
+	require.Len(t, records, 8)
+	outcomes := make([]Outcome, len(records))
+	for i, rec := range records {
+		outcomes[i] = rec.Outcome
+	}
+	assert.Equal(t, []Outcome{Succeeded, Succeeded, RateLimited, Succeeded, Succeeded, Succeeded, Succeeded, Failed}, outcomes)
+	assert.Equal(t, "Suspicious DLL loaded", records[0].Rule)
+	assert.Equal(t, "Defense evasion", records[0].Group)
+	assert.Equal(t, []string{"1234"}, records[0].Args)
+	assert.Equal(t, "access denied", records[7].Error)
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter()
+	limit := config.RateLimit{Max: 1, Interval: time.Second}
+	now := time.Now()
+
+	assert.True(t, l.allow("k", limit, now))
+	assert.False(t, l.allow("k", limit, now.Add(time.Millisecond*500)))
+	assert.True(t, l.allow("k", limit, now.Add(time.Second)))
+	assert.True(t, l.allow("k1", limit, now))
+	assert.True(t, l.allow("k", config.RateLimit{}, now))
+}
+
+func TestQuarantine(t *testing.T) {
+	q := &fakeQuarantiner{moved: make(map[string]string)}
+	SetQuarantiner(q)
+	defer SetQuarantiner(newQuarantiner())
+
+	_, err := Quarantine("C:\\Temp\\mimikatz.exe")
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, Configure(config.Actions{QuarantineDir: dir}))
+	defer Configure(config.Actions{})
+
+	dst, err := Quarantine("C:\\Temp\\mimikatz.exe")
+	require.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(dst))
+	assert.Equal(t, dst, q.moved["C:\\Temp\\mimikatz.exe"])
+	assert.Equal(t, []string{dst}, q.locked)
+
+	_, err = Quarantine("C:\\Windows\\System32\\busy.dll")
+	require.Error(t, err)
+	assert.Len(t, q.locked, 1)
+}
+
+func TestBlockIP(t *testing.T) {
+	fw := &fakeFirewall{}
+	SetFirewall(fw)
+	defer SetFirewall(newFirewall())
+
+	require.NoError(t, BlockIP("172.17.0.3"))
+	require.NoError(t, BlockIP("2001:db8::68"))
+	require.Error(t, BlockIP("172.17.0"))
+	require.Error(t, BlockIP("127.0.0.1"))
+	require.Error(t, BlockIP("0.0.0.0"))
+	assert.Equal(t, []string{"172.17.0.3", "2001:db8::68"}, fw.blocked)
+}
+
+func TestCapture(t *testing.T) {
+	_, err := Capture("Credential dumping", time.Minute)
+	require.Error(t, err)
+
+	r := &fakeRecorder{}
+	SetRecorder(r)
+
+	dir := t.TempDir()
+	require.NoError(t, Configure(config.Actions{CaptureDir: dir, MaxCaptureDuration: time.Minute * 5}))
+	defer Configure(config.Actions{})
+
+	filename, err := Capture("Credential dumping", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, filename, r.filename)
+	assert.Equal(t, time.Minute*5, r.d)
+	assert.Equal(t, dir, filepath.Dir(filename))
+	assert.Regexp(t, `^Credential-dumping-\d{14}\.kcap$`, filepath.Base(filename))
+
+	_, err = Capture("Credential dumping", 0)
+	require.Error(t, err)
+}
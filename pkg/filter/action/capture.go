@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package action
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Recorder records the event stream to the capture file.
+type Recorder interface {
+	// Record starts writing events to the capture file. The
+	// recording is stopped when the duration elapses.
+	Record(filename string, d time.Duration) error
+}
+
+var recorder atomic.Pointer[Recorder]
+
+// SetRecorder registers the recorder used by the capture action.
+func SetRecorder(r Recorder) { recorder.Store(&r) }
+
+// Capture starts a time-bounded recording of the event stream. The duration
+// is capped at the configured maximum capture duration. The capture file is
+// created in the capture directory and named after the rule that triggered
+// the capture. It returns the path of the capture file.
+func Capture(rule string, d time.Duration) (string, error) {
+	r := recorder.Load()
+	if r == nil {
+		return "", errors.New("capture recorder is not available")
+	}
+	c := actionsConfig()
+	if c.CaptureDir == "" {
+		return "", errors.New("capture directory is not configured")
+	}
+	if d <= 0 {
+		return "", fmt.Errorf("invalid capture duration %v", d)
+	}
+	if c.MaxCaptureDuration > 0 && d > c.MaxCaptureDuration {
+		d = c.MaxCaptureDuration
+	}
+	if err := os.MkdirAll(c.CaptureDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("couldn't create capture directory: %v", err)
+	}
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?* `, r) {
+			return '-'
+		}
+		return r
+	}, rule)
+	filename := filepath.Join(c.CaptureDir, fmt.Sprintf("%s-%s.kcap", name, time.Now().Format("20060102150405")))
+	if err := (*r).Record(filename, d); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package action
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Exec runs the script with the given arguments and writes the payload to
+// the script standard input. The script is killed if it doesn't finish
+// within the timeout.
+func Exec(path string, args []string, stdin []byte, timeout time.Duration) error {
+	if path == "" {
+		return fmt.Errorf("no script to execute")
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("%s timed out after %v", path, timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s failed: %v: %s", path, err, msg)
+		}
+		return fmt.Errorf("%s failed: %v", path, err)
+	}
+	return nil
+}
+
+// ExecScript runs the script registered under the given name in the
+// actions config. The script is bounded by the configured execution
+// timeout. It returns the path of the executed script.
+func ExecScript(name string, args []string, stdin []byte) (string, error) {
+	c := actionsConfig()
+	path, ok := c.Scripts[name]
+	if !ok {
+		return "", fmt.Errorf("%s script is not defined", name)
+	}
+	return path, Exec(path, args, stdin, c.ExecTimeout)
+}
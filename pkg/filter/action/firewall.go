@@ -0,0 +1,50 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package action
+
+import (
+	"fmt"
+	"net"
+)
+
+// Firewall abstracts the host firewall that blocks network traffic.
+type Firewall interface {
+	// Block blocks the inbound and outbound traffic to the IP address.
+	Block(ip net.IP) error
+}
+
+var firewall Firewall = newFirewall()
+
+// SetFirewall replaces the firewall used by the block_ip action.
+func SetFirewall(fw Firewall) { firewall = fw }
+
+// BlockIP blocks the network traffic to the IP address.
+func BlockIP(addr string) error {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return fmt.Errorf("%q is not a valid IP address", addr)
+	}
+	if ip.IsLoopback() || ip.IsUnspecified() {
+		return fmt.Errorf("refusing to block %s address", ip)
+	}
+	if err := firewall.Block(ip); err != nil {
+		return fmt.Errorf("fail to block %s: %v", ip, err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package action
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// netshFirewall creates Windows Defender Firewall rules via netsh.
+type netshFirewall struct{}
+
+func newFirewall() Firewall { return netshFirewall{} }
+
+func (netshFirewall) Block(ip net.IP) error {
+	for _, dir := range []string{"in", "out"} {
+		out, err := exec.Command(
+			"netsh", "advfirewall", "firewall", "add", "rule",
+			fmt.Sprintf("name=Fibratus block %s", ip),
+			"dir="+dir,
+			"action=block",
+			"remoteip="+ip.String(),
+		).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
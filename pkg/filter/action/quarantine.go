@@ -0,0 +1,66 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package action
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Quarantiner abstracts the operating system facilities for isolating files.
+type Quarantiner interface {
+	// Move relocates the file to the destination path.
+	Move(src, dst string) error
+	// Lock restricts the access to the file, so it can't be read
+	// or executed by anyone but privileged accounts.
+	Lock(path string) error
+}
+
+var quarantiner Quarantiner = newQuarantiner()
+
+// SetQuarantiner replaces the quarantiner used by the quarantine action.
+func SetQuarantiner(q Quarantiner) { quarantiner = q }
+
+// Quarantine moves the file to the quarantine directory and locks it down.
+// The name of the quarantined file is prefixed with the timestamp, so files
+// with the same name don't overwrite each other. It returns the path of the
+// quarantined file.
+func Quarantine(path string) (string, error) {
+	dir := actionsConfig().QuarantineDir
+	if dir == "" {
+		return "", errors.New("quarantine directory is not configured")
+	}
+	if path == "" {
+		return "", errors.New("no file to quarantine")
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("couldn't create quarantine directory: %v", err)
+	}
+	dst := filepath.Join(dir, fmt.Sprintf("%d-%s", time.Now().UnixNano(), filepath.Base(path)))
+	if err := quarantiner.Move(path, dst); err != nil {
+		return "", fmt.Errorf("couldn't move %s to quarantine: %v", path, err)
+	}
+	if err := quarantiner.Lock(dst); err != nil {
+		return dst, fmt.Errorf("couldn't lock quarantined file %s: %v", dst, err)
+	}
+	return dst, nil
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package action
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// lockedFileSDDL is the protected DACL that only grants access to the
+// SYSTEM account and built-in administrators. Inherited ACEs are removed.
+const lockedFileSDDL = "D:P(A;;FA;;;SY)(A;;FA;;;BA)"
+
+type fsQuarantiner struct{}
+
+func newQuarantiner() Quarantiner { return fsQuarantiner{} }
+
+// Move renames the file. If the destination is on another volume, the
+// file is copied and then deleted.
+func (fsQuarantiner) Move(src, dst string) error {
+	from, err := windows.UTF16PtrFromString(src)
+	if err != nil {
+		return err
+	}
+	to, err := windows.UTF16PtrFromString(dst)
+	if err != nil {
+		return err
+	}
+	return windows.MoveFileEx(from, to, windows.MOVEFILE_COPY_ALLOWED|windows.MOVEFILE_WRITE_THROUGH)
+}
+
+// Lock replaces the file DACL.
+func (fsQuarantiner) Lock(path string) error {
+	sd, err := windows.SecurityDescriptorFromString(lockedFileSDDL)
+	if err != nil {
+		return err
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+	return windows.SetNamedSecurityInfo(
+		path,
+		windows.SE_FILE_OBJECT,
+		windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION,
+		nil,
+		nil,
+		dacl,
+		nil,
+	)
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package action
+
+import (
+	"fmt"
+
+	"github.com/rabbitstack/fibratus/pkg/syscall/process"
+)
+
+// Suspend suspends all threads of the process with specified pid.
+func Suspend(pid uint32) error {
+	h, err := process.Open(process.SuspendResume, false, pid)
+	if err != nil {
+		return fmt.Errorf("couldn't open pid %d for suspension: %v", pid, err)
+	}
+	defer h.Close()
+	if err := process.Suspend(h); err != nil {
+		return fmt.Errorf("fail to suspend pid %d: %v", pid, err)
+	}
+	return nil
+}
+
+// Resume resumes all threads of the suspended process with specified pid.
+func Resume(pid uint32) error {
+	h, err := process.Open(process.SuspendResume, false, pid)
+	if err != nil {
+		return fmt.Errorf("couldn't open pid %d for resumption: %v", pid, err)
+	}
+	defer h.Close()
+	if err := process.Resume(h); err != nil {
+		return fmt.Errorf("fail to resume pid %d: %v", pid, err)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"expvar"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// maxAggregationBuckets determines the maximum number of
+	// distinct groups tracked by the aggregation rule
+	maxAggregationBuckets = 10000
+	// maxAggregationSamples determines the number of most recent
+	// events per group that are propagated to the rule action
+	maxAggregationSamples = 10
+)
+
+var (
+	aggregationBucketsCount   = expvar.NewMap("aggregation.buckets.count")
+	aggregationDroppedBuckets = expvar.NewMap("aggregation.buckets.dropped")
+	aggregationThresholdHits  = expvar.NewMap("aggregation.threshold.hits")
+)
+
+// aggregationState keeps aggregated events of the rule
+// grouped by the values of the aggregation grouping fields.
+// Each group (bucket) tracks the events within the window
+// and evaluates thresholds as new events arrive.
+type aggregationState struct {
+	name string
+	agg  *ql.Aggregation
+	// aggs are aggregate functions referenced in thresholds
+	aggs    []ql.Aggregate
+	buckets map[string]*aggregationBucket
+
+	// values contains aggregate values of the
+	// group that crossed the thresholds
+	values map[string]float64
+	// samples stores the most recent events of
+	// the group that crossed the thresholds
+	samples []*kevent.Kevent
+}
+
+type aggregationBucket struct {
+	// start is the start of the tumbling window
+	start   time.Time
+	entries []aggregationEntry
+	samples []*kevent.Kevent
+}
+
+// aggregationEntry stores the timestamp of the aggregated event
+// and the values of fields referenced in aggregate functions.
+type aggregationEntry struct {
+	timestamp time.Time
+	values    []interface{}
+}
+
+func newAggregationState(name string, agg *ql.Aggregation) *aggregationState {
+	return &aggregationState{
+		name:    name,
+		agg:     agg,
+		aggs:    agg.Aggregates(),
+		buckets: make(map[string]*aggregationBucket),
+	}
+}
+
+// add aggregates the event into the bucket identified by the grouping
+// field values. It returns true if aggregate values of the bucket cross
+// all thresholds, in which case the bucket is disposed, and aggregate
+// values along with sample events are kept until the state is cleared.
+func (s *aggregationState) add(kevt *kevent.Kevent, valuer map[string]interface{}) bool {
+	key := s.key(valuer)
+	ts := kevt.Timestamp
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= maxAggregationBuckets {
+			s.evict(ts)
+		}
+		if len(s.buckets) >= maxAggregationBuckets {
+			log.Warnf("max aggregation buckets reached for rule %q. Dropping %s event", s.name, kevt.Name)
+			aggregationDroppedBuckets.Add(s.name, 1)
+			return false
+		}
+		b = &aggregationBucket{start: ts}
+		s.buckets[key] = b
+		aggregationBucketsCount.Add(s.name, 1)
+	}
+
+	switch s.agg.WindowType {
+	case ql.TumblingWindow:
+		if ts.Sub(b.start) >= s.agg.Window {
+			b.start = ts
+			b.entries = b.entries[:0]
+			b.samples = b.samples[:0]
+		}
+	case ql.SlidingWindow:
+		n := sort.Search(len(b.entries), func(i int) bool {
+			return ts.Sub(b.entries[i].timestamp) <= s.agg.Window
+		})
+		b.entries = b.entries[n:]
+		n = sort.Search(len(b.samples), func(i int) bool {
+			return ts.Sub(b.samples[i].Timestamp) <= s.agg.Window
+		})
+		b.samples = b.samples[n:]
+	}
+
+	entry := aggregationEntry{timestamp: ts, values: make([]interface{}, len(s.aggs))}
+	for i, agg := range s.aggs {
+		if !agg.Field.IsEmpty() {
+			entry.values[i] = valuer[agg.Field.String()]
+		}
+	}
+	b.entries = append(b.entries, entry)
+	b.samples = append(b.samples, kevt)
+	if len(b.samples) > maxAggregationSamples {
+		b.samples = b.samples[len(b.samples)-maxAggregationSamples:]
+	}
+
+	values := s.compute(b)
+	for _, t := range s.agg.Thresholds {
+		if !t.IsCrossed(values[t.Aggregate.String()]) {
+			return false
+		}
+	}
+
+	aggregationThresholdHits.Add(s.name, 1)
+	s.values = values
+	s.samples = b.samples
+	delete(s.buckets, key)
+	aggregationBucketsCount.Add(s.name, -1)
+	return true
+}
+
+// matches returns sample events indexed by their position in the window.
+func (s *aggregationState) matches() map[uint16]*kevent.Kevent {
+	matches := make(map[uint16]*kevent.Kevent, len(s.samples))
+	for i, kevt := range s.samples {
+		matches[uint16(i+1)] = kevt
+	}
+	return matches
+}
+
+func (s *aggregationState) clear() {
+	s.values = nil
+	s.samples = nil
+}
+
+// key builds the bucket key from the grouping field values.
+func (s *aggregationState) key(valuer map[string]interface{}) string {
+	if len(s.agg.By) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	for _, field := range s.agg.By {
+		sb.WriteString(normalizeAggregationValue(valuer[field.String()]))
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+// evict removes buckets without events in the window that ends at the given time.
+func (s *aggregationState) evict(ts time.Time) {
+	for key, b := range s.buckets {
+		if len(b.entries) == 0 || ts.Sub(b.entries[len(b.entries)-1].timestamp) > s.agg.Window {
+			delete(s.buckets, key)
+			aggregationBucketsCount.Add(s.name, -1)
+		}
+	}
+}
+
+// compute calculates aggregate values over the bucket entries.
+func (s *aggregationState) compute(b *aggregationBucket) map[string]float64 {
+	values := make(map[string]float64, len(s.aggs))
+	for i, agg := range s.aggs {
+		var v float64
+		switch agg.Fn {
+		case ql.CountFn:
+			if !agg.Distinct {
+				v = float64(len(b.entries))
+				break
+			}
+			distinct := make(map[string]bool)
+			for _, e := range b.entries {
+				if e.values[i] != nil {
+					distinct[normalizeAggregationValue(e.values[i])] = true
+				}
+			}
+			v = float64(len(distinct))
+		case ql.SumFn, ql.MinFn, ql.MaxFn:
+			var init bool
+			for _, e := range b.entries {
+				n, ok := toFloat(e.values[i])
+				if !ok {
+					continue
+				}
+				switch {
+				case agg.Fn == ql.SumFn:
+					v += n
+				case !init:
+					v = n
+				case agg.Fn == ql.MinFn && n < v, agg.Fn == ql.MaxFn && n > v:
+					v = n
+				}
+				init = true
+			}
+		}
+		values[agg.String()] = v
+	}
+	return values
+}
+
+// normalizeAggregationValue converts the field value to the string.
+// Strings are compared case-insensitively, since most of the string
+// fields represent file paths, registry keys or process names.
+func normalizeAggregationValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.ToLower(val)
+	case net.IP:
+		return val.String()
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// toFloat converts numeric field values to float.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregationStateSlidingWindow(t *testing.T) {
+	p := ql.NewParser(`aggregate window 10s sliding by ps.pid |kevt.name = 'Send'| having sum(net.size) > 1000 and max(net.size) >= 600`)
+	require.True(t, p.IsAggregation())
+	agg, err := p.ParseAggregation()
+	require.NoError(t, err)
+
+	s := newAggregationState("Large outbound transfer", agg)
+	now := time.Now()
+	add := func(pid uint32, size uint32, offset time.Duration) bool {
+		kevt := &kevent.Kevent{Timestamp: now.Add(offset), Name: "Send"}
+		return s.add(kevt, map[string]interface{}{"ps.pid": pid, "net.size": size})
+	}
+
+	require.False(t, add(859, 600, 0))
+	require.False(t, add(859, 300, time.Second*5))
+	// the first event slides out of the window
+	require.False(t, add(859, 300, time.Second*12))
+	require.False(t, add(1024, 900, time.Second*13))
+	require.True(t, add(859, 650, time.Second*14))
+
+	assert.Equal(t, map[string]float64{"sum(net.size)": 1250, "max(net.size)": 650}, s.values)
+	assert.Len(t, s.matches(), 3)
+	s.clear()
+	assert.Nil(t, s.values)
+	// the group that crossed the thresholds is disposed
+	assert.Len(t, s.buckets, 1)
+}
+
+func TestAggregationStateMinCount(t *testing.T) {
+	p := ql.NewParser(`aggregate window 1m |kevt.name = 'Send'| having count() >= 3 and min(net.size) < 10`)
+	require.True(t, p.IsAggregation())
+	agg, err := p.ParseAggregation()
+	require.NoError(t, err)
+
+	s := newAggregationState("Small packets", agg)
+	now := time.Now()
+	add := func(size interface{}, offset time.Duration) bool {
+		kevt := &kevent.Kevent{Timestamp: now.Add(offset), Name: "Send"}
+		return s.add(kevt, map[string]interface{}{"net.size": size})
+	}
+
+	require.False(t, add(uint32(20), 0))
+	require.False(t, add(nil, time.Second))
+	require.True(t, add(uint32(5), time.Second*2))
+	assert.Equal(t, map[string]float64{"count()": 3, "min(net.size)": 5}, s.values)
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"expvar"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// exceptionSuppressions counts rule matches suppressed by each exception
+var exceptionSuppressions = expvar.NewMap("filter.exception.suppressions")
+
+// exception is the rule exception with the compiled condition.
+type exception struct {
+	config.Exception
+	filter Filter
+}
+
+// exceptions is the list of compiled rule exceptions.
+type exceptions []*exception
+
+// compileExceptions loads rule exceptions and compiles their conditions.
+func compileExceptions(c *config.Config) (exceptions, error) {
+	excs, err := c.Filters.LoadExceptions()
+	if err != nil {
+		return nil, err
+	}
+	compiled := make(exceptions, 0, len(excs))
+	for _, e := range excs {
+		f := New(e.Condition, c)
+		if err := f.Compile(); err != nil {
+			return nil, fmt.Errorf("syntax error in %q exception: \n%v", e.Name, err)
+		}
+		if e.IsExpired(time.Now()) {
+			log.Warnf("%q exception expired on %s and won't suppress rule matches", e.Name, e.Expires.Format(time.RFC3339))
+		}
+		compiled = append(compiled, &exception{Exception: e, filter: f})
+	}
+	return compiled, nil
+}
+
+// suppresses determines whether any of the exceptions referencing the
+// rule matches the events that fired the rule. For sequence and threshold
+// rules, the match is suppressed if the condition matches any of the events.
+func (excs exceptions) suppresses(group config.FilterGroup, filter *config.FilterConfig, kevts ...*kevent.Kevent) bool {
+	if len(excs) == 0 {
+		return false
+	}
+	now := time.Now()
+	for _, e := range excs {
+		if e.IsExpired(now) || !e.Selects(group, filter) {
+			continue
+		}
+		for _, kevt := range kevts {
+			if kevt == nil || !e.filter.Run(kevt) {
+				continue
+			}
+			exceptionSuppressions.Add(e.Name, 1)
+			log.Debugf("rule [%s] in group [%s] suppressed by %q exception: %s", filter.Name, group.Name, e.Name, e.Justification)
+			return true
+		}
+	}
+	return false
+}
+
+// events returns the events that fired the rule.
+func (f compiledFilter) events(kevt *kevent.Kevent) []*kevent.Kevent {
+	switch {
+	case f.ss != nil:
+		return mapValues(f.ss.matches)
+	case f.as != nil:
+		return f.as.samples
+	default:
+		return []*kevent.Kevent{kevt}
+	}
+}
+
+// clearState clears the state of the sequence or threshold rule.
+func (f compiledFilter) clearState() {
+	switch {
+	case f.ss != nil:
+		f.ss.clear()
+	case f.as != nil:
+		f.as.clear()
+	}
+}
+
+// mapValues converts the sequence matches map into the slice of events.
+func mapValues(kevts map[uint16]*kevent.Kevent) []*kevent.Kevent {
+	values := make([]*kevent.Kevent, 0, len(kevts))
+	for _, kevt := range kevts {
+		values = append(values, kevt)
+	}
+	return values
+}
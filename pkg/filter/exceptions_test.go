@@ -0,0 +1,136 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"expvar"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExceptions(t *testing.T) {
+	var tests = []struct {
+		exceptions string
+		suppressed bool
+	}{
+		{"_fixtures/exceptions/by_rule.yml", true},
+		{"_fixtures/exceptions/by_label.yml", true},
+		{"_fixtures/exceptions/by_group.yml", false},
+		{"_fixtures/exceptions/expired.yml", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.exceptions, func(t *testing.T) {
+			c := newConfig("_fixtures/exceptions/rules.yml")
+			c.Filters.Exceptions.FromPaths = []string{tt.exceptions}
+			rules := NewRules(c)
+			var matched bool
+			rules.match = func(kevt *kevent.Kevent, kevts map[uint16]*kevent.Kevent, group config.FilterGroup, filter *config.FilterConfig, aggs map[string]float64, occurrences config.Occurrences) error {
+				matched = true
+				return nil
+			}
+			require.NoError(t, rules.Compile())
+			require.Len(t, rules.exceptions, 1)
+
+			kevt := &kevent.Kevent{
+				Type:     ktypes.Recv,
+				Name:     "Recv",
+				Tid:      2484,
+				PID:      859,
+				Category: ktypes.Net,
+				Kparams: kevent.Kparams{
+					kparams.NetDport: {Name: kparams.NetDport, Type: kparams.Uint16, Value: uint16(443)},
+					kparams.NetSport: {Name: kparams.NetSport, Type: kparams.Uint16, Value: uint16(43123)},
+					kparams.NetSIP:   {Name: kparams.NetSIP, Type: kparams.IPv4, Value: net.ParseIP("127.0.0.1")},
+					kparams.NetDIP:   {Name: kparams.NetDIP, Type: kparams.IPv4, Value: net.ParseIP("216.58.201.174")},
+				},
+				Metadata: make(map[kevent.MetadataKey]any),
+			}
+
+			name := rules.exceptions[0].Name
+			before := counterValue(exceptionSuppressions, name)
+			assert.Equal(t, !tt.suppressed, rules.Fire(kevt))
+			assert.Equal(t, !tt.suppressed, matched)
+			if tt.suppressed {
+				assert.Equal(t, before+1, counterValue(exceptionSuppressions, name))
+			}
+		})
+	}
+}
+
+func TestExceptionsSuppressAbsence(t *testing.T) {
+	c := newConfig("_fixtures/sequence_rule_negated.yml")
+	c.Filters.Exceptions.FromPaths = []string{"_fixtures/exceptions/absence.yml"}
+	rules := NewRules(c)
+	matches := make(chan map[uint16]*kevent.Kevent, 1)
+	rules.match = func(kevt *kevent.Kevent, kevts map[uint16]*kevent.Kevent, group config.FilterGroup, filter *config.FilterConfig, aggs map[string]float64, occurrences config.Occurrences) error {
+		matches <- kevts
+		return nil
+	}
+	require.NoError(t, rules.Compile())
+
+	newEvent := func(filename string) *kevent.Kevent {
+		return &kevent.Kevent{
+			Type:      ktypes.CreateFile,
+			Timestamp: time.Now(),
+			Name:      "CreateFile",
+			Tid:       2484,
+			PID:       859,
+			Category:  ktypes.File,
+			PS: &types.PS{
+				Name: "setup.exe",
+				Exe:  "C:\\Temp\\setup.exe",
+			},
+			Kparams: kevent.Kparams{
+				kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: filename},
+			},
+			Metadata: make(map[kevent.MetadataKey]any),
+		}
+	}
+
+	require.False(t, rules.Fire(newEvent("C:\\Temp\\Installer\\dropper.exe")))
+	select {
+	case <-matches:
+		t.Fatal("absence match should be suppressed by the exception")
+	case <-time.After(time.Millisecond * 300):
+	}
+
+	require.False(t, rules.Fire(newEvent("C:\\Temp\\dropper.exe")))
+	select {
+	case <-matches:
+	case <-time.After(time.Second):
+		t.Fatal("sequence should fire when the max span elapses")
+	}
+}
+
+func counterValue(m *expvar.Map, key string) int64 {
+	if v, ok := m.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
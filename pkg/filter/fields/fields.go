@@ -40,3 +40,10 @@ func Get() []FieldInfo {
 	sort.Slice(fi, func(i, j int) bool { return fi[i].Field < fi[j].Field })
 	return fi
 }
+
+// Info returns the metadata descriptor of the given field. Nested
+// fields such as ps.modules[kernel32.dll].size have no descriptor.
+func Info(name Field) (FieldInfo, bool) {
+	fi, ok := fields[name]
+	return fi, ok
+}
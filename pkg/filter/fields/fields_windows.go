@@ -27,7 +27,7 @@ import (
 
 // pathRegexp splits the provided path into different components. The first capture
 // contains the indexed field name. Next is the indexed key and, finally the segment.
-var pathRegexp = regexp.MustCompile(`(pe.sections|pe.resources|ps.envs|ps.modules|ps.ancestor)\[(.+\s*)].?(.*)`)
+var pathRegexp = regexp.MustCompile(`(pe.sections|pe.resources|ps.envs|ps.modules|ps.ancestor|kevt.meta)\[(.+\s*)].?(.*)`)
 
 // Field represents the type alias for the field
 type Field string
@@ -58,6 +58,8 @@ const (
 	PsUsername Field = "ps.username"
 	// PsSessionID represents the session id bound to the process
 	PsSessionID Field = "ps.sessionid"
+	// PsStartTime represents the process start time
+	PsStartTime Field = "ps.start_time"
 	// PsEnvs represents the process environment variables
 	PsEnvs Field = "ps.envs"
 	// PsHandles represents the process handles
@@ -159,6 +161,8 @@ const (
 	PeSymbols Field = "pe.symbols"
 	// PeImports represents imported libraries (e.g. kernel32.dll)
 	PeImports Field = "pe.imports"
+	// PeImphash represents the hash of the imported functions
+	PeImphash Field = "pe.imphash"
 	// PeTimestamp is the PE build timestamp
 	PeTimestamp Field = "pe.timestamp"
 	// PeBaseAddress represents the base address when the binary is loaded
@@ -196,6 +200,8 @@ const (
 	KevtHost Field = "kevt.host"
 	// KevtTime is the event time
 	KevtTime Field = "kevt.time"
+	// KevtTimestamp is the full event timestamp
+	KevtTimestamp Field = "kevt.timestamp"
 	// KevtTimeHour is the hour part of the event time
 	KevtTimeHour Field = "kevt.time.h"
 	// KevtTimeMin is the minute part of the event time
@@ -358,6 +364,7 @@ func (f Field) IsModsSequence() bool        { return strings.HasPrefix(f.String(
 func (f Field) IsAncestorSequence() bool    { return strings.HasPrefix(f.String(), "ps.ancestor[") }
 func (f Field) IsPeSectionsSequence() bool  { return strings.HasPrefix(f.String(), "pe.sections[") }
 func (f Field) IsPeResourcesSequence() bool { return strings.HasPrefix(f.String(), "pe.resources[") }
+func (f Field) IsMetaSequence() bool        { return strings.HasPrefix(f.String(), "kevt.meta[") }
 
 var fields = map[Field]FieldInfo{
 	KevtSeq:         {KevtSeq, "event sequence number", kparams.Uint64, []string{"kevt.seq > 666"}},
@@ -369,6 +376,7 @@ var fields = map[Field]FieldInfo{
 	KevtDesc:        {KevtDesc, "event description", kparams.AnsiString, []string{"kevt.desc contains 'Creates a new process'"}},
 	KevtHost:        {KevtHost, "host name on which the event was produced", kparams.UnicodeString, []string{"kevt.host contains 'kitty'"}},
 	KevtTime:        {KevtTime, "event timestamp as a time string", kparams.Time, []string{"kevt.time = '17:05:32'"}},
+	KevtTimestamp:   {KevtTimestamp, "event timestamp including the date and the time zone", kparams.Time, []string{"hour(kevt.timestamp) >= 22"}},
 	KevtTimeHour:    {KevtTimeHour, "hour within the day on which the event occurred", kparams.Time, []string{"kevt.time.h = 23"}},
 	KevtTimeMin:     {KevtTimeMin, "minute offset within the hour on which the event occurred", kparams.Time, []string{"kevt.time.m = 54"}},
 	KevtTimeSec:     {KevtTimeSec, "second offset within the minute  on which the event occurred", kparams.Time, []string{"kevt.time.s = 0"}},
@@ -381,6 +389,7 @@ var fields = map[Field]FieldInfo{
 	KevtDateWeek:    {KevtDateWeek, "week number within the year on which the event occurred", kparams.Uint8, []string{"kevt.date.week = 2"}},
 	KevtDateWeekday: {KevtDateWeekday, "week day on which the event occurred", kparams.AnsiString, []string{"kevt.date.weekday = 'Monday'"}},
 	KevtNparams:     {KevtNparams, "number of parameters", kparams.Int8, []string{"kevt.nparams > 2"}},
+	KevtMeta:        {KevtMeta, "event metadata keys", kparams.Slice, []string{"kevt.meta in ('rule.name')"}},
 
 	PsPid:               {PsPid, "process identifier", kparams.PID, []string{"ps.pid = 1024"}},
 	PsPpid:              {PsPpid, "parent process identifier", kparams.PID, []string{"ps.ppid = 45"}},
@@ -391,6 +400,7 @@ var fields = map[Field]FieldInfo{
 	PsCwd:               {PsCwd, "process current working directory", kparams.UnicodeString, []string{"ps.cwd = 'C:\\Users\\Default'"}},
 	PsSID:               {PsSID, "security identifier under which this process is run", kparams.UnicodeString, []string{"ps.sid contains 'SYSTEM'"}},
 	PsSessionID:         {PsSessionID, "unique identifier for the current session", kparams.Int16, []string{"ps.sessionid = 1"}},
+	PsStartTime:         {PsStartTime, "process start time", kparams.Time, []string{"date_diff(ps.start_time, kevt.timestamp) < 1m"}},
 	PsDomain:            {PsDomain, "process domain", kparams.UnicodeString, []string{"ps.domain contains 'SERVICE'"}},
 	PsUsername:          {PsUsername, "process username", kparams.UnicodeString, []string{"ps.username contains 'system'"}},
 	PsEnvs:              {PsEnvs, "process environment variables", kparams.Slice, []string{"ps.envs in ('MOZ_CRASHREPORTER_DATA_DIRECTORY')"}},
@@ -485,6 +495,7 @@ var fields = map[Field]FieldInfo{
 	PeSections:       {PeSections, "PE sections", kparams.Object, []string{"pe.sections[.text].entropy > 6.2"}},
 	PeSymbols:        {PeSymbols, "imported symbols", kparams.Slice, []string{"pe.symbols in ('GetTextFaceW', 'GetProcessHeap')"}},
 	PeImports:        {PeImports, "imported dynamic linked libraries", kparams.Slice, []string{"pe.imports in ('msvcrt.dll', 'GDI32.dll'"}},
+	PeImphash:        {PeImphash, "import hash of the imported functions", kparams.AnsiString, []string{"pe.imphash = 'f34d5f2d4577ed6d9ceec516c1f5a744'"}},
 	PeResources:      {PeResources, "version and other resources", kparams.Map, []string{"pe.resources[FileDescription] = 'Notepad'"}},
 	PeCompany:        {PeCompany, "internal company name of the file provided at compile-time", kparams.UnicodeString, []string{"pe.company = 'Microsoft Corporation'"}},
 	PeCopyright:      {PeCopyright, "copyright notice for the file emitted at compile-time", kparams.UnicodeString, []string{"pe.copyright = '© Microsoft Corporation'"}},
@@ -582,6 +593,10 @@ func Lookup(name string) Field {
 		if segment == "" {
 			return Field(name)
 		}
+	case KevtMeta:
+		if segment == "" {
+			return Field(name)
+		}
 	}
 	return None
 }
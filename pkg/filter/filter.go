@@ -29,6 +29,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -51,28 +52,49 @@ type Filter interface {
 	// on the state machine transitions and partial matches to decide whether the
 	// rule is fired.
 	RunSequence(kevt *kevent.Kevent, seqID uint16, partials map[uint16][]*kevent.Kevent) bool
+	// RunAggregation runs a filter with the aggregation expression. If the event
+	// matches the aggregation expression, the values of the fields referenced in
+	// grouping and aggregate functions are returned.
+	RunAggregation(kevt *kevent.Kevent) (map[string]interface{}, bool)
 	// GetStringFields returns field names mapped to their string values.
 	GetStringFields() map[fields.Field][]string
 	// GetSequence returns the sequence descriptor or nil if this filter is not a sequence.
 	GetSequence() *ql.Sequence
 	// IsSequence determines if this filter is a sequence.
 	IsSequence() bool
+	// GetAggregation returns the aggregation descriptor or nil if this filter is not an aggregation.
+	GetAggregation() *ql.Aggregation
+	// IsAggregation determines if this filter is an aggregation.
+	IsAggregation() bool
 }
 
 type filter struct {
 	expr      ql.Expr
 	seq       *ql.Sequence
+	agg       *ql.Aggregation
 	parser    *ql.Parser
 	accessors []accessor
 	fields    []fields.Field
 	// useFuncValuer determines whether we should supply the function valuer
 	useFuncValuer bool
+	// strict enables the three-valued logic for missing field values
+	strict bool
 	// useProcAccessor indicates if the process accessor is called by this filter
 	useProcAccessor bool
 	// useKevtAccessor indicates if the event accessor is called by this filter
 	useKevtAccessor bool
 	// stringFields contains filter field names mapped to their string values
 	stringFields map[fields.Field][]string
+	// profiler records the cost of accessors and function calls
+	profiler *ruleProfiler
+	// prog is the compiled expression of the regular or aggregation filter
+	prog *ql.Program
+	// seqProgs are compiled sequence expressions. Expressions
+	// referencing bound fields are interpreted instead
+	seqProgs []*ql.Program
+	// slots maps program slots to filter fields. Fields not
+	// collected from the expression have the empty slot
+	slots []fields.Field
 }
 
 // Compile parsers the filter expression and builds a binary expression tree
@@ -81,13 +103,21 @@ type filter struct {
 // expressions are replaced with respective event parameters via map valuer.
 // For functions call we grab all the arguments that are evaluated as field
 // literals.
-// Matching the filter involves descending the binary expression tree recursively
-// until all nodes are visited.
+// The expression tree is finally lowered into the program of evaluation closures
+// that is run for each event.
 func (f *filter) Compile() error {
 	var err error
-	if f.parser.IsSequence() {
+	switch {
+	case f.parser.IsSequence():
 		f.seq, err = f.parser.ParseSequence()
-	} else {
+	case f.parser.IsAggregation():
+		f.agg, err = f.parser.ParseAggregation()
+		if err == nil {
+			// aggregated events are selected by
+			// the regular expression evaluation
+			f.expr = f.agg.Expr
+		}
+	default:
 		f.expr, err = f.parser.ParseExpr()
 	}
 	if err != nil {
@@ -108,17 +138,26 @@ func (f *filter) Compile() error {
 				f.addField(field)
 				f.addStringFields(field, expr.LHS)
 			}
+		case *ql.FieldLiteral:
+			// fields nested in function arguments
+			// or arithmetic expressions
+			f.addField(fields.Field(expr.Value))
 		case *ql.Function:
-			for _, arg := range expr.Args {
-				if field, ok := arg.(*ql.FieldLiteral); ok {
-					f.addField(fields.Field(field.Value))
-				}
-			}
 			f.useFuncValuer = true
 		}
 	}
 	if f.expr != nil {
 		ql.WalkFunc(f.expr, walk)
+		if f.agg != nil {
+			for _, field := range f.agg.By {
+				f.addField(field)
+			}
+			for _, agg := range f.agg.Aggregates() {
+				if !agg.Field.IsEmpty() {
+					f.addField(agg.Field)
+				}
+			}
+		}
 	} else {
 		if !f.seq.By.IsEmpty() {
 			f.addField(f.seq.By)
@@ -133,6 +172,37 @@ func (f *filter) Compile() error {
 	if len(f.fields) == 0 && !f.useFuncValuer {
 		return ErrNoFields
 	}
+	f.initAccessors()
+	f.compileProgs()
+	return nil
+}
+
+// compileProgs lowers filter expressions into programs and binds
+// program slots to fields that are resolved by the accessors.
+func (f *filter) compileProgs() {
+	if f.expr != nil {
+		f.prog = ql.CompileWithOptions(f.expr, f.options())
+		f.slots = make([]fields.Field, len(f.prog.Fields()))
+		for i, name := range f.prog.Fields() {
+			for _, field := range f.fields {
+				if field.String() == name {
+					f.slots[i] = field
+					break
+				}
+			}
+		}
+		return
+	}
+	f.seqProgs = make([]*ql.Program, len(f.seq.Expressions))
+	for i, expr := range f.seq.Expressions {
+		if !expr.HasBoundFields() {
+			f.seqProgs[i] = ql.CompileWithOptions(expr.Expr, f.options())
+		}
+	}
+}
+
+// initAccessors enables the accessors required by the filter fields.
+func (f *filter) initAccessors() {
 	for _, field := range f.fields {
 		switch {
 		case field.IsKevtField():
@@ -141,14 +211,13 @@ func (f *filter) Compile() error {
 			f.useProcAccessor = true
 		}
 	}
-	return nil
 }
 
 func (f *filter) Run(kevt *kevent.Kevent) bool {
 	if f.expr == nil {
 		return false
 	}
-	return ql.Eval(f.expr, f.mapValuer(kevt), f.useFuncValuer)
+	return f.prog.Eval(&fieldEnv{f: f, kevt: kevt, values: make([]interface{}, len(f.slots))}, f.observer())
 }
 
 func (f *filter) RunSequence(kevt *kevent.Kevent, seqID uint16, partials map[uint16][]*kevent.Kevent) bool {
@@ -202,7 +271,7 @@ func (f *filter) RunSequence(kevt *kevent.Kevent, seqID uint16, partials map[uin
 				}
 			}
 			n++
-			match = ql.Eval(expr.Expr, valuer, f.useFuncValuer)
+			match = f.eval(expr.Expr, valuer)
 			if match {
 				break
 			}
@@ -225,9 +294,9 @@ func (f *filter) RunSequence(kevt *kevent.Kevent, seqID uint16, partials map[uin
 					}
 				}
 			}
-			match = joinsEqual(joins) && ql.Eval(expr.Expr, valuer, f.useFuncValuer)
+			match = joinsEqual(joins) && f.evalSeq(seqID, valuer)
 		} else {
-			match = ql.Eval(expr.Expr, valuer, f.useFuncValuer)
+			match = f.evalSeq(seqID, valuer)
 		}
 		if match && !by.IsEmpty() {
 			if v := valuer[by.String()]; v != nil {
@@ -238,6 +307,14 @@ func (f *filter) RunSequence(kevt *kevent.Kevent, seqID uint16, partials map[uin
 	return match
 }
 
+func (f *filter) RunAggregation(kevt *kevent.Kevent) (map[string]interface{}, bool) {
+	if f.agg == nil {
+		return nil, false
+	}
+	valuer := f.mapValuer(kevt)
+	return valuer, f.prog.EvalMap(valuer, f.observer())
+}
+
 func joinsEqual(joins []bool) bool {
 	for _, j := range joins {
 		if !j {
@@ -249,8 +326,10 @@ func joinsEqual(joins []bool) bool {
 
 func (f *filter) GetStringFields() map[fields.Field][]string { return f.stringFields }
 
-func (f *filter) IsSequence() bool          { return f.seq != nil }
-func (f *filter) GetSequence() *ql.Sequence { return f.seq }
+func (f *filter) IsSequence() bool                { return f.seq != nil }
+func (f *filter) GetSequence() *ql.Sequence       { return f.seq }
+func (f *filter) IsAggregation() bool             { return f.agg != nil }
+func (f *filter) GetAggregation() *ql.Aggregation { return f.agg }
 
 // InterpolateFields replaces all occurrences of field modifiers in the given string
 // with values extracted from the event. Field modifiers may contain a leading ordinal
@@ -304,31 +383,102 @@ func InterpolateFields(s string, evts []*kevent.Kevent) string {
 	return r
 }
 
+// eval interprets the expression against field values. The cost
+// of function calls is recorded if the filter is profiled.
+func (f *filter) eval(expr ql.Expr, valuer map[string]interface{}) bool {
+	return ql.EvalWithOptions(expr, valuer, f.options(), f.observer())
+}
+
+// options returns the expression evaluation options of the filter.
+func (f *filter) options() ql.Options {
+	return ql.Options{UseFuncValuer: f.useFuncValuer, Strict: f.strict}
+}
+
+// evalSeq evaluates the sequence expression with the compiled
+// program or falls back to the interpreter if the expression
+// wasn't compiled.
+func (f *filter) evalSeq(seqID uint16, valuer map[string]interface{}) bool {
+	if prog := f.seqProgs[seqID]; prog != nil {
+		return prog.EvalMap(valuer, f.observer())
+	}
+	return f.eval(f.seq.Expressions[seqID].Expr, valuer)
+}
+
+// observer returns the function call observer if the filter is profiled.
+func (f *filter) observer() ql.CallObserver {
+	if f.profiler == nil {
+		return nil
+	}
+	return f.profiler.observeFunction
+}
+
 // mapValuer for each field present in the AST, we run the
 // accessors and extract the field values that are
 // supplied to the valuer. The valuer feeds the
 // expression with correct values.
 func (f *filter) mapValuer(kevt *kevent.Kevent) map[string]interface{} {
+	if f.profiler != nil {
+		defer f.profiler.observeAccessors(time.Now())
+	}
 	valuer := make(map[string]interface{}, len(f.fields))
 	for _, field := range f.fields {
-		for _, accessor := range f.accessors {
-			if !accessor.canAccess(kevt, f) {
-				continue
-			}
-			v, err := accessor.get(field, kevt)
-			if err != nil && !kerrors.IsKparamNotFound(err) {
-				accessorErrors.Add(err.Error(), 1)
-				continue
-			}
-			if v != nil {
-				valuer[field.String()] = v
-				break
-			}
+		if v := f.fieldValue(field, kevt); v != nil {
+			valuer[field.String()] = v
 		}
 	}
 	return valuer
 }
 
+// fieldValue extracts the field value from the event with the
+// first accessor that yields the value.
+func (f *filter) fieldValue(field fields.Field, kevt *kevent.Kevent) interface{} {
+	for _, accessor := range f.accessors {
+		if !accessor.canAccess(kevt, f) {
+			continue
+		}
+		v, err := accessor.get(field, kevt)
+		if err != nil && !kerrors.IsKparamNotFound(err) {
+			accessorErrors.Add(err.Error(), 1)
+			continue
+		}
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// fieldEnv feeds the compiled program with field values. Accessors
+// are only called for fields the program actually reaches, and the
+// value is kept for the rest of the evaluation.
+type fieldEnv struct {
+	f      *filter
+	kevt   *kevent.Kevent
+	values []interface{}
+	// resolved flags slots with extracted values. Slots past the
+	// first 64 are extracted on every access
+	resolved uint64
+}
+
+func (e *fieldEnv) Value(slot int) interface{} {
+	if slot < 64 && e.resolved&(1<<slot) != 0 {
+		return e.values[slot]
+	}
+	field := e.f.slots[slot]
+	if field.IsEmpty() {
+		return nil
+	}
+	if e.f.profiler != nil {
+		defer e.f.profiler.observeAccessors(time.Now())
+	}
+	v := e.f.fieldValue(field, e.kevt)
+	e.values[slot] = v
+	if slot < 64 {
+		e.resolved |= 1 << slot
+	}
+	return v
+}
+
 // addField appends a new field to the filter fields list.
 func (f *filter) addField(field fields.Field) {
 	for _, f := range f.fields {
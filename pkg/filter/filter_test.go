@@ -124,6 +124,7 @@ func TestFilterRunProcessKevent(t *testing.T) {
 		},
 	}
 	kevt.Timestamp, _ = time.Parse(time.RFC3339, "2011-05-03T15:04:05.323Z")
+	kevt.PS.StartTime = kevt.Timestamp.Add(-30 * time.Second)
 
 	kevt1 := &kevent.Kevent{
 		Type:     ktypes.OpenProcess,
@@ -187,6 +188,19 @@ func TestFilterRunProcessKevent(t *testing.T) {
 		{`ps.ancestor[any].name contains ('Sys')`, true},
 		{`ps.ancestor[any].name icontains ('sys')`, true},
 		{`ps.ancestor[any].pid in (2034, 343)`, true},
+		{`date_diff(ps.start_time, kevt.timestamp) < 1m`, true},
+		{`date_diff(ps.start_time, kevt.timestamp, 's') = 30`, true},
+		{`DATE_DIFF(ps.start_time, kevt.time, 's') = 30`, true},
+		{`cmdline_flag(ps.sibling.comm, 'k') = 'RPCSS'`, true},
+		{`cmdline_args(ps.sibling.comm) in ('-k')`, true},
+		{`levenshtein(ps.sibling.name, 'svchost.exe') <= 5 and ps.sibling.name != 'svchost.exe'`, true},
+		{`jaro_winkler(ps.sibling.name, 'svchost.exe') > 0.9`, true},
+		{`sha1(ps.sibling.name) = '015896eafc16fb4efb457b0280588ac332e13d17'`, true},
+		{`any(ps.modules, m, m iendswith 'user32.dll')`, true},
+		{`all(ps.modules, m, m istartswith 'kernel')`, false},
+		{`count(ps.modules) = 2 and count(ps.envs, e, e istartswith 'program') = 1`, true},
+		{`ps.modules[1] = 'user32.dll' and ps.modules[2] is null`, true},
+		{`any(ps.modules, m, concat(ps.name, ':', m) = 'svchost.exe:kernel32.dll')`, true},
 	}
 
 	for i, tt := range tests {
@@ -220,6 +234,55 @@ func TestFilterRunProcessKevent(t *testing.T) {
 			t.Errorf("%d. %q ps filter mismatch: exp=%t got=%t", i, tt.filter, tt.matches, matches)
 		}
 	}
+
+	// the parent process is unknown
+	kevt2 := &kevent.Kevent{
+		Type:     ktypes.OpenProcess,
+		Category: ktypes.Process,
+		Kparams:  kpars1,
+		Name:     "OpenProcess",
+		PID:      1023,
+		PS: &pstypes.PS{
+			Name: "svchost.exe",
+			Ppid: 345,
+		},
+	}
+
+	strictCfg := *cfg
+	strictCfg.Filters = &config.Filters{Strict: true}
+
+	var tests2 = []struct {
+		filter  string
+		matches bool
+		strict  bool
+	}{
+
+		{`ps.parent.name is null and ps.name exists`, true, true},
+		{`ps.parent.name exists`, false, false},
+		{`ps.parent.name is not null`, false, false},
+		{`coalesce(ps.parent.name, 'unknown') = 'unknown'`, true, true},
+		{`ps.name = 'svchost.exe' and not ps.parent.name = 'lsass.exe'`, true, false},
+		{`ps.name = 'svchost.exe' and not (ps.parent.name = 'lsass.exe' and ps.pid = 1)`, true, true},
+		{`ps.parent.name = 'lsass.exe' or ps.name = 'svchost.exe'`, true, true},
+	}
+
+	for i, tt := range tests2 {
+		for _, c := range []*config.Config{cfg, &strictCfg} {
+			f := New(tt.filter, c)
+			err := f.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp := tt.matches
+			if c.Filters.Strict {
+				exp = tt.strict
+			}
+			matches := f.Run(kevt2)
+			if matches != exp {
+				t.Errorf("%d. %q ps filter mismatch: strict=%t exp=%t got=%t", i, tt.filter, c.Filters.Strict, exp, matches)
+			}
+		}
+	}
 }
 
 func TestFilterRunThreadKevent(t *testing.T) {
@@ -372,6 +435,10 @@ func TestFilterRunKevent(t *testing.T) {
 		{`kevt.category = 'file'`, true},
 		{`kevt.host = 'archrabbit'`, true},
 		{`kevt.nparams = 4`, true},
+		{`kevt.meta in ('fooz')`, true},
+		{`kevt.meta in ('rule.name')`, false},
+		{`kevt.meta[foo] = 'bar'`, true},
+		{`kevt.meta[rule.name] = 'bar'`, false},
 
 		{`kevt.desc contains 'Creates or opens a new file'`, true},
 
@@ -393,6 +460,21 @@ func TestFilterRunKevent(t *testing.T) {
 		{`substr(kevt.desc, indexof(kevt.desc, '\\'), indexof(kevt.desc, 'NOT')) = 'Creates or opens a new file, directory, I/O device, pipe, console'`, true},
 		{`entropy(file.name) > 120`, true},
 		{`regex(file.name, '\\\\Device\\\\HarddiskVolume[2-9]+\\\\.*')`, true},
+		{`hour(kevt.timestamp, 'UTC') = 15`, true},
+		{`hour(kevt.time) = 15`, true},
+		{`HOUR(kevt.time, 'Europe/Madrid') = 17`, true},
+		{`WEEKDAY(kevt.time) = 'Tuesday'`, true},
+		{`weekday(kevt.timestamp, 'UTC') = 'Tuesday'`, true},
+		{`weekday(kevt.timestamp, 'UTC') in ('Saturday', 'Sunday')`, false},
+		{`in_time_range('09:00', '17:00', 'UTC', kevt.timestamp)`, true},
+		{`in_time_range('22:00', '06:00', 'UTC', kevt.timestamp)`, false},
+		{`in_time_range('22:00', '06:00', 'Asia/Tokyo', kevt.timestamp)`, true},
+		{`in_time_range('09:00', '17:00')`, true},
+		{`in_time_range('22:00', '06:00', 'Asia/Tokyo')`, true},
+		{`date_diff('2011-05-03T15:00:00Z', kevt.timestamp) > 4m and date_diff('2011-05-03T15:00:00Z', kevt.timestamp) < 4m6s`, true},
+		{`date_diff(kevt.timestamp, '2011-05-03T15:00:00Z') < -4m`, true},
+		{`date_diff('2011-05-03T15:00:00Z', kevt.timestamp, 's') = 245`, true},
+		{`date_diff('2011-05-01', kevt.timestamp, 'd') >= 2`, true},
 	}
 
 	for i, tt := range tests {
@@ -521,6 +603,7 @@ func TestFilterRunPE(t *testing.T) {
 				Symbols:          []string{"SelectObject", "GetTextFaceW", "EnumFontsW", "TextOutW", "GetProcessHeap"},
 				Imports:          []string{"GDI32.dll", "USER32.dll", "msvcrt.dll", "api-ms-win-core-libraryloader-l1-2-0.dl"},
 				VersionResources: map[string]string{"CompanyName": "Microsoft Corporation", "FileDescription": "Notepad", "FileVersion": "10.0.18362.693"},
+				Imphash:          "28e7cf1bf0a237602e55689d52623b82",
 			},
 		},
 	}
@@ -538,6 +621,8 @@ func TestFilterRunPE(t *testing.T) {
 		{`pe.nsymbols = 10 AND pe.nsections = 2`, true},
 		{`pe.nsections > 1`, true},
 		{`pe.address.base = '140000000' AND pe.address.entrypoint = '20110'`, true},
+		{`pe.imphash = '28e7cf1bf0a237602e55689d52623b82'`, true},
+		{`pe.imphash = imphash('kernel32.dll.CreateFileW, kernel32.dll.CloseHandle, user32.dll.MessageBoxA')`, true},
 	}
 
 	for i, tt := range tests {
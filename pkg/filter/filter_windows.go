@@ -20,6 +20,7 @@ package filter
 
 import (
 	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/aggregator"
 	"github.com/rabbitstack/fibratus/pkg/config"
 	"github.com/rabbitstack/fibratus/pkg/filter/fields"
 	"github.com/rabbitstack/fibratus/pkg/filter/ql"
@@ -62,7 +63,7 @@ func New(expr string, config *config.Config) Filter {
 	}
 
 	var parser *ql.Parser
-	if fconfig.HasMacros() {
+	if fconfig.HasMacros() || fconfig.HasLookups() {
 		parser = ql.NewParserWithConfig(expr, fconfig)
 	} else {
 		parser = ql.NewParser(expr)
@@ -73,6 +74,7 @@ func New(expr string, config *config.Config) Filter {
 		accessors:    accessors,
 		fields:       make([]fields.Field, 0),
 		stringFields: make(map[fields.Field][]string),
+		strict:       fconfig.Strict,
 	}
 }
 
@@ -82,6 +84,9 @@ func NewFromCLI(args []string, config *config.Config) (Filter, error) {
 	if expr == "" {
 		return nil, nil
 	}
+	if err := config.Filters.LoadLookups(); err != nil {
+		return nil, err
+	}
 	filter := New(expr, config)
 	if err := filter.Compile(); err != nil {
 		return nil, fmt.Errorf("bad filter:\n%v", err)
@@ -89,6 +94,18 @@ func NewFromCLI(args []string, config *config.Config) (Filter, error) {
 	return filter, nil
 }
 
+// NewOutputCompiler returns the compiler of filter expressions
+// that select events routed to outputs.
+func NewOutputCompiler(config *config.Config) aggregator.FilterCompiler {
+	return func(expr string) (aggregator.Filter, error) {
+		filter := New(expr, config)
+		if err := filter.Compile(); err != nil {
+			return nil, err
+		}
+		return filter, nil
+	}
+}
+
 // NewFromCLIWithAllAccessors builds and compiles a filter with all field accessors enabled.
 func NewFromCLIWithAllAccessors(args []string) (Filter, error) {
 	expr := strings.Join(args, " ")
@@ -19,9 +19,14 @@
 package filter
 
 import (
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
 	"github.com/rabbitstack/fibratus/pkg/config"
 	"github.com/rabbitstack/fibratus/pkg/filter/action"
-	"text/template"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
 )
 
 // NewFuncMap returns the template func map
@@ -31,10 +36,40 @@ func NewFuncMap() template.FuncMap {
 	return config.FilterFuncMap()
 }
 
-// InitFuncs assigns late-bound functions to the func map.
-func InitFuncs(funcMap template.FuncMap) {
+// InitFuncs assigns late-bound functions to the func map. Action
+// functions are bound to the context of the rule that produced the
+// match, so they can be rate limited and audited per rule.
+func InitFuncs(funcMap template.FuncMap, ctx *config.ActionContext) {
 	funcMap["emit"] = emit
-	funcMap["kill"] = kill
+	funcMap["kill"] = func(pid uint32) string {
+		return run(ctx, "kill", false, func() (string, error) { return "", action.Kill(pid) }, pid)
+	}
+	funcMap["suspend"] = func(pid uint32) string {
+		return run(ctx, "suspend", false, func() (string, error) { return "", action.Suspend(pid) }, pid)
+	}
+	funcMap["resume"] = func(pid uint32) string {
+		return run(ctx, "resume", false, func() (string, error) { return "", action.Resume(pid) }, pid)
+	}
+	funcMap["quarantine"] = func(path string) string {
+		path = InterpolateFields(path, ctx.Events)
+		return run(ctx, "quarantine", false, func() (string, error) { return action.Quarantine(path) }, path)
+	}
+	funcMap["block_ip"] = func(ip string) string {
+		ip = InterpolateFields(ip, ctx.Events)
+		return run(ctx, "block_ip", true, func() (string, error) { return "", action.BlockIP(ip) }, ip)
+	}
+	funcMap["tag"] = func(key, value string) string {
+		return tag(ctx, key, InterpolateFields(value, ctx.Events))
+	}
+	funcMap["exec"] = func(script string, args ...string) string {
+		for i, arg := range args {
+			args[i] = InterpolateFields(arg, ctx.Events)
+		}
+		return execScript(ctx, script, args)
+	}
+	funcMap["capture"] = func(duration string) string {
+		return capture(ctx, duration)
+	}
 }
 
 // emit sends the rule alert via all configured alert senders.
@@ -46,11 +81,84 @@ func emit(ctx *config.ActionContext, title string, text string, args ...string)
 	return ""
 }
 
-// kill terminates a process with specified pid.
-func kill(pid uint32) string {
-	err := action.Kill(pid)
-	if err != nil {
+// run executes the action under the rate limit and audit log. The
+// error is returned as the template output, so the rule action fails.
+func run(ctx *config.ActionContext, name string, async bool, fn func() (string, error), args ...any) string {
+	sargs := make([]string, len(args))
+	for i, arg := range args {
+		sargs[i] = fmt.Sprintf("%v", arg)
+	}
+	if err := action.Execute(ctx, name, sargs, async, fn); err != nil {
 		return err.Error()
 	}
 	return ""
 }
+
+// tag attaches the metadata to all events that triggered the rule,
+// so the tag is propagated to outputs.
+func tag(ctx *config.ActionContext, key, value string) string {
+	if key == "" {
+		return "tag key is empty"
+	}
+	return run(ctx, "tag", false, func() (string, error) {
+		for _, kevt := range ctx.Events {
+			if kevt.Metadata == nil {
+				kevt.Metadata = make(kevent.Metadata)
+			}
+			kevt.AddMeta(kevent.MetadataKey(key), value)
+		}
+		return "", nil
+	}, key, value)
+}
+
+// execScript runs the configured script. The rule context
+// and the matched events are written to the script stdin.
+func execScript(ctx *config.ActionContext, script string, args []string) string {
+	events := make([]json.RawMessage, 0, len(ctx.Events))
+	for _, kevt := range ctx.Events {
+		events = append(events, kevt.MarshalJSON())
+	}
+	labels := make(map[string]string, len(ctx.Group.Labels))
+	for k, v := range ctx.Group.Labels {
+		labels[k] = v
+	}
+	payload := struct {
+		Rule   string            `json:"rule"`
+		Group  string            `json:"group"`
+		Labels map[string]string `json:"labels,omitempty"`
+		Events []json.RawMessage `json:"events"`
+	}{
+		Group:  ctx.Group.Name,
+		Labels: labels,
+		Events: events,
+	}
+	if ctx.Filter != nil {
+		payload.Rule = ctx.Filter.Name
+		for k, v := range ctx.Filter.Labels {
+			labels[k] = v
+		}
+	}
+	stdin, err := json.Marshal(payload)
+	if err != nil {
+		return err.Error()
+	}
+	sargs := make([]any, 0, len(args)+1)
+	sargs = append(sargs, script)
+	for _, arg := range args {
+		sargs = append(sargs, arg)
+	}
+	return run(ctx, "exec", true, func() (string, error) { return action.ExecScript(script, args, stdin) }, sargs...)
+}
+
+// capture starts the time-bounded recording of the event stream.
+func capture(ctx *config.ActionContext, duration string) string {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return fmt.Sprintf("invalid capture duration: %v", err)
+	}
+	rule := ctx.Group.Name
+	if ctx.Filter != nil {
+		rule = ctx.Filter.Name
+	}
+	return run(ctx, "capture", false, func() (string, error) { return action.Capture(rule, d) }, d)
+}
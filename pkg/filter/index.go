@@ -0,0 +1,319 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"expvar"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// indexSkippedEvaluations counts rule evaluations skipped by the rule index
+	indexSkippedEvaluations = expvar.NewMap("filter.index.skipped.evaluations")
+	// indexedRulesCount is the number of rules with at least one indexed condition
+	indexedRulesCount = expvar.NewInt("filter.index.rules.count")
+)
+
+// indexedFields are the cheap discriminator fields whose equality,
+// set membership, and prefix conditions are extracted into the index.
+var indexedFields = []fields.Field{
+	fields.KevtName,
+	fields.KevtCategory,
+	fields.PsName,
+	fields.FileExtension,
+	fields.RegistryKeyName,
+}
+
+// ruleIndex narrows down the rules that can possibly match the event.
+// The index is built from the conditions of simple rules that must be
+// satisfied for the rule to match. Each such condition, called a guard,
+// compares a discriminator field against string constants with one of
+// the =, ~=, in, iin, startswith or istartswith operators. Guard values
+// are stored in hash tables, and prefixes are stored in tries, so the
+// guards satisfied by the event are resolved without evaluating rules.
+// A rule is only evaluated if all of its guards are satisfied.
+//
+// Guards are only built from ASCII constants and are matched
+// case-insensitively. This way, the index never rejects the rule
+// the expression would match, while the rule evaluation takes care
+// of the exact comparison.
+type ruleIndex struct {
+	// guards maps indexed rules to the identifiers of their guards
+	guards map[*compiledFilter][]int
+	// nguards is the total number of guards in the index
+	nguards int
+	fields  []*fieldIndex
+	// valuer extracts the values of discriminator fields
+	valuer *filter
+}
+
+// fieldIndex stores the guards declared on the discriminator field.
+type fieldIndex struct {
+	field fields.Field
+	// values maps lowercase values to guard identifiers
+	values map[string][]int
+	// prefixes resolves guard identifiers by value prefixes
+	prefixes *prefixTrie
+	// all contains all guard identifiers of the field. All guards
+	// are satisfied if the value can't be matched through the index
+	all []int
+}
+
+// guard is the condition extracted from the rule expression.
+type guard struct {
+	field    fields.Field
+	values   []string
+	prefixes []string
+}
+
+func newRuleIndex(c *config.Config) *ruleIndex {
+	valuer := New("", c).(*filter)
+	for _, field := range indexedFields {
+		valuer.addField(field)
+	}
+	valuer.initAccessors()
+	return &ruleIndex{guards: make(map[*compiledFilter][]int), valuer: valuer}
+}
+
+// add extracts guards from the rule and indexes them.
+// Sequences and aggregations are never indexed.
+func (idx *ruleIndex) add(f *compiledFilter) {
+	if f.ss != nil || f.as != nil {
+		return
+	}
+	flt, ok := f.filter.(*filter)
+	if !ok || flt.expr == nil {
+		return
+	}
+	guards := extractGuards(flt.expr)
+	if len(guards) == 0 {
+		return
+	}
+	ids := make([]int, 0, len(guards))
+	for _, g := range guards {
+		id := idx.nguards
+		idx.nguards++
+		fi := idx.fieldIndex(g.field)
+		for _, v := range g.values {
+			fi.values[v] = append(fi.values[v], id)
+		}
+		for _, prefix := range g.prefixes {
+			fi.prefixes.insert(prefix, id)
+		}
+		fi.all = append(fi.all, id)
+		ids = append(ids, id)
+	}
+	idx.guards[f] = ids
+}
+
+func (idx *ruleIndex) fieldIndex(field fields.Field) *fieldIndex {
+	for _, fi := range idx.fields {
+		if fi.field == field {
+			return fi
+		}
+	}
+	fi := &fieldIndex{field: field, values: make(map[string][]int), prefixes: newPrefixTrie()}
+	idx.fields = append(idx.fields, fi)
+	return fi
+}
+
+// size returns the number of indexed rules.
+func (idx *ruleIndex) size() int {
+	if idx == nil {
+		return 0
+	}
+	return len(idx.guards)
+}
+
+// candidates resolves the guards satisfied by the event. A nil
+// slice is returned if the index doesn't contain any rules.
+func (idx *ruleIndex) candidates(kevt *kevent.Kevent) []bool {
+	if idx.size() == 0 {
+		return nil
+	}
+	satisfied := make([]bool, idx.nguards)
+	for _, fi := range idx.fields {
+		v, ok := idx.valuer.fieldValue(fi.field, kevt).(string)
+		if !ok || !isASCII(v) {
+			// non-string values and strings with non-ASCII
+			// characters may satisfy the guard in ways the
+			// case-insensitive lookup can't reproduce
+			for _, id := range fi.all {
+				satisfied[id] = true
+			}
+			continue
+		}
+		v = strings.ToLower(v)
+		for _, id := range fi.values[v] {
+			satisfied[id] = true
+		}
+		fi.prefixes.walk(v, func(id int) { satisfied[id] = true })
+	}
+	return satisfied
+}
+
+// admits determines if the rule can match the event given the
+// satisfied guards. Rules without guards are always admitted.
+func (idx *ruleIndex) admits(f *compiledFilter, satisfied []bool) bool {
+	if satisfied == nil {
+		return true
+	}
+	for _, id := range idx.guards[f] {
+		if !satisfied[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// extractGuards returns guards of the expression. The expression
+// only matches if all top-level conjuncts evaluate to true, so each
+// conjunct comparing the discriminator field with constants is the
+// necessary condition of the match.
+func extractGuards(expr ql.Expr) []guard {
+	guards := make([]guard, 0)
+	for _, conjunct := range conjuncts(expr) {
+		if g, ok := newGuard(conjunct); ok {
+			guards = append(guards, g)
+		}
+	}
+	return guards
+}
+
+func conjuncts(expr ql.Expr) []ql.Expr {
+	switch e := expr.(type) {
+	case *ql.ParenExpr:
+		return conjuncts(e.Expr)
+	case *ql.BinaryExpr:
+		if e.Op == ql.And {
+			return append(conjuncts(e.LHS), conjuncts(e.RHS)...)
+		}
+	}
+	return []ql.Expr{expr}
+}
+
+func newGuard(expr ql.Expr) (guard, bool) {
+	e, ok := expr.(*ql.BinaryExpr)
+	if !ok {
+		return guard{}, false
+	}
+	lhs, ok := e.LHS.(*ql.FieldLiteral)
+	if !ok || !isIndexedField(fields.Field(lhs.Value)) {
+		return guard{}, false
+	}
+	var values []string
+	switch rhs := e.RHS.(type) {
+	case *ql.StringLiteral:
+		if e.Op == ql.In || e.Op == ql.IIn {
+			return guard{}, false
+		}
+		values = []string{rhs.Value}
+	case *ql.ListLiteral:
+		if e.Op == ql.Eq || e.Op == ql.IEq {
+			return guard{}, false
+		}
+		values = rhs.Values
+	default:
+		return guard{}, false
+	}
+	for _, v := range values {
+		if !isASCII(v) {
+			return guard{}, false
+		}
+	}
+	g := guard{field: fields.Field(lhs.Value)}
+	switch e.Op {
+	case ql.Eq, ql.IEq, ql.In, ql.IIn:
+		for _, v := range values {
+			g.values = append(g.values, strings.ToLower(v))
+		}
+	case ql.Startswith, ql.IStartswith:
+		for _, v := range values {
+			g.prefixes = append(g.prefixes, strings.ToLower(v))
+		}
+	default:
+		return guard{}, false
+	}
+	return g, true
+}
+
+func isIndexedField(field fields.Field) bool {
+	for _, f := range indexedFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// prefixTrie is the byte-wise trie that resolves identifiers
+// associated with all prefixes of the given string.
+type prefixTrie struct {
+	children map[byte]*prefixTrie
+	ids      []int
+}
+
+func newPrefixTrie() *prefixTrie {
+	return &prefixTrie{children: make(map[byte]*prefixTrie)}
+}
+
+func (t *prefixTrie) insert(prefix string, id int) {
+	n := t
+	for i := 0; i < len(prefix); i++ {
+		child, ok := n.children[prefix[i]]
+		if !ok {
+			child = newPrefixTrie()
+			n.children[prefix[i]] = child
+		}
+		n = child
+	}
+	n.ids = append(n.ids, id)
+}
+
+// walk visits identifiers of all prefixes of s.
+func (t *prefixTrie) walk(s string, fn func(id int)) {
+	n := t
+	for i := 0; ; i++ {
+		for _, id := range n.ids {
+			fn(id)
+		}
+		if i == len(s) {
+			return
+		}
+		child, ok := n.children[s[i]]
+		if !ok {
+			return
+		}
+		n = child
+	}
+}
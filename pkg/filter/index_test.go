@@ -0,0 +1,112 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"testing"
+
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleIndex(t *testing.T) {
+	rules := NewRules(newConfig("_fixtures/indexed_rules.yml"))
+	require.NoError(t, rules.Compile())
+	// the last rule is a disjunction, so it has no guards
+	assert.Equal(t, 3, rules.index.size())
+
+	admitted := func(kevt *kevent.Kevent) []string {
+		satisfied := rules.index.candidates(kevt)
+		names := make([]string, 0)
+		for _, g := range rules.groups {
+			for _, f := range g.filters {
+				if rules.index.admits(f, satisfied) {
+					names = append(names, f.config.Name)
+				}
+			}
+		}
+		return names
+	}
+
+	newFileEvent := func(proc, filename string) *kevent.Kevent {
+		return &kevent.Kevent{
+			Type:     ktypes.CreateFile,
+			Name:     "CreateFile",
+			Category: ktypes.File,
+			PS:       &types.PS{Name: proc},
+			Kparams: kevent.Kparams{
+				kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: filename},
+			},
+			Metadata: make(map[kevent.MetadataKey]any),
+		}
+	}
+
+	assert.Equal(t, []string{"Shell spawned executable dropped", "File touched by unusual process"}, admitted(newFileEvent("CMD.exe", "C:\\Temp\\dropper.exe")))
+	assert.Equal(t, []string{"Library dropped", "File touched by unusual process"}, admitted(newFileEvent("rundll32.exe", "C:\\Temp\\payload.DLL")))
+	assert.Equal(t, []string{"File touched by unusual process"}, admitted(newFileEvent("explorer.exe", "C:\\Temp\\payload.dll")))
+	// non-ASCII values can't be resolved through the index
+	assert.Equal(t, []string{"Shell spawned executable dropped", "File touched by unusual process"}, admitted(newFileEvent("\u212amd.exe", "C:\\Temp\\a.txt")))
+
+	regEvent := &kevent.Kevent{
+		Type:     ktypes.RegSetValue,
+		Name:     "RegSetValue",
+		Category: ktypes.Registry,
+		PS:       &types.PS{Name: "reg.exe"},
+		Kparams: kevent.Kparams{
+			kparams.RegKeyName: {Name: kparams.RegKeyName, Type: kparams.UnicodeString, Value: "HKEY_LOCAL_MACHINE\\SOFTWARE\\MICROSOFT\\Windows\\CurrentVersion\\Run\\Updater"},
+		},
+		Metadata: make(map[kevent.MetadataKey]any),
+	}
+	assert.Equal(t, []string{"Run key modified", "File touched by unusual process"}, admitted(regEvent))
+}
+
+func TestRuleIndexSkipsEvaluations(t *testing.T) {
+	rules := NewRules(newConfig("_fixtures/indexed_rules.yml"))
+	var fired []string
+	rules.match = func(kevt *kevent.Kevent, _ map[uint16]*kevent.Kevent, group config.FilterGroup, filter *config.FilterConfig, aggs map[string]float64, occurrences config.Occurrences) error {
+		fired = append(fired, filter.Name)
+		return nil
+	}
+	require.NoError(t, rules.Compile())
+
+	kevt := &kevent.Kevent{
+		Type:     ktypes.CreateFile,
+		Name:     "CreateFile",
+		Category: ktypes.File,
+		PS:       &types.PS{Name: "powershell.exe"},
+		Kparams: kevent.Kparams{
+			kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Temp\\dropper.exe"},
+		},
+		Metadata: make(map[kevent.MetadataKey]any),
+	}
+	skipped := indexSkippedEvaluations.Get("Library dropped")
+	require.True(t, rules.Fire(kevt))
+	assert.Equal(t, []string{"Shell spawned executable dropped"}, fired)
+
+	fired = nil
+	kevt.PS.Name = "explorer.exe"
+	require.True(t, rules.Fire(kevt))
+	assert.Equal(t, []string{"File touched by unusual process"}, fired)
+	assert.NotEqual(t, skipped, indexSkippedEvaluations.Get("Library dropped"))
+}
@@ -0,0 +1,426 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"gopkg.in/yaml.v3"
+	"sort"
+	"strings"
+)
+
+// LintSeverity designates the severity of the lint issue.
+type LintSeverity string
+
+const (
+	// LintError is the severity of issues that prevent the rule from loading or matching.
+	LintError LintSeverity = "error"
+	// LintWarning is the severity of issues that don't prevent the rules from loading.
+	LintWarning LintSeverity = "warning"
+)
+
+// LintIssue describes a problem found in the rule or macro file.
+type LintIssue struct {
+	// Resource is the file path or URL of the rule or macro file.
+	Resource string `json:"resource"`
+	// Line is the line number in the resource. Zero if unknown.
+	Line     int          `json:"line,omitempty"`
+	Group    string       `json:"group,omitempty"`
+	Rule     string       `json:"rule,omitempty"`
+	Macro    string       `json:"macro,omitempty"`
+	Severity LintSeverity `json:"severity"`
+	Message  string       `json:"message"`
+}
+
+// String returns the issue in the file:line: severity: message format.
+func (i LintIssue) String() string {
+	var sb strings.Builder
+	sb.WriteString(i.Resource)
+	if i.Line > 0 {
+		sb.WriteString(fmt.Sprintf(":%d", i.Line))
+	}
+	sb.WriteString(fmt.Sprintf(": %s: ", i.Severity))
+	switch {
+	case i.Rule != "":
+		sb.WriteString(fmt.Sprintf("[%s/%s] ", i.Group, i.Rule))
+	case i.Group != "":
+		sb.WriteString(fmt.Sprintf("[%s] ", i.Group))
+	case i.Macro != "":
+		sb.WriteString(fmt.Sprintf("[%s] ", i.Macro))
+	}
+	sb.WriteString(i.Message)
+	return sb.String()
+}
+
+// isNumericType determines if the parameter type is a numeric or boolean value.
+func isNumericType(typ kparams.Type) bool {
+	switch typ {
+	case kparams.Int8, kparams.Uint8, kparams.Int16, kparams.Uint16,
+		kparams.Int32, kparams.Uint32, kparams.Int64, kparams.Uint64,
+		kparams.Float, kparams.Double, kparams.Bool,
+		kparams.PID, kparams.TID, kparams.Port:
+		return true
+	default:
+		return false
+	}
+}
+
+// ruleLines keeps line numbers of the rule attributes.
+type ruleLines struct {
+	name      int
+	condition int
+	action    int
+}
+
+// resourceLines maps group and rule names to
+// line numbers in the raw rule/macro file. Rules
+// with the same name are kept in the declaration
+// order.
+type resourceLines struct {
+	groups map[string]int
+	rules  map[string][]ruleLines
+	macros map[string]int
+}
+
+// nextRule pops the lines of the next rule with the given name.
+func (r resourceLines) nextRule(group, rule string) ruleLines {
+	key := ruleKey(group, rule)
+	lines := r.rules[key]
+	if len(lines) == 0 {
+		return ruleLines{}
+	}
+	r.rules[key] = lines[1:]
+	return lines[0]
+}
+
+func ruleKey(group, rule string) string { return group + "\x00" + rule }
+
+// scanLines decodes the raw yaml node tree and records the
+// lines where groups, rules and macros are declared. It never
+// fails; missing positions are reported as zero lines.
+func scanLines(b []byte) resourceLines {
+	lines := resourceLines{
+		groups: make(map[string]int),
+		rules:  make(map[string][]ruleLines),
+		macros: make(map[string]int),
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil || len(doc.Content) == 0 {
+		return lines
+	}
+	for _, n := range doc.Content[0].Content {
+		var group string
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			switch key.Value {
+			case "group":
+				group = val.Value
+				lines.groups[group] = key.Line
+			case "macro":
+				lines.macros[val.Value] = key.Line
+			}
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			if key.Value != "rules" && key.Value != "from-strings" {
+				continue
+			}
+			for _, r := range val.Content {
+				var name string
+				var rl ruleLines
+				for j := 0; j+1 < len(r.Content); j += 2 {
+					k, v := r.Content[j], r.Content[j+1]
+					switch k.Value {
+					case "name":
+						name = v.Value
+						rl.name = k.Line
+					case "condition", "def":
+						rl.condition = k.Line
+					case "action":
+						rl.action = k.Line
+					}
+				}
+				key := ruleKey(group, name)
+				lines.rules[key] = append(lines.rules[key], rl)
+			}
+		}
+	}
+	return lines
+}
+
+// linter accumulates issues while inspecting rule and macro files.
+type linter struct {
+	c       *config.Config
+	issues  []LintIssue
+	macros  map[string]*config.Macro
+	fields  map[fields.Field]fields.FieldInfo
+	refs    map[string]bool
+	rules   map[string]string
+	groups  map[string]string
+	filters *config.Filters
+}
+
+// Lint loads all macros and rule groups declared in the config and
+// reports every problem found instead of stopping at the first one. The
+// error is only returned if the rule, macro, or lookup table files can't be read.
+func Lint(c *config.Config) ([]LintIssue, error) {
+	l := &linter{
+		c:      c,
+		issues: make([]LintIssue, 0),
+		macros: make(map[string]*config.Macro),
+		fields: make(map[fields.Field]fields.FieldInfo),
+		refs:   make(map[string]bool),
+		rules:  make(map[string]string),
+		groups: make(map[string]string),
+	}
+	for _, f := range fields.Get() {
+		l.fields[f.Field] = f
+	}
+
+	macroResources, err := c.Filters.LoadMacroResources()
+	if err != nil {
+		return nil, err
+	}
+	macroLines := make(map[string]LintIssue)
+	for _, res := range macroResources {
+		macros, err := config.DecodeMacros(res)
+		if err != nil {
+			l.add(LintIssue{Resource: res.Name, Severity: LintError, Message: err.Error()})
+			continue
+		}
+		lines := scanLines(res.Content)
+		for i := range macros {
+			macro := macros[i]
+			issue := LintIssue{Resource: res.Name, Line: lines.macros[macro.ID], Macro: macro.ID}
+			if prev, ok := macroLines[macro.ID]; ok {
+				issue.Severity = LintWarning
+				issue.Message = fmt.Sprintf("macro redeclared. Previous declaration at %s", location(prev))
+				l.add(issue)
+			}
+			macroLines[macro.ID] = issue
+			l.macros[macro.ID] = &macro
+		}
+	}
+	l.filters = config.FiltersWithMacros(l.macros)
+	l.filters.Lookups = c.Filters.Lookups
+	if err := l.filters.LoadLookups(); err != nil {
+		return nil, err
+	}
+
+	ruleResources, err := c.Filters.LoadRuleResources()
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range ruleResources {
+		l.lintResource(res)
+	}
+
+	// macros can reference other macros, so
+	// we have to follow references from the
+	// macros that are used by the rules
+	queue := make([]string, 0, len(l.refs))
+	for ref := range l.refs {
+		queue = append(queue, ref)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		macro, ok := l.macros[id]
+		if !ok || macro.Expr == "" {
+			continue
+		}
+		for _, ident := range ql.Idents(macro.Expr) {
+			if _, ok := l.macros[ident.Name]; ok && !l.refs[ident.Name] {
+				l.refs[ident.Name] = true
+				queue = append(queue, ident.Name)
+			}
+		}
+	}
+	for id, issue := range macroLines {
+		if l.refs[id] {
+			continue
+		}
+		issue.Severity = LintWarning
+		issue.Message = "macro is not referenced by any rule"
+		l.add(issue)
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		if l.issues[i].Resource != l.issues[j].Resource {
+			return l.issues[i].Resource < l.issues[j].Resource
+		}
+		return l.issues[i].Line < l.issues[j].Line
+	})
+	return l.issues, nil
+}
+
+func (l *linter) add(issue LintIssue) { l.issues = append(l.issues, issue) }
+
+func location(issue LintIssue) string {
+	if issue.Line > 0 {
+		return fmt.Sprintf("%s:%d", issue.Resource, issue.Line)
+	}
+	return issue.Resource
+}
+
+func (l *linter) lintResource(res config.Resource) {
+	groups, err := config.DecodeFilterGroups(res)
+	if err != nil {
+		l.add(LintIssue{Resource: res.Name, Severity: LintError, Message: err.Error()})
+		return
+	}
+	lines := scanLines(res.Content)
+
+	for _, group := range groups {
+		gi := LintIssue{Resource: res.Name, Line: lines.groups[group.Name], Group: group.Name}
+		key := group.Policy.String() + group.Name
+		if prev, ok := l.groups[key]; ok {
+			gi.Severity = LintError
+			gi.Message = fmt.Sprintf("duplicate group with %q policy. Previous declaration at %s", group.Policy, prev)
+			l.add(gi)
+		} else {
+			l.groups[key] = location(gi)
+		}
+
+		for _, rule := range append(group.Rules, group.FromStrings...) {
+			rl := lines.nextRule(group.Name, rule.Name)
+			issue := func(line int, severity LintSeverity, msg string) {
+				l.add(LintIssue{
+					Resource: res.Name,
+					Line:     line,
+					Group:    group.Name,
+					Rule:     rule.Name,
+					Severity: severity,
+					Message:  msg,
+				})
+			}
+
+			loc := location(LintIssue{Resource: res.Name, Line: rl.name})
+			if prev, ok := l.rules[rule.Name]; ok {
+				issue(rl.name, LintError, fmt.Sprintf("duplicate rule name. Previous declaration at %s", prev))
+			} else {
+				l.rules[rule.Name] = loc
+			}
+
+			if rule.Action != "" {
+				if group.Policy == config.ExcludePolicy {
+					issue(rl.action, LintError, "rule actions are not allowed in groups with exclude policy")
+				}
+				if err := rule.ParseAction(res.Name); err != nil {
+					issue(rl.action, LintError, fmt.Sprintf("invalid action template: %v", err))
+				}
+			}
+
+			for _, msg := range l.lintCondition(expr(rule)) {
+				issue(rl.condition, msg.severity, msg.text)
+			}
+		}
+	}
+}
+
+type lintMessage struct {
+	severity LintSeverity
+	text     string
+}
+
+// lintCondition compiles the rule condition and inspects the
+// resulting expression tree for operators that never match.
+func (l *linter) lintCondition(cond string) []lintMessage {
+	msgs := make([]lintMessage, 0)
+
+	// record macro references and spot
+	// identifiers that look like fields
+	var unknown []string
+	for _, ident := range ql.Idents(cond) {
+		if _, ok := l.macros[ident.Name]; ok {
+			l.refs[ident.Name] = true
+			continue
+		}
+		if strings.Contains(ident.Name, ".") {
+			unknown = append(unknown, ident.Name)
+		}
+	}
+
+	c := *l.c
+	c.Filters = l.filters
+	f := New(cond, &c).(*filter)
+	if err := f.Compile(); err != nil {
+		// the parser doesn't tell misspelled fields apart from
+		// other syntax errors, so identifiers that look like
+		// fields are reported as hints next to the parse error
+		lines := make([]string, 0, len(unknown)+1)
+		for _, name := range unknown {
+			lines = append(lines, fmt.Sprintf("unknown field %q", name))
+		}
+		lines = append(lines, err.Error())
+		return append(msgs, lintMessage{LintError, strings.Join(lines, "\n")})
+	}
+
+	walk := func(n ql.Node) {
+		expr, ok := n.(*ql.BinaryExpr)
+		if !ok {
+			return
+		}
+		// string operators never match numeric fields
+		switch expr.Op {
+		case ql.Contains, ql.IContains, ql.Startswith, ql.IStartswith,
+			ql.Endswith, ql.IEndswith, ql.Matches, ql.IMatches,
+			ql.Fuzzy, ql.IFuzzy, ql.Fuzzynorm, ql.IFuzzynorm:
+		default:
+			return
+		}
+		lhs, ok := expr.LHS.(*ql.FieldLiteral)
+		if !ok {
+			return
+		}
+		info, ok := l.fields[fields.Field(lhs.Value)]
+		if !ok || !isNumericType(info.Type) {
+			return
+		}
+		msgs = append(msgs, lintMessage{LintError, fmt.Sprintf("%q operator can never match %q field of %s type",
+			strings.ToLower(expr.Op.String()), lhs.Value, info.Type)})
+	}
+	if f.expr != nil {
+		ql.WalkFunc(f.expr, walk)
+	}
+	if f.seq != nil {
+		for _, expr := range f.seq.Expressions {
+			ql.WalkFunc(expr.Expr, walk)
+		}
+	}
+	if f.agg != nil {
+		// only the count aggregate applies to non-numeric fields
+		for _, agg := range f.agg.Aggregates() {
+			if agg.Fn == ql.CountFn {
+				continue
+			}
+			info, ok := l.fields[agg.Field]
+			if !ok || isNumericType(info.Type) {
+				continue
+			}
+			msgs = append(msgs, lintMessage{LintError, fmt.Sprintf("%q aggregate requires the numeric field, but %q is of %s type",
+				agg.Fn, agg.Field, info.Type)})
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2020-2021 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	c := newConfig("_fixtures/lint/rules.yml")
+	c.Filters.Macros.FromPaths = []string{"_fixtures/lint/macros.yml"}
+
+	issues, err := Lint(c)
+	require.NoError(t, err)
+
+	var tests = []struct {
+		rule     string
+		macro    string
+		line     int
+		severity LintSeverity
+		msg      string
+	}{
+		{"unknown field", "", 7, LintError, `unknown field "ps.nme"`},
+		{"impossible operator", "", 9, LintError, `"startswith" operator can never match "net.dport" field`},
+		{"bad function argument", "", 11, LintError, "CIDR_CONTAINS"},
+		{"valid rule", "", 14, LintError, "duplicate rule name"},
+		{"invalid action", "", 18, LintError, "invalid action template"},
+		{"non-numeric aggregate", "", 20, LintError, `"sum" aggregate requires the numeric field, but "file.name"`},
+		{"", "unused_macro", 10, LintWarning, "macro is not referenced by any rule"},
+	}
+
+	require.Len(t, issues, len(tests))
+	for _, tt := range tests {
+		var found bool
+		for _, issue := range issues {
+			if issue.Rule != tt.rule || issue.Macro != tt.macro {
+				continue
+			}
+			found = true
+			assert.Equal(t, tt.line, issue.Line)
+			assert.Equal(t, tt.severity, issue.Severity)
+			assert.Contains(t, issue.Message, tt.msg)
+		}
+		assert.True(t, found, "expected lint issue for %s%s", tt.rule, tt.macro)
+	}
+
+	// the parse error is reported along with the unknown field hint
+	for _, issue := range issues {
+		if issue.Rule == "unknown field" {
+			assert.Contains(t, issue.Message, "expected field")
+		}
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// profileSamples is the number of most recent evaluation
+// durations kept for computing the latency percentiles
+const profileSamples = 1024
+
+// ErrProfilingDisabled is returned when rule profiles are requested,
+// but the rule engine was not configured to record them.
+var ErrProfilingDisabled = errors.New("rule profiling is disabled. Enable it with the filters.profiling.enabled option")
+
+// RuleProfile contains evaluation statistics of the rule. Durations
+// are expressed in nanoseconds.
+type RuleProfile struct {
+	// Group is the name of the group the rule belongs to
+	Group string `json:"group"`
+	// Rule is the rule name
+	Rule string `json:"rule"`
+	// Evaluations is the number of times the rule was evaluated
+	Evaluations uint64 `json:"evaluations"`
+	// Matches is the number of times the rule matched
+	Matches uint64 `json:"matches"`
+	// TotalTime is the cumulative evaluation time
+	TotalTime time.Duration `json:"total-time"`
+	// AvgTime is the average evaluation time
+	AvgTime time.Duration `json:"avg-time"`
+	// P99Time is the 99th percentile of the most recent evaluation times
+	P99Time time.Duration `json:"p99-time"`
+	// AccessorTime is the cumulative time spent extracting field values
+	AccessorTime time.Duration `json:"accessor-time"`
+	// FunctionTime is the cumulative time spent in each function call
+	FunctionTime map[string]time.Duration `json:"function-time"`
+}
+
+// ruleProfiler records evaluation statistics of the compiled filter.
+type ruleProfiler struct {
+	mu sync.Mutex
+
+	evaluations uint64
+	matches     uint64
+	total       time.Duration
+	accessors   time.Duration
+	functions   map[string]time.Duration
+
+	// samples is the ring buffer of the most recent evaluation durations
+	samples [profileSamples]time.Duration
+	next    int
+}
+
+func newRuleProfiler() *ruleProfiler {
+	return &ruleProfiler{functions: make(map[string]time.Duration)}
+}
+
+// observe records the rule evaluation that started at the given time.
+func (p *ruleProfiler) observe(start time.Time, match bool) {
+	d := time.Since(start)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.evaluations++
+	if match {
+		p.matches++
+	}
+	p.total += d
+	p.samples[p.next%profileSamples] = d
+	p.next++
+}
+
+// observeAccessors records the field extraction that started at the given time.
+func (p *ruleProfiler) observeAccessors(start time.Time) {
+	d := time.Since(start)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.accessors += d
+}
+
+// observeFunction records the duration of the function call.
+func (p *ruleProfiler) observeFunction(name string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.functions[name] += d
+}
+
+// profile returns the snapshot of the rule evaluation statistics.
+func (p *ruleProfiler) profile(group, rule string) RuleProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	profile := RuleProfile{
+		Group:        group,
+		Rule:         rule,
+		Evaluations:  p.evaluations,
+		Matches:      p.matches,
+		TotalTime:    p.total,
+		AccessorTime: p.accessors,
+		FunctionTime: make(map[string]time.Duration, len(p.functions)),
+	}
+	for name, d := range p.functions {
+		profile.FunctionTime[name] = d
+	}
+	if p.evaluations > 0 {
+		profile.AvgTime = p.total / time.Duration(p.evaluations)
+	}
+	n := p.next
+	if n > profileSamples {
+		n = profileSamples
+	}
+	if n > 0 {
+		samples := make([]time.Duration, n)
+		copy(samples, p.samples[:n])
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		profile.P99Time = samples[(n*99-1)/100]
+	}
+	return profile
+}
+
+// Profiles returns evaluation statistics of all rules ordered by
+// the cumulative evaluation time, so the most expensive rules come
+// first.
+func (r *Rules) Profiles() ([]RuleProfile, error) {
+	if !r.config.Filters.Profiling.Enabled {
+		return nil, ErrProfilingDisabled
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	profiles := make([]RuleProfile, 0)
+	for _, g := range r.groups {
+		for _, f := range g.filters {
+			if f.prof == nil {
+				continue
+			}
+			profiles = append(profiles, f.prof.profile(g.group.Name, f.config.Name))
+		}
+	}
+	sort.SliceStable(profiles, func(i, j int) bool { return profiles[i].TotalTime > profiles[j].TotalTime })
+	return profiles, nil
+}
+
+// profile starts recording statistics of the compiled filter.
+func (f *compiledFilter) profile(p *ruleProfiler) {
+	f.prof = p
+	if filter, ok := f.filter.(*filter); ok {
+		filter.profiler = p
+	}
+}
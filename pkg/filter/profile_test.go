@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const profiledGroup = `
+- group: Temp files
+  enabled: true
+  rules:
+    - name: Dropper created in temp directory
+      condition: kevt.name = 'CreateFile' and lower(file.name) = 'c:\\temp\\dropper.exe'
+    - name: PowerShell created a file
+      condition: kevt.name = 'CreateFile' and ps.name = 'powershell.exe'
+`
+
+func TestRuleProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yml")
+	require.NoError(t, os.WriteFile(path, []byte(profiledGroup), 0600))
+
+	c := newConfig(path)
+	rules := NewRules(c)
+	require.NoError(t, rules.Compile())
+	_, err := rules.Profiles()
+	require.Equal(t, ErrProfilingDisabled, err)
+
+	c.Filters.Profiling.Enabled = true
+	rules = NewRules(c)
+	require.NoError(t, rules.Compile())
+
+	newEvent := func(filename string) *kevent.Kevent {
+		return &kevent.Kevent{
+			Type:      ktypes.CreateFile,
+			Timestamp: time.Now(),
+			Name:      "CreateFile",
+			Tid:       2484,
+			PID:       859,
+			Category:  ktypes.File,
+			PS: &types.PS{
+				Name: "cmd.exe",
+				Exe:  "C:\\Windows\\system32\\cmd.exe",
+			},
+			Kparams: kevent.Kparams{
+				kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: filename},
+			},
+			Metadata: make(map[kevent.MetadataKey]any),
+		}
+	}
+
+	require.False(t, rules.Fire(newEvent("C:\\Temp\\notes.txt")))
+	require.True(t, rules.Fire(newEvent("C:\\Temp\\Dropper.exe")))
+
+	profiles, err := rules.Profiles()
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+
+	var dropper RuleProfile
+	for _, p := range profiles {
+		assert.Equal(t, "Temp files", p.Group)
+		if p.Rule == "Dropper created in temp directory" {
+			dropper = p
+		}
+	}
+	// the first rule of the or group that matches
+	// stops the evaluation of the remaining rules
+	assert.Equal(t, uint64(2), dropper.Evaluations)
+	assert.Equal(t, uint64(1), dropper.Matches)
+	assert.True(t, dropper.TotalTime > 0)
+	assert.True(t, dropper.P99Time > 0)
+	assert.True(t, dropper.P99Time <= dropper.TotalTime)
+	assert.Equal(t, dropper.TotalTime/2, dropper.AvgTime)
+	assert.True(t, dropper.AccessorTime > 0)
+	assert.Contains(t, dropper.FunctionTime, "LOWER")
+	assert.True(t, profiles[0].TotalTime >= profiles[1].TotalTime)
+}
+
+func TestRuleProfilerPercentile(t *testing.T) {
+	p := newRuleProfiler()
+	for i := 1; i <= 100; i++ {
+		p.mu.Lock()
+		p.samples[p.next%profileSamples] = time.Duration(i) * time.Millisecond
+		p.next++
+		p.mu.Unlock()
+	}
+	assert.Equal(t, time.Millisecond*99, p.profile("", "").P99Time)
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WindowType determines how events are assigned to aggregation windows.
+type WindowType uint8
+
+const (
+	// TumblingWindow splits the timeline into consecutive,
+	// non-overlapping windows of the fixed duration
+	TumblingWindow WindowType = iota
+	// SlidingWindow aggregates all events that occurred
+	// within the window duration before the current event
+	SlidingWindow
+)
+
+// String returns the window type name.
+func (w WindowType) String() string {
+	switch w {
+	case TumblingWindow:
+		return "tumbling"
+	case SlidingWindow:
+		return "sliding"
+	default:
+		return ""
+	}
+}
+
+// AggregateFn identifies the aggregate function.
+type AggregateFn string
+
+const (
+	// CountFn counts events or distinct field values in the window
+	CountFn AggregateFn = "count"
+	// SumFn sums numeric field values in the window
+	SumFn AggregateFn = "sum"
+	// MinFn yields the minimum numeric field value in the window
+	MinFn AggregateFn = "min"
+	// MaxFn yields the maximum numeric field value in the window
+	MaxFn AggregateFn = "max"
+)
+
+// Aggregate represents the aggregate function call, e.g. count(distinct file.name).
+type Aggregate struct {
+	Fn       AggregateFn
+	Distinct bool
+	Field    fields.Field
+}
+
+// String returns the string representation of the aggregate
+// function call. It is used as the key of the aggregate value.
+func (a Aggregate) String() string {
+	switch {
+	case a.Distinct:
+		return fmt.Sprintf("%s(distinct %s)", a.Fn, a.Field)
+	case a.Field.IsEmpty():
+		return fmt.Sprintf("%s()", a.Fn)
+	default:
+		return fmt.Sprintf("%s(%s)", a.Fn, a.Field)
+	}
+}
+
+// Threshold compares the aggregate value with the constant.
+type Threshold struct {
+	Aggregate Aggregate
+	Op        token
+	Value     float64
+}
+
+// String returns the string representation of the threshold.
+func (t Threshold) String() string {
+	return fmt.Sprintf("%s %s %s", t.Aggregate, t.Op, strconv.FormatFloat(t.Value, 'f', -1, 64))
+}
+
+// IsCrossed determines if the aggregate value crosses the threshold.
+func (t Threshold) IsCrossed(v float64) bool {
+	switch t.Op {
+	case Gt:
+		return v > t.Value
+	case Gte:
+		return v >= t.Value
+	case Lt:
+		return v < t.Value
+	case Lte:
+		return v <= t.Value
+	case Eq:
+		return v == t.Value
+	case Neq:
+		return v != t.Value
+	}
+	return false
+}
+
+// Aggregation groups events matching the expression by the specified
+// fields and computes aggregate values over the time window. The
+// aggregation matches when all thresholds are crossed.
+type Aggregation struct {
+	Window     time.Duration
+	WindowType WindowType
+	By         []fields.Field
+	Expr       Expr
+	Thresholds []Threshold
+}
+
+// Aggregates returns all distinct aggregates referenced in thresholds.
+func (a Aggregation) Aggregates() []Aggregate {
+	aggs := make([]Aggregate, 0, len(a.Thresholds))
+	seen := make(map[Aggregate]bool)
+	for _, t := range a.Thresholds {
+		if seen[t.Aggregate] {
+			continue
+		}
+		seen[t.Aggregate] = true
+		aggs = append(aggs, t.Aggregate)
+	}
+	return aggs
+}
+
+// String returns the string representation of the aggregation.
+func (a Aggregation) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("aggregate window %s %s", a.Window, a.WindowType))
+	if len(a.By) > 0 {
+		by := make([]string, len(a.By))
+		for i, f := range a.By {
+			by[i] = f.String()
+		}
+		sb.WriteString(" by " + strings.Join(by, ", "))
+	}
+	sb.WriteString(" |" + a.Expr.String() + "| having ")
+	for i, t := range a.Thresholds {
+		if i > 0 {
+			sb.WriteString(" and ")
+		}
+		sb.WriteString(t.String())
+	}
+	return sb.String()
+}
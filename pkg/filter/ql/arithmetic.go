@@ -0,0 +1,213 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"math"
+	"time"
+)
+
+// numberKind designates the class of the numeric operand.
+type numberKind uint8
+
+const (
+	notNumber numberKind = iota
+	signedNumber
+	unsignedNumber
+	floatNumber
+)
+
+// number is the numeric operand promoted to the widest type of its class.
+type number struct {
+	kind numberKind
+	i    int64
+	u    uint64
+	f    float64
+}
+
+func toNumber(v interface{}) number {
+	switch n := v.(type) {
+	case int:
+		return number{kind: signedNumber, i: int64(n)}
+	case int8:
+		return number{kind: signedNumber, i: int64(n)}
+	case int16:
+		return number{kind: signedNumber, i: int64(n)}
+	case int32:
+		return number{kind: signedNumber, i: int64(n)}
+	case int64:
+		return number{kind: signedNumber, i: n}
+	case time.Duration:
+		return number{kind: signedNumber, i: int64(n)}
+	case uint:
+		return number{kind: unsignedNumber, u: uint64(n)}
+	case uint8:
+		return number{kind: unsignedNumber, u: uint64(n)}
+	case uint16:
+		return number{kind: unsignedNumber, u: uint64(n)}
+	case uint32:
+		return number{kind: unsignedNumber, u: uint64(n)}
+	case uint64:
+		return number{kind: unsignedNumber, u: n}
+	case float32:
+		return number{kind: floatNumber, f: float64(n)}
+	case float64:
+		return number{kind: floatNumber, f: n}
+	}
+	return number{}
+}
+
+func (n number) float() float64 {
+	switch n.kind {
+	case signedNumber:
+		return float64(n.i)
+	case unsignedNumber:
+		return float64(n.u)
+	}
+	return n.f
+}
+
+// evalArithmetic applies the arithmetic or bitwise operator to numeric operands.
+// Operands are promoted as follows:
+//
+//   - if any of the operands is a floating point number, the result is float64
+//   - if both operands are unsigned integers, the result is uint64. Subtracting
+//     the larger number yields the negative int64 instead of wrapping around
+//   - otherwise, the result is int64. If the unsigned operand overflows int64,
+//     the unsigned arithmetic is used for the non-negative signed operand, and
+//     the floating point arithmetic for the negative one
+//
+// Nil is returned if any of the operands is not a number, when dividing by zero,
+// or when applying bitwise operators to floating point numbers.
+func evalArithmetic(op token, lhs, rhs interface{}) interface{} {
+	l, r := toNumber(lhs), toNumber(rhs)
+	if l.kind == notNumber || r.kind == notNumber {
+		return nil
+	}
+	switch {
+	case l.kind == floatNumber || r.kind == floatNumber:
+		return evalFloat(op, l.float(), r.float())
+	case l.kind == unsignedNumber && r.kind == unsignedNumber:
+		return evalUnsigned(op, l.u, r.u)
+	case l.kind == signedNumber && r.kind == signedNumber:
+		return evalSigned(op, l.i, r.i)
+	}
+	// mixed signed and unsigned operands
+	s, u := l, r
+	if l.kind == unsignedNumber {
+		s, u = r, l
+	}
+	switch {
+	case u.u <= math.MaxInt64:
+		u.i = int64(u.u)
+	case s.i >= 0:
+		s.u = uint64(s.i)
+		if l.kind == signedNumber {
+			return evalUnsigned(op, s.u, u.u)
+		}
+		return evalUnsigned(op, u.u, s.u)
+	default:
+		return evalFloat(op, l.float(), r.float())
+	}
+	if l.kind == signedNumber {
+		return evalSigned(op, s.i, u.i)
+	}
+	return evalSigned(op, u.i, s.i)
+}
+
+func evalSigned(op token, lhs, rhs int64) interface{} {
+	switch op {
+	case Add:
+		return lhs + rhs
+	case Sub:
+		return lhs - rhs
+	case Mul:
+		return lhs * rhs
+	case Div:
+		if rhs == 0 {
+			return nil
+		}
+		return lhs / rhs
+	case Mod:
+		if rhs == 0 {
+			return nil
+		}
+		return lhs % rhs
+	case BitAnd:
+		return lhs & rhs
+	case BitOr:
+		return lhs | rhs
+	}
+	return nil
+}
+
+func evalUnsigned(op token, lhs, rhs uint64) interface{} {
+	switch op {
+	case Add:
+		return lhs + rhs
+	case Sub:
+		if lhs >= rhs {
+			return lhs - rhs
+		}
+		d := rhs - lhs
+		if d <= math.MaxInt64 {
+			return -int64(d)
+		}
+		return -float64(d)
+	case Mul:
+		return lhs * rhs
+	case Div:
+		if rhs == 0 {
+			return nil
+		}
+		return lhs / rhs
+	case Mod:
+		if rhs == 0 {
+			return nil
+		}
+		return lhs % rhs
+	case BitAnd:
+		return lhs & rhs
+	case BitOr:
+		return lhs | rhs
+	}
+	return nil
+}
+
+func evalFloat(op token, lhs, rhs float64) interface{} {
+	switch op {
+	case Add:
+		return lhs + rhs
+	case Sub:
+		return lhs - rhs
+	case Mul:
+		return lhs * rhs
+	case Div:
+		if rhs == 0 {
+			return nil
+		}
+		return lhs / rhs
+	case Mod:
+		if rhs == 0 {
+			return nil
+		}
+		return math.Mod(lhs, rhs)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArithmeticExpressions(t *testing.T) {
+	m := map[string]interface{}{
+		"ps.name":        "cmd.exe",
+		"kevt.pid":       uint32(1234),
+		"file.io.size":   uint64(614400),
+		"kevt.time.s":    uint8(120),
+		"file.offset":    uint64(8192),
+		"image.size":     uint32(20480),
+		"image.checksum": uint32(0x22),
+		"pe.nsections":   uint16(3),
+	}
+
+	var tests = []struct {
+		expr    string
+		matches bool
+	}{
+		{`file.io.size / 1024 > 500`, true},
+		{`file.io.size / 1024 = 600`, true},
+		{`kevt.time.s % 60 = 0`, true},
+		{`image.size - file.offset > 4096`, true},
+		{`file.offset - image.size < 0`, true},
+		{`file.offset - image.size = -12288`, true},
+		{`kevt.pid + 1 = 1235`, true},
+		{`kevt.pid * 2 - 1 = 2467`, true},
+		{`kevt.pid / 0 = 0`, false},
+		{`kevt.pid % 0 != 1`, false},
+		{`kevt.pid / 4.0 = 308.5`, true},
+		{`image.checksum & 2 = 2`, true},
+		{`image.checksum & 4 = 4`, false},
+		{`image.checksum | 4 = 38`, true},
+		{`length(ps.name) + 1 = 8`, true},
+		{`pe.nsections * -2 = -6`, true},
+		{`1 + 2 * 3 = 7`, true},
+		{`(1 + 2) * 3 = 9`, true},
+		{`10 - 4 - 3 = 3`, true},
+		{`2 | 4 & 6 = 6`, true},
+		{`7 & 3 + 1 = 4`, true},
+		{`ps.name + 1 = 1`, false},
+		{`kevt.pid = 1234 and not (kevt.pid + 1)`, false},
+		{`kevt.pid + 1 = 1235 and ps.name = 'cmd.exe'`, true},
+	}
+
+	for i, tt := range tests {
+		p := NewParser(tt.expr)
+		expr, err := p.ParseExpr()
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.matches, Eval(expr, m, true), "%d. %q interpreter match mismatch", i, tt.expr)
+		assert.Equal(t, tt.matches, Compile(expr, true).EvalMap(m, nil), "%d. %q compiled match mismatch", i, tt.expr)
+	}
+}
+
+func TestEvalArithmetic(t *testing.T) {
+	var tests = []struct {
+		op       token
+		lhs, rhs interface{}
+		v        interface{}
+	}{
+		{Add, int64(2), int64(3), int64(5)},
+		{Add, uint32(2), uint16(3), uint64(5)},
+		{Add, uint32(2), int64(-3), int64(-1)},
+		{Add, 2, uint8(3), int64(5)},
+		{Add, uint64(2), 1.5, 3.5},
+		{Sub, uint64(2), uint64(5), int64(-3)},
+		{Sub, uint64(math.MaxUint64), int64(1), uint64(math.MaxUint64 - 1)},
+		{Sub, int64(-1), uint64(math.MaxUint64), -float64(math.MaxUint64)},
+		{Mul, int64(-4), uint32(3), int64(-12)},
+		{Div, uint64(7), uint64(2), uint64(3)},
+		{Div, int64(7), 2.0, 3.5},
+		{Div, int64(7), int64(0), nil},
+		{Div, 7.0, 0.0, nil},
+		{Mod, int64(7), int64(3), int64(1)},
+		{Mod, 7.5, int64(2), 1.5},
+		{Mod, uint64(7), uint64(0), nil},
+		{BitAnd, uint32(0x22), uint32(0x2), uint64(0x2)},
+		{BitOr, uint32(0x20), int64(0x2), int64(0x22)},
+		{BitAnd, 1.0, int64(1), nil},
+		{Add, "1", int64(1), nil},
+		{Add, nil, int64(1), nil},
+		{Add, true, int64(1), nil},
+		{Sub, 90 * time.Second, 30 * time.Second, int64(60 * time.Second)},
+	}
+
+	for i, tt := range tests {
+		assert.Equal(t, tt.v, evalArithmetic(tt.op, tt.lhs, tt.rhs), "%d. %v %s %v", i, tt.lhs, tt.op, tt.rhs)
+	}
+}
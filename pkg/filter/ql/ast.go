@@ -22,20 +22,51 @@ package ql
 
 import (
 	fuzzysearch "github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/rabbitstack/fibratus/pkg/config"
 	"github.com/rabbitstack/fibratus/pkg/util/wildcard"
 	"net"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Eval evaluates expr against a map that contains the field values.
 func Eval(expr Expr, m map[string]interface{}, useFuncValuer bool) bool {
+	return EvalWithObserver(expr, m, useFuncValuer, nil)
+}
+
+// CallObserver is notified with the duration of each function call.
+type CallObserver func(name string, d time.Duration)
+
+// EvalWithObserver evaluates expr against a map that contains the field
+// values and reports the duration of function calls to the observer.
+func EvalWithObserver(expr Expr, m map[string]interface{}, useFuncValuer bool, observer CallObserver) bool {
+	return EvalWithOptions(expr, m, Options{UseFuncValuer: useFuncValuer}, observer)
+}
+
+// Options control the evaluation of expressions.
+type Options struct {
+	// UseFuncValuer determines if function calls are evaluated.
+	UseFuncValuer bool
+	// Strict enables the three-valued logic. Comparisons with
+	// missing operands evaluate to unknown instead of false. The
+	// unknown value propagates through negations and logical
+	// operators, so the expression doesn't match unless the
+	// outcome is decided by other operands.
+	Strict bool
+}
+
+// EvalWithOptions evaluates expr against a map that contains the field values
+// with the given options and reports the duration of function calls to the
+// observer if it is not nil.
+func EvalWithOptions(expr Expr, m map[string]interface{}, opts Options, observer CallObserver) bool {
 	var eval ValuerEval
-	if useFuncValuer {
-		eval = ValuerEval{Valuer: MultiValuer(MapValuer(m), FunctionValuer{m})}
+	if opts.UseFuncValuer {
+		eval = ValuerEval{Valuer: MultiValuer(MapValuer(m), FunctionValuer{m: m, observer: observer})}
 	} else {
 		eval = ValuerEval{Valuer: MapValuer(m)}
 	}
+	eval.Strict = opts.Strict
 	v, ok := eval.Eval(expr).(bool)
 	if !ok {
 		return false
@@ -101,6 +132,9 @@ type ValuerEval struct {
 	// IntegerFloatDivision will set the eval system to treat
 	// a division between two integers as a floating point division.
 	IntegerFloatDivision bool
+
+	// Strict enables the three-valued logic for comparisons with missing operands.
+	Strict bool
 }
 
 // Eval evaluates an expression and returns a value.
@@ -139,8 +173,8 @@ func (v *ValuerEval) Eval(expr Expr) interface{} {
 				return nil
 			}
 			return nil
-		case *ParenExpr:
-			v := v.Eval(expr1.Expr)
+		case *ParenExpr, *ExistsExpr, *QuantifierExpr:
+			v := v.Eval(expr1)
 			if v == nil {
 				return nil
 			}
@@ -151,12 +185,34 @@ func (v *ValuerEval) Eval(expr Expr) interface{} {
 		default:
 			return nil
 		}
+	case *ExistsExpr:
+		return (v.Eval(expr.Expr) != nil) != expr.Null
+	case *IndexExpr:
+		return index(v.Eval(expr.Expr), expr.Index)
+	case *QuantifierExpr:
+		var pred func(interface{}) interface{}
+		if expr.Pred != nil {
+			pred = func(elem interface{}) interface{} {
+				eval := *v
+				eval.Valuer = MultiValuer(MapValuer{expr.Var: elem}, v.Valuer)
+				return eval.Eval(expr.Pred)
+			}
+		}
+		return quantify(expr.Fn, v.Eval(expr.Expr), v.Strict, pred)
+	case *VariableLiteral:
+		val, ok := v.Valuer.Value(expr.Value)
+		if !ok {
+			return nil
+		}
+		return val
 	case *IntegerLiteral:
 		return expr.Value
 	case *UnsignedLiteral:
 		return expr.Value
 	case *DecimalLiteral:
 		return expr.Value
+	case *DurationLiteral:
+		return expr.Value
 	case *ParenExpr:
 		return v.Eval(expr.Expr)
 	case *StringLiteral:
@@ -179,6 +235,8 @@ func (v *ValuerEval) Eval(expr Expr) interface{} {
 		return val
 	case *IPLiteral:
 		return expr.Value
+	case *LookupLiteral:
+		return expr.Table
 	case *Function:
 		if valuer, ok := v.Valuer.(CallValuer); ok {
 			var args []interface{}
@@ -211,6 +269,83 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		}
 	}
 	rhs := v.Eval(expr.RHS)
+	if v.Strict {
+		return evalStrict(expr.Op, lhs, rhs)
+	}
+	return evalBinary(expr.Op, lhs, rhs)
+}
+
+// evalStrict applies the binary operator with the three-valued logic,
+// where nil stands for the unknown value. Comparing the unknown value
+// yields unknown. The conjunction is false if any operand is false, and
+// the disjunction is true if any operand is true. Otherwise, logical
+// operators yield unknown if any operand is unknown.
+func evalStrict(op token, lhs, rhs interface{}) interface{} {
+	switch op {
+	case And:
+		return strictTri(lhs).and(strictTri(rhs)).value()
+	case Or:
+		return strictTri(lhs).or(strictTri(rhs)).value()
+	}
+	if lhs == nil || rhs == nil {
+		return nil
+	}
+	return evalBinary(op, lhs, rhs)
+}
+
+// fuzzyMatch runs the fuzzy matcher and reports no match if the matcher
+// panics, which happens when case folding some non-ASCII strings.
+func fuzzyMatch(match func(source, target string) bool, source, target string) (matched bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			matched = false
+		}
+	}()
+	return match(source, target)
+}
+
+// evalLookup determines if the operand is contained in the lookup table.
+// IP addresses are also matched against CIDR ranges of the table.
+func evalLookup(op token, lhs interface{}, table *config.LookupTable) interface{} {
+	contains := table.Contains
+	switch op {
+	case In:
+	case IIn:
+		contains = table.ContainsFold
+	default:
+		return nil
+	}
+	switch v := lhs.(type) {
+	case string:
+		return contains(v)
+	case []string:
+		for _, s := range v {
+			if contains(s) {
+				return true
+			}
+		}
+		return false
+	case net.IP:
+		return table.ContainsIP(v)
+	}
+	return nil
+}
+
+// evalBinary applies the binary operator to the evaluated operands.
+func evalBinary(op token, lhs, rhs interface{}) interface{} {
+	// durations are compared and computed as the number of nanoseconds
+	if d, ok := lhs.(time.Duration); ok {
+		lhs = int64(d)
+	}
+	if d, ok := rhs.(time.Duration); ok {
+		rhs = int64(d)
+	}
+	if op.isArithmetic() {
+		return evalArithmetic(op, lhs, rhs)
+	}
+	if table, ok := rhs.(*config.LookupTable); ok {
+		return evalLookup(op, lhs, table)
+	}
 	if lhs == nil && rhs != nil {
 		// when the LHS is nil and the RHS is a boolean, implicitly cast the
 		// nil to false.
@@ -227,7 +362,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 	switch lhs := lhs.(type) {
 	case bool:
 		rhs, ok := rhs.(bool)
-		switch expr.Op {
+		switch op {
 		case And:
 			return ok && (lhs && rhs)
 		case Or:
@@ -239,9 +374,24 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		}
 	case int:
 		switch rhs := rhs.(type) {
+		case int:
+			switch op {
+			case Eq:
+				return lhs == rhs
+			case Neq:
+				return lhs != rhs
+			case Lt:
+				return lhs < rhs
+			case Lte:
+				return lhs <= rhs
+			case Gt:
+				return lhs > rhs
+			case Gte:
+				return lhs >= rhs
+			}
 		case float64:
 			lhs := float64(lhs)
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == rhs
 			case Neq:
@@ -256,7 +406,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case int64:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return int64(lhs) == rhs
 			case Neq:
@@ -271,7 +421,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return int64(lhs) >= rhs
 			}
 		case uint64:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return uint64(lhs) == rhs
 			case Neq:
@@ -298,7 +448,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return uint64(lhs) >= rhs
 			}
 		case []uint16:
-			switch expr.Op {
+			switch op {
 			case In:
 				for _, i := range rhs {
 					if int(i) == lhs {
@@ -312,7 +462,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		switch rhs := rhs.(type) {
 		case float64:
 			lhs := float64(lhs)
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == rhs
 			case Neq:
@@ -327,7 +477,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case int64:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return int64(lhs) == rhs
 			case Neq:
@@ -342,7 +492,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return int64(lhs) >= rhs
 			}
 		case uint64:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return uint64(lhs) == rhs
 			case Neq:
@@ -370,7 +520,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		}
 
 		rhs := rhsf
-		switch expr.Op {
+		switch op {
 		case Eq:
 			return ok && (lhs == rhs)
 		case Neq:
@@ -389,7 +539,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		switch rhs := rhs.(type) {
 		case float64:
 			lhs := float64(lhs)
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == rhs
 			case Neq:
@@ -404,7 +554,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case int64:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == rhs
 			case Neq:
@@ -419,7 +569,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case uint64:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return uint64(lhs) == rhs
 			case Neq:
@@ -451,7 +601,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		switch rhs := rhs.(type) {
 		case float64:
 			lhs := float64(lhs)
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == rhs
 			case Neq:
@@ -466,7 +616,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case int64:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == uint64(rhs)
 			case Neq:
@@ -493,7 +643,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= uint64(rhs)
 			}
 		case uint64:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == rhs
 			case Neq:
@@ -512,7 +662,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		switch rhs := rhs.(type) {
 		case float64:
 			lhs := float64(lhs)
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == rhs
 			case Neq:
@@ -527,7 +677,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case int32:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == uint32(rhs)
 			case Neq:
@@ -554,7 +704,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= uint32(rhs)
 			}
 		case int64:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == uint32(rhs)
 			case Neq:
@@ -581,7 +731,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= uint32(rhs)
 			}
 		case uint32:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == rhs
 			case Neq:
@@ -600,7 +750,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		switch rhs := rhs.(type) {
 		case float64:
 			lhs := float64(lhs)
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == rhs
 			case Neq:
@@ -615,7 +765,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case int32:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == uint16(rhs)
 			case Neq:
@@ -642,7 +792,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= uint16(rhs)
 			}
 		case int64:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == uint16(rhs)
 			case Neq:
@@ -669,7 +819,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= uint16(rhs)
 			}
 		case uint16:
-			switch expr.Op {
+			switch op {
 			case Eq:
 				return lhs == rhs
 			case Neq:
@@ -684,7 +834,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case []string:
-			switch expr.Op {
+			switch op {
 			case In:
 				for _, s := range rhs {
 					n, err := strconv.Atoi(s)
@@ -699,7 +849,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 			}
 		}
 	case string:
-		switch expr.Op {
+		switch op {
 		case Eq:
 			rhs, ok := rhs.(string)
 			if !ok {
@@ -855,10 +1005,10 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		case Fuzzy:
 			switch rhs := rhs.(type) {
 			case string:
-				return fuzzysearch.Match(rhs, lhs)
+				return fuzzyMatch(fuzzysearch.Match, rhs, lhs)
 			case []string:
 				for _, s := range rhs {
-					if fuzzysearch.Match(s, lhs) {
+					if fuzzyMatch(fuzzysearch.Match, s, lhs) {
 						return true
 					}
 				}
@@ -869,10 +1019,10 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		case IFuzzy:
 			switch rhs := rhs.(type) {
 			case string:
-				return fuzzysearch.MatchFold(rhs, lhs)
+				return fuzzyMatch(fuzzysearch.MatchFold, rhs, lhs)
 			case []string:
 				for _, s := range rhs {
-					if fuzzysearch.MatchFold(s, lhs) {
+					if fuzzyMatch(fuzzysearch.MatchFold, s, lhs) {
 						return true
 					}
 				}
@@ -883,10 +1033,10 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		case Fuzzynorm:
 			switch rhs := rhs.(type) {
 			case string:
-				return fuzzysearch.MatchNormalized(rhs, lhs)
+				return fuzzyMatch(fuzzysearch.MatchNormalized, rhs, lhs)
 			case []string:
 				for _, s := range rhs {
-					if fuzzysearch.MatchNormalized(s, lhs) {
+					if fuzzyMatch(fuzzysearch.MatchNormalized, s, lhs) {
 						return true
 					}
 				}
@@ -897,10 +1047,10 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		case IFuzzynorm:
 			switch rhs := rhs.(type) {
 			case string:
-				return fuzzysearch.MatchNormalizedFold(rhs, lhs)
+				return fuzzyMatch(fuzzysearch.MatchNormalizedFold, rhs, lhs)
 			case []string:
 				for _, s := range rhs {
-					if fuzzysearch.MatchNormalizedFold(s, lhs) {
+					if fuzzyMatch(fuzzysearch.MatchNormalizedFold, s, lhs) {
 						return true
 					}
 				}
@@ -910,7 +1060,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 			}
 		}
 	case net.IP:
-		switch expr.Op {
+		switch op {
 		case Eq:
 			rhs, ok := rhs.(net.IP)
 			if !ok {
@@ -958,7 +1108,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 			return strings.HasSuffix(lhs.String(), rhs)
 		}
 	case []string:
-		switch expr.Op {
+		switch op {
 		case Contains:
 			s, ok := rhs.(string)
 			if !ok {
@@ -1103,7 +1253,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 
 	// the types were not comparable. If our operation was an equality operation,
 	// return false instead of true.
-	switch expr.Op {
+	switch op {
 	case Eq, IEq, Neq, Lt, Lte, Gt, Gte:
 		return false
 	}
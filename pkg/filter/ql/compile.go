@@ -0,0 +1,977 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	fuzzysearch "github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql/functions"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/util/wildcard"
+	"net"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Env provides field values to the compiled program.
+type Env interface {
+	// Value returns the value of the field bound to the
+	// given slot or nil if the field has no value.
+	Value(slot int) interface{}
+}
+
+// Program is the expression lowered into the tree of evaluation closures.
+// Field operands are bound to slots that are resolved through the environment
+// supplied at evaluation time. Evaluating the program yields exactly the same
+// outcome as interpreting the expression with the valuer.
+type Program struct {
+	root   *node
+	fields []string
+	// nvars is the number of variables bound by quantifiers
+	nvars int
+}
+
+// Compile lowers the expression into the program. Constant subexpressions
+// are folded, string operators with constant operands are specialized, and
+// operands of conjunctions and disjunctions are reordered so the cheapest
+// operands are evaluated first. Functions are only called if useFuncValuer
+// is true, mirroring the behaviour of the interpreter.
+func Compile(expr Expr, useFuncValuer bool) *Program {
+	return CompileWithOptions(expr, Options{UseFuncValuer: useFuncValuer})
+}
+
+// CompileWithOptions lowers the expression into the program that
+// evaluates the expression with the given options.
+func CompileWithOptions(expr Expr, opts Options) *Program {
+	c := &compiler{slots: make(map[string]int), vars: make(map[string]int), useFuncValuer: opts.UseFuncValuer, strict: opts.Strict, eval: evalBinary}
+	if opts.Strict {
+		c.eval = evalStrict
+	}
+	root := c.compile(expr)
+	return &Program{root: root, fields: c.fields, nvars: c.nvars}
+}
+
+// Fields returns field names indexed by their slots.
+func (p *Program) Fields() []string { return p.fields }
+
+// Eval evaluates the program against field values provided by the environment
+// and reports the duration of function calls to the observer if it is not nil.
+func (p *Program) Eval(env Env, observer CallObserver) bool {
+	ctx := &evalContext{env: env, observer: observer}
+	if p.nvars > 0 {
+		ctx.vars = make([]interface{}, p.nvars)
+	}
+	if p.root.pred != nil {
+		return p.root.pred(ctx) == triTrue
+	}
+	v, ok := p.root.value(ctx).(bool)
+	return ok && v
+}
+
+// EvalMap evaluates the program against a map that contains the field values.
+func (p *Program) EvalMap(m map[string]interface{}, observer CallObserver) bool {
+	return p.Eval(mapEnv{fields: p.fields, m: m}, observer)
+}
+
+type mapEnv struct {
+	fields []string
+	m      map[string]interface{}
+}
+
+func (e mapEnv) Value(slot int) interface{} { return e.m[e.fields[slot]] }
+
+// tri is the outcome of the predicate evaluation. Binary expressions
+// evaluate to true, false, or nil when operands are not comparable.
+// Keeping nil apart from false matters, because negating nil yields nil.
+type tri uint8
+
+const (
+	triNil tri = iota
+	triFalse
+	triTrue
+)
+
+func toTri(v interface{}) tri {
+	if b, ok := v.(bool); ok {
+		return boolTri(b)
+	}
+	return triNil
+}
+
+// strictTri converts the operand of the logical operator in
+// the strict mode. Only nil values are unknown, while values
+// other than booleans are false.
+func strictTri(v interface{}) tri {
+	if v == nil {
+		return triNil
+	}
+	if b, ok := v.(bool); ok {
+		return boolTri(b)
+	}
+	return triFalse
+}
+
+func boolTri(b bool) tri {
+	if b {
+		return triTrue
+	}
+	return triFalse
+}
+
+func (t tri) not() tri {
+	switch t {
+	case triTrue:
+		return triFalse
+	case triFalse:
+		return triTrue
+	}
+	return triNil
+}
+
+func (t tri) and(o tri) tri {
+	switch {
+	case t == triFalse || o == triFalse:
+		return triFalse
+	case t == triNil || o == triNil:
+		return triNil
+	}
+	return triTrue
+}
+
+func (t tri) or(o tri) tri {
+	switch {
+	case t == triTrue || o == triTrue:
+		return triTrue
+	case t == triNil || o == triNil:
+		return triNil
+	}
+	return triFalse
+}
+
+func (t tri) value() interface{} {
+	switch t {
+	case triTrue:
+		return true
+	case triFalse:
+		return false
+	}
+	return nil
+}
+
+type evalContext struct {
+	env      Env
+	observer CallObserver
+	// vars holds collection elements bound by quantifiers
+	vars []interface{}
+}
+
+type (
+	valueFn func(*evalContext) interface{}
+	predFn  func(*evalContext) tri
+)
+
+// node is the compiled expression node.
+type node struct {
+	value valueFn
+	// pred is only set for nodes that always evaluate to a bool or nil
+	pred predFn
+	// cost is the estimated evaluation cost of the node
+	cost int
+	// isConst is set if the node always evaluates to the constant c
+	isConst bool
+	c       interface{}
+	// field is the name of the field if the node is the field operand
+	field string
+	// op and operands are kept for flattening nested conjunctions and disjunctions
+	op       token
+	operands []*node
+}
+
+func constNode(v interface{}) *node {
+	n := &node{isConst: true, c: v, value: func(*evalContext) interface{} { return v }}
+	switch v.(type) {
+	case nil, bool:
+		t := toTri(v)
+		n.pred = func(*evalContext) tri { return t }
+	}
+	return n
+}
+
+func predNode(pred predFn, cost int) *node {
+	return &node{
+		pred:  pred,
+		value: func(ctx *evalContext) interface{} { return pred(ctx).value() },
+		cost:  cost,
+	}
+}
+
+type compiler struct {
+	slots  map[string]int
+	fields []string
+	// vars maps variables in scope to their slots in the evaluation context
+	vars          map[string]int
+	nvars         int
+	useFuncValuer bool
+	strict        bool
+	// eval applies binary operators to the evaluated operands
+	eval func(op token, lhs, rhs interface{}) interface{}
+}
+
+func (c *compiler) compile(expr Expr) *node {
+	if expr == nil {
+		return constNode(nil)
+	}
+	switch expr := expr.(type) {
+	case *ParenExpr:
+		return c.compile(expr.Expr)
+	case *BinaryExpr:
+		return c.compileBinary(expr)
+	case *NotExpr:
+		return c.compileNot(expr)
+	case *ExistsExpr:
+		return c.compileExists(expr)
+	case *IndexExpr:
+		return c.compileIndex(expr)
+	case *QuantifierExpr:
+		return c.compileQuantifier(expr)
+	case *VariableLiteral:
+		return c.compileVar(expr.Value)
+	case *IntegerLiteral:
+		return constNode(expr.Value)
+	case *UnsignedLiteral:
+		return constNode(expr.Value)
+	case *DecimalLiteral:
+		return constNode(expr.Value)
+	case *DurationLiteral:
+		return constNode(expr.Value)
+	case *StringLiteral:
+		return constNode(expr.Value)
+	case *ListLiteral:
+		return constNode(expr.Values)
+	case *BoolLiteral:
+		return constNode(expr.Value)
+	case *IPLiteral:
+		return constNode(expr.Value)
+	case *LookupLiteral:
+		return constNode(expr.Table)
+	case *FieldLiteral:
+		return c.compileField(expr.Value)
+	case *BoundFieldLiteral:
+		return c.compileField(expr.Value)
+	case *Function:
+		return c.compileFunction(expr)
+	}
+	return constNode(nil)
+}
+
+func (c *compiler) compileField(name string) *node {
+	slot, ok := c.slots[name]
+	if !ok {
+		slot = len(c.fields)
+		c.slots[name] = slot
+		c.fields = append(c.fields, name)
+	}
+	return &node{
+		value: func(ctx *evalContext) interface{} { return ctx.env.Value(slot) },
+		cost:  fieldCost(name),
+		field: name,
+	}
+}
+
+func (c *compiler) compileFunction(expr *Function) *node {
+	if !c.useFuncValuer {
+		return constNode(nil)
+	}
+	fn, ok := funcs[strings.ToUpper(expr.Name)]
+	if !ok {
+		return constNode(nil)
+	}
+	name := fn.Name().String()
+	cost := functionCost(fn.Name())
+	args := make([]*node, len(expr.Args))
+	for i, arg := range expr.Args {
+		args[i] = c.compile(arg)
+		cost += args[i].cost
+	}
+	return &node{
+		value: func(ctx *evalContext) interface{} {
+			var vals []interface{}
+			if len(args) > 0 {
+				vals = make([]interface{}, len(args))
+				for i, arg := range args {
+					vals[i] = arg.value(ctx)
+				}
+			}
+			var start time.Time
+			if ctx.observer != nil {
+				start = time.Now()
+			}
+			v, ok := fn.Call(vals)
+			if ctx.observer != nil {
+				ctx.observer(name, time.Since(start))
+			}
+			if !ok {
+				return nil
+			}
+			return v
+		},
+		cost: cost,
+	}
+}
+
+func (c *compiler) compileExists(expr *ExistsExpr) *node {
+	n := c.compile(expr.Expr)
+	null := expr.Null
+	if n.isConst {
+		return constNode((n.c != nil) != null)
+	}
+	value := n.value
+	return predNode(func(ctx *evalContext) tri { return boolTri((value(ctx) != nil) != null) }, n.cost)
+}
+
+func (c *compiler) compileIndex(expr *IndexExpr) *node {
+	n := c.compile(expr.Expr)
+	i := expr.Index
+	if n.isConst {
+		return constNode(index(n.c, i))
+	}
+	value := n.value
+	return &node{
+		value: func(ctx *evalContext) interface{} { return index(value(ctx), i) },
+		cost:  n.cost,
+	}
+}
+
+func (c *compiler) compileVar(name string) *node {
+	slot, ok := c.vars[name]
+	if !ok {
+		return constNode(nil)
+	}
+	return &node{value: func(ctx *evalContext) interface{} { return ctx.vars[slot] }}
+}
+
+// compileQuantifier binds the variable to the new slot while compiling
+// the predicate. The previous binding is restored afterwards, so the
+// inner quantifier may shadow the variable of the enclosing quantifier.
+func (c *compiler) compileQuantifier(expr *QuantifierExpr) *node {
+	fn, strict := expr.Fn, c.strict
+	n := c.compile(expr.Expr)
+	coll := n.value
+	if expr.Pred == nil {
+		return &node{
+			value: func(ctx *evalContext) interface{} { return quantify(fn, coll(ctx), strict, nil) },
+			cost:  n.cost + 1,
+		}
+	}
+
+	slot := c.nvars
+	c.nvars++
+	prev, shadowed := c.vars[expr.Var]
+	c.vars[expr.Var] = slot
+	pred := c.compile(expr.Pred)
+	if shadowed {
+		c.vars[expr.Var] = prev
+	} else {
+		delete(c.vars, expr.Var)
+	}
+
+	value := pred.value
+	eval := func(ctx *evalContext) interface{} {
+		return quantify(fn, coll(ctx), strict, func(elem interface{}) interface{} {
+			ctx.vars[slot] = elem
+			return value(ctx)
+		})
+	}
+	cost := n.cost + quantifierCost*(pred.cost+1)
+	if fn == CountQuantifier {
+		return &node{value: eval, cost: cost}
+	}
+	return predNode(func(ctx *evalContext) tri { return toTri(eval(ctx)) }, cost)
+}
+
+func (c *compiler) compileNot(expr *NotExpr) *node {
+	switch expr.Expr.(type) {
+	case *BinaryExpr, *ParenExpr, *Function, *ExistsExpr, *QuantifierExpr:
+	default:
+		return constNode(nil)
+	}
+	n := c.compile(expr.Expr)
+	if n.isConst {
+		return constNode(toTri(n.c).not().value())
+	}
+	if n.pred != nil {
+		pred := n.pred
+		return predNode(func(ctx *evalContext) tri { return pred(ctx).not() }, n.cost)
+	}
+	value := n.value
+	return predNode(func(ctx *evalContext) tri { return toTri(value(ctx)).not() }, n.cost)
+}
+
+func (c *compiler) compileBinary(expr *BinaryExpr) *node {
+	op := expr.Op
+	lhs, rhs := c.compile(expr.LHS), c.compile(expr.RHS)
+	switch {
+	case (op == And || op == Or) && lhs.pred != nil && rhs.pred != nil:
+		operands := append(flatten(op, lhs), flatten(op, rhs)...)
+		if c.strict {
+			return compileStrictLogical(op, operands)
+		}
+		return compileLogical(op, operands)
+	case op == And || op == Or:
+		return compileGeneric(op, lhs, rhs, c.eval)
+	case lhs.isConst && rhs.isConst:
+		return constNode(c.eval(op, lhs.c, rhs.c))
+	case op.isArithmetic():
+		// arithmetic expressions yield values rather than predicates
+		l, r := lhs.value, rhs.value
+		return &node{
+			value: func(ctx *evalContext) interface{} { return evalArithmetic(op, l(ctx), r(ctx)) },
+			cost:  lhs.cost + rhs.cost + opCost(op),
+		}
+	case rhs.isConst:
+		if n := compileConstOperand(op, lhs, rhs.c, c.eval); n != nil {
+			return n
+		}
+	}
+	return compileGeneric(op, lhs, rhs, c.eval)
+}
+
+// flatten returns the operands of the node if it is the
+// conjunction or disjunction with the same operator.
+func flatten(op token, n *node) []*node {
+	if n.op == op && n.operands != nil {
+		return n.operands
+	}
+	return []*node{n}
+}
+
+// compileLogical builds the n-ary conjunction or disjunction of predicates.
+// Evaluating the chain of predicates left to right yields the following
+// outcomes, which don't depend on the order of operands:
+//
+//   - and: false if any operand is false, true if all operands are true,
+//     nil if all operands are nil, and false for the mix of true and nil
+//   - or: true if any operand is true, false if all operands are false,
+//     nil if all operands are nil, and false for the mix of false and nil
+//
+// This allows for evaluating the cheapest operands first.
+func compileLogical(op token, operands []*node) *node {
+	var (
+		dynamic                   []*node
+		sawTrue, sawFalse, sawNil bool
+		cost                      int
+	)
+	for _, o := range operands {
+		if !o.isConst {
+			dynamic = append(dynamic, o)
+			cost += o.cost
+			continue
+		}
+		switch toTri(o.c) {
+		case triTrue:
+			sawTrue = true
+		case triFalse:
+			sawFalse = true
+		default:
+			sawNil = true
+		}
+	}
+
+	var n *node
+	switch op {
+	case And:
+		switch {
+		case sawFalse:
+			n = constNode(false)
+		case len(dynamic) == 0 && sawTrue && sawNil:
+			n = constNode(false)
+		case len(dynamic) == 0 && sawNil:
+			n = constNode(nil)
+		case len(dynamic) == 0:
+			n = constNode(true)
+		default:
+			preds := orderByCost(dynamic)
+			n = predNode(func(ctx *evalContext) tri {
+				t, nul := sawTrue, sawNil
+				for _, pred := range preds {
+					switch pred(ctx) {
+					case triFalse:
+						return triFalse
+					case triTrue:
+						t = true
+					default:
+						nul = true
+					}
+					if t && nul {
+						return triFalse
+					}
+				}
+				if t {
+					return triTrue
+				}
+				return triNil
+			}, cost)
+		}
+	default:
+		switch {
+		case sawTrue:
+			n = constNode(true)
+		case len(dynamic) == 0 && sawFalse:
+			n = constNode(false)
+		case len(dynamic) == 0:
+			n = constNode(nil)
+		default:
+			preds := orderByCost(dynamic)
+			n = predNode(func(ctx *evalContext) tri {
+				f := sawFalse
+				for _, pred := range preds {
+					switch pred(ctx) {
+					case triTrue:
+						return triTrue
+					case triFalse:
+						f = true
+					}
+				}
+				if f {
+					return triFalse
+				}
+				return triNil
+			}, cost)
+		}
+	}
+	n.op = op
+	n.operands = operands
+	return n
+}
+
+// compileStrictLogical builds the n-ary conjunction or disjunction of
+// predicates with the three-valued logic. The conjunction is false if
+// any operand is false, true if all operands are true, and nil otherwise.
+// The disjunction is true if any operand is true, false if all operands
+// are false, and nil otherwise.
+func compileStrictLogical(op token, operands []*node) *node {
+	var (
+		dynamic []*node
+		acc     = triTrue
+		cost    int
+	)
+	if op == Or {
+		acc = triFalse
+	}
+	for _, o := range operands {
+		if !o.isConst {
+			dynamic = append(dynamic, o)
+			cost += o.cost
+			continue
+		}
+		if op == And {
+			acc = acc.and(toTri(o.c))
+		} else {
+			acc = acc.or(toTri(o.c))
+		}
+	}
+
+	var n *node
+	switch {
+	case op == And && acc == triFalse, op == Or && acc == triTrue, len(dynamic) == 0:
+		n = constNode(acc.value())
+	case op == And:
+		preds := orderByCost(dynamic)
+		n = predNode(func(ctx *evalContext) tri {
+			t := acc
+			for _, pred := range preds {
+				if t = t.and(pred(ctx)); t == triFalse {
+					return triFalse
+				}
+			}
+			return t
+		}, cost)
+	default:
+		preds := orderByCost(dynamic)
+		n = predNode(func(ctx *evalContext) tri {
+			t := acc
+			for _, pred := range preds {
+				if t = t.or(pred(ctx)); t == triTrue {
+					return triTrue
+				}
+			}
+			return t
+		}, cost)
+	}
+	n.op = op
+	n.operands = operands
+	return n
+}
+
+func orderByCost(nodes []*node) []predFn {
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].cost < nodes[j].cost })
+	preds := make([]predFn, len(nodes))
+	for i, n := range nodes {
+		preds[i] = n.pred
+	}
+	return preds
+}
+
+// compileGeneric evaluates both operands and applies the
+// operator the same way the interpreter does.
+func compileGeneric(op token, lhs, rhs *node, evalBinary func(token, interface{}, interface{}) interface{}) *node {
+	l, r := lhs.value, rhs.value
+	cost := lhs.cost + rhs.cost + opCost(op)
+	switch op {
+	case And:
+		return predNode(func(ctx *evalContext) tri {
+			v := l(ctx)
+			if b, ok := v.(bool); ok && !b {
+				return triFalse
+			}
+			return toTri(evalBinary(op, v, r(ctx)))
+		}, cost)
+	case Or:
+		return predNode(func(ctx *evalContext) tri {
+			v := l(ctx)
+			if b, ok := v.(bool); ok && b {
+				return triTrue
+			}
+			return toTri(evalBinary(op, v, r(ctx)))
+		}, cost)
+	}
+	return predNode(func(ctx *evalContext) tri { return toTri(evalBinary(op, l(ctx), r(ctx))) }, cost)
+}
+
+// compileConstOperand specializes the operator for the constant right-hand side
+// operand. The specialized matcher only kicks in if the left-hand side operand
+// evaluates to the expected type. Otherwise, the generic evaluation is used.
+func compileConstOperand(op token, lhs *node, rc interface{}, evalBinary func(token, interface{}, interface{}) interface{}) *node {
+	l := lhs.value
+	cost := lhs.cost + opCost(op)
+	typ, hasType := fieldType(lhs)
+	switch {
+	case !hasType || isStringType(typ):
+		match := stringMatcher(op, rc)
+		if match == nil {
+			return nil
+		}
+		return predNode(func(ctx *evalContext) tri {
+			v := l(ctx)
+			if s, ok := v.(string); ok {
+				return boolTri(match(s))
+			}
+			return toTri(evalBinary(op, v, rc))
+		}, cost)
+	case typ == kparams.IP && op == In:
+		addrs, ok := rc.([]string)
+		if !ok {
+			return nil
+		}
+		ips := make([]net.IP, len(addrs))
+		for i, addr := range addrs {
+			ips[i] = net.ParseIP(addr)
+		}
+		return predNode(func(ctx *evalContext) tri {
+			v := l(ctx)
+			if ip, ok := v.(net.IP); ok {
+				for _, addr := range ips {
+					if addr.Equal(ip) {
+						return triTrue
+					}
+				}
+				return triFalse
+			}
+			return toTri(evalBinary(op, v, rc))
+		}, cost)
+	}
+	return nil
+}
+
+// stringMatcher returns the function that evaluates the operator for
+// the string left-hand side operand and the constant right-hand side
+// operand. Nil is returned if the operator can't be specialized.
+func stringMatcher(op token, rc interface{}) func(string) bool {
+	never := func(string) bool { return false }
+	switch r := rc.(type) {
+	case string:
+		lr := strings.ToLower(r)
+		switch op {
+		case Eq:
+			return func(s string) bool { return s == r }
+		case IEq:
+			return func(s string) bool { return strings.EqualFold(s, r) }
+		case Neq:
+			return func(s string) bool { return s != r }
+		case Contains:
+			return func(s string) bool { return strings.Contains(s, r) }
+		case IContains:
+			return func(s string) bool { return strings.Contains(strings.ToLower(s), lr) }
+		case Startswith:
+			return func(s string) bool { return strings.HasPrefix(s, r) }
+		case IStartswith:
+			return func(s string) bool { return strings.HasPrefix(strings.ToLower(s), lr) }
+		case Endswith:
+			return func(s string) bool { return strings.HasSuffix(s, r) }
+		case IEndswith:
+			return func(s string) bool { return strings.HasSuffix(strings.ToLower(s), lr) }
+		case Matches:
+			return func(s string) bool { return wildcard.Match(r, s) }
+		case IMatches:
+			return func(s string) bool { return wildcard.Match(lr, strings.ToLower(s)) }
+		case Fuzzy:
+			return func(s string) bool { return fuzzyMatch(fuzzysearch.Match, r, s) }
+		case IFuzzy:
+			return func(s string) bool { return fuzzyMatch(fuzzysearch.MatchFold, r, s) }
+		case Fuzzynorm:
+			return func(s string) bool { return fuzzyMatch(fuzzysearch.MatchNormalized, r, s) }
+		case IFuzzynorm:
+			return func(s string) bool { return fuzzyMatch(fuzzysearch.MatchNormalizedFold, r, s) }
+		case In, IIn, Lt, Lte, Gt, Gte:
+			return never
+		}
+	case []string:
+		lr := make([]string, len(r))
+		for i, s := range r {
+			lr[i] = strings.ToLower(s)
+		}
+		switch op {
+		case In:
+			set := make(map[string]struct{}, len(r))
+			for _, s := range r {
+				set[s] = struct{}{}
+			}
+			return func(s string) bool {
+				_, ok := set[s]
+				return ok
+			}
+		case IIn:
+			return newFoldSet(r).contains
+		case Contains:
+			return anyOf(r, strings.Contains, false)
+		case IContains:
+			return anyOf(lr, strings.Contains, true)
+		case Startswith:
+			return anyOf(r, strings.HasPrefix, false)
+		case IStartswith:
+			return anyOf(lr, strings.HasPrefix, true)
+		case Endswith:
+			return anyOf(r, strings.HasSuffix, false)
+		case IEndswith:
+			return anyOf(lr, strings.HasSuffix, true)
+		case Matches:
+			return newPatternSet(r, false).match
+		case IMatches:
+			return newPatternSet(r, true).match
+		case Fuzzy:
+			return anyOf(r, func(s, pat string) bool { return fuzzyMatch(fuzzysearch.Match, pat, s) }, false)
+		case IFuzzy:
+			return anyOf(r, func(s, pat string) bool { return fuzzyMatch(fuzzysearch.MatchFold, pat, s) }, false)
+		case Fuzzynorm:
+			return anyOf(r, func(s, pat string) bool { return fuzzyMatch(fuzzysearch.MatchNormalized, pat, s) }, false)
+		case IFuzzynorm:
+			return anyOf(r, func(s, pat string) bool { return fuzzyMatch(fuzzysearch.MatchNormalizedFold, pat, s) }, false)
+		case Eq, IEq, Neq, Lt, Lte, Gt, Gte:
+			return never
+		}
+	}
+	return nil
+}
+
+// anyOf returns the matcher that is satisfied if the predicate
+// holds for any of the values. The matched string is lowercased
+// once per evaluation if lower is true.
+func anyOf(values []string, pred func(s, v string) bool, lower bool) func(string) bool {
+	return func(s string) bool {
+		if lower {
+			s = strings.ToLower(s)
+		}
+		for _, v := range values {
+			if pred(s, v) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// foldSet performs case-insensitive membership tests. ASCII values
+// are resolved by the map lookup. Strings with non-ASCII characters
+// are compared with Unicode case folding, since some non-ASCII runes,
+// like the Kelvin sign, fold to ASCII letters.
+type foldSet struct {
+	ascii  map[string]struct{}
+	others []string
+	all    []string
+}
+
+func newFoldSet(values []string) *foldSet {
+	s := &foldSet{ascii: make(map[string]struct{}), all: values}
+	for _, v := range values {
+		if isASCII(v) {
+			s.ascii[strings.ToLower(v)] = struct{}{}
+		} else {
+			s.others = append(s.others, v)
+		}
+	}
+	return s
+}
+
+func (s *foldSet) contains(v string) bool {
+	values := s.all
+	if isASCII(v) {
+		if _, ok := s.ascii[strings.ToLower(v)]; ok {
+			return true
+		}
+		values = s.others
+	}
+	for _, val := range values {
+		if strings.EqualFold(val, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// patternSet matches strings against wildcard patterns. Patterns without
+// wildcards are resolved by the map lookup as long as the matched string
+// is valid UTF-8, because the wildcard matcher compares runes.
+type patternSet struct {
+	exact     map[string]struct{}
+	wildcards []string
+	all       []string
+	any       bool
+	fold      bool
+}
+
+func newPatternSet(patterns []string, fold bool) *patternSet {
+	p := &patternSet{exact: make(map[string]struct{}), fold: fold}
+	for _, pat := range patterns {
+		if fold {
+			pat = strings.ToLower(pat)
+		}
+		switch {
+		case pat == "*":
+			p.any = true
+		case !strings.ContainsAny(pat, "*?") && utf8.ValidString(pat):
+			p.exact[pat] = struct{}{}
+		default:
+			p.wildcards = append(p.wildcards, pat)
+		}
+		p.all = append(p.all, pat)
+	}
+	return p
+}
+
+func (p *patternSet) match(s string) bool {
+	if p.any {
+		return true
+	}
+	if p.fold {
+		s = strings.ToLower(s)
+	}
+	patterns := p.all
+	if utf8.ValidString(s) {
+		if _, ok := p.exact[s]; ok {
+			return true
+		}
+		patterns = p.wildcards
+	}
+	for _, pat := range patterns {
+		if wildcard.Match(pat, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldType returns the type of the field operand as declared in the field metadata.
+func fieldType(n *node) (kparams.Type, bool) {
+	if n.field == "" {
+		return kparams.Unknown, false
+	}
+	fi, ok := fields.Info(fields.Field(n.field))
+	if !ok {
+		return kparams.Unknown, false
+	}
+	return fi.Type, true
+}
+
+// isStringType determines if fields of the given type may yield string values.
+func isStringType(typ kparams.Type) bool {
+	switch typ {
+	case kparams.Int8, kparams.Uint8, kparams.Int16, kparams.Uint16, kparams.Int32, kparams.Uint32,
+		kparams.Int64, kparams.Uint64, kparams.Float, kparams.Double, kparams.Bool, kparams.PID,
+		kparams.TID, kparams.Port, kparams.IP, kparams.IPv4, kparams.IPv6:
+		return false
+	}
+	return true
+}
+
+// fieldCost estimates the cost of resolving the field value. Event
+// fields are cheap to access, while process fields require the lookup
+// in the process state, and PE fields may require parsing the image.
+func fieldCost(name string) int {
+	switch {
+	case strings.HasPrefix(name, "pe."):
+		return 8
+	case strings.HasPrefix(name, "ps.ancestor"), strings.HasPrefix(name, "ps.modules"), strings.HasPrefix(name, "ps.envs"):
+		return 4
+	case strings.HasPrefix(name, "ps."):
+		return 2
+	}
+	return 1
+}
+
+// functionCost estimates the cost of the function call. Functions
+// touching the file system, registry, or scanning memory are the
+// most expensive ones.
+func functionCost(fn functions.Fn) int {
+	switch fn {
+	case functions.YaraFn:
+		return 1000
+	case functions.GetRegValueFn, functions.SymlinkFn, functions.IsMinidumpFn, functions.GlobFn:
+		return 100
+	case functions.EntropyFn, functions.RegexFn, functions.MD5Fn:
+		return 20
+	}
+	return 10
+}
+
+// quantifierCost estimates the cost of iterating the collection.
+// The cost of the predicate is multiplied by this factor.
+const quantifierCost = 8
+
+func opCost(op token) int {
+	switch op {
+	case Fuzzy, IFuzzy, Fuzzynorm, IFuzzynorm:
+		return 16
+	case Matches, IMatches:
+		return 4
+	case IEq, IIn, IContains, IStartswith, IEndswith:
+		return 2
+	}
+	return 1
+}
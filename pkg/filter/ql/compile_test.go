@@ -0,0 +1,315 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	m := map[string]interface{}{
+		"ps.name":   "cmd.exe",
+		"ps.exe":    "C:\\Windows\\System32\\cmd.exe",
+		"ps.args":   []string{"/c", "whoami"},
+		"kevt.pid":  uint32(4),
+		"net.dport": uint16(443),
+		"net.dip":   net.ParseIP("10.0.0.5"),
+		"file.name": "C:\\Users\\admin\\lsass.dmp",
+		// boolean field values
+		"kevt.meta[is_wow64]": false,
+	}
+
+	var tests = []struct {
+		expr    string
+		matches bool
+	}{
+		{`ps.name = 'cmd.exe'`, true},
+		{`ps.name ~= 'CMD.EXE'`, true},
+		{`ps.name in ('powershell.exe', 'cmd.exe')`, true},
+		{`ps.name iin ('POWERSHELL.EXE', 'CMD.EXE')`, true},
+		{`ps.exe imatches ('c:\\windows\\*\\cmd.exe', 'c:\\temp\\*')`, true},
+		{`ps.exe matches 'C:\\Windows\\System32\\cmd.exe'`, true},
+		{`ps.exe matches ('C:\\Windows\\System32\\CMD.exe')`, false},
+		{`file.name iendswith ('.DMP', '.tmp') and not ps.name startswith 'svc'`, true},
+		{`kevt.pid = 4 and ps.name = 'cmd.exe'`, true},
+		{`kevt.pid = 4 and ps.name = 'svchost.exe'`, false},
+		{`kevt.pid = 8 or ps.name icontains 'CMD'`, true},
+		{`net.dip in ('10.0.0.5', '10.0.0.6') and net.dport in (80, 443)`, true},
+		{`kevt.pid = 4 and not (ps.name = 'cmd.exe' and kevt.pid = 4)`, false},
+		{`kevt.pid = 4 and not (ps.parent.name = 'explorer.exe')`, true},
+		{`ps.args in ('whoami')`, true},
+		{`kevt.meta[is_wow64] = false`, true},
+		{`kevt.meta[is_wow64] = true or kevt.meta[is_wow64]`, false},
+		{`lower(ps.name) = 'cmd.exe' and length(ps.name) = 7`, true},
+		{`1 = 1 and ps.name = 'cmd.exe'`, true},
+		{`1 = 2 or ps.name = 'notepad.exe'`, false},
+		{`kevt.pid = 4 and true`, true},
+		{`any(ps.args, a, a = 'whoami')`, true},
+		{`any(ps.args, a, a startswith '-')`, false},
+		{`all(ps.args, a, length(a) < 7)`, true},
+		{`all(ps.args, a, a = '/c')`, false},
+		{`count(ps.args) = 2 and count(ps.args, a, a icontains 'WHO') = 1`, true},
+		{`any(ps.args, a, all(ps.args, b, length(b) <= length(a)))`, true},
+		{`any(ps.args, a, any(('/c', '/k'), a, a = '/k'))`, true},
+		{`count(ps.args) = length(ps.args)`, true},
+		{`ps.args[1] = 'whoami' and ps.args[0] exists and ps.args[2] is null`, true},
+		{`upper(ps.args[0]) = '/C'`, true},
+		{`kevt.pid = 4 and not any(ps.args, a, a = 'dbghelp.dll')`, true},
+		{`kevt.pid = 4 and not any(ps.modules, m, m = 'dbghelp.dll')`, false},
+	}
+
+	for i, tt := range tests {
+		p := NewParser(tt.expr)
+		expr, err := p.ParseExpr()
+		require.NoError(t, err, tt.expr)
+		prog := Compile(expr, true)
+		assert.Equal(t, tt.matches, prog.EvalMap(m, nil), "%d. %q compiled match mismatch", i, tt.expr)
+		assert.Equal(t, Eval(expr, m, true), prog.EvalMap(m, nil), "%d. %q differs from the interpreter", i, tt.expr)
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	m := map[string]interface{}{
+		"ps.name":  "cmd.exe",
+		"kevt.pid": uint32(4),
+	}
+
+	var tests = []struct {
+		expr   string
+		strict bool
+		lax    bool
+	}{
+		{`ps.name = 'cmd.exe' and not ps.parent.name = 'explorer.exe'`, false, true},
+		{`ps.name = 'cmd.exe' and not (ps.parent.name = 'explorer.exe')`, false, true},
+		{`ps.name = 'cmd.exe' and ps.parent.name != 'explorer.exe'`, false, false},
+		{`ps.parent.name = 'explorer.exe' or ps.name = 'cmd.exe'`, true, true},
+		{`ps.name = 'cmd.exe' and not (ps.parent.name = 'explorer.exe' and kevt.pid = 8)`, true, true},
+		{`ps.name = 'cmd.exe' and not (ps.parent.name = 'explorer.exe' or kevt.pid = 4)`, false, false},
+		{`ps.name = 'cmd.exe' and ps.parent.name is null`, true, true},
+		{`ps.name = 'cmd.exe' and ps.parent.name exists`, false, false},
+		{`ps.name exists and kevt.pid is not null`, true, true},
+		{`ps.name = 'cmd.exe' and not ps.parent.name exists`, true, true},
+		{`ps.name = 'cmd.exe' and coalesce(ps.parent.name, 'unknown') != 'explorer.exe'`, true, true},
+		{`coalesce(ps.parent.name, ps.name) = 'cmd.exe'`, true, true},
+		{`ps.name = 'cmd.exe' and not coalesce(ps.parent.name, ps.parent.exe) = 'explorer.exe'`, false, true},
+	}
+
+	for i, tt := range tests {
+		p := NewParser(tt.expr)
+		expr, err := p.ParseExpr()
+		require.NoError(t, err, tt.expr)
+		for _, opts := range []Options{{UseFuncValuer: true, Strict: true}, {UseFuncValuer: true}} {
+			matches := tt.lax
+			if opts.Strict {
+				matches = tt.strict
+			}
+			assert.Equal(t, matches, EvalWithOptions(expr, m, opts, nil), "%d. %q interpreted match mismatch: strict=%t", i, tt.expr, opts.Strict)
+			assert.Equal(t, matches, CompileWithOptions(expr, opts).EvalMap(m, nil), "%d. %q compiled match mismatch: strict=%t", i, tt.expr, opts.Strict)
+		}
+	}
+}
+
+// TestCompileDifferential generates random expressions and field values,
+// and asserts the compiled program agrees with the interpreter.
+func TestCompileDifferential(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		expr := randExpr(r, 4)
+		for _, opts := range []Options{{UseFuncValuer: true}, {}, {UseFuncValuer: true, Strict: true}} {
+			prog := CompileWithOptions(expr, opts)
+			for j := 0; j < 8; j++ {
+				m := randValues(r)
+				require.Equal(t, EvalWithOptions(expr, m, opts, nil), prog.EvalMap(m, nil), "expr: %s values: %v options: %+v", expr, m, opts)
+			}
+		}
+	}
+}
+
+var diffFields = []string{"ps.name", "ps.exe", "ps.args", "kevt.pid", "net.dport", "net.dip", "file.name", "ps.is_wow64", "pe.nsections"}
+
+var diffStrings = []string{
+	"cmd.exe", "CMD.EXE", "Cmd.Exe", "svchost.exe", "", "*", "cmd*", "c?d.exe", "*.EXE",
+	"C:\\Windows\\System32\\cmd.exe", "c:\\windows\\*", "\u212a", "k", "K", "\xff", "\ufffd", "ſ", "s",
+	"10.0.0.5", "443", "4",
+}
+
+var diffOps = []token{
+	Eq, IEq, Neq, Lt, Lte, Gt, Gte, In, IIn, Contains, IContains, Startswith, IStartswith,
+	Endswith, IEndswith, Matches, IMatches, Fuzzy, IFuzzy, Fuzzynorm, IFuzzynorm, And, Or,
+}
+
+var arithOps = []token{Add, Sub, Mul, Div, Mod, BitAnd, BitOr}
+
+// diffFuncs maps function names to their arity
+var diffFuncs = map[string]int{"lower": 1, "upper": 1, "length": 1, "base": 1, "concat": 2, "undefined": 1}
+
+var diffFuncNames = []string{"lower", "upper", "length", "base", "concat", "undefined"}
+
+func randString(r *rand.Rand) string { return diffStrings[r.Intn(len(diffStrings))] }
+
+func randStrings(r *rand.Rand) []string {
+	vals := make([]string, r.Intn(4))
+	for i := range vals {
+		vals[i] = randString(r)
+	}
+	return vals
+}
+
+func randValue(r *rand.Rand) interface{} {
+	switch r.Intn(10) {
+	case 0:
+		return nil
+	case 1:
+		return r.Intn(2) == 0
+	case 2:
+		return uint32(r.Intn(6))
+	case 3:
+		return uint16(r.Intn(500))
+	case 4:
+		return int64(r.Intn(6) - 2)
+	case 5:
+		return float64(r.Intn(6))
+	case 6:
+		return randStrings(r)
+	case 7:
+		return net.ParseIP([]string{"10.0.0.5", "10.0.0.6", "::1"}[r.Intn(3)])
+	default:
+		return randString(r)
+	}
+}
+
+func randValues(r *rand.Rand) map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, f := range diffFields {
+		if v := randValue(r); v != nil {
+			m[f] = v
+		}
+	}
+	return m
+}
+
+func randOperand(r *rand.Rand, depth int) Expr {
+	switch r.Intn(10) {
+	case 0, 1, 2:
+		return &FieldLiteral{Value: diffFields[r.Intn(len(diffFields))]}
+	case 3:
+		return &StringLiteral{Value: randString(r)}
+	case 4:
+		return &ListLiteral{Values: randStrings(r)}
+	case 5:
+		switch r.Intn(4) {
+		case 0:
+			return &IntegerLiteral{Value: int64(r.Intn(6) - 2)}
+		case 1:
+			return &UnsignedLiteral{Value: uint64(r.Intn(6))}
+		case 2:
+			return &DecimalLiteral{Value: float64(r.Intn(6))}
+		default:
+			return &IPLiteral{Value: net.ParseIP("10.0.0.5")}
+		}
+	case 6:
+		return &BoolLiteral{Value: r.Intn(2) == 0}
+	case 7:
+		name := diffFuncNames[r.Intn(len(diffFuncNames))]
+		args := make([]Expr, diffFuncs[name])
+		for i := range args {
+			args[i] = randOperand(r, depth-1)
+		}
+		return &Function{Name: name, Args: args}
+	case 8:
+		op := arithOps[r.Intn(len(arithOps))]
+		return &BinaryExpr{Op: op, LHS: randOperand(r, depth-1), RHS: randOperand(r, depth-1)}
+	default:
+		if depth <= 0 {
+			return &FieldLiteral{Value: diffFields[r.Intn(len(diffFields))]}
+		}
+		return randExpr(r, depth-1)
+	}
+}
+
+func randExpr(r *rand.Rand, depth int) Expr {
+	if depth <= 0 {
+		return &BinaryExpr{Op: diffOps[r.Intn(len(diffOps)-2)], LHS: randOperand(r, 0), RHS: randOperand(r, 0)}
+	}
+	switch r.Intn(8) {
+	case 0:
+		return &NotExpr{Expr: randExpr(r, depth-1)}
+	case 5:
+		return &ExistsExpr{Expr: &FieldLiteral{Value: diffFields[r.Intn(len(diffFields))]}, Null: r.Intn(2) == 0}
+	case 6:
+		q := randQuantifier(r, depth)
+		if q.Fn == CountQuantifier {
+			return &BinaryExpr{Op: Gte, LHS: q, RHS: &IntegerLiteral{Value: int64(r.Intn(3))}}
+		}
+		return q
+	case 1:
+		return &NotExpr{Expr: &ParenExpr{Expr: randOperand(r, depth-1)}}
+	case 2:
+		return &ParenExpr{Expr: randExpr(r, depth-1)}
+	case 3, 4:
+		op := And
+		if r.Intn(2) == 0 {
+			op = Or
+		}
+		return &BinaryExpr{Op: op, LHS: randExpr(r, depth-1), RHS: randExpr(r, depth-1)}
+	default:
+		return &BinaryExpr{Op: diffOps[r.Intn(len(diffOps))], LHS: randOperand(r, depth-1), RHS: randOperand(r, depth-1)}
+	}
+}
+
+var quantifiers = []QuantifierFn{AnyQuantifier, AllQuantifier, CountQuantifier}
+
+// randQuantifier generates quantifiers over slice fields with predicates
+// that compare the element with random operands or other elements.
+func randQuantifier(r *rand.Rand, depth int) *QuantifierExpr {
+	q := &QuantifierExpr{Fn: quantifiers[r.Intn(len(quantifiers))], Expr: &FieldLiteral{Value: "ps.args"}, Var: "a"}
+	if r.Intn(4) == 0 {
+		q.Expr = &ListLiteral{Values: randStrings(r)}
+	}
+	var rhs Expr
+	switch r.Intn(3) {
+	case 0:
+		rhs = &IndexExpr{Expr: &FieldLiteral{Value: "ps.args"}, Index: r.Intn(3)}
+	case 1:
+		if depth > 1 {
+			inner := randQuantifier(r, depth-1)
+			if inner.Pred != nil {
+				inner.Var = "b"
+				inner.Pred = &BinaryExpr{Op: diffOps[r.Intn(len(diffOps)-2)], LHS: &VariableLiteral{Value: "a"}, RHS: &VariableLiteral{Value: "b"}}
+			}
+			if inner.Fn != CountQuantifier {
+				q.Pred = &BinaryExpr{Op: And, LHS: inner, RHS: &NotExpr{Expr: &ExistsExpr{Expr: &VariableLiteral{Value: "a"}, Null: true}}}
+			} else {
+				q.Pred = &BinaryExpr{Op: Gt, LHS: inner, RHS: &IntegerLiteral{Value: int64(r.Intn(3))}}
+			}
+		}
+		rhs = randOperand(r, 0)
+	default:
+		rhs = randOperand(r, 0)
+	}
+	if q.Pred == nil && (q.Fn != CountQuantifier || r.Intn(2) == 0) {
+		q.Pred = &BinaryExpr{Op: diffOps[r.Intn(len(diffOps)-2)], LHS: &VariableLiteral{Value: "a"}, RHS: rhs}
+	}
+	return q
+}
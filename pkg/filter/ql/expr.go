@@ -57,3 +57,27 @@ type NotExpr struct {
 
 // String returns a string representation of the not expression.
 func (e *NotExpr) String() string { return fmt.Sprintf("(%s)", e.Expr.String()) }
+
+// ExistsExpr checks whether the field has a value. If Null is true, the
+// expression checks the field has no value, i.e. the field is null.
+type ExistsExpr struct {
+	Expr Expr
+	Null bool
+}
+
+// String returns a string representation of the exists expression.
+func (e *ExistsExpr) String() string {
+	if e.Null {
+		return fmt.Sprintf("%s is null", e.Expr.String())
+	}
+	return fmt.Sprintf("%s exists", e.Expr.String())
+}
+
+// IndexExpr represents the element of the collection at the given position, e.g. ps.args[2].
+type IndexExpr struct {
+	Expr  Expr
+	Index int
+}
+
+// String returns a string representation of the index expression.
+func (e *IndexExpr) String() string { return fmt.Sprintf("%s[%d]", e.Expr.String(), e.Index) }
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/rabbitstack/fibratus/pkg/filter/ql/functions"
 )
@@ -46,30 +47,47 @@ var (
 )
 
 var funcs = map[string]FunctionDef{
-	functions.CIDRContainsFn.String(): &functions.CIDRContains{},
-	functions.MD5Fn.String():          &functions.MD5{},
-	functions.ConcatFn.String():       &functions.Concat{},
-	functions.LtrimFn.String():        &functions.Ltrim{},
-	functions.RtrimFn.String():        &functions.Rtrim{},
-	functions.LowerFn.String():        &functions.Lower{},
-	functions.UpperFn.String():        &functions.Upper{},
-	functions.ReplaceFn.String():      &functions.Replace{},
-	functions.SplitFn.String():        &functions.Split{},
-	functions.LengthFn.String():       &functions.Length{},
-	functions.IndexOfFn.String():      &functions.IndexOf{},
-	functions.SubstrFn.String():       &functions.Substr{},
-	functions.EntropyFn.String():      &functions.Entropy{},
-	functions.RegexFn.String():        functions.NewRegex(),
-	functions.IsMinidumpFn.String():   &functions.IsMinidump{},
-	functions.BaseFn.String():         &functions.Base{},
-	functions.DirFn.String():          &functions.Dir{},
-	functions.SymlinkFn.String():      &functions.Symlink{},
-	functions.ExtFn.String():          &functions.Ext{},
-	functions.GlobFn.String():         &functions.Glob{},
-	functions.IsAbsFn.String():        &functions.IsAbs{},
-	functions.VolumeFn.String():       &functions.Volume{},
-	functions.GetRegValueFn.String():  &functions.GetRegValue{},
-	functions.YaraFn.String():         &functions.Yara{},
+	functions.CIDRContainsFn.String():  &functions.CIDRContains{},
+	functions.MD5Fn.String():           &functions.MD5{},
+	functions.ConcatFn.String():        &functions.Concat{},
+	functions.LtrimFn.String():         &functions.Ltrim{},
+	functions.RtrimFn.String():         &functions.Rtrim{},
+	functions.LowerFn.String():         &functions.Lower{},
+	functions.UpperFn.String():         &functions.Upper{},
+	functions.ReplaceFn.String():       &functions.Replace{},
+	functions.SplitFn.String():         &functions.Split{},
+	functions.LengthFn.String():        &functions.Length{},
+	functions.IndexOfFn.String():       &functions.IndexOf{},
+	functions.SubstrFn.String():        &functions.Substr{},
+	functions.EntropyFn.String():       &functions.Entropy{},
+	functions.RegexFn.String():         functions.NewRegex(),
+	functions.IsMinidumpFn.String():    &functions.IsMinidump{},
+	functions.BaseFn.String():          &functions.Base{},
+	functions.DirFn.String():           &functions.Dir{},
+	functions.SymlinkFn.String():       &functions.Symlink{},
+	functions.ExtFn.String():           &functions.Ext{},
+	functions.GlobFn.String():          &functions.Glob{},
+	functions.IsAbsFn.String():         &functions.IsAbs{},
+	functions.VolumeFn.String():        &functions.Volume{},
+	functions.GetRegValueFn.String():   &functions.GetRegValue{},
+	functions.YaraFn.String():          &functions.Yara{},
+	functions.HourFn.String():          &functions.Hour{},
+	functions.WeekdayFn.String():       &functions.Weekday{},
+	functions.DateDiffFn.String():      &functions.DateDiff{},
+	functions.InTimeRangeFn.String():   &functions.InTimeRange{},
+	functions.Base64DecodeFn.String():  &functions.Base64Decode{},
+	functions.HexDecodeFn.String():     &functions.HexDecode{},
+	functions.URLDecodeFn.String():     &functions.URLDecode{},
+	functions.XorDecodeFn.String():     &functions.XorDecode{},
+	functions.CmdlineArgsFn.String():   &functions.CmdlineArgs{},
+	functions.CmdlineFlagFn.String():   &functions.CmdlineFlag{},
+	functions.SHA1Fn.String():          &functions.SHA1{},
+	functions.SHA256Fn.String():        &functions.SHA256{},
+	functions.ImphashFn.String():       &functions.Imphash{},
+	functions.LevenshteinFn.String():   &functions.Levenshtein{},
+	functions.JaroWinklerFn.String():   &functions.JaroWinkler{},
+	functions.SsdeepCompareFn.String(): &functions.SsdeepCompare{},
+	functions.CoalesceFn.String():      &functions.Coalesce{},
 }
 
 // FunctionDef is the interface that all function definitions have to satisfy.
@@ -85,7 +103,8 @@ type FunctionDef interface {
 // FunctionValuer implements the CallValuer interface and delegates
 // the evaluation of function calls to the corresponding functions.
 type FunctionValuer struct {
-	m map[string]interface{}
+	m        map[string]interface{}
+	observer CallObserver
 }
 
 func (f FunctionValuer) Value(key string) (interface{}, bool) {
@@ -93,12 +112,18 @@ func (f FunctionValuer) Value(key string) (interface{}, bool) {
 	return v, ok
 }
 
-func (FunctionValuer) Call(name string, args []interface{}) (interface{}, bool) {
+func (f FunctionValuer) Call(name string, args []interface{}) (interface{}, bool) {
 	fn, ok := funcs[strings.ToUpper(name)]
 	if !ok {
 		return nil, false
 	}
-	return fn.Call(args)
+	if f.observer == nil {
+		return fn.Call(args)
+	}
+	start := time.Now()
+	v, ok := fn.Call(args)
+	f.observer(fn.Name().String(), time.Since(start))
+	return v, ok
 }
 
 func functionNames() []string {
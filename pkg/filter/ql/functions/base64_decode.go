@@ -0,0 +1,137 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// utf16Encoding forces the decoded payload to be interpreted as UTF-16LE text
+	utf16Encoding = "utf16le"
+	// rawEncoding leaves the decoded payload intact
+	rawEncoding = "raw"
+)
+
+// base64Encodings are tried in order until the string is successfully decoded.
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.RawStdEncoding,
+	base64.URLEncoding,
+	base64.RawURLEncoding,
+}
+
+// Base64Decode decodes the base64 encoded string. Standard and URL-safe
+// alphabets are recognized, with or without padding. If the decoded payload
+// looks like UTF-16LE text, e.g. the argument of the PowerShell -EncodedCommand
+// parameter, it is converted to UTF-8, unless the encoding says otherwise.
+type Base64Decode struct{}
+
+func (f Base64Decode) Call(args []interface{}) (interface{}, bool) {
+	if len(args) < 1 {
+		return false, false
+	}
+	s := strings.TrimSpace(parseString(0, args))
+	if s == "" {
+		return false, false
+	}
+	var (
+		b   []byte
+		err error
+	)
+	for _, enc := range base64Encodings {
+		b, err = enc.DecodeString(s)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return false, false
+	}
+	switch parseString(1, args) {
+	case "":
+		if isUTF16LE(b) {
+			return decodeUTF16LE(b), true
+		}
+		return string(b), true
+	case utf16Encoding:
+		if len(b)%2 != 0 {
+			return false, false
+		}
+		return decodeUTF16LE(b), true
+	case rawEncoding:
+		return string(b), true
+	default:
+		return false, false
+	}
+}
+
+func (f Base64Decode) Desc() FunctionDesc {
+	desc := FunctionDesc{
+		Name: Base64DecodeFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "string", Types: []ArgType{Field, Func, String}, Required: true},
+			{Keyword: "encoding", Types: []ArgType{String}},
+		},
+		ArgsValidationFunc: func(args []string) error {
+			if len(args) == 1 {
+				return nil
+			}
+			if args[1] != utf16Encoding && args[1] != rawEncoding {
+				return fmt.Errorf("%s is not a valid encoding. Available encodings are: utf16le,raw", args[1])
+			}
+			return nil
+		},
+	}
+	return desc
+}
+
+func (f Base64Decode) Name() Fn { return Base64DecodeFn }
+
+// isUTF16LE determines if the byte slice is likely the UTF-16LE encoded text.
+// The text composed mostly of ASCII characters has the high byte of the vast
+// majority of code units set to zero.
+func isUTF16LE(b []byte) bool {
+	if len(b) < 2 || len(b)%2 != 0 {
+		return false
+	}
+	var zeros int
+	for i := 1; i < len(b); i += 2 {
+		if b[i] == 0 {
+			zeros++
+		}
+	}
+	return zeros*4 >= len(b)/2*3
+}
+
+// decodeUTF16LE converts the UTF-16LE encoded text to the UTF-8 string.
+func decodeUTF16LE(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u = append(u, uint16(b[i])|uint16(b[i+1])<<8)
+	}
+	// skip the byte order mark
+	if len(u) > 0 && u[0] == 0xfeff {
+		u = u[1:]
+	}
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBase64Decode(t *testing.T) {
+	var tests = []struct {
+		args     []interface{}
+		expected interface{}
+	}{
+		{
+			[]interface{}{"aGVsbG8gd29ybGQ="},
+			"hello world",
+		},
+		{
+			[]interface{}{"aGVsbG8gd29ybGQ"},
+			"hello world",
+		},
+		{
+			// PowerShell -EncodedCommand payload for IEX (New-Object Net.WebClient)
+			[]interface{}{"SQBFAFgAIAAoAE4AZQB3AC0ATwBiAGoAZQBjAHQAIABOAGUAdAAuAFcAZQBiAEMAbABpAGUAbgB0ACkA"},
+			"IEX (New-Object Net.WebClient)",
+		},
+		{
+			[]interface{}{"SQBFAFgAIAAoAE4AZQB3AC0ATwBiAGoAZQBjAHQAIABOAGUAdAAuAFcAZQBiAEMAbABpAGUAbgB0ACkA", "raw"},
+			"I\x00E\x00X\x00 \x00(\x00N\x00e\x00w\x00-\x00O\x00b\x00j\x00e\x00c\x00t\x00 \x00N\x00e\x00t\x00.\x00W\x00e\x00b\x00C\x00l\x00i\x00e\x00n\x00t\x00)\x00",
+		},
+		{
+			[]interface{}{"//5pAGUAeAA=", "utf16le"},
+			"iex",
+		},
+		{
+			[]interface{}{"_-8", "raw"},
+			"\xff\xef",
+		},
+		{
+			[]interface{}{"not base64!"},
+			false,
+		},
+		{
+			[]interface{}{""},
+			false,
+		},
+	}
+
+	for i, tt := range tests {
+		f := Base64Decode{}
+		res, _ := f.Call(tt.args)
+		assert.Equal(t, tt.expected, res, fmt.Sprintf("%d. result mismatch: exp=%v got=%v", i, tt.expected, res))
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/util/cmdline"
+)
+
+// CmdlineArgs splits the command line into arguments. The
+// quotes enclosing the argument are removed.
+type CmdlineArgs struct{}
+
+func (f CmdlineArgs) Call(args []interface{}) (interface{}, bool) {
+	if len(args) < 1 {
+		return false, false
+	}
+	argv := cmdline.Split(parseString(0, args))
+	for i, arg := range argv {
+		argv[i] = unquote(arg)
+	}
+	return argv, true
+}
+
+func (f CmdlineArgs) Desc() FunctionDesc {
+	desc := FunctionDesc{
+		Name: CmdlineArgsFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "cmdline", Types: []ArgType{Field, Func, String}, Required: true},
+		},
+	}
+	return desc
+}
+
+func (f CmdlineArgs) Name() Fn { return CmdlineArgsFn }
+
+// unquote removes double quotes enclosing the argument.
+func unquote(arg string) string {
+	if len(arg) >= 2 && arg[0] == '"' && arg[len(arg)-1] == '"' {
+		return arg[1 : len(arg)-1]
+	}
+	return arg
+}
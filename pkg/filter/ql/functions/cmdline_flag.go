@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"strings"
+
+	"github.com/rabbitstack/fibratus/pkg/util/cmdline"
+)
+
+// CmdlineFlag returns the value of the command line flag. The flag is
+// matched by any of the given names case-insensitively, and it may be
+// prefixed with -, --, or /. The value is either the next argument, or
+// the part that follows the : or = separator, e.g. -File:script.ps1.
+// The empty string is returned if the flag has no value.
+type CmdlineFlag struct{}
+
+func (f CmdlineFlag) Call(args []interface{}) (interface{}, bool) {
+	if len(args) < 2 {
+		return false, false
+	}
+	argv := cmdline.Split(parseString(0, args))
+	names := make([]string, 0, len(args)-1)
+	for i := 1; i < len(args); i++ {
+		if name := parseString(i, args); name != "" {
+			names = append(names, name)
+		}
+	}
+	// skip the executable
+	for i := 1; i < len(argv); i++ {
+		name, value, hasValue := splitFlag(argv[i])
+		if name == "" || !matchesFlag(name, names) {
+			continue
+		}
+		if hasValue {
+			return unquote(value), true
+		}
+		if i+1 < len(argv) && !isFlag(argv[i+1]) {
+			return unquote(argv[i+1]), true
+		}
+		return "", true
+	}
+	return false, false
+}
+
+func (f CmdlineFlag) Desc() FunctionDesc {
+	desc := FunctionDesc{
+		Name: CmdlineFlagFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "cmdline", Types: []ArgType{Field, Func, String}, Required: true},
+			{Keyword: "flag", Types: []ArgType{String}, Required: true},
+		},
+	}
+	offset := len(desc.Args)
+	// add optional flag name aliases
+	for i := offset; i < maxArgs; i++ {
+		desc.Args = append(desc.Args, FunctionArgDesc{Keyword: "flag", Types: []ArgType{String}})
+	}
+	return desc
+}
+
+func (f CmdlineFlag) Name() Fn { return CmdlineFlagFn }
+
+func isFlag(arg string) bool { return strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "/") }
+
+// splitFlag strips the prefix from the flag argument and splits
+// the flag name and the value given after the : or = separator.
+func splitFlag(arg string) (string, string, bool) {
+	if !isFlag(arg) {
+		return "", "", false
+	}
+	arg = strings.TrimLeft(arg, "-/")
+	if n := strings.IndexAny(arg, ":="); n > 0 {
+		return arg[:n], arg[n+1:], true
+	}
+	return arg, "", false
+}
+
+func matchesFlag(name string, names []string) bool {
+	for _, n := range names {
+		if strings.EqualFold(name, strings.TrimLeft(n, "-/")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import "fmt"
+
+// Coalesce returns the first argument that has a value. Fields
+// that can't be resolved for the event and failed function calls
+// have no value.
+type Coalesce struct{}
+
+func (f Coalesce) Call(args []interface{}) (interface{}, bool) {
+	for _, arg := range args {
+		if arg != nil {
+			return arg, true
+		}
+	}
+	return false, false
+}
+
+func (f Coalesce) Desc() FunctionDesc {
+	desc := FunctionDesc{
+		Name: CoalesceFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "value1", Types: []ArgType{Field, Func, String, Number, Bool, IP}, Required: true},
+			{Keyword: "value2", Types: []ArgType{Field, Func, String, Number, Bool, IP}, Required: true},
+		},
+	}
+	offset := len(desc.Args)
+	// add optional arguments
+	for i := offset; i < maxArgs; i++ {
+		desc.Args = append(desc.Args, FunctionArgDesc{Keyword: fmt.Sprintf("value%d", i+1), Types: []ArgType{Field, Func, String, Number, Bool, IP}})
+	}
+	return desc
+}
+
+func (f Coalesce) Name() Fn { return CoalesceFn }
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"time"
+)
+
+var dateDiffUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+}
+
+// DateDiff computes the time elapsed between the first and the second
+// timestamp. Without the unit, the function returns the duration that
+// can be compared against duration literals, e.g. 5m. Otherwise, the
+// number of whole units is returned.
+type DateDiff struct{}
+
+func (f DateDiff) Call(args []interface{}) (interface{}, bool) {
+	if len(args) < 2 {
+		return false, false
+	}
+	from, ok := parseTime(0, args)
+	if !ok {
+		return false, false
+	}
+	to, ok := parseTime(1, args)
+	if !ok {
+		return false, false
+	}
+	d := to.Sub(from)
+	if len(args) == 2 {
+		return d, true
+	}
+	unit, ok := dateDiffUnits[parseString(2, args)]
+	if !ok {
+		return false, false
+	}
+	return int64(d / unit), true
+}
+
+func (f DateDiff) Desc() FunctionDesc {
+	desc := FunctionDesc{
+		Name: DateDiffFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "from", Types: []ArgType{Field, Func, String}, Required: true, Timestamp: true},
+			{Keyword: "to", Types: []ArgType{Field, Func, String}, Required: true, Timestamp: true},
+			{Keyword: "unit", Types: []ArgType{String}},
+		},
+		ArgsValidationFunc: func(args []string) error {
+			if len(args) < 3 {
+				return nil
+			}
+			if _, ok := dateDiffUnits[args[2]]; !ok {
+				return fmt.Errorf("%s is not a valid time unit. Available units are: ns,us,ms,s,m,h,d", args[2])
+			}
+			return nil
+		},
+	}
+	return desc
+}
+
+func (f DateDiff) Name() Fn { return DateDiffFn }
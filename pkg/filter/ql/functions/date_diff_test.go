@@ -0,0 +1,72 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateDiff(t *testing.T) {
+	started := time.Date(2022, time.March, 5, 23, 15, 0, 0, time.UTC)
+	ts := started.Add(90 * time.Second)
+
+	var tests = []struct {
+		args     []interface{}
+		expected interface{}
+	}{
+		{
+			[]interface{}{started, ts},
+			90 * time.Second,
+		},
+		{
+			[]interface{}{started, ts, "s"},
+			int64(90),
+		},
+		{
+			[]interface{}{started, ts, "m"},
+			int64(1),
+		},
+		{
+			[]interface{}{ts, started, "ms"},
+			int64(-90000),
+		},
+		{
+			[]interface{}{"2022-03-01T00:00:00Z", started, "d"},
+			int64(4),
+		},
+		{
+			[]interface{}{started, ts, "fortnight"},
+			false,
+		},
+		{
+			[]interface{}{nil, ts},
+			false,
+		},
+	}
+
+	for i, tt := range tests {
+		f := DateDiff{}
+		res, _ := f.Call(tt.args)
+		assert.Equal(t, tt.expected, res, fmt.Sprintf("%d. result mismatch: exp=%v got=%v", i, tt.expected, res))
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	// Windows hosts don't ship the IANA time zone database
+	_ "time/tzdata"
+)
+
+// timeLayouts are the layouts of string arguments representing timestamps.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+// locations caches time zone locations as loading them requires reading the time zone database.
+var locations sync.Map
+
+// parseTime yields the timestamp from the specific position in the args slice.
+// The argument is either the time value or the string in one of the timestamp
+// layouts. Strings without the time zone are interpreted in the local time zone.
+func parseTime(index int, args []interface{}) (time.Time, bool) {
+	if index > len(args)-1 {
+		return time.Time{}, false
+	}
+	switch v := args[index].(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseClock parses the time of the day in HH:MM or HH:MM:SS
+// format and returns the time elapsed since midnight.
+func parseClock(s string) (time.Duration, bool) {
+	var layout string
+	switch strings.Count(s, ":") {
+	case 1:
+		layout = "15:04"
+	case 2:
+		layout = "15:04:05"
+	default:
+		return 0, false
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return 0, false
+	}
+	return clock(t), true
+}
+
+// clock returns the time elapsed since midnight of the timestamp.
+func clock(t time.Time) time.Duration {
+	h, m, s := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second + time.Duration(t.Nanosecond())
+}
+
+// inLocation converts the timestamp to the time zone given at the specific
+// position in the args slice. The timestamp is left intact if the time zone
+// argument is absent.
+func inLocation(t time.Time, index int, args []interface{}) (time.Time, bool) {
+	tz := parseString(index, args)
+	if tz == "" {
+		return t, true
+	}
+	loc, err := loadLocation(tz)
+	if err != nil {
+		return t, false
+	}
+	return t.In(loc), true
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+func validateLocation(tz string) error {
+	if _, err := loadLocation(tz); err != nil {
+		return fmt.Errorf("%s is not a valid time zone: %v", tz, err)
+	}
+	return nil
+}
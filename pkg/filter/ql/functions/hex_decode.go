@@ -0,0 +1,54 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// hexReplacer removes separators and escape prefixes from the hex string.
+var hexReplacer = strings.NewReplacer(`\x`, "", " ", "", "0x", "", "0X", "")
+
+// HexDecode decodes the hexadecimal string. The string may contain
+// the 0x prefix, \x escapes, or spaces between bytes.
+type HexDecode struct{}
+
+func (f HexDecode) Call(args []interface{}) (interface{}, bool) {
+	if len(args) < 1 {
+		return false, false
+	}
+	b, err := hex.DecodeString(hexReplacer.Replace(parseString(0, args)))
+	if err != nil {
+		return false, false
+	}
+	return string(b), true
+}
+
+func (f HexDecode) Desc() FunctionDesc {
+	desc := FunctionDesc{
+		Name: HexDecodeFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "string", Types: []ArgType{Field, Func, String}, Required: true},
+		},
+	}
+	return desc
+}
+
+func (f HexDecode) Name() Fn { return HexDecodeFn }
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"time"
+)
+
+// Hour returns the hour within the day of the given timestamp. The
+// timestamp is converted to the time zone if the time zone is specified.
+type Hour struct{}
+
+func (f Hour) Call(args []interface{}) (interface{}, bool) {
+	if len(args) < 1 {
+		return false, false
+	}
+	t, ok := parseTime(0, args)
+	if !ok {
+		// time of the day string, e.g. 17:05:32. The time
+		// of the day can't be converted to the time zone
+		d, ok := parseClock(parseString(0, args))
+		if !ok || parseString(1, args) != "" {
+			return false, false
+		}
+		return int(d / time.Hour), true
+	}
+	t, ok = inLocation(t, 1, args)
+	if !ok {
+		return false, false
+	}
+	return t.Hour(), true
+}
+
+func (f Hour) Desc() FunctionDesc {
+	desc := FunctionDesc{
+		Name: HourFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "timestamp", Types: []ArgType{Field, Func, String}, Required: true, Timestamp: true},
+			{Keyword: "tz", Types: []ArgType{String}},
+		},
+		ArgsValidationFunc: func(args []string) error {
+			if len(args) > 1 {
+				if _, ok := parseClock(args[0]); ok {
+					return fmt.Errorf("time zone can't be applied to the time of the day %s", args[0])
+				}
+				return validateLocation(args[1])
+			}
+			return nil
+		},
+	}
+	return desc
+}
+
+func (f Hour) Name() Fn { return HourFn }
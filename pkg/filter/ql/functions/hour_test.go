@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHour(t *testing.T) {
+	ts := time.Date(2022, time.March, 5, 23, 15, 0, 0, time.UTC)
+
+	var tests = []struct {
+		args     []interface{}
+		expected interface{}
+	}{
+		{
+			[]interface{}{ts},
+			23,
+		},
+		{
+			[]interface{}{ts, "Europe/Madrid"},
+			0,
+		},
+		{
+			[]interface{}{ts, "America/New_York"},
+			18,
+		},
+		{
+			[]interface{}{"17:05:32"},
+			17,
+		},
+		{
+			[]interface{}{"17:05:32", "Europe/Madrid"},
+			false,
+		},
+		{
+			[]interface{}{"2022-03-05T06:30:00Z"},
+			6,
+		},
+		{
+			[]interface{}{ts, "Mars/Olympus"},
+			false,
+		},
+		{
+			[]interface{}{time.Time{}},
+			false,
+		},
+		{
+			[]interface{}{nil},
+			false,
+		},
+	}
+
+	for i, tt := range tests {
+		f := Hour{}
+		res, _ := f.Call(tt.args)
+		assert.Equal(t, tt.expected, res, fmt.Sprintf("%d. result mismatch: exp=%v got=%v", i, tt.expected, res))
+	}
+}
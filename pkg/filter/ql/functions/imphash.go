@@ -0,0 +1,64 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"strings"
+
+	"github.com/rabbitstack/fibratus/pkg/util/hashers"
+)
+
+// Imphash computes the import hash from the list of imported functions.
+// Imports are given in the library.function form, e.g. kernel32.dll.CreateFileW.
+// The string argument is interpreted as the comma-separated list of imports.
+type Imphash struct{}
+
+func (f Imphash) Call(args []interface{}) (interface{}, bool) {
+	if len(args) != 1 {
+		return false, false
+	}
+
+	var imports []string
+	switch v := args[0].(type) {
+	case []string:
+		imports = v
+	case string:
+		imports = strings.Split(v, ",")
+		for i, imp := range imports {
+			imports[i] = strings.TrimSpace(imp)
+		}
+	}
+
+	hash := hashers.Imphash(imports)
+	if hash == "" {
+		return false, false
+	}
+	return hash, true
+}
+
+func (f Imphash) Desc() FunctionDesc {
+	return FunctionDesc{
+		Name: ImphashFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "imports", Types: []ArgType{Field, String, Func, Slice}, Required: true},
+		},
+	}
+}
+
+func (f Imphash) Name() Fn { return ImphashFn }
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImphash(t *testing.T) {
+	var tests = []struct {
+		args     []interface{}
+		expected interface{}
+	}{
+		{
+			[]interface{}{[]string{"kernel32.dll.CreateFileW", "kernel32.dll.CloseHandle", "user32.dll.MessageBoxA"}},
+			"28e7cf1bf0a237602e55689d52623b82",
+		},
+		{
+			[]interface{}{"KERNEL32.dll.CreateFileW, KERNEL32.dll.CloseHandle, USER32.dll.MessageBoxA"},
+			"28e7cf1bf0a237602e55689d52623b82",
+		},
+		{
+			[]interface{}{[]string{"CreateFileW:KERNEL32.dll", "CloseHandle:KERNEL32.dll", "MessageBoxA:USER32.dll"}},
+			"28e7cf1bf0a237602e55689d52623b82",
+		},
+		{
+			[]interface{}{[]string{"kernel32.dll.CloseHandle", "kernel32.dll.CreateFileW", "user32.dll.MessageBoxA"}},
+			"211c2743772dc6e8ee9949f5d5808265",
+		},
+		{
+			[]interface{}{[]string{"CreateFileW"}},
+			false,
+		},
+		{
+			[]interface{}{1},
+			false,
+		},
+	}
+
+	for i, tt := range tests {
+		f := Imphash{}
+		res, _ := f.Call(tt.args)
+		assert.Equal(t, tt.expected, res, fmt.Sprintf("%d. result mismatch: exp=%v got=%v", i, tt.expected, res))
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"time"
+)
+
+// InTimeRange determines if the time of the day falls within the range given by the
+// start and end times of the day. The range spans midnight if the end time precedes
+// the start time. The parser supplies the event timestamp if the timestamp is omitted.
+// The timestamp is converted to the time zone if the time zone is specified.
+type InTimeRange struct{}
+
+func (f InTimeRange) Call(args []interface{}) (interface{}, bool) {
+	if len(args) < 4 {
+		return false, false
+	}
+	start, ok := parseClock(parseString(0, args))
+	if !ok {
+		return false, false
+	}
+	end, ok := parseClock(parseString(1, args))
+	if !ok {
+		return false, false
+	}
+	t, ok := parseTime(3, args)
+	if !ok {
+		// time of the day string, e.g. 17:05:32
+		c, ok := parseClock(parseString(3, args))
+		if !ok {
+			return false, false
+		}
+		return inTimeRange(c, start, end), true
+	}
+	t, ok = inLocation(t, 2, args)
+	if !ok {
+		return false, false
+	}
+	return inTimeRange(clock(t), start, end), true
+}
+
+func (f InTimeRange) Desc() FunctionDesc {
+	desc := FunctionDesc{
+		Name: InTimeRangeFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "start", Types: []ArgType{String}, Required: true},
+			{Keyword: "end", Types: []ArgType{String}, Required: true},
+			{Keyword: "tz", Types: []ArgType{String}},
+			{Keyword: "timestamp", Types: []ArgType{Field, Func, String}, Timestamp: true},
+		},
+		ArgsValidationFunc: func(args []string) error {
+			for _, arg := range args[:2] {
+				if _, ok := parseClock(arg); !ok {
+					return fmt.Errorf("%s is not a valid time of the day. Expected HH:MM or HH:MM:SS format", arg)
+				}
+			}
+			if len(args) > 2 {
+				return validateLocation(args[2])
+			}
+			return nil
+		},
+	}
+	return desc
+}
+
+func (f InTimeRange) Name() Fn { return InTimeRangeFn }
+
+func inTimeRange(c, start, end time.Duration) bool {
+	if start <= end {
+		return c >= start && c < end
+	}
+	return c >= start || c < end
+}
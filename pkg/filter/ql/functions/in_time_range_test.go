@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInTimeRange(t *testing.T) {
+	ts := time.Date(2022, time.March, 5, 23, 15, 0, 0, time.UTC)
+
+	var tests = []struct {
+		args     []interface{}
+		expected interface{}
+	}{
+		{
+			[]interface{}{"22:00", "06:00", "UTC", ts},
+			true,
+		},
+		{
+			[]interface{}{"22:00", "06:00", "UTC", ts.Add(7 * time.Hour)},
+			false,
+		},
+		{
+			[]interface{}{"22:00", "06:00", "America/New_York", ts},
+			false,
+		},
+		{
+			[]interface{}{"09:00", "18:30:00", "America/New_York", ts},
+			true,
+		},
+		{
+			[]interface{}{"09:00", "17:00", "UTC", "17:00:00"},
+			false,
+		},
+		{
+			[]interface{}{"09:00", "17:00", "UTC", "08:59:59"},
+			false,
+		},
+		{
+			[]interface{}{"09:00", "17:00", "UTC", "09:00:00"},
+			true,
+		},
+		{
+			[]interface{}{"9", "17:00"},
+			false,
+		},
+		{
+			[]interface{}{"09:00", "17:00"},
+			false,
+		},
+	}
+
+	for i, tt := range tests {
+		f := InTimeRange{}
+		res, _ := f.Call(tt.args)
+		assert.Equal(t, tt.expected, res, fmt.Sprintf("%d. result mismatch: exp=%v got=%v", i, tt.expected, res))
+	}
+
+	validate := InTimeRange{}.Desc().ArgsValidationFunc
+	assert.NoError(t, validate([]string{"22:00", "06:00", "Europe/Madrid"}))
+	assert.Error(t, validate([]string{"22", "06:00"}))
+	assert.Error(t, validate([]string{"22:00", "25:00"}))
+	assert.Error(t, validate([]string{"22:00", "06:00", "Mars/Olympus"}))
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+const (
+	// jaroWinklerBoostThreshold is the Jaro similarity above which the common prefix boost is applied
+	jaroWinklerBoostThreshold = 0.7
+	// jaroWinklerPrefixSize is the maximum length of the common prefix
+	jaroWinklerPrefixSize = 4
+	// jaroWinklerScale is the weight given to the common prefix
+	jaroWinklerScale = 0.1
+)
+
+// JaroWinkler computes the Jaro-Winkler similarity between two strings. The
+// similarity is in the range of 0 to 1, where 1 means the strings are equal.
+// Strings that share the common prefix are given the higher similarity, which
+// makes the metric suitable for comparing short strings such as file names.
+type JaroWinkler struct{}
+
+func (f JaroWinkler) Call(args []interface{}) (interface{}, bool) {
+	if len(args) != 2 {
+		return false, false
+	}
+	s1, ok := args[0].(string)
+	if !ok {
+		return false, false
+	}
+	s2, ok := args[1].(string)
+	if !ok {
+		return false, false
+	}
+	return jaroWinkler([]rune(s1), []rune(s2)), true
+}
+
+func (f JaroWinkler) Desc() FunctionDesc {
+	return FunctionDesc{
+		Name: JaroWinklerFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "string1", Types: []ArgType{Field, String, Func}, Required: true},
+			{Keyword: "string2", Types: []ArgType{Field, String, Func}, Required: true},
+		},
+	}
+}
+
+func (f JaroWinkler) Name() Fn { return JaroWinklerFn }
+
+func jaroWinkler(s1, s2 []rune) float64 {
+	sim := jaro(s1, s2)
+	if sim <= jaroWinklerBoostThreshold {
+		return sim
+	}
+	var prefix int
+	for prefix < len(s1) && prefix < len(s2) && prefix < jaroWinklerPrefixSize && s1[prefix] == s2[prefix] {
+		prefix++
+	}
+	return sim + float64(prefix)*jaroWinklerScale*(1-sim)
+}
+
+func jaro(s1, s2 []rune) float64 {
+	if len(s1) == 0 && len(s2) == 0 {
+		return 1
+	}
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+	// characters are considered matching only if
+	// they are not farther than the match distance
+	dist := len(s1)
+	if len(s2) > dist {
+		dist = len(s2)
+	}
+	dist = dist/2 - 1
+	if dist < 0 {
+		dist = 0
+	}
+
+	matches1 := make([]bool, len(s1))
+	matches2 := make([]bool, len(s2))
+	var m int
+	for i := range s1 {
+		start, end := i-dist, i+dist+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(s2) {
+			end = len(s2)
+		}
+		for j := start; j < end; j++ {
+			if matches2[j] || s1[i] != s2[j] {
+				continue
+			}
+			matches1[i], matches2[j] = true, true
+			m++
+			break
+		}
+	}
+	if m == 0 {
+		return 0
+	}
+
+	// count matching characters that appear in different order
+	var t, j int
+	for i := range s1 {
+		if !matches1[i] {
+			continue
+		}
+		for !matches2[j] {
+			j++
+		}
+		if s1[i] != s2[j] {
+			t++
+		}
+		j++
+	}
+
+	mf := float64(m)
+	return (mf/float64(len(s1)) + mf/float64(len(s2)) + (mf-float64(t)/2)/mf) / 3
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJaroWinkler(t *testing.T) {
+	var tests = []struct {
+		args     []interface{}
+		expected float64
+	}{
+		{
+			[]interface{}{"svchost.exe", "svchost.exe"},
+			1,
+		},
+		{
+			[]interface{}{"MARTHA", "MARHTA"},
+			0.9611,
+		},
+		{
+			[]interface{}{"DIXON", "DICKSONX"},
+			0.8133,
+		},
+		{
+			[]interface{}{"svchost.exe", "scvhost.exe"},
+			0.9727,
+		},
+		{
+			[]interface{}{"explorer.exe", "notepad.exe"},
+			0.5732,
+		},
+		{
+			[]interface{}{"", "cmd.exe"},
+			0,
+		},
+	}
+
+	for i, tt := range tests {
+		f := JaroWinkler{}
+		res, ok := f.Call(tt.args)
+		assert.True(t, ok)
+		assert.InDelta(t, tt.expected, res, 0.0001, fmt.Sprintf("%d. result mismatch: exp=%v got=%v", i, tt.expected, res))
+	}
+
+	f := JaroWinkler{}
+	res, ok := f.Call([]interface{}{"cmd.exe", 1})
+	assert.False(t, ok)
+	assert.Equal(t, false, res)
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+// maxLevenshteinLength is the maximum number of characters in each of the
+// strings for which the edit distance is computed. The computation cost grows
+// with the product of string lengths, so the function doesn't produce the
+// value for longer strings, e.g. command lines.
+const maxLevenshteinLength = 256
+
+// Levenshtein computes the edit distance between two strings, that is, the
+// minimum number of single character insertions, deletions, or substitutions
+// required to transform one string into the other.
+type Levenshtein struct{}
+
+func (f Levenshtein) Call(args []interface{}) (interface{}, bool) {
+	if len(args) != 2 {
+		return false, false
+	}
+	s1, ok := args[0].(string)
+	if !ok {
+		return false, false
+	}
+	s2, ok := args[1].(string)
+	if !ok {
+		return false, false
+	}
+	r1, r2 := []rune(s1), []rune(s2)
+	if len(r1) > maxLevenshteinLength || len(r2) > maxLevenshteinLength {
+		return false, false
+	}
+	return levenshtein(r1, r2), true
+}
+
+func (f Levenshtein) Desc() FunctionDesc {
+	return FunctionDesc{
+		Name: LevenshteinFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "string1", Types: []ArgType{Field, String, Func}, Required: true},
+			{Keyword: "string2", Types: []ArgType{Field, String, Func}, Required: true},
+		},
+	}
+}
+
+func (f Levenshtein) Name() Fn { return LevenshteinFn }
+
+func levenshtein(s1, s2 []rune) int {
+	if len(s1) < len(s2) {
+		s1, s2 = s2, s1
+	}
+	// only the previous row of the distance
+	// matrix is required to compute the next one
+	row := make([]int, len(s2)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(s1); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(s2); j++ {
+			cost := 1
+			if s1[i-1] == s2[j-1] {
+				cost = 0
+			}
+			cur := row[j]
+			row[j] = minInt(row[j]+1, minInt(row[j-1]+1, prev+cost))
+			prev = cur
+		}
+	}
+	return row[len(s2)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
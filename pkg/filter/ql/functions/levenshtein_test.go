@@ -0,0 +1,77 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevenshtein(t *testing.T) {
+	var tests = []struct {
+		args     []interface{}
+		expected interface{}
+	}{
+		{
+			[]interface{}{"svchost.exe", "svchost.exe"},
+			0,
+		},
+		{
+			[]interface{}{"svchost.exe", "scvhost.exe"},
+			2,
+		},
+		{
+			[]interface{}{"lsass.exe", "lsas.exe"},
+			1,
+		},
+		{
+			[]interface{}{"kitten", "sitting"},
+			3,
+		},
+		{
+			[]interface{}{"", "cmd.exe"},
+			7,
+		},
+		{
+			[]interface{}{"csrss.exe", "csrß.exe"},
+			2,
+		},
+		{
+			[]interface{}{"cmd.exe", 1},
+			false,
+		},
+		{
+			[]interface{}{strings.Repeat("a", 257), "cmd.exe"},
+			false,
+		},
+		{
+			[]interface{}{strings.Repeat("a", 256), strings.Repeat("b", 256)},
+			256,
+		},
+	}
+
+	for i, tt := range tests {
+		f := Levenshtein{}
+		res, _ := f.Call(tt.args)
+		assert.Equal(t, tt.expected, res, fmt.Sprintf("%d. result mismatch: exp=%v got=%v", i, tt.expected, res))
+	}
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSHA1Call(t *testing.T) {
+	call := SHA1{}
+
+	res, _ := call.Call([]interface{}{`HKEY_LOCAL_MACHINE\SYSTEM\Setup\Pid`})
+	assert.Equal(t, "00ba947d7664f7ab4d33b24247ea055bcbb9abe9", res)
+}
+
+func TestSHA1Desc(t *testing.T) {
+	call := SHA1{}
+	desc := call.Desc()
+
+	assert.Equal(t, desc.RequiredArgs(), 1)
+	assert.Len(t, desc.Args, 1)
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSHA256Call(t *testing.T) {
+	call := SHA256{}
+
+	res, _ := call.Call([]interface{}{`HKEY_LOCAL_MACHINE\SYSTEM\Setup\Pid`})
+	assert.Equal(t, "4b7dde24f59b5a7599ab7db88ddaa0fbe849d541bf58f16c5dfcd4d77b355e0e", res)
+}
+
+func TestSHA256Desc(t *testing.T) {
+	call := SHA256{}
+	desc := call.Desc()
+
+	assert.Equal(t, desc.RequiredArgs(), 1)
+	assert.Len(t, desc.Args, 1)
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// spamsumLength is the maximum length of the ssdeep signature
+	spamsumLength = 64
+	// rollingWindow is the size of the rolling hash window
+	rollingWindow = 7
+	// minBlocksize is the smallest ssdeep block size
+	minBlocksize = 3
+)
+
+// SsdeepCompare computes the similarity score between two ssdeep fuzzy
+// hashes. The score is in the range of 0 to 100, where 0 means there is
+// no similarity and 100 means the hashes are identical. Hashes are given
+// in the blocksize:signature:signature format and can only be compared
+// if their block sizes are equal, or one is twice the size of the other.
+type SsdeepCompare struct{}
+
+func (f SsdeepCompare) Call(args []interface{}) (interface{}, bool) {
+	if len(args) != 2 {
+		return false, false
+	}
+	h1, ok := parseSsdeep(parseString(0, args))
+	if !ok {
+		return false, false
+	}
+	h2, ok := parseSsdeep(parseString(1, args))
+	if !ok {
+		return false, false
+	}
+	return h1.compare(h2), true
+}
+
+func (f SsdeepCompare) Desc() FunctionDesc {
+	return FunctionDesc{
+		Name: SsdeepCompareFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "hash1", Types: []ArgType{Field, String, Func}, Required: true},
+			{Keyword: "hash2", Types: []ArgType{Field, String, Func}, Required: true},
+		},
+	}
+}
+
+func (f SsdeepCompare) Name() Fn { return SsdeepCompareFn }
+
+type ssdeepHash struct {
+	blockSize uint64
+	// sig1 is the signature computed with the block size
+	sig1 string
+	// sig2 is the signature computed with the double block size
+	sig2 string
+}
+
+func parseSsdeep(s string) (ssdeepHash, bool) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 {
+		return ssdeepHash{}, false
+	}
+	blockSize, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil || blockSize < minBlocksize {
+		return ssdeepHash{}, false
+	}
+	sig2 := parts[2]
+	// strip the file name that follows the hash in ssdeep output
+	if n := strings.IndexByte(sig2, ','); n >= 0 {
+		sig2 = sig2[:n]
+	}
+	if len(parts[1]) > spamsumLength || len(sig2) > spamsumLength {
+		return ssdeepHash{}, false
+	}
+	return ssdeepHash{blockSize: blockSize, sig1: eliminateSequences(parts[1]), sig2: eliminateSequences(sig2)}, true
+}
+
+func (h ssdeepHash) compare(o ssdeepHash) int {
+	if h.blockSize != o.blockSize && h.blockSize*2 != o.blockSize && o.blockSize*2 != h.blockSize {
+		return 0
+	}
+	if h.blockSize == o.blockSize && h.sig1 == o.sig1 {
+		return 100
+	}
+	switch {
+	case h.blockSize == o.blockSize:
+		score1 := scoreSignatures(h.sig1, o.sig1, h.blockSize)
+		score2 := scoreSignatures(h.sig2, o.sig2, h.blockSize*2)
+		if score1 > score2 {
+			return score1
+		}
+		return score2
+	case h.blockSize*2 == o.blockSize:
+		return scoreSignatures(h.sig2, o.sig1, o.blockSize)
+	default:
+		return scoreSignatures(h.sig1, o.sig2, h.blockSize)
+	}
+}
+
+// eliminateSequences truncates sequences of the same character longer
+// than three characters. Such sequences carry little information and
+// would otherwise inflate the score.
+func eliminateSequences(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if i >= 3 && s[i] == s[i-1] && s[i] == s[i-2] && s[i] == s[i-3] {
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// scoreSignatures scores two signatures computed with the same block size.
+func scoreSignatures(s1, s2 string, blockSize uint64) int {
+	if !hasCommonSubstring(s1, s2) {
+		return 0
+	}
+	// scale the edit distance by the signature
+	// lengths to produce the score from 0 to 100
+	score := uint64(editDistance(s1, s2)) * spamsumLength / uint64(len(s1)+len(s2))
+	score = 100 * score / spamsumLength
+	if score >= 100 {
+		return 0
+	}
+	score = 100 - score
+	// small block sizes can't produce the perfect
+	// match score unless the signatures are long enough
+	if blockSize >= (99+rollingWindow)/rollingWindow*minBlocksize {
+		return int(score)
+	}
+	n := len(s1)
+	if len(s2) < n {
+		n = len(s2)
+	}
+	if limit := blockSize / minBlocksize * uint64(n); score > limit {
+		return int(limit)
+	}
+	return int(score)
+}
+
+// hasCommonSubstring determines if signatures share a substring
+// as long as the rolling hash window.
+func hasCommonSubstring(s1, s2 string) bool {
+	if len(s1) < rollingWindow || len(s2) < rollingWindow {
+		return false
+	}
+	windows := make(map[string]struct{}, len(s1)-rollingWindow+1)
+	for i := 0; i+rollingWindow <= len(s1); i++ {
+		windows[s1[i:i+rollingWindow]] = struct{}{}
+	}
+	for i := 0; i+rollingWindow <= len(s2); i++ {
+		if _, ok := windows[s2[i:i+rollingWindow]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistance computes the edit distance where the insertion and
+// deletion have the cost of one, while the substitution costs two.
+func editDistance(s1, s2 string) int {
+	row := make([]int, len(s2)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(s1); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(s2); j++ {
+			cost := 2
+			if s1[i-1] == s2[j-1] {
+				cost = 0
+			}
+			cur := row[j]
+			row[j] = minInt(row[j]+1, minInt(row[j-1]+1, prev+cost))
+			prev = cur
+		}
+	}
+	return row[len(s2)]
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSsdeepCompare(t *testing.T) {
+	hash := "96:KQhaGCVZGhr83h3bc0ok3892m12wzgnH5w2pw+sxNEI58:FIVkH4x73h39LH+2w+sxaD"
+
+	var tests = []struct {
+		args     []interface{}
+		expected interface{}
+	}{
+		{
+			[]interface{}{hash, hash},
+			100,
+		},
+		{
+			[]interface{}{hash, hash + `,"C:\Windows\Temp\dropper.exe"`},
+			100,
+		},
+		{
+			[]interface{}{hash, "96:KQhaGCVZGhr83h3bc0ok3892m12wzgnH5w2pwZZxNEI58:FIVkH4x73h39LH+2wZZxaD"},
+			97,
+		},
+		{
+			[]interface{}{hash, "96:MD9fHjsEuddrg31904l8bgx5ROg2MQZHZqpAlycowOsexbHDbk:MJwz/l2PqGqqbr2yk6pVgrwPV"},
+			0,
+		},
+		{
+			// the block size is twice the size of the first hash block size
+			[]interface{}{hash, "192:FIVkH4x73h39LH+2w+sxaDzzz:abcdefghijklmn"},
+			94,
+		},
+		{
+			[]interface{}{hash, "48:KQhaGCVZGhr83h3bcXXXXXXXXX:KQhaGCVZGhr83h3bc0ok3892m12wzgnH5w2pwZZxNEI58"},
+			97,
+		},
+		{
+			// incompatible block sizes
+			[]interface{}{hash, "384:KQhaGCVZGhr83h3bc0ok3892m12wzgnH5w2pw+sxNEI58:FIVkH4x73h39LH+2w+sxaD"},
+			0,
+		},
+		{
+			// the score is limited for small block sizes
+			[]interface{}{"3:aaaaaaaaabcdefg:abc", "3:aaabcdefgh:abc"},
+			9,
+		},
+		{
+			[]interface{}{hash, "e1105070ba828007508566e28a2b8d4c"},
+			false,
+		},
+	}
+
+	for i, tt := range tests {
+		f := SsdeepCompare{}
+		res, _ := f.Call(tt.args)
+		assert.Equal(t, tt.expected, res, fmt.Sprintf("%d. result mismatch: exp=%v got=%v", i, tt.expected, res))
+	}
+}
@@ -72,6 +72,40 @@ const (
 	GetRegValueFn
 	// YaraFn represents the YARA function
 	YaraFn
+	// HourFn represents the HOUR function
+	HourFn
+	// WeekdayFn represents the WEEKDAY function
+	WeekdayFn
+	// DateDiffFn represents the DATE_DIFF function
+	DateDiffFn
+	// InTimeRangeFn represents the IN_TIME_RANGE function
+	InTimeRangeFn
+	// Base64DecodeFn represents the BASE64_DECODE function
+	Base64DecodeFn
+	// HexDecodeFn represents the HEX_DECODE function
+	HexDecodeFn
+	// URLDecodeFn represents the URL_DECODE function
+	URLDecodeFn
+	// XorDecodeFn represents the XOR_DECODE function
+	XorDecodeFn
+	// CmdlineArgsFn represents the CMDLINE_ARGS function
+	CmdlineArgsFn
+	// CmdlineFlagFn represents the CMDLINE_FLAG function
+	CmdlineFlagFn
+	// SHA1Fn represents the SHA1 function
+	SHA1Fn
+	// SHA256Fn represents the SHA256 function
+	SHA256Fn
+	// ImphashFn represents the IMPHASH function
+	ImphashFn
+	// LevenshteinFn represents the LEVENSHTEIN function
+	LevenshteinFn
+	// JaroWinklerFn represents the JARO_WINKLER function
+	JaroWinklerFn
+	// SsdeepCompareFn represents the SSDEEP_COMPARE function
+	SsdeepCompareFn
+	// CoalesceFn represents the COALESCE function
+	CoalesceFn
 )
 
 // ArgType is the type alias for the argument value type.
@@ -147,6 +181,10 @@ type FunctionArgDesc struct {
 	Keyword  string
 	Required bool
 	Types    []ArgType
+	// Timestamp indicates the argument expects the timestamp.
+	// The time of the day field given in such argument is
+	// resolved to the full event timestamp.
+	Timestamp bool
 }
 
 // ContainsType returns true if the argument satisfies the given argument type.
@@ -208,6 +246,40 @@ func (f Fn) String() string {
 		return "GET_REG_VALUE"
 	case YaraFn:
 		return "YARA"
+	case HourFn:
+		return "HOUR"
+	case WeekdayFn:
+		return "WEEKDAY"
+	case DateDiffFn:
+		return "DATE_DIFF"
+	case InTimeRangeFn:
+		return "IN_TIME_RANGE"
+	case Base64DecodeFn:
+		return "BASE64_DECODE"
+	case HexDecodeFn:
+		return "HEX_DECODE"
+	case URLDecodeFn:
+		return "URL_DECODE"
+	case XorDecodeFn:
+		return "XOR_DECODE"
+	case CmdlineArgsFn:
+		return "CMDLINE_ARGS"
+	case CmdlineFlagFn:
+		return "CMDLINE_FLAG"
+	case SHA1Fn:
+		return "SHA1"
+	case SHA256Fn:
+		return "SHA256"
+	case ImphashFn:
+		return "IMPHASH"
+	case LevenshteinFn:
+		return "LEVENSHTEIN"
+	case JaroWinklerFn:
+		return "JARO_WINKLER"
+	case SsdeepCompareFn:
+		return "SSDEEP_COMPARE"
+	case CoalesceFn:
+		return "COALESCE"
 	default:
 		return "UNDEFINED"
 	}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+// Weekday returns the name of the week day of the given timestamp. The
+// timestamp is converted to the time zone if the time zone is specified.
+type Weekday struct{}
+
+func (f Weekday) Call(args []interface{}) (interface{}, bool) {
+	if len(args) < 1 {
+		return false, false
+	}
+	t, ok := parseTime(0, args)
+	if !ok {
+		return false, false
+	}
+	t, ok = inLocation(t, 1, args)
+	if !ok {
+		return false, false
+	}
+	return t.Weekday().String(), true
+}
+
+func (f Weekday) Desc() FunctionDesc {
+	desc := FunctionDesc{
+		Name: WeekdayFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "timestamp", Types: []ArgType{Field, Func, String}, Required: true, Timestamp: true},
+			{Keyword: "tz", Types: []ArgType{String}},
+		},
+		ArgsValidationFunc: func(args []string) error {
+			if len(args) > 1 {
+				return validateLocation(args[1])
+			}
+			return nil
+		},
+	}
+	return desc
+}
+
+func (f Weekday) Name() Fn { return WeekdayFn }
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+// XorDecode applies the XOR operation to every byte of the string with
+// the key. The string key is repeated over the input, while the numeric
+// key is the single byte key.
+type XorDecode struct{}
+
+func (f XorDecode) Call(args []interface{}) (interface{}, bool) {
+	if len(args) < 2 {
+		return false, false
+	}
+	s := parseString(0, args)
+	var key []byte
+	switch k := args[1].(type) {
+	case string:
+		key = []byte(k)
+	case int64:
+		if k < 0 || k > 0xff {
+			return false, false
+		}
+		key = []byte{byte(k)}
+	case int:
+		if k < 0 || k > 0xff {
+			return false, false
+		}
+		key = []byte{byte(k)}
+	}
+	if len(key) == 0 {
+		return false, false
+	}
+	b := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		b[i] = s[i] ^ key[i%len(key)]
+	}
+	return string(b), true
+}
+
+func (f XorDecode) Desc() FunctionDesc {
+	desc := FunctionDesc{
+		Name: XorDecodeFn,
+		Args: []FunctionArgDesc{
+			{Keyword: "string", Types: []ArgType{Field, Func, String}, Required: true},
+			{Keyword: "key", Types: []ArgType{String, Number}, Required: true},
+		},
+	}
+	return desc
+}
+
+func (f XorDecode) Name() Fn { return XorDecodeFn }
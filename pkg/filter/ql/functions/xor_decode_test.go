@@ -0,0 +1,56 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXorDecode(t *testing.T) {
+	var tests = []struct {
+		args     []interface{}
+		expected interface{}
+	}{
+		{
+			[]interface{}{"\x22\x2c\x25\x6c\x24\x39\x24", int64(0x41)},
+			"cmd-exe",
+		},
+		{
+			[]interface{}{"\x08\x08\x1d\x45\x00\x01\x0e", "key"},
+			"cmd.exe",
+		},
+		{
+			[]interface{}{"cmd.exe", ""},
+			false,
+		},
+		{
+			[]interface{}{"cmd.exe", int64(256)},
+			false,
+		},
+	}
+
+	for i, tt := range tests {
+		f := XorDecode{}
+		res, _ := f.Call(tt.args)
+		assert.Equal(t, tt.expected, res, fmt.Sprintf("%d. result mismatch: exp=%v got=%v", i, tt.expected, res))
+	}
+}
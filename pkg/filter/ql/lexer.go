@@ -101,6 +101,18 @@ func (s *scanner) scan() (tok token, pos int, lit string) {
 		}
 		s.r.unread()
 		return Lt, pos, ""
+	case '+':
+		return Add, pos, ""
+	case '-':
+		return Sub, pos, ""
+	case '*':
+		return Mul, pos, ""
+	case '/':
+		return Div, pos, ""
+	case '%':
+		return Mod, pos, ""
+	case '&':
+		return BitAnd, pos, ""
 	case '(':
 		return Lparen, pos, ""
 	case ')':
@@ -53,6 +53,14 @@ func TestScanner(t *testing.T) {
 		{s: `IN`, tok: In},
 		{s: `in`, tok: In},
 
+		// arithmetic operators
+		{s: `+`, tok: Add},
+		{s: `-`, tok: Sub},
+		{s: `*`, tok: Mul},
+		{s: `/`, tok: Div},
+		{s: `%`, tok: Mod},
+		{s: `&`, tok: BitAnd},
+
 		// misc tokens
 		{s: `(`, tok: Lparen},
 		{s: `)`, tok: Rparen},
@@ -21,6 +21,7 @@ package ql
 import (
 	"bytes"
 	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/config"
 	"github.com/rabbitstack/fibratus/pkg/filter/fields"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/util/hashers"
@@ -58,6 +59,11 @@ type DecimalLiteral struct {
 	Value float64
 }
 
+// DurationLiteral represents the time duration literal, e.g. 5m or 1h30m.
+type DurationLiteral struct {
+	Value time.Duration
+}
+
 // BoolLiteral represents the logical true/false literal.
 type BoolLiteral struct {
 	Value bool
@@ -72,6 +78,11 @@ type BoundFieldLiteral struct {
 	Value string
 }
 
+// VariableLiteral references the collection element bound by the quantifier.
+type VariableLiteral struct {
+	Value string
+}
+
 func (i IPLiteral) String() string {
 	return i.Value.String()
 }
@@ -96,10 +107,31 @@ func (d DecimalLiteral) String() string {
 	return strconv.FormatFloat(d.Value, 'e', -1, 64)
 }
 
+// String returns the duration in the largest unit the duration is a multiple of.
+func (d DurationLiteral) String() string {
+	units := []struct {
+		d    time.Duration
+		unit string
+	}{
+		{time.Hour, "h"}, {time.Minute, "m"}, {time.Second, "s"},
+		{time.Millisecond, "ms"}, {time.Microsecond, "us"},
+	}
+	for _, u := range units {
+		if d.Value%u.d == 0 {
+			return strconv.FormatInt(int64(d.Value/u.d), 10) + u.unit
+		}
+	}
+	return strconv.FormatInt(int64(d.Value), 10) + "ns"
+}
+
 func (b BoolLiteral) String() string {
 	return strconv.FormatBool(b.Value)
 }
 
+func (v VariableLiteral) String() string {
+	return v.Value
+}
+
 func (b BoundFieldLiteral) String() string {
 	return b.Value
 }
@@ -139,6 +171,14 @@ func (s *ListLiteral) String() string {
 	return buf.String()
 }
 
+// LookupLiteral represents the reference to the lookup table.
+type LookupLiteral struct {
+	Table *config.LookupTable
+}
+
+// String returns a string representation of the literal.
+func (l *LookupLiteral) String() string { return l.Table.String() }
+
 // Function represents a function call.
 type Function struct {
 	Name string
@@ -166,6 +206,37 @@ func (f *Function) String() string {
 	return fmt.Sprintf("%s(%s)", f.Name, strings.Join(str, ", "))
 }
 
+// resolveTimestamps replaces the time of the day field given
+// in the timestamp argument with the full event timestamp field.
+// The time of the day lacks the date and the time zone, so the
+// time functions wouldn't be able to parse it. The omitted optional
+// timestamp argument defaults to the event timestamp, and optional
+// arguments preceding it are supplied as empty strings.
+func (f *Function) resolveTimestamps() {
+	fn, ok := funcs[strings.ToUpper(f.Name)]
+	if !ok {
+		return
+	}
+	args := fn.Desc().Args
+	for i, expr := range f.Args {
+		if i >= len(args) || !args[i].Timestamp {
+			continue
+		}
+		if field, ok := expr.(*FieldLiteral); ok && fields.Field(field.Value) == fields.KevtTime {
+			f.Args[i] = &FieldLiteral{Value: fields.KevtTimestamp.String()}
+		}
+	}
+	for i := len(f.Args); i < len(args); i++ {
+		if !args[i].Timestamp {
+			continue
+		}
+		for len(f.Args) < i {
+			f.Args = append(f.Args, &StringLiteral{})
+		}
+		f.Args = append(f.Args, &FieldLiteral{Value: fields.KevtTimestamp.String()})
+	}
+}
+
 // validate ensures that the function name obtained
 // from the parser exists within the internal functions
 // catalog. It also validates the function signature to
@@ -193,7 +264,8 @@ func (f *Function) validate() error {
 		arg := fn.Desc().Args[i]
 		typ := functions.Unknown
 		switch reflect.TypeOf(expr) {
-		case reflect.TypeOf(&FieldLiteral{}), reflect.TypeOf(&BoundFieldLiteral{}):
+		case reflect.TypeOf(&FieldLiteral{}), reflect.TypeOf(&BoundFieldLiteral{}),
+			reflect.TypeOf(&VariableLiteral{}), reflect.TypeOf(&IndexExpr{}):
 			typ = functions.Field
 		case reflect.TypeOf(&IPLiteral{}):
 			typ = functions.IP
@@ -201,7 +273,7 @@ func (f *Function) validate() error {
 			typ = functions.String
 		case reflect.TypeOf(&IntegerLiteral{}):
 			typ = functions.Number
-		case reflect.TypeOf(&Function{}):
+		case reflect.TypeOf(&Function{}), reflect.TypeOf(&QuantifierExpr{}):
 			typ = functions.Func
 		case reflect.TypeOf(&ListLiteral{}):
 			typ = functions.Slice
@@ -221,6 +293,10 @@ type SequenceExpr struct {
 	By          fields.Field
 	BoundFields []*BoundFieldLiteral
 	Alias       string
+	// Negated indicates the sequence expression matches
+	// when no event satisfies the expression within the
+	// sequence max span
+	Negated bool
 
 	buckets map[uint32]bool
 }
@@ -297,6 +373,36 @@ func (s Sequence) IsConstrained() bool {
 	return !s.By.IsEmpty() || !s.Expressions[0].By.IsEmpty()
 }
 
+// IsNegated determines if the last expression in the sequence is negated.
+func (s Sequence) IsNegated() bool {
+	return s.Expressions[len(s.Expressions)-1].Negated
+}
+
+// validateNegated checks the negated expression is the last one
+// in the sequence. The absence of the event can only be confirmed
+// when the max span deadline is reached, so the sequence requires
+// the max span.
+func (s Sequence) validateNegated() error {
+	for i, expr := range s.Expressions {
+		if !expr.Negated {
+			continue
+		}
+		if i == 0 {
+			return fmt.Errorf("the first expression in the sequence can't be negated")
+		}
+		if i != len(s.Expressions)-1 {
+			return fmt.Errorf("only the last expression in the sequence can be negated")
+		}
+		if expr.Alias != "" {
+			return fmt.Errorf("negated expression can't be aliased")
+		}
+		if s.MaxSpan == 0 {
+			return fmt.Errorf("sequences with negated expressions require the 'maxspan' statement")
+		}
+	}
+	return nil
+}
+
 func (s Sequence) impairBy() bool {
 	b := make(map[bool]int, len(s.Expressions))
 	for _, expr := range s.Expressions {
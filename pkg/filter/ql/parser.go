@@ -25,8 +25,10 @@ import (
 	"fmt"
 	"github.com/rabbitstack/fibratus/pkg/config"
 	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
 	"github.com/rabbitstack/fibratus/pkg/util/multierror"
 	"net"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -37,6 +39,8 @@ type Parser struct {
 	s    *bufScanner
 	c    *config.Filters
 	expr string
+	// vars contains variables bound by enclosing quantifiers
+	vars []string
 }
 
 // NewParser builds a new parser instance from the expression string.
@@ -94,6 +98,9 @@ func (p *Parser) ParseSequence() (*Sequence, error) {
 			if seq.impairBy() {
 				return nil, fmt.Errorf("%s: all expressions require the 'by' statement", p.expr)
 			}
+			if err := seq.validateNegated(); err != nil {
+				return nil, fmt.Errorf("%s: %v", p.expr, err)
+			}
 			return seq, nil
 		}
 		p.unscan()
@@ -102,6 +109,14 @@ func (p *Parser) ParseSequence() (*Sequence, error) {
 		if tok != Pipe {
 			return nil, newParseError(tokstr(tok, lit), []string{"|"}, posStart, p.expr)
 		}
+		// the expression preceded by the `not` operator
+		// designates the absence of the matching event
+		var negated bool
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok == Not {
+			negated = true
+		} else {
+			p.unscan()
+		}
 		expr, err := p.ParseExpr()
 		if err != nil {
 			return nil, err
@@ -130,6 +145,7 @@ func (p *Parser) ParseSequence() (*Sequence, error) {
 			seqexpr = SequenceExpr{Expr: expr}
 			p.unscan()
 		}
+		seqexpr.Negated = negated
 		seqexpr.init()
 		seqexpr.walk()
 		exprs = append(exprs, seqexpr)
@@ -146,6 +162,213 @@ func (p *Parser) IsSequence() bool {
 	return false
 }
 
+// IsAggregation checks whether the expression given to the parser is an aggregation.
+func (p *Parser) IsAggregation() bool {
+	tok, _, _ := p.scanIgnoreWhitespace()
+	if tok == Aggr {
+		return true
+	}
+	p.unscan()
+	return false
+}
+
+// ParseAggregation parses the windowed aggregation. The aggregation consists
+// of the window duration and optional window type, the optional list of
+// grouping fields, the expression that selects aggregated events enclosed
+// in pipes, and thresholds on aggregate values. This method assumes the
+// AGGREGATE token has already been consumed. For example:
+//
+//	aggregate
+//	window 1m sliding
+//	by ps.pid
+//	|kevt.name = 'CreateFile' and file.status != 'Success'|
+//	having count(distinct file.name) > 20
+func (p *Parser) ParseAggregation() (*Aggregation, error) {
+	agg := &Aggregation{}
+
+	// parse window duration and type
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok != Window {
+		return nil, newParseError(tokstr(tok, lit), []string{"window"}, pos, p.expr)
+	}
+	var err error
+	agg.Window, err = p.parseDuration()
+	if err != nil {
+		return nil, err
+	}
+	if agg.Window <= 0 {
+		return nil, fmt.Errorf("%s: window duration must be positive", p.expr)
+	}
+	tok, pos, lit = p.scanIgnoreWhitespace()
+	switch {
+	case tok == Ident && strings.EqualFold(lit, SlidingWindow.String()):
+		agg.WindowType = SlidingWindow
+	case tok == Ident && strings.EqualFold(lit, TumblingWindow.String()):
+		agg.WindowType = TumblingWindow
+	case tok == Ident:
+		return nil, newParseError(tokstr(tok, lit), []string{"sliding", "tumbling"}, pos, p.expr)
+	default:
+		p.unscan()
+	}
+
+	// parse optional grouping fields
+	tok, _, _ = p.scanIgnoreWhitespace()
+	if tok == By {
+		for {
+			tok, pos, lit := p.scanIgnoreWhitespace()
+			if tok != Field {
+				return nil, newParseError(tokstr(tok, lit), []string{"field"}, pos, p.expr)
+			}
+			agg.By = append(agg.By, fields.Field(lit))
+			if tok, _, _ := p.scanIgnoreWhitespace(); tok != Comma {
+				p.unscan()
+				break
+			}
+		}
+	} else {
+		p.unscan()
+	}
+
+	// parse the expression enclosed in pipes
+	tok, pos, lit = p.scanIgnoreWhitespace()
+	if tok != Pipe {
+		return nil, newParseError(tokstr(tok, lit), []string{"|"}, pos, p.expr)
+	}
+	agg.Expr, err = p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	tok, pos, lit = p.scanIgnoreWhitespace()
+	if tok != Pipe {
+		return nil, newParseError(tokstr(tok, lit), []string{"|"}, pos, p.expr)
+	}
+
+	// parse thresholds
+	tok, pos, lit = p.scanIgnoreWhitespace()
+	if tok != Having {
+		return nil, newParseError(tokstr(tok, lit), []string{"having"}, pos, p.expr)
+	}
+	for {
+		threshold, err := p.parseThreshold()
+		if err != nil {
+			return nil, err
+		}
+		agg.Thresholds = append(agg.Thresholds, threshold)
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		if tok == EOF {
+			return agg, nil
+		}
+		if tok != And {
+			return nil, newParseError(tokstr(tok, lit), []string{"and", "EOF"}, pos, p.expr)
+		}
+	}
+}
+
+// parseThreshold parses the aggregate function call compared to the numeric constant.
+func (p *Parser) parseThreshold() (Threshold, error) {
+	var t Threshold
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok != Ident {
+		return t, newParseError(tokstr(tok, lit), []string{"count", "sum", "min", "max"}, pos, p.expr)
+	}
+	fn := AggregateFn(strings.ToLower(lit))
+	switch fn {
+	case CountFn, SumFn, MinFn, MaxFn:
+	default:
+		return t, newParseError(tokstr(tok, lit), []string{"count", "sum", "min", "max"}, pos, p.expr)
+	}
+	if tok, pos, lit := p.scan(); tok != Lparen {
+		return t, newParseError(tokstr(tok, lit), []string{"'('"}, pos, p.expr)
+	}
+	t.Aggregate.Fn = fn
+
+	tok, pos, lit = p.scanIgnoreWhitespace()
+	if tok == Ident && strings.EqualFold(lit, "distinct") {
+		if fn != CountFn {
+			return t, fmt.Errorf("%s: distinct is only allowed in the count aggregate", p.expr)
+		}
+		t.Aggregate.Distinct = true
+		tok, pos, lit = p.scanIgnoreWhitespace()
+	}
+	switch tok {
+	case Field:
+		t.Aggregate.Field = fields.Field(lit)
+		tok, pos, lit = p.scanIgnoreWhitespace()
+	case Rparen:
+		if fn != CountFn || t.Aggregate.Distinct {
+			return t, newParseError(tokstr(tok, lit), []string{"field"}, pos, p.expr)
+		}
+	default:
+		return t, newParseError(tokstr(tok, lit), []string{"field"}, pos, p.expr)
+	}
+	if tok != Rparen {
+		return t, newParseError(tokstr(tok, lit), []string{"')'"}, pos, p.expr)
+	}
+	if fn == CountFn && !t.Aggregate.Distinct && !t.Aggregate.Field.IsEmpty() {
+		return t, fmt.Errorf("%s: count aggregate accepts either no arguments or the distinct field", p.expr)
+	}
+
+	// parse comparison operator and the constant
+	tok, pos, lit = p.scanIgnoreWhitespace()
+	switch tok {
+	case Gt, Gte, Lt, Lte, Eq, Neq:
+		t.Op = tok
+	default:
+		return t, newParseError(tokstr(tok, lit), []string{">", ">=", "<", "<=", "=", "!="}, pos, p.expr)
+	}
+	tok, pos, lit = p.scanIgnoreWhitespace()
+	if tok != Integer && tok != Decimal {
+		return t, newParseError(tokstr(tok, lit), []string{"number"}, pos, p.expr)
+	}
+	v, err := strconv.ParseFloat(lit, 64)
+	if err != nil {
+		return t, &ParseError{Message: "unable to parse number", Pos: pos}
+	}
+	t.Value = v
+	return t, nil
+}
+
+// Identifier represents the bare identifier found in the expression.
+type Identifier struct {
+	Name string
+	Pos  int
+}
+
+// Idents scans the expression and returns all bare identifiers
+// that don't resolve to keywords, fields, function names or sequence
+// aliases. Such identifiers are either macro references or misspelled
+// fields.
+func Idents(expr string) []Identifier {
+	s := newScanner(strings.NewReader(expr))
+	idents := make([]Identifier, 0)
+
+	var prev token
+	var pending *Identifier
+	for {
+		tok, pos, lit := s.scan()
+		if tok == EOF {
+			break
+		}
+		if tok == WS {
+			continue
+		}
+		// identifier followed by the left
+		// parenthesis is the function name
+		if pending != nil && tok != Lparen {
+			idents = append(idents, *pending)
+		}
+		pending = nil
+		if tok == Ident && prev != As {
+			pending = &Identifier{Name: lit, Pos: pos}
+		}
+		prev = tok
+	}
+	if pending != nil {
+		idents = append(idents, *pending)
+	}
+	return idents
+}
+
 // ParseExpr parses an expression by building the binary expression tree.
 func (p *Parser) ParseExpr() (Expr, error) {
 	var err error
@@ -161,6 +384,13 @@ func (p *Parser) ParseExpr() (Expr, error) {
 	for {
 		// if the next token is NOT an operator then return the expression.
 		op, pos, lit := p.scanIgnoreWhitespace()
+		// the pipe followed by the operand is the bitwise OR operator.
+		// Otherwise, it terminates the sequence or aggregation expression
+		if op == Pipe {
+			if tok, _, _ := p.peekIgnoreWhitespace(); isOperandStart(tok) {
+				op = BitOr
+			}
+		}
 		if !op.isOperator() {
 			p.unscan()
 			if op != EOF && op != Rparen && op != Comma && op != Pipe {
@@ -173,7 +403,7 @@ func (p *Parser) ParseExpr() (Expr, error) {
 			// expect LPAREN after in
 			tok, pos, lit := p.scanIgnoreWhitespace()
 			p.unscan()
-			if tok != Lparen && (p.c != nil && !p.c.IsMacroList(lit)) {
+			if tok != Lparen && !(tok == BoundField && strings.EqualFold(lit, lookupFunc)) && (p.c != nil && !p.c.IsMacroList(lit)) {
 				return nil, newParseError(tokstr(op, lit), []string{"'('"}, pos, p.expr)
 			}
 		}
@@ -273,11 +503,21 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 	switch tok {
 	case Ident:
 		if tok0, _, _ := p.scan(); tok0 == Lparen {
+			if isQuantifier(lit) {
+				return p.parseQuantifier(QuantifierFn(strings.ToLower(lit)))
+			}
 			return p.parseFunction(lit)
 		}
 		// unscan lparen token
 		p.unscan()
 
+		if p.isVar(lit) {
+			return p.parseExists(&VariableLiteral{Value: lit})
+		}
+		if expr, err := p.parseIndex(lit, pos); expr != nil || err != nil {
+			return expr, err
+		}
+
 		// expand macros
 		if p.c != nil {
 			macro := p.c.GetMacro(lit)
@@ -300,15 +540,42 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 	case Str:
 		return &StringLiteral{Value: lit}, nil
 	case Field:
-		return &FieldLiteral{Value: lit}, nil
+		return p.parseExists(&FieldLiteral{Value: lit})
 	case BoundField:
+		if strings.EqualFold(lit, lookupFunc) {
+			return p.parseLookup()
+		}
 		n := strings.Index(lit, ".")
 		if n > 0 && fields.Lookup(lit[n+1:]) == "" {
 			return nil, newParseError(tokstr(tok, lit), []string{"field after bound ref"}, pos+n, p.expr)
 		}
-		return &BoundFieldLiteral{Value: lit}, nil
+		return p.parseExists(&BoundFieldLiteral{Value: lit})
 	case True, False:
 		return &BoolLiteral{Value: tok == True}, nil
+	case Sub:
+		// negative numeric literal
+		tok, pos, lit := p.scan()
+		switch tok {
+		case Integer:
+			v, err := strconv.ParseInt("-"+lit, 10, 64)
+			if err != nil {
+				return nil, &ParseError{Message: "unable to parse integer", Pos: pos}
+			}
+			return &IntegerLiteral{Value: v}, nil
+		case Decimal:
+			v, err := strconv.ParseFloat("-"+lit, 64)
+			if err != nil {
+				return nil, &ParseError{Message: "unable to parse decimal", Pos: pos}
+			}
+			return &DecimalLiteral{Value: v}, nil
+		case Duration:
+			d, err := parseDuration("-" + lit)
+			if err != nil {
+				return nil, &ParseError{Message: err.Error(), Pos: pos}
+			}
+			return &DurationLiteral{Value: d}, nil
+		}
+		return nil, newParseError(tokstr(tok, lit), []string{"number"}, pos, p.expr)
 	case Integer:
 		v, err := strconv.ParseInt(lit, 10, 64)
 		if err != nil {
@@ -326,6 +593,12 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 			return nil, &ParseError{Message: "unable to parse decimal", Pos: pos}
 		}
 		return &DecimalLiteral{Value: v}, nil
+	case Duration:
+		d, err := parseDuration(lit)
+		if err != nil {
+			return nil, &ParseError{Message: err.Error(), Pos: pos}
+		}
+		return &DurationLiteral{Value: d}, nil
 	}
 
 	expectations := []string{"field", "bound field", "string", "number", "bool", "ip", "function"}
@@ -339,6 +612,104 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 	return nil, newParseError(tokstr(tok, lit), expectations, pos, p.expr)
 }
 
+// parseExists parses the optional existence check that follows
+// the field, i.e. field exists, field is null, or field is not null.
+func (p *Parser) parseExists(expr Expr) (Expr, error) {
+	tok, _, _ := p.scanIgnoreWhitespace()
+	switch tok {
+	case Exists:
+		return &ExistsExpr{Expr: expr}, nil
+	case Is:
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		negated := tok == Not
+		if negated {
+			tok, pos, lit = p.scanIgnoreWhitespace()
+		}
+		if tok != Null {
+			return nil, newParseError(tokstr(tok, lit), []string{"null"}, pos, p.expr)
+		}
+		return &ExistsExpr{Expr: expr, Null: !negated}, nil
+	}
+	p.unscan()
+	return expr, nil
+}
+
+// indexRegexp matches the field followed by the element position, e.g. ps.args[2]
+var indexRegexp = regexp.MustCompile(`^(.+)\[(\d+)]$`)
+
+// parseIndex parses the accessor of the slice field element. It
+// returns nil if the identifier doesn't reference the element of
+// the known field.
+func (p *Parser) parseIndex(lit string, pos int) (Expr, error) {
+	groups := indexRegexp.FindStringSubmatch(lit)
+	if len(groups) != 3 {
+		return nil, nil
+	}
+	field := fields.Lookup(groups[1])
+	if field == "" {
+		return nil, nil
+	}
+	if info, ok := fields.Info(field); ok && info.Type != kparams.Slice {
+		return nil, newParseError(tokstr(Ident, lit), []string{"slice field"}, pos, p.expr)
+	}
+	i, err := strconv.Atoi(groups[2])
+	if err != nil {
+		return nil, &ParseError{Message: "unable to parse index", Pos: pos}
+	}
+	return p.parseExists(&IndexExpr{Expr: &FieldLiteral{Value: string(field)}, Index: i})
+}
+
+// parseQuantifier parses the quantifier applied to the collection, e.g.
+// any(ps.modules, m, m iendswith 'dbghelp.dll'). The count quantifier
+// may omit the variable and the predicate. This function assumes the
+// quantifier name and LPAREN have been consumed.
+func (p *Parser) parseQuantifier(fn QuantifierFn) (Expr, error) {
+	coll, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	expr := &QuantifierExpr{Fn: fn, Expr: coll}
+
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok == Rparen && fn == CountQuantifier {
+		return expr, nil
+	}
+	if tok != Comma {
+		return nil, newParseError(tokstr(tok, lit), []string{","}, pos, p.expr)
+	}
+	tok, pos, lit = p.scanIgnoreWhitespace()
+	if tok != Ident {
+		return nil, newParseError(tokstr(tok, lit), []string{"variable"}, pos, p.expr)
+	}
+	expr.Var = lit
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != Comma {
+		return nil, newParseError(tokstr(tok, lit), []string{","}, pos, p.expr)
+	}
+
+	// the variable is only visible in the predicate
+	p.vars = append(p.vars, expr.Var)
+	expr.Pred, err = p.ParseExpr()
+	p.vars = p.vars[:len(p.vars)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != Rparen {
+		return nil, newParseError(tokstr(tok, lit), []string{")"}, pos, p.expr)
+	}
+	return expr, nil
+}
+
+// isVar determines if the identifier references the variable bound by the quantifier.
+func (p *Parser) isVar(name string) bool {
+	for _, v := range p.vars {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Parser) parseList() ([]string, error) {
 	tok, pos, lit := p.scanIgnoreWhitespace()
 	if tok != Str && tok != IP && tok != Integer {
@@ -346,6 +717,12 @@ func (p *Parser) parseList() ([]string, error) {
 	}
 	idents := []string{lit}
 
+	// the list item must be followed by the comma or RPAREN.
+	// Otherwise, this is the grouped expression, e.g. (1024 * 3)
+	if tok, pos, lit := p.peekIgnoreWhitespace(); tok != Comma && tok != Rparen {
+		return []string{}, newParseError(tokstr(tok, lit), []string{"','", "')'"}, pos, p.expr)
+	}
+
 	// parse remaining identifiers
 	for {
 		if tok, _, _ := p.scanIgnoreWhitespace(); tok != Comma {
@@ -411,10 +788,37 @@ func (p *Parser) parseFunction(name string) (*Function, error) {
 	if err := fn.validate(); err != nil {
 		return nil, err
 	}
+	fn.resolveTimestamps()
 
 	return fn, nil
 }
 
+// lookupFunc references lookup tables in expressions, e.g. $lookup('trusted_binaries')
+const lookupFunc = "$lookup"
+
+// parseLookup parses the reference to the lookup table. This
+// function assumes the $lookup token has been consumed.
+func (p *Parser) parseLookup() (*LookupLiteral, error) {
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != Lparen {
+		return nil, newParseError(tokstr(tok, lit), []string{"'('"}, pos, p.expr)
+	}
+	tok, pos, name := p.scanIgnoreWhitespace()
+	if tok != Str {
+		return nil, newParseError(tokstr(tok, name), []string{"lookup table name"}, pos, p.expr)
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != Rparen {
+		return nil, newParseError(tokstr(tok, lit), []string{"')'"}, pos, p.expr)
+	}
+	var table *config.LookupTable
+	if p.c != nil {
+		table = p.c.GetLookup(name)
+	}
+	if table == nil {
+		return nil, newParseError(name, []string{"existing lookup table"}, pos, p.expr)
+	}
+	return &LookupLiteral{Table: table}, nil
+}
+
 // parseDuration parses a string and returns a duration literal.
 func (p *Parser) parseDuration() (time.Duration, error) {
 	tok, pos, lit := p.scanIgnoreWhitespace()
@@ -539,5 +943,27 @@ func (p *Parser) scanIgnoreWhitespace() (tok token, pos int, lit string) {
 	}
 }
 
+// peekIgnoreWhitespace returns the next non-whitespace token without consuming it.
+func (p *Parser) peekIgnoreWhitespace() (tok token, pos int, lit string) {
+	tok, pos, lit = p.scan()
+	if tok != WS {
+		p.unscan()
+		return
+	}
+	tok, pos, lit = p.scan()
+	p.unscan()
+	p.unscan()
+	return
+}
+
+// isOperandStart determines whether the token can start the operand of the binary expression.
+func isOperandStart(tok token) bool {
+	switch tok {
+	case Field, BoundField, Str, Ident, Integer, Decimal, Duration, IP, True, False, Lparen, Sub:
+		return true
+	}
+	return false
+}
+
 // unscan pushes the previously read token back onto the buffer.
 func (p *Parser) unscan() { p.s.unscan() }
@@ -21,6 +21,8 @@ package ql
 import (
 	"errors"
 	"github.com/rabbitstack/fibratus/pkg/config"
+	"net"
+	"strings"
 	"testing"
 	"time"
 )
@@ -64,6 +66,39 @@ func TestParser(t *testing.T) {
 		{expr: "ip_cidr(net.dip) = '24'", err: errors.New("ip_cidr function is undefined. Did you mean one of CIDR_CONTAINS|MD5?")},
 
 		{expr: "ps.name = 'cmd.exe' and not cidr_contains(net.sip, '172.14.0.0')"},
+
+		{expr: "image.size - file.offset > 4096"},
+		{expr: "file.io.size / 1024 > 500"},
+		{expr: "kevt.time.s % 60 = 0 and kevt.pid * 2 + 1 >= -1.5"},
+		{expr: "(1024 * 3) < file.io.size"},
+		{expr: "length(ps.name) - 4 = 3"},
+		{expr: "kevt.pid & 1 = 1 or kevt.pid | 6 != 6"},
+		{expr: "kevt.time.ns - 5m > 0 and kevt.pid != -1h30m"},
+		{expr: "file.io.size / > 500", err: errors.New("file.io.size / > 500" +
+			"	^ expected field, string, number, bool, ip")},
+		{expr: "file.io.size - - 1 > 500", err: errors.New("file.io.size - - 1 > 500" +
+			"	^ expected number")},
+		{expr: "ps.parent.name exists"},
+		{expr: "ps.parent.name is null and ps.name = 'cmd.exe'"},
+		{expr: "ps.parent.name is not null and not ps.parent.name = 'explorer.exe'"},
+		{expr: "coalesce(ps.parent.name, 'unknown') != 'explorer.exe'"},
+		{expr: "ps.parent.name is nul", err: errors.New("ps.parent.name is nul" +
+			"	^ expected null")},
+		{expr: "any(ps.modules, m, m iendswith 'dbghelp.dll')"},
+		{expr: "all(ps.args, a, length(a) < 100 and not a startswith '-enc')"},
+		{expr: "count(ps.args) > 10"},
+		{expr: "count(ps.args, a, a icontains 'http') >= 2"},
+		{expr: "any(ps.modules, m, any(pe.imports, i, i ~= m))"},
+		{expr: "ps.args[2] = '-enc' and ps.args[0] exists"},
+		{expr: "lower(ps.args[1]) = 'bypass'"},
+		{expr: "any(ps.args, a, a = 'x') and a = 'x'", err: errors.New("any(ps.args, a, a = 'x') and a = 'x'" +
+			"	^ expected field, bound field, string, number, bool, ip, function")},
+		{expr: "any(ps.args, 'a', a = 'x')", err: errors.New("any(ps.args, 'a', a = 'x')" +
+			"	^ expected variable")},
+		{expr: "any(ps.args)", err: errors.New("any(ps.args)" +
+			"	^ expected ,")},
+		{expr: "ps.name[1] = 'c'", err: errors.New("ps.name[1] = 'c'" +
+			"	^ expected slice field")},
 	}
 
 	for i, tt := range tests {
@@ -163,6 +198,60 @@ func TestExpandMacros(t *testing.T) {
 	}
 }
 
+func TestLookupTables(t *testing.T) {
+	c := config.FiltersWithLookups(
+		config.NewLookupTable("trusted_binaries", []string{`C:\Windows\System32\svchost.exe`, `C:\Windows\explorer.exe`}),
+		config.NewLookupTable("c2_ranges", []string{"185.220.101.0/24", "45.153.160.2", "2a0b:f4c2::/32"}),
+	)
+	m := map[string]interface{}{
+		"ps.exe":     `c:\windows\explorer.exe`,
+		"ps.modules": []string{`C:\Windows\System32\kernel32.dll`, `C:\Windows\System32\svchost.exe`},
+		"net.dip":    net.ParseIP("185.220.101.7"),
+		"net.sip":    net.ParseIP("2a0b:f4c2:10::1"),
+		"kevt.pid":   uint32(1234),
+	}
+
+	var tests = []struct {
+		expr    string
+		matches bool
+		err     error
+	}{
+		{expr: `ps.exe in $lookup('trusted_binaries')`, matches: false},
+		{expr: `ps.exe iin $LOOKUP('trusted_binaries')`, matches: true},
+		{expr: `ps.exe not iin $lookup('trusted_binaries')`, matches: false},
+		{expr: `ps.modules in $lookup('trusted_binaries')`, matches: true},
+		{expr: `net.dip in $lookup('c2_ranges')`, matches: true},
+		{expr: `net.sip in $lookup('c2_ranges') and ps.exe iin $lookup('trusted_binaries')`, matches: true},
+		{expr: `net.dip in $lookup('trusted_binaries')`, matches: false},
+		{expr: `kevt.pid in $lookup('c2_ranges')`, matches: false},
+		{expr: `ps.exe in $lookup('unknown')`, err: errors.New("ps.exe in $lookup('unknown')" +
+			"	^ expected existing lookup table")},
+		{expr: `ps.exe in $lookup(trusted_binaries)`, err: errors.New("ps.exe in $lookup(trusted_binaries)" +
+			"	^ expected lookup table name")},
+	}
+
+	for i, tt := range tests {
+		p := NewParserWithConfig(tt.expr, c)
+		expr, err := p.ParseExpr()
+		if err == nil && tt.err != nil {
+			t.Errorf("%d. exp=%s expected error=%v", i, tt.expr, tt.err)
+			continue
+		} else if err != nil && tt.err == nil {
+			t.Errorf("%d. exp=%s got error=%v", i, tt.expr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if Eval(expr, m, true) != tt.matches {
+			t.Errorf("%d. %q interpreter lookup mismatch: exp=%t", i, tt.expr, tt.matches)
+		}
+		if Compile(expr, true).EvalMap(m, nil) != tt.matches {
+			t.Errorf("%d. %q compiled lookup mismatch: exp=%t", i, tt.expr, tt.matches)
+		}
+	}
+}
+
 func TestParseSequence(t *testing.T) {
 	var tests = []struct {
 		expr          string
@@ -280,6 +369,56 @@ func TestParseSequence(t *testing.T) {
 			time.Second * 30,
 			false,
 		},
+		{
+
+			`maxspan 1m
+			 |kevt.name = 'CreateProcess' and kevt.pid | 1 = 5| by ps.pid
+			 |kevt.name = 'WriteFile' and file.io.size / 1024 > 500| by ps.pid
+			`,
+			nil,
+			time.Minute,
+			true,
+		},
+		{
+
+			`maxspan 1m
+			 |kevt.name = 'CreateFile'| by file.name
+			 |not kevt.name = 'SetFileInformation'| by file.name
+			`,
+			nil,
+			time.Minute,
+			true,
+		},
+		{
+
+			`|kevt.name = 'CreateFile'| by file.name
+			 |not kevt.name = 'SetFileInformation'| by file.name
+			`,
+			errors.New("sequences with negated expressions require the 'maxspan' statement"),
+			time.Duration(0),
+			true,
+		},
+		{
+
+			`maxspan 1m
+			 |not kevt.name = 'CreateFile'|
+			 |kevt.name = 'SetFileInformation'|
+			`,
+			errors.New("the first expression in the sequence can't be negated"),
+			time.Minute,
+			false,
+		},
+		{
+
+			`maxspan 1m
+			 |kevt.name = 'CreateProcess'|
+			 |not kevt.name = 'CreateFile'|
+			 |kevt.name = 'SetFileInformation'|
+			`,
+			errors.New("only the last expression in the sequence can be negated"),
+			time.Minute,
+			false,
+		},
 	}
 
 	for i, tt := range tests {
@@ -300,3 +439,177 @@ func TestParseSequence(t *testing.T) {
 		}
 	}
 }
+
+func TestParseNegatedSequence(t *testing.T) {
+	p := NewParser(`maxspan 2m
+		|kevt.name = 'CreateFile' and file.extension = '.exe'| by file.name
+		|not (kevt.name = 'SetFileInformation' or kevt.name = 'DeleteFile')| by file.name
+	`)
+	seq, err := p.ParseSequence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seq.Expressions) != 2 {
+		t.Fatalf("expected 2 expressions but got %d", len(seq.Expressions))
+	}
+	if seq.Expressions[0].Negated {
+		t.Errorf("expected the first expression not to be negated")
+	}
+	if !seq.Expressions[1].Negated || !seq.IsNegated() {
+		t.Errorf("expected the last expression to be negated")
+	}
+	if _, ok := seq.Expressions[1].Expr.(*ParenExpr); !ok {
+		t.Errorf("expected parenthesized expression but got %T", seq.Expressions[1].Expr)
+	}
+}
+
+func TestParseAggregation(t *testing.T) {
+	var tests = []struct {
+		expr       string
+		err        error
+		window     time.Duration
+		windowType WindowType
+		by         int
+		thresholds string
+	}{
+		{
+			`window 1m by ps.pid |kevt.name = 'CreateFile'| having count(distinct file.name) > 20`,
+			nil,
+			time.Minute,
+			TumblingWindow,
+			1,
+			"count(distinct file.name) > 20",
+		},
+		{
+			`window 30s sliding by ps.pid, net.dip |kevt.name = 'Send'| having sum(net.size) >= 1048576 and count() > 5`,
+			nil,
+			time.Second * 30,
+			SlidingWindow,
+			2,
+			"sum(net.size) >= 1048576, count() > 5",
+		},
+		{
+			`window 10s tumbling |kevt.name = 'RegSetValue'| having max(registry.value) < 2.5`,
+			nil,
+			time.Second * 10,
+			TumblingWindow,
+			0,
+			"max(registry.value) < 2.5",
+		},
+		{
+			`by ps.pid |kevt.name = 'CreateFile'| having count() > 20`,
+			errors.New("expected window"),
+			0,
+			TumblingWindow,
+			0,
+			"",
+		},
+		{
+			`window 1m hopping |kevt.name = 'CreateFile'| having count() > 20`,
+			errors.New("expected sliding, tumbling"),
+			0,
+			TumblingWindow,
+			0,
+			"",
+		},
+		{
+			`window 1m |kevt.name = 'CreateFile'|`,
+			errors.New("expected having"),
+			0,
+			TumblingWindow,
+			0,
+			"",
+		},
+		{
+			`window 1m |kevt.name = 'CreateFile'| having avg(file.io.size) > 20`,
+			errors.New("expected count, sum, min, max"),
+			0,
+			TumblingWindow,
+			0,
+			"",
+		},
+		{
+			`window 1m |kevt.name = 'CreateFile'| having sum(distinct file.io.size) > 20`,
+			errors.New("distinct is only allowed in the count aggregate"),
+			0,
+			TumblingWindow,
+			0,
+			"",
+		},
+		{
+			`window 1m |kevt.name = 'CreateFile'| having count(file.name) > 20`,
+			errors.New("count aggregate accepts either no arguments or the distinct field"),
+			0,
+			TumblingWindow,
+			0,
+			"",
+		},
+		{
+			`window 1m |kevt.name = 'CreateFile'| having count() > 'a'`,
+			errors.New("expected number"),
+			0,
+			TumblingWindow,
+			0,
+			"",
+		},
+	}
+
+	for i, tt := range tests {
+		p := NewParser("aggregate " + tt.expr)
+		if !p.IsAggregation() {
+			t.Fatalf("%d. exp=%s expected aggregation", i, tt.expr)
+		}
+		agg, err := p.ParseAggregation()
+		if err == nil && tt.err != nil {
+			t.Errorf("%d. exp=%s expected error=\n%v", i, tt.expr, tt.err)
+			continue
+		} else if err != nil && tt.err == nil {
+			t.Errorf("%d. exp=%s got error=\n%v", i, tt.expr, err)
+			continue
+		}
+		if agg == nil {
+			continue
+		}
+		if agg.Window != tt.window {
+			t.Errorf("%d. exp=%s window=%v got window=%v", i, tt.expr, tt.window, agg.Window)
+		}
+		if agg.WindowType != tt.windowType {
+			t.Errorf("%d. exp=%s window type=%v got window type=%v", i, tt.expr, tt.windowType, agg.WindowType)
+		}
+		if len(agg.By) != tt.by {
+			t.Errorf("%d. exp=%s by=%d got by=%d", i, tt.expr, tt.by, len(agg.By))
+		}
+		thresholds := make([]string, len(agg.Thresholds))
+		for i, t := range agg.Thresholds {
+			thresholds[i] = t.String()
+		}
+		if strings.Join(thresholds, ", ") != tt.thresholds {
+			t.Errorf("%d. exp=%s thresholds=%s got thresholds=%s", i, tt.expr, tt.thresholds, strings.Join(thresholds, ", "))
+		}
+	}
+}
+
+func TestIdents(t *testing.T) {
+	var tests = []struct {
+		expr   string
+		idents []string
+	}{
+		{"ps.name = 'cmd.exe'", []string{}},
+		{"spawn_process and ps.name in msoffice_binaries", []string{"spawn_process", "msoffice_binaries"}},
+		{"ps.nme = 'cmd.exe' and length(ps.name) > 2", []string{"ps.nme"}},
+		{"sequence maxspan 1m |spawn_process| as e1 |create_file and file.name = $e1.ps.exe|", []string{"spawn_process", "create_file"}},
+	}
+
+	for i, tt := range tests {
+		idents := Idents(tt.expr)
+		if len(idents) != len(tt.idents) {
+			t.Errorf("%d. exp=%s expected idents=%v got idents=%v", i, tt.expr, tt.idents, idents)
+			continue
+		}
+		for j, ident := range idents {
+			if ident.Name != tt.idents[j] {
+				t.Errorf("%d. exp=%s expected ident=%s got ident=%s", i, tt.expr, tt.idents[j], ident.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QuantifierFn represents the quantifier applied to elements of the collection.
+type QuantifierFn string
+
+const (
+	// AnyQuantifier yields true if the predicate is true for any element
+	AnyQuantifier QuantifierFn = "any"
+	// AllQuantifier yields true if the predicate is true for all elements
+	AllQuantifier QuantifierFn = "all"
+	// CountQuantifier yields the number of elements that satisfy the
+	// predicate, or the number of elements if the predicate is omitted
+	CountQuantifier QuantifierFn = "count"
+)
+
+func isQuantifier(name string) bool {
+	switch QuantifierFn(strings.ToLower(name)) {
+	case AnyQuantifier, AllQuantifier, CountQuantifier:
+		return true
+	}
+	return false
+}
+
+// QuantifierExpr applies the predicate to each element of the collection,
+// e.g. any(ps.modules, m, m iendswith 'dbghelp.dll'). The element is bound
+// to the variable that can be referenced anywhere in the predicate.
+type QuantifierExpr struct {
+	Fn   QuantifierFn
+	Expr Expr
+	Var  string
+	Pred Expr
+}
+
+// String returns a string representation of the quantifier expression.
+func (e *QuantifierExpr) String() string {
+	if e.Pred == nil {
+		return fmt.Sprintf("%s(%s)", e.Fn, e.Expr.String())
+	}
+	return fmt.Sprintf("%s(%s, %s, %s)", e.Fn, e.Expr.String(), e.Var, e.Pred.String())
+}
+
+// each calls fn for every element of the collection until fn returns false.
+// It returns false if the value is not a collection.
+func each(v interface{}, fn func(elem interface{}) bool) bool {
+	switch s := v.(type) {
+	case []string:
+		for _, elem := range s {
+			if !fn(elem) {
+				break
+			}
+		}
+	case []interface{}:
+		for _, elem := range s {
+			if !fn(elem) {
+				break
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// index returns the element of the collection at the given
+// position or nil if the position is out of collection bounds.
+func index(v interface{}, i int) interface{} {
+	switch s := v.(type) {
+	case []string:
+		if i < len(s) {
+			return s[i]
+		}
+	case []interface{}:
+		if i < len(s) {
+			return s[i]
+		}
+	}
+	return nil
+}
+
+// quantify applies the quantifier to outcomes of the predicate evaluated
+// for collection elements. If the value is not a collection, the result
+// is nil. In strict mode, the any quantifier behaves like the disjunction
+// of outcomes and the all quantifier like the conjunction, so unknown
+// outcomes make the result unknown unless it is decided by other elements.
+func quantify(fn QuantifierFn, coll interface{}, strict bool, pred func(elem interface{}) interface{}) interface{} {
+	var n int
+	res := boolTri(fn == AllQuantifier)
+	ok := each(coll, func(elem interface{}) bool {
+		if pred == nil {
+			n++
+			return true
+		}
+		var t tri
+		if strict {
+			t = strictTri(pred(elem))
+		} else {
+			t = boolTri(toTri(pred(elem)) == triTrue)
+		}
+		switch fn {
+		case AnyQuantifier:
+			res = res.or(t)
+			return res != triTrue
+		case AllQuantifier:
+			res = res.and(t)
+			return res != triFalse
+		}
+		if t == triTrue {
+			n++
+		}
+		return true
+	})
+	if !ok {
+		return nil
+	}
+	if fn == CountQuantifier {
+		return n
+	}
+	return res.value()
+}
@@ -70,6 +70,13 @@ const (
 	Lte         // <=
 	Gt          // >
 	Gte         // >=
+	Add         // +
+	Sub         // -
+	Mul         // *
+	Div         // /
+	Mod         // %
+	BitAnd      // &
+	BitOr       // |
 	opEnd
 
 	Lparen // (
@@ -82,6 +89,14 @@ const (
 	MaxSpan // MAXSPAN
 	By      // BY
 	As      // AS
+
+	Aggr   // AGGREGATE
+	Window // WINDOW
+	Having // HAVING
+
+	Exists // EXISTS
+	Is     // IS
+	Null   // NULL
 )
 
 var keywords map[string]token
@@ -91,7 +106,7 @@ func init() {
 	for _, tok := range []token{And, Or, Contains, IContains, In,
 		IIn, Not, Startswith, IStartswith, Endswith, IEndswith,
 		Matches, IMatches, Fuzzy, IFuzzy, Fuzzynorm, IFuzzynorm,
-		Seq, MaxSpan, By, As} {
+		Seq, MaxSpan, By, As, Aggr, Window, Having, Exists, Is, Null} {
 		keywords[strings.ToLower(tokens[tok])] = tok
 	}
 	keywords["true"] = True
@@ -143,6 +158,14 @@ var tokens = [...]string{
 	Gt:  ">",
 	Gte: ">=",
 
+	Add:    "+",
+	Sub:    "-",
+	Mul:    "*",
+	Div:    "/",
+	Mod:    "%",
+	BitAnd: "&",
+	BitOr:  "|",
+
 	Lparen: "(",
 	Rparen: ")",
 	Comma:  ",",
@@ -153,11 +176,22 @@ var tokens = [...]string{
 	MaxSpan: "MAXSPAN",
 	By:      "BY",
 	As:      "AS",
+
+	Aggr:   "AGGREGATE",
+	Window: "WINDOW",
+	Having: "HAVING",
+
+	Exists: "EXISTS",
+	Is:     "IS",
+	Null:   "NULL",
 }
 
 // isOperator determines whether the current token is an operator.
 func (tok token) isOperator() bool { return tok > opBeg && tok < opEnd }
 
+// isArithmetic determines whether the current token is an arithmetic or bitwise operator.
+func (tok token) isArithmetic() bool { return tok >= Add && tok <= BitOr }
+
 // String returns the string representation of the token.
 func (tok token) String() string {
 	if tok >= 0 && tok < token(len(tokens)) {
@@ -180,6 +214,14 @@ func (tok token) precedence() int {
 	case In, IIn, Contains, IContains, Startswith, IStartswith, Endswith, IEndswith,
 		Matches, IMatches, Fuzzy, IFuzzy, Fuzzynorm, IFuzzynorm:
 		return 5
+	case BitOr:
+		return 6
+	case BitAnd:
+		return 7
+	case Add, Sub:
+		return 8
+	case Mul, Div, Mod:
+		return 9
 	}
 	return 0
 }
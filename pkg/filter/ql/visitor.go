@@ -38,6 +38,13 @@ func Walk(v Visitor, node Node) {
 		Walk(v, n.RHS)
 	case *NotExpr:
 		Walk(v, n.Expr)
+	case *ExistsExpr:
+		Walk(v, n.Expr)
+	case *IndexExpr:
+		Walk(v, n.Expr)
+	case *QuantifierExpr:
+		Walk(v, n.Expr)
+		Walk(v, n.Pred)
 	case *Function:
 		for _, expr := range n.Args {
 			Walk(v, expr)
@@ -0,0 +1,230 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"expvar"
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+	"path/filepath"
+	"time"
+)
+
+// reloadDelay specifies how long the watcher waits for subsequent
+// changes in rule sources before recompiling the rule set. Editors
+// often produce a burst of events when saving a single file.
+const reloadDelay = time.Millisecond * 500
+
+var (
+	ruleReloads      = expvar.NewInt("filter.reloads")
+	ruleReloadErrors = expvar.NewInt("filter.reload.errors")
+)
+
+// Reload recompiles lookup tables, macros and rule groups from all sources and swaps
+// in the new rule set. The active rule set is only replaced if all the
+// groups compile successfully. Sequence and aggregation rules of groups
+// whose hash didn't change keep their in-flight state.
+func (r *Rules) Reload() error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	rs, err := r.compile()
+	if err != nil {
+		ruleReloadErrors.Add(1)
+		return err
+	}
+
+	r.mu.RLock()
+	active := r.groups
+	r.mu.RUnlock()
+
+	dropped := rs.inherit(active)
+	r.swap(rs)
+	for _, ss := range dropped {
+		ss.dispose()
+	}
+
+	ruleReloads.Add(1)
+	log.Infof("rules reloaded. %d rule group(s) active", len(rs.groups))
+	return nil
+}
+
+// inherit carries over the state of sequence and aggregation rules from
+// the active groups to the groups with the same hash in this rule set.
+// The state is inherited only if the rule expression remained identical,
+// since otherwise the state machine and partials wouldn't line up with
+// the new expression. The sequence states that weren't inherited by any
+// rule are returned.
+func (rs *ruleSet) inherit(active []*filterGroup) []*sequenceState {
+	groups := make(map[uint32]*filterGroup, len(active))
+	for _, g := range active {
+		groups[g.group.Hash()] = g
+	}
+	inherited := make(map[*sequenceState]bool)
+	for _, g := range rs.groups {
+		prevGroup, ok := groups[g.group.Hash()]
+		if !ok {
+			continue
+		}
+		for _, f := range g.filters {
+			prev := prevGroup.findFilter(f.config.Name)
+			if prev == nil || expr(prev.config) != expr(f.config) {
+				continue
+			}
+			if f.prof != nil && prev.prof != nil {
+				f.profile(prev.prof)
+			}
+			// suppression windows are kept as long as the
+			// suppression settings of the rule don't change
+			if f.sup != nil && prev.sup != nil && f.sup.equals(prev.sup) {
+				prev.sup.mu.Lock()
+				prev.sup.flush = f.sup.flush
+				prev.sup.mu.Unlock()
+				f.sup = prev.sup
+			}
+			if f.ss != nil && prev.ss != nil {
+				// the absence callback executes the action
+				// of the rule, which might have changed
+				prev.ss.mu.Lock()
+				prev.ss.absent = f.ss.absent
+				prev.ss.mu.Unlock()
+				f.ss = prev.ss
+				inherited[prev.ss] = true
+			}
+			if f.as != nil && prev.as != nil {
+				f.as = prev.as
+			}
+		}
+	}
+
+	dropped := make([]*sequenceState, 0)
+	for _, g := range active {
+		for _, f := range g.filters {
+			if f.ss != nil && !inherited[f.ss] {
+				dropped = append(dropped, f.ss)
+			}
+		}
+	}
+	return dropped
+}
+
+// findFilter returns the compiled filter with the given rule name.
+func (g *filterGroup) findFilter(name string) *compiledFilter {
+	for _, f := range g.filters {
+		if f.config.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// dispose stops pending max span deadlines and discards
+// the state of the sequence that is no longer active.
+func (s *sequenceState) dispose() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, span := range s.spanDeadlines {
+		span.Stop()
+	}
+	s.absent = nil
+	s.clear()
+}
+
+// Watch watches files referenced in rule, macro, lookup, and exception paths and
+// reloads the rule set in the background when any of the files is created,
+// modified, or removed. Rules loaded from URLs are not watched.
+func (r *Rules) Watch() error {
+	patterns := make([]string, 0)
+	patterns = append(patterns, r.config.Filters.Rules.FromPaths...)
+	patterns = append(patterns, r.config.Filters.Macros.FromPaths...)
+	patterns = append(patterns, r.config.Filters.Lookups.FromPaths...)
+	patterns = append(patterns, r.config.Filters.Exceptions.FromPaths...)
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	watched := make(map[string]bool)
+	for _, pattern := range patterns {
+		// the directory can also contain glob
+		// expressions, e.g. Rules\*\*.yml
+		dirs, err := filepath.Glob(filepath.Dir(pattern))
+		if err != nil {
+			log.Warnf("invalid rule path %s: %v", pattern, err)
+			continue
+		}
+		for _, dir := range dirs {
+			if watched[dir] {
+				continue
+			}
+			if err := w.Add(dir); err != nil {
+				log.Warnf("unable to watch %s directory for rule changes: %v", dir, err)
+				continue
+			}
+			log.Infof("watching %s directory for rule changes", dir)
+			watched[dir] = true
+		}
+	}
+	r.watcher = w
+
+	go r.watch(w, patterns)
+
+	return nil
+}
+
+func (r *Rules) watch(w *fsnotify.Watcher, patterns []string) {
+	var reload *time.Timer
+	for {
+		select {
+		case e, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if e.Op == fsnotify.Chmod || !matchesPath(patterns, e.Name) {
+				continue
+			}
+			log.Debugf("rule source %s changed: %s", e.Name, e.Op)
+			if reload != nil {
+				reload.Stop()
+			}
+			reload = time.AfterFunc(reloadDelay, func() {
+				if err := r.Reload(); err != nil {
+					log.Errorf("unable to reload rules. Keeping the active rule set: %v", err)
+				}
+			})
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log.Warnf("rule watcher error: %v", err)
+		}
+	}
+}
+
+// matchesPath determines if the path matches any of the glob patterns.
+func matchesPath(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
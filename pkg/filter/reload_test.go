@@ -0,0 +1,199 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sequenceGroup = `
+- group: Command shell execution and temp files
+  enabled: true
+  rules:
+    - name: Command shell created a temp file
+      condition: >
+        sequence
+        maxspan 1h
+        by ps.pid
+        |kevt.name = 'CreateProcess' and ps.name = 'cmd.exe'|
+        |kevt.name = 'CreateFile' and file.name icontains 'temp'|
+`
+
+const connectGroup = `
+- group: Outbound connections
+  enabled: true
+  rules:
+    - name: Connection to HTTPS port
+      condition: kevt.name = 'Connect' and net.dport = 443
+`
+
+func newReloadEvents() (*kevent.Kevent, *kevent.Kevent) {
+	kevt1 := &kevent.Kevent{
+		Type:      ktypes.CreateProcess,
+		Timestamp: time.Now(),
+		Name:      "CreateProcess",
+		Tid:       2484,
+		PID:       859,
+		PS: &types.PS{
+			Name: "cmd.exe",
+			Exe:  "C:\\Windows\\system32\\cmd.exe",
+		},
+		Kparams: kevent.Kparams{
+			kparams.ProcessID: {Name: kparams.ProcessID, Type: kparams.Uint32, Value: uint32(4143)},
+		},
+		Metadata: make(map[kevent.MetadataKey]any),
+	}
+	kevt2 := &kevent.Kevent{
+		Type:      ktypes.CreateFile,
+		Timestamp: time.Now().Add(time.Millisecond),
+		Name:      "CreateFile",
+		Tid:       2484,
+		PID:       859,
+		Category:  ktypes.File,
+		PS: &types.PS{
+			Name: "cmd.exe",
+			Exe:  "C:\\Windows\\system32\\cmd.exe",
+		},
+		Kparams: kevent.Kparams{
+			kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Temp\\dropper.exe"},
+		},
+		Metadata: make(map[kevent.MetadataKey]any),
+	}
+	return kevt1, kevt2
+}
+
+func TestReloadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yml")
+	require.NoError(t, os.WriteFile(path, []byte(sequenceGroup), 0600))
+
+	rules := NewRules(newConfig(path))
+	require.NoError(t, rules.Compile())
+
+	kevt1, kevt2 := newReloadEvents()
+	require.False(t, rules.Fire(kevt1))
+	ss := rules.filterGroups[ktypes.CreateFile.Hash()][0].filters[0].ss
+
+	// the sequence group didn't change, so
+	// the in-flight sequence state is kept
+	require.NoError(t, os.WriteFile(path, []byte(sequenceGroup+connectGroup), 0600))
+	require.NoError(t, rules.Reload())
+	assert.Len(t, rules.groups, 2)
+	assert.Contains(t, rules.filterGroups, ktypes.Connect.Hash())
+	assert.Equal(t, ss, rules.filterGroups[ktypes.CreateFile.Hash()][0].filters[0].ss)
+
+	// the rule set is not swapped if any of the rules fails to compile
+	require.NoError(t, os.WriteFile(path, []byte(sequenceGroup+connectGroup+`
+- group: Broken
+  enabled: true
+  rules:
+    - name: Broken rule
+      condition: kevt.name = 'CreateFile' and file.name icontains
+`), 0600))
+	require.Error(t, rules.Reload())
+	assert.Len(t, rules.groups, 2)
+
+	require.True(t, rules.Fire(kevt2))
+
+	// the sequence state of the removed group is discarded
+	require.False(t, rules.Fire(kevt1))
+	require.NoError(t, os.WriteFile(path, []byte(connectGroup), 0600))
+	require.NoError(t, rules.Reload())
+	assert.Len(t, rules.groups, 1)
+	assert.NotContains(t, rules.filterGroups, ktypes.CreateFile.Hash())
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	assert.Len(t, ss.partials, 0)
+	assert.Len(t, ss.spanDeadlines, 0)
+}
+
+func TestReloadFailureKeepsMacros(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.yml")
+	macros := filepath.Join(dir, "macros.yml")
+	require.NoError(t, os.WriteFile(path, []byte(connectGroup), 0600))
+	require.NoError(t, os.WriteFile(macros, []byte(`
+- macro: spawn_process
+  expr: kevt.name = 'CreateProcess'
+`), 0600))
+
+	c := newConfig(path)
+	c.Filters.Macros.FromPaths = []string{macros}
+	rules := NewRules(c)
+	require.NoError(t, rules.Compile())
+	require.NotNil(t, c.Filters.GetMacro("spawn_process"))
+
+	// macros loaded by the failed reload don't
+	// replace the macros of the active rule set
+	require.NoError(t, os.WriteFile(macros, []byte(`
+- macro: write_file
+  expr: kevt.name = 'CreateFile'
+`), 0600))
+	require.NoError(t, os.WriteFile(path, []byte(connectGroup+`
+- group: Broken
+  enabled: true
+  rules:
+    - name: Broken rule
+      condition: kevt.name = 'CreateFile' and file.name icontains
+`), 0600))
+	require.Error(t, rules.Reload())
+	assert.NotNil(t, c.Filters.GetMacro("spawn_process"))
+	assert.Nil(t, c.Filters.GetMacro("write_file"))
+
+	// the successful reload commits the new macros
+	require.NoError(t, os.WriteFile(path, []byte(connectGroup), 0600))
+	require.NoError(t, rules.Reload())
+	assert.Nil(t, c.Filters.GetMacro("spawn_process"))
+	assert.NotNil(t, c.Filters.GetMacro("write_file"))
+}
+
+func TestWatchRules(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sequence.yml"), []byte(sequenceGroup), 0600))
+
+	rules := NewRules(newConfig(filepath.Join(dir, "*.yml")))
+	require.NoError(t, rules.Compile())
+	require.NoError(t, rules.Watch())
+	defer rules.Close()
+
+	// files that don't match the pattern are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(connectGroup), 0600))
+	time.Sleep(reloadDelay * 2)
+	assert.Len(t, rules.groupsSnapshot(), 1)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "connect.yml"), []byte(connectGroup), 0600))
+	require.Eventually(t, func() bool {
+		return len(rules.groupsSnapshot()) == 2
+	}, time.Second*5, time.Millisecond*100)
+}
+
+func (r *Rules) groupsSnapshot() []*filterGroup {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.groups
+}
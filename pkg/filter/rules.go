@@ -25,7 +25,9 @@ import (
 	"errors"
 	"expvar"
 	"fmt"
+	"github.com/fsnotify/fsnotify"
 	fsm "github.com/qmuntal/stateless"
+	"github.com/rabbitstack/fibratus/pkg/filter/action"
 	"github.com/rabbitstack/fibratus/pkg/filter/fields"
 	"github.com/rabbitstack/fibratus/pkg/util/hashers"
 	"net"
@@ -36,6 +38,7 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/util/atomic"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
@@ -59,6 +62,8 @@ var (
 	matchTransitionErrors = expvar.NewInt("sequence.match.transition.errors")
 	partialsPerSequence   = expvar.NewMap("sequence.partials.count")
 	partialExpirations    = expvar.NewMap("sequence.partial.expirations")
+	absenceMatches        = expvar.NewMap("sequence.absence.matches")
+	absenceCancellations  = expvar.NewMap("sequence.absence.cancellations")
 
 	ErrInvalidFilter = func(rule, group string, err error) error {
 		return fmt.Errorf("syntax error in rule %q located in %q group: \n%v", rule, group, err)
@@ -94,9 +99,52 @@ var (
 type Rules struct {
 	filterGroups    map[uint32]filterGroups
 	excludePolicies bool
-	config          *config.Config
+	// groups contains all compiled filter groups
+	groups     []*filterGroup
+	exceptions exceptions
+	index      *ruleIndex
+	config     *config.Config
+	// match is invoked when a rule in the group with
+	// include policy fires. By default, it executes
+	// the rule action.
+	match matchFunc
+	// quit stops periodic sequence state snapshots
+	quit chan struct{}
+
+	// mu guards the active rule set which is swapped on reload
+	mu sync.RWMutex
+	// reloadMu serializes compilations of the rule set
+	reloadMu sync.Mutex
+	watcher  *fsnotify.Watcher
+}
+
+// ruleSet is the compiled set of filter groups
+// indexed by event type or event category hash.
+type ruleSet struct {
+	filterGroups    map[uint32]filterGroups
+	excludePolicies bool
+	groups          []*filterGroup
+	exceptions      exceptions
+	index           *ruleIndex
+	// filters is the filters config with lookup tables
+	// and macros the rule set was compiled with
+	filters *config.Filters
 }
 
+// matchFunc is the callback that receives the matching event or
+// sequence matches along with the group and the rule that fired.
+// Aggregation rules additionally pass the aggregate values. The
+// occurrences describe the matches collapsed by the suppression
+// window, and are zero if the rule doesn't suppress matches.
+type matchFunc func(
+	kevt *kevent.Kevent,
+	kevts map[uint16]*kevent.Kevent,
+	group config.FilterGroup,
+	filter *config.FilterConfig,
+	aggs map[string]float64,
+	occurrences config.Occurrences,
+) error
+
 type filterGroup struct {
 	group   config.FilterGroup
 	filters []*compiledFilter
@@ -105,6 +153,9 @@ type filterGroup struct {
 type compiledFilter struct {
 	filter Filter
 	ss     *sequenceState
+	as     *aggregationState
+	sup    *suppressionState
+	prof   *ruleProfiler
 	config *config.FilterConfig
 }
 
@@ -129,6 +180,7 @@ type sequenceState struct {
 	// rule to rule index mapping. Indices start at 1
 	idxs          map[fsm.State]uint16
 	spanDeadlines map[fsm.State]*time.Timer
+	deadlines     map[fsm.State]time.Time
 	inDeadline    atomic.Bool
 	inExpired     bool
 	initialState  fsm.State
@@ -136,6 +188,21 @@ type sequenceState struct {
 	// matchedRules keeps the mapping between rule indexes and
 	// their matches.
 	matchedRules map[uint16]bool
+
+	// negatedState is the state of the negated expression. Once the
+	// sequence reaches this state, each join key has its own absence
+	// deadline, and the sequence fires for the key whose deadline is
+	// reached before any event matches the negated expression
+	negatedState fsm.State
+	// absences keeps the pending absence of the negated expression
+	// for each join key of the upstream partials
+	absences map[string]*absence
+	// absent is invoked with sequence matches when no event
+	// satisfied the negated expression within the max span
+	absent func(matches map[uint16]*kevent.Kevent)
+
+	// mu serializes sequence evaluation and deadline transitions
+	mu sync.Mutex
 }
 
 func newSequenceState(name, initialState string, maxSpan time.Duration) *sequenceState {
@@ -147,8 +214,10 @@ func newSequenceState(name, initialState string, maxSpan time.Duration) *sequenc
 		matches:       make(map[uint16]*kevent.Kevent),
 		idxs:          make(map[fsm.State]uint16),
 		spanDeadlines: make(map[fsm.State]*time.Timer),
+		deadlines:     make(map[fsm.State]time.Time),
 		initialState:  fsm.State(initialState),
 		inDeadline:    atomic.MakeBool(false),
+		absences:      make(map[string]*absence),
 	}
 
 	ss.initFSM(initialState)
@@ -163,8 +232,9 @@ func (s *sequenceState) isStateSchedulable(state fsm.State) bool {
 func (s *sequenceState) initFSM(initialState string) {
 	s.fsm = fsm.NewStateMachine(initialState)
 	s.fsm.OnTransitioned(func(ctx context.Context, transition fsm.Transition) {
-		// schedule span deadline for the current state unless initial/meta states
-		if s.maxSpan != 0 && s.isStateSchedulable(s.currentState()) {
+		// schedule span deadline for the current state unless initial/meta states.
+		// The negated state deadlines are scheduled per join key by absences
+		if s.maxSpan != 0 && s.isStateSchedulable(s.currentState()) && s.currentState() != s.negatedState {
 			log.Debugf("scheduling max span deadline of %v for rule %s", s.maxSpan, s.currentState())
 			s.scheduleMaxSpanDeadline(s.currentState(), s.maxSpan)
 		}
@@ -189,6 +259,7 @@ func (s *sequenceState) initFSM(initialState string) {
 				log.Debugf("stopped max span deadline for rule %s", transition.Source)
 				span.Stop()
 				delete(s.spanDeadlines, transition.Source)
+				delete(s.deadlines, transition.Source)
 			}
 			// save rule match
 			s.matchedRules[s.idxs[transition.Source]] = true
@@ -231,25 +302,43 @@ func (s *sequenceState) currentState() fsm.State {
 	return s.fsm.MustState()
 }
 
-func (s *sequenceState) addPartial(rule string, kevt *kevent.Kevent) {
+// addPartial appends the partial to the slot of the rule. It
+// returns false if the partial is dropped or already present.
+func (s *sequenceState) addPartial(rule string, kevt *kevent.Kevent) bool {
 	i := s.idxs[rule]
 	if len(s.partials[i]) > maxOutstandingPartials {
 		log.Warnf("max partials encountered in sequence %s slot [%d]. "+
 			"Dropping incoming partial", s.name, s.idxs[rule])
-		return
+		return false
 	}
 	key := kevt.PartialKey()
 	if key != 0 {
 		for _, p := range s.partials[i] {
 			if key == p.PartialKey() {
 				log.Debugf("%s event tuple already in sequence state", kevt.Name)
-				return
+				return false
 			}
 		}
 	}
 	log.Debugf("adding partial to slot [%d] for rule %q: %s", i, rule, kevt)
 	partialsPerSequence.Add(s.name, 1)
 	s.partials[i] = append(s.partials[i], kevt)
+	return true
+}
+
+// joinPartials collects partials that could be joined
+// by the sequence fields into sequence matches.
+func (s *sequenceState) joinPartials() {
+	nseqs := uint16(len(s.partials))
+	for i := uint16(1); i < nseqs+1; i++ {
+		for _, outer := range s.partials[i] {
+			for _, inner := range s.partials[i+1] {
+				if compareSeqJoin(outer.SequenceBy(), inner.SequenceBy()) {
+					s.matches[i], s.matches[i+1] = outer, inner
+				}
+			}
+		}
+	}
 }
 
 func (s *sequenceState) isAfter(rule string, kevt *kevent.Kevent) bool {
@@ -261,10 +350,15 @@ func (s *sequenceState) isAfter(rule string, kevt *kevent.Kevent) bool {
 }
 
 func (s *sequenceState) clear() {
+	for _, a := range s.absences {
+		a.timer.Stop()
+	}
+	s.absences = make(map[string]*absence)
 	s.partials = make(map[uint16][]*kevent.Kevent)
 	s.matches = make(map[uint16]*kevent.Kevent)
 	s.matchedRules = make(map[uint16]bool)
 	s.spanDeadlines = make(map[fsm.State]*time.Timer)
+	s.deadlines = make(map[fsm.State]time.Time)
 	partialsPerSequence.Delete(s.name)
 }
 
@@ -339,9 +433,11 @@ func (s *sequenceState) next(i int) bool {
 
 func (s *sequenceState) scheduleMaxSpanDeadline(rule fsm.State, maxSpan time.Duration) {
 	t := time.AfterFunc(maxSpan, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
 		inState, _ := s.fsm.IsInState(rule)
 		if inState {
-			log.Infof("max span of %v exceded for rule %s", maxSpan, rule)
+			log.Infof("max span of %v exceded for rule %s", s.maxSpan, rule)
 			s.inDeadline.Store(true)
 			// transitions to deadline state
 			err := s.cancelTransition(rule)
@@ -357,12 +453,150 @@ func (s *sequenceState) scheduleMaxSpanDeadline(rule fsm.State, maxSpan time.Dur
 		}
 	})
 	s.spanDeadlines[rule] = t
+	s.deadlines[rule] = time.Now().Add(maxSpan)
+}
+
+// absence is the pending absence of the negated expression
+// for the join key of the upstream partials.
+type absence struct {
+	// kevt is the upstream partial that armed the deadline
+	kevt     *kevent.Kevent
+	deadline time.Time
+	timer    *time.Timer
+}
+
+// joinKey returns the key that identifies the sequence join value.
+// String values are joined case-insensitively. Sequences without
+// the join field keep all partials under the empty key.
+func joinKey(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return "string:" + strings.ToLower(val)
+	case net.IP:
+		return "ip:" + val.String()
+	}
+	return fmt.Sprintf("%T:%v", v, v)
+}
+
+// armAbsence schedules the absence deadline for the join key of the
+// upstream partial. The deadline of the key is armed by its first
+// partial, so subsequent partials with the same key don't extend it.
+func (s *sequenceState) armAbsence(kevt *kevent.Kevent, deadline time.Time) {
+	key := joinKey(kevt.SequenceBy())
+	if _, ok := s.absences[key]; ok {
+		return
+	}
+	a := &absence{kevt: kevt, deadline: deadline}
+	a.timer = time.AfterFunc(time.Until(deadline), func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// the absence was cancelled or the state disposed
+		if s.absences[key] != a {
+			return
+		}
+		s.absenceMatch(key)
+	})
+	s.absences[key] = a
+}
+
+// absenceMatch fires the sequence for the join key whose max span
+// deadline is reached in the negated expression state. No event with
+// the same key matched the negated expression, so the sequence fires
+// with the upstream partials of the key. Partials of other keys keep
+// waiting for their own deadlines.
+func (s *sequenceState) absenceMatch(key string) {
+	delete(s.absences, key)
+	matches := s.joinAbsence(key)
+	s.dropPartials(key)
+	if len(matches) > 0 {
+		log.Infof("no events matched the negated expression in %q sequence within %v", s.name, s.maxSpan)
+		absenceMatches.Add(s.name, 1)
+		if s.absent != nil {
+			s.absent(matches)
+		}
+	}
+	s.resetAbsence()
+}
+
+// absenceCancel discards the pending absence of the join key when the
+// event satisfies the negated expression before the max span deadline
+// is reached. Pending absences of other keys are left intact.
+func (s *sequenceState) absenceCancel(kevt *kevent.Kevent) {
+	key := joinKey(kevt.SequenceBy())
+	a, ok := s.absences[key]
+	if !ok {
+		return
+	}
+	log.Debugf("negated expression in %q sequence matched. Discarding the pending absence", s.name)
+	a.timer.Stop()
+	delete(s.absences, key)
+	s.dropPartials(key)
+	absenceCancellations.Add(s.name, 1)
+	s.resetAbsence()
+}
+
+// joinAbsence collects the upstream partials of the join key. The
+// earliest partial in each slot is the one the deadline is tied to.
+func (s *sequenceState) joinAbsence(key string) map[uint16]*kevent.Kevent {
+	matches := make(map[uint16]*kevent.Kevent)
+	for i, partials := range s.partials {
+		for _, p := range partials {
+			if joinKey(p.SequenceBy()) == key {
+				matches[i] = p
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// dropPartials removes the partials of the join key from all slots.
+func (s *sequenceState) dropPartials(key string) {
+	for i, partials := range s.partials {
+		n := 0
+		for _, p := range partials {
+			if joinKey(p.SequenceBy()) != key {
+				partials[n] = p
+				n++
+			}
+		}
+		partialsPerSequence.Add(s.name, int64(n-len(partials)))
+		s.partials[i] = partials[:n]
+	}
+}
+
+// resetAbsence resets the sequence once no join keys are waiting for
+// the absence deadline. Upstream partials that didn't reach the negated
+// expression are discarded along with the sequence state.
+func (s *sequenceState) resetAbsence() {
+	if len(s.absences) > 0 {
+		return
+	}
+	if s.isInitialState() {
+		s.clear()
+		return
+	}
+	// transitions to deadline state
+	err := s.cancelTransition(s.currentState())
+	if err != nil {
+		log.Warnf("cancel transition failed: %v", err)
+		return
+	}
+	// transitions from deadline state to initial state
+	err = s.fsm.Fire(resetTransition)
+	if err != nil {
+		log.Warnf("unable to transition to initial state: %v", err)
+	}
 }
 
 func (s *sequenceState) expire(e *kevent.Kevent) bool {
 	if !e.IsTerminateProcess() {
 		return false
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	canExpire := func(lhs, rhs *kevent.Kevent) bool {
 		if lhs.Type == ktypes.CreateProcess {
 			p1, _ := lhs.Kparams.GetPid()
@@ -419,8 +653,8 @@ func newFilterGroup(g config.FilterGroup, filters []*compiledFilter) *filterGrou
 	return &filterGroup{group: g, filters: filters}
 }
 
-func newCompiledFilter(f Filter, filterConfig *config.FilterConfig, ss *sequenceState) *compiledFilter {
-	return &compiledFilter{config: filterConfig, filter: f, ss: ss}
+func newCompiledFilter(f Filter, filterConfig *config.FilterConfig, ss *sequenceState, as *aggregationState) *compiledFilter {
+	return &compiledFilter{config: filterConfig, filter: f, ss: ss, as: as}
 }
 
 // isScoped determines if this filter is scoped, i.e. it has the event name or category
@@ -453,8 +687,8 @@ func (groups filterGroups) hasIncludePolicy() bool {
 	return false
 }
 
-func (r *Rules) isGroupMapped(scopeHash, groupHash uint32) bool {
-	for h, groups := range r.filterGroups {
+func (rs *ruleSet) isGroupMapped(scopeHash, groupHash uint32) bool {
+	for h, groups := range rs.filterGroups {
 		for _, g := range groups {
 			if h == scopeHash && g.group.Hash() == groupHash {
 				return true
@@ -465,14 +699,34 @@ func (r *Rules) isGroupMapped(scopeHash, groupHash uint32) bool {
 }
 
 // NewRules produces a fresh rules instance.
-func NewRules(c *config.Config) Rules {
-	rules := Rules{
+func NewRules(c *config.Config) *Rules {
+	rules := &Rules{
 		filterGroups: make(map[uint32]filterGroups),
 		config:       c,
+		match:        runFilterAction,
+		quit:         make(chan struct{}, 1),
 	}
 	return rules
 }
 
+// Close stops watching rule sources and periodic
+// snapshots, and persists the final sequence state.
+func (r *Rules) Close() error {
+	if r.watcher != nil {
+		if err := r.watcher.Close(); err != nil {
+			log.Warnf("unable to close rule watcher: %v", err)
+		}
+	}
+	if r.config.Filters.Sequences.StateFile == "" {
+		return nil
+	}
+	select {
+	case r.quit <- struct{}{}:
+	default:
+	}
+	return r.SnapshotSequences()
+}
+
 func expr(c *config.FilterConfig) string {
 	if c.Condition != "" {
 		return c.Condition
@@ -480,28 +734,60 @@ func expr(c *config.FilterConfig) string {
 	return c.Def
 }
 
-// Compile loads macros and rule groups from all
-// indicated resources and creates the rules for
+// Compile loads lookup tables, macros and rule groups from
+// all indicated resources and creates the rules for
 // each filter group. It also sets up the state
 // machine transitions for sequence rule group policies.
 func (r *Rules) Compile() error {
-	if err := r.config.Filters.LoadMacros(); err != nil {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	rs, err := r.compile()
+	if err != nil {
 		return err
 	}
-	groups, err := r.config.Filters.LoadGroups()
-	if err != nil {
+	if err := action.Configure(r.config.Filters.Actions); err != nil {
 		return err
 	}
+	r.swap(rs)
+	return nil
+}
+
+// compile builds the rule set from macros and rule groups
+// without altering the rule set that is currently active.
+// Lookup tables and macros are loaded into the copy of the
+// filters config, which is committed along with the rule set.
+func (r *Rules) compile() (*ruleSet, error) {
+	filtersConfig := *r.config.Filters
+	c := *r.config
+	c.Filters = &filtersConfig
+	if err := filtersConfig.LoadLookups(); err != nil {
+		return nil, err
+	}
+	if err := filtersConfig.LoadMacros(); err != nil {
+		return nil, err
+	}
+	groups, err := filtersConfig.LoadGroups()
+	if err != nil {
+		return nil, err
+	}
+	excs, err := compileExceptions(&c)
+	if err != nil {
+		return nil, err
+	}
+	rs := &ruleSet{
+		filterGroups: make(map[uint32]filterGroups),
+		exceptions:   excs,
+		index:        newRuleIndex(&c),
+		filters:      &filtersConfig,
+	}
 	for _, group := range groups {
 		if group.IsDisabled() {
 			log.Warnf("rule group [%s] disabled", group.Name)
 			continue
 		}
 		if group.Policy == config.ExcludePolicy {
-			r.excludePolicies = true
+			rs.excludePolicies = true
 		}
-		filterGroupsCount.Add(1)
-		filterGroupsCountByPolicy.Add(group.Policy.String(), 1)
 		log.Infof("loading rule group [%s] with %q policy", group.Name, group.Policy)
 
 		// compile filters and populate the groups. Additionally, for
@@ -512,10 +798,10 @@ func (r *Rules) Compile() error {
 
 		for _, filterConfig := range rules {
 			rule := filterConfig.Name
-			f := New(expr(filterConfig), r.config)
+			f := New(expr(filterConfig), &c)
 			err := f.Compile()
 			if err != nil {
-				return ErrInvalidFilter(rule, group.Name, err)
+				return nil, ErrInvalidFilter(rule, group.Name, err)
 			}
 			var seqState *sequenceState
 			if f.IsSequence() {
@@ -550,12 +836,37 @@ func (r *Rules) Compile() error {
 				seqState.fsm.Configure(sequenceTerminalState).Permit(resetTransition, initialState)
 				seqState.fsm.Configure(sequenceDeadlineState).Permit(resetTransition, initialState)
 				seqState.fsm.Configure(sequenceExpiredState).Permit(resetTransition, initialState)
+				// the sequence with the negated expression fires
+				// when its max span deadline is reached, which
+				// happens outside the event processing flow
+				if seq.IsNegated() {
+					seqState.negatedState = fsm.State(expressions[len(expressions)-1].Expr.String())
+				}
+			}
+			var aggState *aggregationState
+			if f.IsAggregation() {
+				aggState = newAggregationState(rule, f.GetAggregation())
+			}
+			cf := newCompiledFilter(f, filterConfig, seqState, aggState)
+			if sup := group.SuppressionFor(filterConfig); sup != nil {
+				cf.sup, err = newSuppressionState(rule, *sup, &c)
+				if err != nil {
+					return nil, ErrInvalidFilter(rule, group.Name, err)
+				}
+				cf.sup.flush = r.flushFunc(group, filterConfig)
+			}
+			if seqState != nil && seqState.negatedState != "" {
+				seqState.absent = r.absenceFunc(rs, group, cf)
+			}
+			if filtersConfig.Profiling.Enabled {
+				cf.profile(newRuleProfiler())
 			}
-			filtersCount.Add(1)
-			filters = append(filters, newCompiledFilter(f, filterConfig, seqState))
+			rs.index.add(cf)
+			filters = append(filters, cf)
 		}
 
 		g := newFilterGroup(group, filters)
+		rs.groups = append(rs.groups, g)
 
 		// traverse all filters in the groups and determine
 		// the event type from the filter field name expression.
@@ -575,16 +886,38 @@ func (r *Rules) Compile() error {
 				for _, v := range values {
 					if name == fields.KevtName || name == fields.KevtCategory {
 						hash := hashers.FnvUint32([]byte(v))
-						if r.isGroupMapped(hash, g.group.Hash()) {
+						if rs.isGroupMapped(hash, g.group.Hash()) {
 							continue
 						}
-						r.filterGroups[hash] = append(r.filterGroups[hash], g)
+						rs.filterGroups[hash] = append(rs.filterGroups[hash], g)
 					}
 				}
 			}
 		}
 	}
-	return nil
+	return rs, nil
+}
+
+// swap replaces the active rule set with the given rule set.
+func (r *Rules) swap(rs *ruleSet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config.Filters = rs.filters
+	r.filterGroups = rs.filterGroups
+	r.excludePolicies = rs.excludePolicies
+	r.groups = rs.groups
+	r.exceptions = rs.exceptions
+	r.index = rs.index
+
+	var nfilters int64
+	filterGroupsCountByPolicy.Init()
+	for _, g := range rs.groups {
+		filterGroupsCountByPolicy.Add(g.group.Policy.String(), 1)
+		nfilters += int64(len(g.filters))
+	}
+	filterGroupsCount.Set(int64(len(rs.groups)))
+	filtersCount.Set(nfilters)
+	indexedRulesCount.Set(int64(rs.index.size()))
 }
 
 func (r *Rules) findGroups(kevt *kevent.Kevent) filterGroups {
@@ -597,6 +930,8 @@ func (r *Rules) findGroups(kevt *kevent.Kevent) filterGroups {
 }
 
 func (r *Rules) Fire(kevt *kevent.Kevent) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	// no rules were loaded into the engine in
 	// which the event is forwarded to the aggregator
 	// unless the CLI filter decides otherwise
@@ -610,13 +945,16 @@ func (r *Rules) Fire(kevt *kevent.Kevent) bool {
 	if len(groups) == 0 {
 		return false
 	}
+	// resolve the rules that can possibly match
+	// the event from the conditions in the index
+	satisfied := r.index.candidates(kevt)
 	// exclude policies take precedence over
 	// groups with include policies, so we first
 	// evaluate those. If no filter matches occur,
 	// we let pass the event but only if there are
 	// no groups with include policy
 	if r.excludePolicies {
-		if r.runRules(groups, config.ExcludePolicy, kevt) {
+		if r.runRules(groups, config.ExcludePolicy, kevt, satisfied) {
 			return false
 		}
 		if !groups.hasIncludePolicy() {
@@ -625,10 +963,56 @@ func (r *Rules) Fire(kevt *kevent.Kevent) bool {
 	}
 	// run include policy rules. At this point none of
 	// the groups with exclude policies got matched
-	return r.runRules(groups, config.IncludePolicy, kevt)
+	return r.runRules(groups, config.IncludePolicy, kevt, satisfied)
+}
+
+// absenceFunc returns the callback that executes the rule action
+// when the sequence with the negated expression fires. The callback
+// is invoked outside the event flow, so it consults the exceptions
+// of the rule set the sequence was compiled in.
+func (r *Rules) absenceFunc(rs *ruleSet, group config.FilterGroup, f *compiledFilter) func(map[uint16]*kevent.Kevent) {
+	return func(matches map[uint16]*kevent.Kevent) {
+		if rs.exceptions.suppresses(group, f.config, mapValues(matches)...) {
+			return
+		}
+		includeOrFilterMatches.Add(f.config.Name, 1)
+		log.Debugf("rule [%s] in group [%s] matched", f.config.Name, group.Name)
+		if err := r.runAction(f, group, ruleMatch{kevts: matches}); err != nil {
+			log.Warnf("unable to execute %q rule action: %v", f.config.Name, err)
+		}
+	}
+}
+
+// runAction executes the action of the rule that fired unless
+// the match falls into the suppression window of the rule.
+func (r *Rules) runAction(f *compiledFilter, group config.FilterGroup, m ruleMatch) error {
+	if f.sup == nil {
+		return r.match(m.kevt, m.kevts, group, f.config, m.aggs, config.Occurrences{})
+	}
+	occurrences, ok := f.sup.add(m)
+	if !ok {
+		return nil
+	}
+	return r.match(m.kevt, m.kevts, group, f.config, m.aggs, occurrences)
+}
+
+// flushFunc returns the callback that executes the rule action
+// with matches collapsed when the suppression window elapses.
+func (r *Rules) flushFunc(group config.FilterGroup, filter *config.FilterConfig) func(ruleMatch, config.Occurrences) {
+	return func(m ruleMatch, occurrences config.Occurrences) {
+		log.Debugf("rule [%s] in group [%s] matched %d time(s) in suppression window", filter.Name, group.Name, occurrences.Count)
+		if err := r.match(m.kevt, m.kevts, group, filter, m.aggs, occurrences); err != nil {
+			log.Warnf("unable to execute %q rule action: %v", filter.Name, err)
+		}
+	}
 }
 
-func (r *Rules) runSequence(kevt *kevent.Kevent, f *compiledFilter) bool {
+func (r *Rules) runSequence(kevt *kevent.Kevent, f *compiledFilter) (match bool) {
+	if f.prof != nil {
+		defer func(start time.Time) { f.prof.observe(start, match) }(time.Now())
+	}
+	f.ss.mu.Lock()
+	defer f.ss.mu.Unlock()
 	seq := f.filter.GetSequence()
 	for i, expr := range seq.Expressions {
 		// only try to evaluate the expression
@@ -643,9 +1027,21 @@ func (r *Rules) runSequence(kevt *kevent.Kevent, f *compiledFilter) bool {
 		}
 		rule := expr.Expr.String()
 		matches := f.run(kevt, uint16(i))
+		// the event matching the negated expression
+		// breaks the sequence before it can fire
+		if expr.Negated {
+			if matches {
+				f.ss.absenceCancel(kevt)
+			}
+			continue
+		}
 		// append the partial and transition state machine
 		if matches && f.ss.isAfter(rule, kevt) {
-			f.ss.addPartial(rule, kevt)
+			// the partial of the expression preceding the negated
+			// expression arms the absence deadline of its join key
+			if f.ss.addPartial(rule, kevt) && seq.IsNegated() && i == len(seq.Expressions)-2 {
+				f.ss.armAbsence(kevt, time.Now().Add(f.ss.maxSpan))
+			}
 			err := f.ss.matchTransition(rule, kevt)
 			if err != nil {
 				matchTransitionErrors.Add(1)
@@ -659,21 +1055,33 @@ func (r *Rules) runSequence(kevt *kevent.Kevent, f *compiledFilter) bool {
 	// collect all events involved in the rule match
 	isTerminal := f.ss.isTerminalState()
 	if isTerminal {
-		nseqs := uint16(len(f.ss.partials))
-		for i := uint16(1); i < nseqs+1; i++ {
-			for _, outer := range f.ss.partials[i] {
-				for _, inner := range f.ss.partials[i+1] {
-					if compareSeqJoin(outer.SequenceBy(), inner.SequenceBy()) {
-						f.ss.matches[i], f.ss.matches[i+1] = outer, inner
-					}
-				}
-			}
-		}
+		f.ss.joinPartials()
 	}
 	return isTerminal
 }
 
-func (r *Rules) runRules(groups filterGroups, policy config.FilterGroupPolicy, kevt *kevent.Kevent) bool {
+// runAggregation aggregates the event if it matches the aggregation
+// expression and determines whether aggregate thresholds are crossed.
+func (r *Rules) runAggregation(kevt *kevent.Kevent, f *compiledFilter) (match bool) {
+	if f.prof != nil {
+		defer func(start time.Time) { f.prof.observe(start, match) }(time.Now())
+	}
+	valuer, ok := f.filter.RunAggregation(kevt)
+	if !ok {
+		return false
+	}
+	return f.as.add(kevt, valuer)
+}
+
+// runFilter evaluates the rule with the simple expression.
+func (r *Rules) runFilter(kevt *kevent.Kevent, f *compiledFilter, i uint16) (match bool) {
+	if f.prof != nil {
+		defer func(start time.Time) { f.prof.observe(start, match) }(time.Now())
+	}
+	return f.run(kevt, i)
+}
+
+func (r *Rules) runRules(groups filterGroups, policy config.FilterGroupPolicy, kevt *kevent.Kevent, satisfied []bool) bool {
 nextGroup:
 	for _, g := range groups {
 		if g.group.Policy != policy {
@@ -691,8 +1099,14 @@ nextGroup:
 					continue
 				}
 				match = r.runSequence(kevt, f)
+			} else if f.as != nil {
+				match = r.runAggregation(kevt, f)
+			} else if !r.index.admits(f, satisfied) {
+				// the rule can't match the event, so
+				// it is treated as if it didn't match
+				indexSkippedEvaluations.Add(f.config.Name, 1)
 			} else {
-				match = f.run(kevt, uint16(i))
+				match = r.runFilter(kevt, f, uint16(i))
 				if match {
 					// transition sequence states since a match
 					// in a simple rule could trigger multiple
@@ -705,9 +1119,13 @@ nextGroup:
 							continue
 						}
 						if r.runSequence(kevt, f) {
+							if r.exceptions.suppresses(g.group, f.config, f.events(kevt)...) {
+								f.ss.clear()
+								continue
+							}
 							kevt.AddMeta(kevent.RuleNameKey, f.config.Name)
 							log.Debugf("rule [%s] in group [%s] matched", f.config.Name, g.group.Name)
-							err := runFilterAction(nil, f.ss.matches, g.group, f.config)
+							err := r.runAction(f, g.group, ruleMatch{kevts: f.ss.matches})
 							if err != nil {
 								log.Warnf("unable to execute %q rule action: %v", f.config.Name, err)
 							}
@@ -734,6 +1152,10 @@ nextGroup:
 			case config.IncludePolicy:
 				switch g.group.Relation {
 				case config.OrRelation:
+					if match && r.exceptions.suppresses(g.group, f.config, f.events(kevt)...) {
+						f.clearState()
+						continue
+					}
 					if match {
 						includeOrFilterMatches.Add(f.config.Name, 1)
 						log.Debugf("rule [%s] in group [%s] matched", f.config.Name, g.group.Name)
@@ -744,11 +1166,15 @@ nextGroup:
 							kevt.AddMeta(kevent.MetadataKey(k), v)
 						}
 						var err error
-						if f.ss != nil {
-							err = runFilterAction(nil, f.ss.matches, g.group, f.config)
+						switch {
+						case f.ss != nil:
+							err = r.runAction(f, g.group, ruleMatch{kevts: f.ss.matches})
 							f.ss.clear()
-						} else {
-							err = runFilterAction(kevt, nil, g.group, f.config)
+						case f.as != nil:
+							err = r.runAction(f, g.group, ruleMatch{kevts: f.as.matches(), aggs: f.as.values})
+							f.as.clear()
+						default:
+							err = r.runAction(f, g.group, ruleMatch{kevt: kevt})
 						}
 						if err != nil {
 							log.Warnf("unable to execute %q rule action: %v", f.config.Name, err)
@@ -773,14 +1199,22 @@ nextGroup:
 				}
 			case config.IncludePolicy:
 				for _, f := range g.filters {
+					if r.exceptions.suppresses(g.group, f.config, f.events(kevt)...) {
+						f.clearState()
+						continue
+					}
 					includeAndFilterMatches.Add(f.config.Name, 1)
 					log.Debugf("rule [%s] in group [%s] matched", f.config.Name, g.group.Name)
 					var err error
-					if f.ss != nil {
-						err = runFilterAction(nil, f.ss.matches, g.group, f.config)
+					switch {
+					case f.ss != nil:
+						err = r.runAction(f, g.group, ruleMatch{kevts: f.ss.matches})
 						f.ss.clear()
-					} else {
-						err = runFilterAction(kevt, nil, g.group, f.config)
+					case f.as != nil:
+						err = r.runAction(f, g.group, ruleMatch{kevts: f.as.matches(), aggs: f.as.values})
+						f.as.clear()
+					default:
+						err = r.runAction(f, g.group, ruleMatch{kevt: kevt})
 					}
 					if err != nil {
 						log.Warnf("unable to execute %q rule action: %v", f.config.Name, err)
@@ -801,6 +1235,8 @@ func runFilterAction(
 	kevts map[uint16]*kevent.Kevent,
 	group config.FilterGroup,
 	filter *config.FilterConfig,
+	aggs map[string]float64,
+	occurrences config.Occurrences,
 ) error {
 	if filter != nil && filter.Action == "" {
 		return nil
@@ -821,22 +1257,31 @@ func runFilterAction(
 		}
 		sort.Slice(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
 	}
+	if occurrences.Count == 0 && len(events) > 0 {
+		occurrences = config.Occurrences{
+			Count:     1,
+			FirstSeen: events[len(events)-1].Timestamp,
+			LastSeen:  events[len(events)-1].Timestamp,
+		}
+	}
+
+	ctx := &config.ActionContext{
+		Kevt:        kevt,
+		Kevts:       matches,
+		Events:      events,
+		Aggregates:  aggs,
+		Occurrences: occurrences,
+		Filter:      filter,
+		Group:       group,
+	}
 
 	fmap := NewFuncMap()
-	InitFuncs(fmap)
+	InitFuncs(fmap, ctx)
 	tmpl, err := template.New(group.Name).Funcs(fmap).Parse(string(actionBlock))
 	if err != nil {
 		return err
 	}
 
-	ctx := &config.ActionContext{
-		Kevt:   kevt,
-		Kevts:  matches,
-		Events: events,
-		Filter: filter,
-		Group:  group,
-	}
-
 	var bb bytes.Buffer
 	if err := tmpl.Execute(&bb, ctx); err != nil {
 		return err
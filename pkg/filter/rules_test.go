@@ -114,6 +114,25 @@ func newConfig(fromFiles ...string) *config.Config {
 	return c
 }
 
+func newFileEvent(typ ktypes.Ktype, name, filename string) *kevent.Kevent {
+	return &kevent.Kevent{
+		Type:      typ,
+		Timestamp: time.Now(),
+		Name:      name,
+		Tid:       2484,
+		PID:       859,
+		Category:  ktypes.File,
+		PS: &types.PS{
+			Name: "cmd.exe",
+			Exe:  "C:\\Windows\\system32\\cmd.exe",
+		},
+		Kparams: kevent.Kparams{
+			kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: filename},
+		},
+		Metadata: make(map[kevent.MetadataKey]any),
+	}
+}
+
 func TestCompileMergeGroups(t *testing.T) {
 	rules := NewRules(newConfig("_fixtures/merged_groups.yml"))
 	require.NoError(t, rules.Compile())
@@ -496,6 +515,184 @@ func TestSimpleSequencePolicyWithMaxSpanNotReached(t *testing.T) {
 	require.True(t, rules.Fire(kevt2))
 }
 
+func TestNegatedSequenceRule(t *testing.T) {
+	rules := NewRules(newConfig("_fixtures/sequence_rule_negated.yml"))
+	matches := make(chan map[uint16]*kevent.Kevent, 1)
+	rules.match = func(kevt *kevent.Kevent, kevts map[uint16]*kevent.Kevent, group config.FilterGroup, filter *config.FilterConfig, aggs map[string]float64, occurrences config.Occurrences) error {
+		assert.Equal(t, "Dropped executable is not removed", filter.Name)
+		matches <- kevts
+		return nil
+	}
+	require.NoError(t, rules.Compile())
+
+	// the dropped executable is deleted within the
+	// max span, so the sequence is reset without firing
+	require.False(t, rules.Fire(newFileEvent(ktypes.CreateFile, "CreateFile", "C:\\Temp\\dropper.exe")))
+	require.False(t, rules.Fire(newFileEvent(ktypes.DeleteFile, "DeleteFile", "C:\\Temp\\dropper.exe")))
+	select {
+	case <-matches:
+		t.Fatal("sequence shouldn't fire when the negated expression matches")
+	case <-time.After(time.Millisecond * 200):
+	}
+
+	// deletion of the unrelated file doesn't break
+	// the sequence, and when the max span elapses
+	// the rule fires with the upstream event match
+	kevt := newFileEvent(ktypes.CreateFile, "CreateFile", "C:\\Temp\\dropper.exe")
+	require.False(t, rules.Fire(kevt))
+	require.False(t, rules.Fire(newFileEvent(ktypes.DeleteFile, "DeleteFile", "C:\\Temp\\notes.txt")))
+	select {
+	case kevts := <-matches:
+		require.Len(t, kevts, 1)
+		assert.Equal(t, kevt, kevts[1])
+	case <-time.After(time.Second):
+		t.Fatal("sequence should fire when the max span elapses")
+	}
+
+	// the sequence state is reset after firing
+	ss := rules.filterGroups[ktypes.CreateFile.Hash()][0].filters[0].ss
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	assert.True(t, ss.isInitialState())
+	assert.Len(t, ss.partials, 0)
+}
+
+func TestNegatedSequenceRuleByJoinKey(t *testing.T) {
+	rules := NewRules(newConfig("_fixtures/sequence_rule_negated.yml"))
+	matches := make(chan map[uint16]*kevent.Kevent, 2)
+	rules.match = func(kevt *kevent.Kevent, kevts map[uint16]*kevent.Kevent, group config.FilterGroup, filter *config.FilterConfig, aggs map[string]float64, occurrences config.Occurrences) error {
+		matches <- kevts
+		return nil
+	}
+	require.NoError(t, rules.Compile())
+
+	// the deletion of the first dropped executable
+	// only discards the absence of its own join key
+	kevt := newFileEvent(ktypes.CreateFile, "CreateFile", "C:\\Temp\\dropper2.exe")
+	require.False(t, rules.Fire(newFileEvent(ktypes.CreateFile, "CreateFile", "C:\\Temp\\dropper1.exe")))
+	require.False(t, rules.Fire(kevt))
+	require.False(t, rules.Fire(newFileEvent(ktypes.DeleteFile, "DeleteFile", "C:\\Temp\\dropper1.exe")))
+
+	select {
+	case kevts := <-matches:
+		require.Len(t, kevts, 1)
+		assert.Equal(t, kevt, kevts[1])
+	case <-time.After(time.Second):
+		t.Fatal("sequence should fire for the join key without the negated event")
+	}
+	select {
+	case <-matches:
+		t.Fatal("sequence shouldn't fire for the join key with the negated event")
+	case <-time.After(time.Millisecond * 200):
+	}
+
+	ss := rules.filterGroups[ktypes.CreateFile.Hash()][0].filters[0].ss
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	assert.True(t, ss.isInitialState())
+	assert.Len(t, ss.absences, 0)
+}
+
+func TestNegatedSequenceRuleStaggeredJoinKeys(t *testing.T) {
+	rules := NewRules(newConfig("_fixtures/sequence_rule_negated.yml"))
+	type match struct {
+		kevt *kevent.Kevent
+		time time.Time
+	}
+	matches := make(chan match, 2)
+	rules.match = func(kevt *kevent.Kevent, kevts map[uint16]*kevent.Kevent, group config.FilterGroup, filter *config.FilterConfig, aggs map[string]float64, occurrences config.Occurrences) error {
+		matches <- match{kevt: kevts[1], time: time.Now()}
+		return nil
+	}
+	require.NoError(t, rules.Compile())
+
+	// the second join key arrives halfway through the max
+	// span of the first one, so each key fires on its own
+	// deadline with its own upstream event
+	kevt1 := newFileEvent(ktypes.CreateFile, "CreateFile", "C:\\Temp\\dropper1.exe")
+	kevt2 := newFileEvent(ktypes.CreateFile, "CreateFile", "C:\\Temp\\dropper2.exe")
+	start1 := time.Now()
+	require.False(t, rules.Fire(kevt1))
+	time.Sleep(time.Millisecond * 50)
+	start2 := time.Now()
+	require.False(t, rules.Fire(kevt2))
+
+	for _, want := range []struct {
+		kevt  *kevent.Kevent
+		start time.Time
+	}{{kevt1, start1}, {kevt2, start2}} {
+		select {
+		case m := <-matches:
+			assert.Equal(t, want.kevt, m.kevt)
+			assert.True(t, m.time.Sub(want.start) >= time.Millisecond*100)
+		case <-time.After(time.Second):
+			t.Fatal("sequence should fire for each join key")
+		}
+	}
+}
+
+func TestAggregationRule(t *testing.T) {
+	rules := NewRules(newConfig("_fixtures/aggregation_rule.yml"))
+	var (
+		fired int
+		kevts map[uint16]*kevent.Kevent
+		aggs  map[string]float64
+	)
+	rules.match = func(kevt *kevent.Kevent, matches map[uint16]*kevent.Kevent, group config.FilterGroup, filter *config.FilterConfig, values map[string]float64, occurrences config.Occurrences) error {
+		assert.Equal(t, "Excessive failed file opens", filter.Name)
+		fired++
+		kevts, aggs = matches, values
+		return nil
+	}
+	require.NoError(t, rules.Compile())
+
+	now := time.Now()
+	newEvent := func(pid uint32, filename, status string, offset time.Duration) *kevent.Kevent {
+		return &kevent.Kevent{
+			Type:      ktypes.CreateFile,
+			Timestamp: now.Add(offset),
+			Name:      "CreateFile",
+			Tid:       2484,
+			PID:       pid,
+			Category:  ktypes.File,
+			PS: &types.PS{
+				Name: "cmd.exe",
+				Exe:  "C:\\Windows\\system32\\cmd.exe",
+			},
+			Kparams: kevent.Kparams{
+				kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: filename},
+				kparams.NTStatus: {Name: kparams.NTStatus, Type: kparams.UnicodeString, Value: status},
+			},
+			Metadata: make(map[kevent.MetadataKey]any),
+		}
+	}
+
+	// successful opens and repeated paths don't count
+	require.False(t, rules.Fire(newEvent(859, "C:\\Users\\admin\\a.txt", "Success", 0)))
+	require.False(t, rules.Fire(newEvent(859, "C:\\Users\\admin\\b.txt", "Access is denied.", time.Second)))
+	require.False(t, rules.Fire(newEvent(859, "C:\\Users\\admin\\B.txt", "Access is denied.", time.Second*2)))
+	require.False(t, rules.Fire(newEvent(859, "C:\\Users\\admin\\c.txt", "Access is denied.", time.Second*3)))
+	// events from other processes are aggregated in their own groups
+	require.False(t, rules.Fire(newEvent(1024, "C:\\Users\\admin\\d.txt", "Access is denied.", time.Second*4)))
+	require.Equal(t, 0, fired)
+
+	kevt := newEvent(859, "C:\\Users\\admin\\d.txt", "Access is denied.", time.Second*5)
+	require.True(t, rules.Fire(kevt))
+	require.Equal(t, 1, fired)
+	assert.Equal(t, map[string]float64{"count(distinct file.name)": 3}, aggs)
+	require.Len(t, kevts, 4)
+	assert.Equal(t, kevt, kevts[4])
+
+	// the group starts over after the threshold is crossed
+	require.False(t, rules.Fire(newEvent(859, "C:\\Users\\admin\\e.txt", "Access is denied.", time.Second*6)))
+
+	// events outside the tumbling window are not aggregated
+	require.False(t, rules.Fire(newEvent(1024, "C:\\Users\\admin\\e.txt", "Access is denied.", time.Second*10)))
+	require.False(t, rules.Fire(newEvent(1024, "C:\\Users\\admin\\f.txt", "Access is denied.", time.Minute+time.Second*5)))
+	require.False(t, rules.Fire(newEvent(1024, "C:\\Users\\admin\\g.txt", "Access is denied.", time.Minute+time.Second*6)))
+	require.Equal(t, 1, fired)
+}
+
 func TestComplexSequenceRule(t *testing.T) {
 	rules := NewRules(newConfig("_fixtures/sequence_rule_complex.yml"))
 	require.NoError(t, rules.Compile())
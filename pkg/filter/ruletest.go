@@ -0,0 +1,399 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
+	"gopkg.in/yaml.v3"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RuleTest describes a single rule test case. Each test case
+// feeds the fixture events through the rule engine and compares
+// the names of the rules that fired with the expected matches.
+type RuleTest struct {
+	// Name is the test case name.
+	Name string `yaml:"name"`
+	// Description is the optional test case description.
+	Description string `yaml:"description"`
+	// Rules contains optional rule file paths. If specified, they
+	// override the rule paths given in the filters config.
+	Rules []string `yaml:"rules"`
+	// Events is the path of the JSON file with fixture events.
+	Events string `yaml:"events"`
+	// Matches contains the names of the rules and sequences
+	// that are expected to fire for the fixture events. An
+	// empty list asserts no rule fires.
+	Matches []string `yaml:"matches"`
+}
+
+// RuleTestResult represents the outcome of the rule test case.
+type RuleTestResult struct {
+	Test RuleTest
+	// Fired contains the names of the rules that fired.
+	Fired []string
+	// Missing contains expected rules that didn't fire.
+	Missing []string
+	// Unexpected contains rules that fired but were not expected.
+	Unexpected []string
+	// Err is set if the test case couldn't be executed.
+	Err error
+}
+
+// Passed determines if the test case succeeded.
+func (r RuleTestResult) Passed() bool {
+	return r.Err == nil && len(r.Missing) == 0 && len(r.Unexpected) == 0
+}
+
+// LoadRuleTests reads the YAML file with rule test cases. Relative
+// rule and event fixture paths are resolved against the directory
+// of the test file.
+func LoadRuleTests(path string) ([]RuleTest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load rule tests: %v", err)
+	}
+	var tests []RuleTest
+	if err := yaml.Unmarshal(b, &tests); err != nil {
+		return nil, fmt.Errorf("%q is invalid rule tests file: %v", path, err)
+	}
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	for i, test := range tests {
+		if test.Name == "" {
+			return nil, fmt.Errorf("rule test #%d in %s has no name", i+1, path)
+		}
+		if test.Events == "" {
+			return nil, fmt.Errorf("%q rule test in %s has no events", test.Name, path)
+		}
+		tests[i].Events = resolve(test.Events)
+		for j, p := range test.Rules {
+			tests[i].Rules[j] = resolve(p)
+		}
+	}
+	return tests, nil
+}
+
+// RunRuleTests executes rule test cases. Every test case compiles
+// a fresh set of rules, so sequence state is never shared between
+// test cases. Rule actions are not executed. Instead, the names of
+// the rules that fired are recorded and compared against the expected
+// matches. All field accessors are enabled regardless of the kernel
+// stream settings.
+func RunRuleTests(c *config.Config, tests []RuleTest) []RuleTestResult {
+	results := make([]RuleTestResult, 0, len(tests))
+	for _, test := range tests {
+		results = append(results, runRuleTest(c, test))
+	}
+	return results
+}
+
+func runRuleTest(c *config.Config, test RuleTest) RuleTestResult {
+	res := RuleTestResult{Test: test}
+
+	b, err := os.ReadFile(test.Events)
+	if err != nil {
+		res.Err = fmt.Errorf("couldn't load fixture events: %v", err)
+		return res
+	}
+	evts, err := DecodeFixtureEvents(b)
+	if err != nil {
+		res.Err = fmt.Errorf("invalid fixture events in %s: %v", test.Events, err)
+		return res
+	}
+
+	rules := NewRules(ruleTestConfig(c, test))
+	fired := make(map[string]bool)
+	rules.match = func(kevt *kevent.Kevent, kevts map[uint16]*kevent.Kevent, group config.FilterGroup, filter *config.FilterConfig, aggs map[string]float64, occurrences config.Occurrences) error {
+		fired[filter.Name] = true
+		return nil
+	}
+	if err := rules.Compile(); err != nil {
+		res.Err = err
+		return res
+	}
+	for _, evt := range evts {
+		rules.Fire(evt)
+	}
+
+	expected := make(map[string]bool)
+	for _, name := range test.Matches {
+		expected[name] = true
+		if !fired[name] {
+			res.Missing = append(res.Missing, name)
+		}
+	}
+	for name := range fired {
+		res.Fired = append(res.Fired, name)
+		if !expected[name] {
+			res.Unexpected = append(res.Unexpected, name)
+		}
+	}
+	sort.Strings(res.Fired)
+	sort.Strings(res.Unexpected)
+	return res
+}
+
+// ruleTestConfig derives the config for the test case by
+// enabling all event accessors and replacing rule paths
+// if they are declared in the test case.
+func ruleTestConfig(c *config.Config, test RuleTest) *config.Config {
+	cfg := *c
+	cfg.Kstream.EnableThreadKevents = true
+	cfg.Kstream.EnableImageKevents = true
+	cfg.Kstream.EnableFileIOKevents = true
+	cfg.Kstream.EnableRegistryKevents = true
+	cfg.Kstream.EnableNetKevents = true
+	cfg.Kstream.EnableHandleKevents = true
+	cfg.PE.Enabled = true
+
+	filters := &config.Filters{}
+	if c.Filters != nil {
+		*filters = *c.Filters
+	}
+	if len(test.Rules) > 0 {
+		filters.Rules = config.Rules{FromPaths: test.Rules}
+	}
+	cfg.Filters = filters
+	return &cfg
+}
+
+// fixtureEvent is the JSON representation of the event in
+// rule test fixtures. It follows the layout of the event.
+type fixtureEvent struct {
+	Seq         uint64                  `json:"seq"`
+	PID         uint32                  `json:"pid"`
+	Tid         uint32                  `json:"tid"`
+	CPU         uint8                   `json:"cpu"`
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Host        string                  `json:"host"`
+	Timestamp   time.Time               `json:"timestamp"`
+	Kparams     map[string]fixtureParam `json:"kparams"`
+	Metadata    map[string]string       `json:"metadata"`
+	PS          *pstypes.PS             `json:"ps"`
+}
+
+// fixtureParam is the event parameter declared in the fixture. The
+// parameter is either given as an object with the type and value
+// keys, or as a bare string that is assumed to be a Unicode string.
+type fixtureParam struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (p *fixtureParam) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		p.Type, p.Value = "unicode", b
+		return nil
+	}
+	type param fixtureParam
+	var par param
+	if err := json.Unmarshal(b, &par); err != nil {
+		return err
+	}
+	*p = fixtureParam(par)
+	return nil
+}
+
+var fixtureParamTypes = map[string]kparams.Type{
+	"unicode": kparams.UnicodeString,
+	"ansi":    kparams.AnsiString,
+	"int8":    kparams.Int8,
+	"uint8":   kparams.Uint8,
+	"int16":   kparams.Int16,
+	"uint16":  kparams.Uint16,
+	"int32":   kparams.Int32,
+	"uint32":  kparams.Uint32,
+	"int64":   kparams.Int64,
+	"uint64":  kparams.Uint64,
+	"float":   kparams.Float,
+	"double":  kparams.Double,
+	"bool":    kparams.Bool,
+	"sid":     kparams.SID,
+	"pid":     kparams.PID,
+	"tid":     kparams.TID,
+	"port":    kparams.Port,
+	"ipv4":    kparams.IPv4,
+	"ipv6":    kparams.IPv6,
+	"hex8":    kparams.HexInt8,
+	"hex16":   kparams.HexInt16,
+	"hex32":   kparams.HexInt32,
+	"hex64":   kparams.HexInt64,
+	"time":    kparams.Time,
+	"slice":   kparams.Slice,
+	"enum":    kparams.Enum,
+}
+
+// value converts the raw parameter value to the representation
+// expected by the event accessors for the declared type.
+func (p fixtureParam) value(name string) (kparams.Type, kparams.Value, error) {
+	typ, ok := fixtureParamTypes[strings.ToLower(p.Type)]
+	if !ok {
+		return kparams.Unknown, nil, fmt.Errorf("%q parameter has unknown type %q", name, p.Type)
+	}
+	var err error
+	unmarshal := func(v any) {
+		if err = json.Unmarshal(p.Value, v); err != nil {
+			err = fmt.Errorf("%q parameter has invalid %s value: %v", name, p.Type, err)
+		}
+	}
+	switch typ {
+	case kparams.UnicodeString, kparams.AnsiString, kparams.SID:
+		var v string
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.Int8:
+		var v int8
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.Uint8, kparams.Enum:
+		var v uint8
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.Int16:
+		var v int16
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.Uint16, kparams.Port:
+		var v uint16
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.Int32:
+		var v int32
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.Uint32, kparams.PID, kparams.TID:
+		var v uint32
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.Int64:
+		var v int64
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.Uint64:
+		var v uint64
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.Float:
+		var v float32
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.Double:
+		var v float64
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.Bool:
+		var v bool
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.IPv4, kparams.IPv6:
+		var v string
+		unmarshal(&v)
+		ip := net.ParseIP(v)
+		if err == nil && ip == nil {
+			err = fmt.Errorf("%q parameter has invalid IP address %q", name, v)
+		}
+		return typ, ip, err
+	case kparams.HexInt8, kparams.HexInt16, kparams.HexInt32, kparams.HexInt64:
+		var v string
+		unmarshal(&v)
+		return typ, kparams.Hex(v), err
+	case kparams.Time:
+		var v time.Time
+		unmarshal(&v)
+		return typ, v, err
+	case kparams.Slice:
+		var v []string
+		unmarshal(&v)
+		return typ, v, err
+	}
+	return typ, nil, fmt.Errorf("%q parameter has unsupported type %q", name, p.Type)
+}
+
+// DecodeFixtureEvents decodes the JSON array of fixture events. The event
+// type and category are resolved from the event name. Events without a
+// timestamp get a synthetic timestamp that preserves the order of events
+// in the array, so they can be correlated by sequence rules.
+func DecodeFixtureEvents(b []byte) ([]*kevent.Kevent, error) {
+	var fixtures []fixtureEvent
+	if err := json.Unmarshal(b, &fixtures); err != nil {
+		return nil, err
+	}
+	evts := make([]*kevent.Kevent, 0, len(fixtures))
+	ts := time.Now()
+	for i, f := range fixtures {
+		ktype := ktypes.KeventNameToKtype(f.Name)
+		if ktype == ktypes.UnknownKtype {
+			return nil, fmt.Errorf("event #%d has unknown event name %q", i+1, f.Name)
+		}
+		evt := &kevent.Kevent{
+			Seq:         f.Seq,
+			PID:         f.PID,
+			Tid:         f.Tid,
+			CPU:         f.CPU,
+			Type:        ktype,
+			Name:        f.Name,
+			Category:    ktype.Category(),
+			Description: f.Description,
+			Host:        f.Host,
+			Timestamp:   f.Timestamp,
+			Kparams:     make(kevent.Kparams),
+			Metadata:    make(kevent.Metadata),
+			PS:          f.PS,
+		}
+		if evt.Description == "" {
+			evt.Description = ktype.Description()
+		}
+		if evt.Seq == 0 {
+			evt.Seq = uint64(i + 1)
+		}
+		if evt.Timestamp.IsZero() {
+			evt.Timestamp = ts.Add(time.Duration(i) * time.Millisecond)
+		}
+		for name, par := range f.Kparams {
+			typ, v, err := par.value(name)
+			if err != nil {
+				return nil, fmt.Errorf("event #%d: %v", i+1, err)
+			}
+			evt.Kparams[name] = &kevent.Kparam{Name: name, Type: typ, Value: v}
+		}
+		for k, v := range f.Metadata {
+			evt.AddMeta(kevent.MetadataKey(k), v)
+		}
+		evts = append(evts, evt)
+	}
+	return evts, nil
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2020-2021 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"net"
+	"testing"
+
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunRuleTests(t *testing.T) {
+	tests, err := LoadRuleTests("_fixtures/ruletest/tests.yml")
+	require.NoError(t, err)
+	require.Len(t, tests, 3)
+
+	results := RunRuleTests(newConfig(), tests)
+	require.Len(t, results, 3)
+
+	assert.True(t, results[0].Passed())
+	assert.True(t, results[1].Passed())
+	assert.Equal(t, []string{"Command shell created a temp file"}, results[1].Fired)
+
+	assert.False(t, results[2].Passed())
+	assert.Nil(t, results[2].Err)
+	assert.Equal(t, []string{"Command shell created a temp file"}, results[2].Missing)
+	assert.Equal(t, []string{"match https connections"}, results[2].Unexpected)
+}
+
+func TestDecodeFixtureEvents(t *testing.T) {
+	kevts, err := DecodeFixtureEvents([]byte(`[
+	  {
+	    "name": "Recv",
+	    "pid": 859,
+	    "kparams": {
+	      "dport": {"type": "port", "value": 443},
+	      "dip": {"type": "ipv4", "value": "216.58.201.174"},
+	      "file_name": "C:\\Windows\\notepad.exe"
+	    }
+	  },
+	  {
+	    "name": "CreateFile"
+	  }
+	]`))
+	require.NoError(t, err)
+	require.Len(t, kevts, 2)
+
+	kevt := kevts[0]
+	assert.Equal(t, ktypes.Recv, kevt.Type)
+	assert.Equal(t, ktypes.Net, kevt.Category)
+	assert.Equal(t, uint64(1), kevt.Seq)
+
+	dport, err := kevt.Kparams.GetUint16(kparams.NetDport)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(443), dport)
+	dip, err := kevt.Kparams.GetIP(kparams.NetDIP)
+	require.NoError(t, err)
+	assert.Equal(t, net.ParseIP("216.58.201.174").To4(), dip.To4())
+	filename, err := kevt.Kparams.GetString(kparams.FileName)
+	require.NoError(t, err)
+	assert.Equal(t, "C:\\Windows\\notepad.exe", filename)
+
+	assert.True(t, kevts[1].Timestamp.After(kevt.Timestamp))
+
+	_, err = DecodeFixtureEvents([]byte(`[{"name": "Unknown"}]`))
+	require.Error(t, err)
+}
@@ -0,0 +1,450 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	fsm "github.com/qmuntal/stateless"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/rabbitstack/fibratus/pkg/util/hashers"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// sequenceSnapshotVersion designates the current version of the sequence snapshot format
+	sequenceSnapshotVersion uint16 = 1
+	// defaultSequenceSnapshotInterval determines the default period for snapshotting the sequence state
+	defaultSequenceSnapshotInterval = time.Minute
+)
+
+var (
+	sequenceSnapshotErrors = expvar.NewInt("sequence.snapshot.errors")
+	sequencesRestored      = expvar.NewInt("sequence.snapshot.restored")
+	sequencesDiscarded     = expvar.NewMap("sequence.snapshot.discarded")
+
+	errSequenceDeadlineElapsed = errors.New("max span deadline elapsed")
+)
+
+// sequenceSnapshot is the persisted state of all in-flight sequences.
+type sequenceSnapshot struct {
+	Version   uint16          `json:"version"`
+	Timestamp time.Time       `json:"timestamp"`
+	Sequences []sequenceEntry `json:"sequences"`
+}
+
+// sequenceEntry stores the state of the single sequence rule. The
+// sequence is identified by the group hash and the rule name, while
+// the expression hash detects if the rule was modified in the meantime.
+type sequenceEntry struct {
+	Group        string                       `json:"group"`
+	GroupHash    uint32                       `json:"group-hash"`
+	Rule         string                       `json:"rule"`
+	ExprHash     uint32                       `json:"expr-hash"`
+	State        string                       `json:"state"`
+	MatchedRules []uint16                     `json:"matched-rules"`
+	Deadline     *time.Time                   `json:"deadline,omitempty"`
+	Partials     map[uint16][]sequencePartial `json:"partials"`
+}
+
+// sequencePartial stores the partial event in the raw format. The raw
+// format only carries the process state for process creation events and
+// stringifies metadata values, so the process state and the typed value
+// of the sequence join field are persisted alongside the event. The
+// partial that armed the absence deadline of its join key keeps the
+// deadline.
+type sequencePartial struct {
+	Kevt     []byte        `json:"kevt"`
+	PS       []byte        `json:"ps,omitempty"`
+	SeqBy    *sequenceJoin `json:"seq-by,omitempty"`
+	Deadline *time.Time    `json:"deadline,omitempty"`
+}
+
+// sequenceJoin represents the typed value of the sequence join field.
+type sequenceJoin struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func newSequenceJoin(v any) *sequenceJoin {
+	switch val := v.(type) {
+	case string:
+		return &sequenceJoin{Type: "string", Value: val}
+	case uint8:
+		return &sequenceJoin{Type: "uint8", Value: strconv.FormatUint(uint64(val), 10)}
+	case uint16:
+		return &sequenceJoin{Type: "uint16", Value: strconv.FormatUint(uint64(val), 10)}
+	case uint32:
+		return &sequenceJoin{Type: "uint32", Value: strconv.FormatUint(uint64(val), 10)}
+	case uint64:
+		return &sequenceJoin{Type: "uint64", Value: strconv.FormatUint(val, 10)}
+	case uint:
+		return &sequenceJoin{Type: "uint", Value: strconv.FormatUint(uint64(val), 10)}
+	case int:
+		return &sequenceJoin{Type: "int", Value: strconv.Itoa(val)}
+	case net.IP:
+		return &sequenceJoin{Type: "ip", Value: val.String()}
+	}
+	return nil
+}
+
+// value converts the persisted join value to its original type.
+func (j sequenceJoin) value() (any, error) {
+	switch j.Type {
+	case "string":
+		return j.Value, nil
+	case "uint8":
+		n, err := strconv.ParseUint(j.Value, 10, 8)
+		return uint8(n), err
+	case "uint16":
+		n, err := strconv.ParseUint(j.Value, 10, 16)
+		return uint16(n), err
+	case "uint32":
+		n, err := strconv.ParseUint(j.Value, 10, 32)
+		return uint32(n), err
+	case "uint64":
+		return strconv.ParseUint(j.Value, 10, 64)
+	case "uint":
+		n, err := strconv.ParseUint(j.Value, 10, 64)
+		return uint(n), err
+	case "int":
+		return strconv.Atoi(j.Value)
+	case "ip":
+		ip := net.ParseIP(j.Value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip address %q", j.Value)
+		}
+		return ip, nil
+	}
+	return nil, fmt.Errorf("unknown sequence join type %q", j.Type)
+}
+
+func newSequencePartial(kevt *kevent.Kevent) sequencePartial {
+	p := sequencePartial{Kevt: kevt.MarshalRaw(), SeqBy: newSequenceJoin(kevt.SequenceBy())}
+	if kevt.PS != nil {
+		p.PS = kevt.PS.Marshal()
+	}
+	return p
+}
+
+func (p sequencePartial) kevent() (*kevent.Kevent, error) {
+	kevt, err := kevent.NewFromKcap(p.Kevt)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.PS) > 0 {
+		kevt.PS, err = pstypes.NewFromKcap(p.PS)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.SeqBy != nil {
+		v, err := p.SeqBy.value()
+		if err != nil {
+			return nil, err
+		}
+		kevt.AddMeta(kevent.RuleSequenceByKey, v)
+	}
+	return kevt, nil
+}
+
+// snapshot captures the state of the sequence. It returns false if
+// the sequence is in the initial state, i.e. none of its rules matched.
+func (s *sequenceState) snapshot() (sequenceEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.isInitialState() {
+		return sequenceEntry{}, false
+	}
+	state := s.currentState()
+	e := sequenceEntry{
+		State:        fmt.Sprintf("%v", state),
+		MatchedRules: make([]uint16, 0, len(s.matchedRules)),
+		Partials:     make(map[uint16][]sequencePartial, len(s.partials)),
+	}
+	for idx, matched := range s.matchedRules {
+		if matched {
+			e.MatchedRules = append(e.MatchedRules, idx)
+		}
+	}
+	sort.Slice(e.MatchedRules, func(i, j int) bool { return e.MatchedRules[i] < e.MatchedRules[j] })
+	if deadline, ok := s.deadlines[state]; ok {
+		e.Deadline = &deadline
+	}
+	for idx, partials := range s.partials {
+		for _, kevt := range partials {
+			p := newSequencePartial(kevt)
+			if a, ok := s.absences[joinKey(kevt.SequenceBy())]; ok && a.kevt == kevt {
+				deadline := a.deadline
+				p.Deadline = &deadline
+			}
+			e.Partials[idx] = append(e.Partials[idx], p)
+		}
+	}
+	return e, true
+}
+
+// restore rebuilds the sequence state from the snapshot entry. The
+// state machine is advanced to the persisted position, and the max
+// span deadline is rescheduled to fire after the remaining duration.
+// Absence deadlines are rearmed for each join key, and the partials
+// of join keys whose absence deadline elapsed are discarded.
+func (s *sequenceState) restore(e sequenceEntry, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := fsm.State(e.State)
+	if _, ok := s.idxs[state]; !ok {
+		return fmt.Errorf("unknown sequence state %q", e.State)
+	}
+	var remaining time.Duration
+	if e.Deadline != nil {
+		remaining = e.Deadline.Sub(now)
+		if remaining <= 0 {
+			return errSequenceDeadlineElapsed
+		}
+	}
+
+	partials := make(map[uint16][]*kevent.Kevent, len(e.Partials))
+	absences := make(map[*kevent.Kevent]time.Time)
+	elapsed := make(map[string]bool)
+	for idx, ps := range e.Partials {
+		for _, p := range ps {
+			kevt, err := p.kevent()
+			if err != nil {
+				return fmt.Errorf("unable to restore partial in slot [%d]: %v", idx, err)
+			}
+			if p.Deadline != nil {
+				if p.Deadline.After(now) {
+					absences[kevt] = *p.Deadline
+				} else {
+					elapsed[joinKey(kevt.SequenceBy())] = true
+				}
+			}
+			partials[idx] = append(partials[idx], kevt)
+		}
+	}
+	if state == s.negatedState {
+		for idx, ps := range partials {
+			n := 0
+			for _, kevt := range ps {
+				if !elapsed[joinKey(kevt.SequenceBy())] {
+					ps[n] = kevt
+					n++
+				}
+			}
+			partials[idx] = ps[:n]
+		}
+		if len(absences) == 0 {
+			return errSequenceDeadlineElapsed
+		}
+	}
+
+	for i := 0; s.currentState() != state; i++ {
+		if i >= len(s.idxs) {
+			return fmt.Errorf("unable to transition to %q state", e.State)
+		}
+		if err := s.fsm.Fire(matchTransition, nil); err != nil {
+			return err
+		}
+	}
+	if e.Deadline != nil {
+		if span, ok := s.spanDeadlines[state]; ok {
+			span.Stop()
+		}
+		s.scheduleMaxSpanDeadline(state, remaining)
+	}
+
+	s.partials = partials
+	s.matchedRules = make(map[uint16]bool, len(e.MatchedRules))
+	for _, idx := range e.MatchedRules {
+		s.matchedRules[idx] = true
+	}
+	for _, ps := range partials {
+		partialsPerSequence.Add(s.name, int64(len(ps)))
+	}
+	for kevt, deadline := range absences {
+		s.armAbsence(kevt, deadline)
+	}
+	return nil
+}
+
+// sequenceKey identifies the sequence rule across restarts.
+func sequenceKey(groupHash uint32, rule string) string {
+	return strconv.FormatUint(uint64(groupHash), 10) + rule
+}
+
+// filterGroupSequence binds the sequence rule to its group.
+type filterGroupSequence struct {
+	group     string
+	groupHash uint32
+	filter    *compiledFilter
+}
+
+func (s filterGroupSequence) exprHash() uint32 {
+	return hashers.FnvUint32([]byte(expr(s.filter.config)))
+}
+
+// sequences returns all compiled sequence rules indexed by the sequence key.
+func (r *Rules) sequences() map[string]*filterGroupSequence {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seqs := make(map[string]*filterGroupSequence)
+	for _, g := range r.groups {
+		for _, f := range g.filters {
+			if f.ss == nil {
+				continue
+			}
+			hash := g.group.Hash()
+			seqs[sequenceKey(hash, f.config.Name)] = &filterGroupSequence{group: g.group.Name, groupHash: hash, filter: f}
+		}
+	}
+	return seqs
+}
+
+// snapshotSequences captures the state of all in-flight sequences.
+func (r *Rules) snapshotSequences(now time.Time) *sequenceSnapshot {
+	snap := &sequenceSnapshot{
+		Version:   sequenceSnapshotVersion,
+		Timestamp: now,
+		Sequences: make([]sequenceEntry, 0),
+	}
+	for _, seq := range r.sequences() {
+		e, ok := seq.filter.ss.snapshot()
+		if !ok {
+			continue
+		}
+		e.Group, e.GroupHash = seq.group, seq.groupHash
+		e.Rule, e.ExprHash = seq.filter.config.Name, seq.exprHash()
+		snap.Sequences = append(snap.Sequences, e)
+	}
+	return snap
+}
+
+// restoreSequences rebuilds sequence states from the snapshot and
+// returns the number of restored sequences. Sequences of removed or
+// modified rules, and sequences whose deadlines elapsed are discarded.
+func (r *Rules) restoreSequences(snap *sequenceSnapshot, now time.Time) int {
+	seqs := r.sequences()
+	var n int
+	for _, e := range snap.Sequences {
+		seq, ok := seqs[sequenceKey(e.GroupHash, e.Rule)]
+		if !ok {
+			log.Warnf("%q sequence rule in %q group not found. Discarding persisted state", e.Rule, e.Group)
+			sequencesDiscarded.Add(e.Rule, 1)
+			continue
+		}
+		if seq.exprHash() != e.ExprHash {
+			log.Warnf("%q sequence rule in %q group was modified. Discarding persisted state", e.Rule, e.Group)
+			sequencesDiscarded.Add(e.Rule, 1)
+			continue
+		}
+		if err := seq.filter.ss.restore(e, now); err != nil {
+			log.Warnf("discarding persisted state of %q sequence rule in %q group: %v", e.Rule, e.Group, err)
+			sequencesDiscarded.Add(e.Rule, 1)
+			continue
+		}
+		n++
+	}
+	sequencesRestored.Add(int64(n))
+	return n
+}
+
+// SnapshotSequences writes the state of in-flight sequences to the state
+// file. The snapshot is first written to the temporary file which then
+// replaces the state file, so the state file is never left truncated.
+func (r *Rules) SnapshotSequences() error {
+	path := r.config.Filters.Sequences.StateFile
+	if path == "" {
+		return nil
+	}
+	b, err := json.Marshal(r.snapshotSequences(time.Now()))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// RestoreSequences rebuilds the state of sequences persisted in the state
+// file. This method should be called after the rules are compiled.
+func (r *Rules) RestoreSequences() error {
+	path := r.config.Filters.Sequences.StateFile
+	if path == "" {
+		return nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var snap sequenceSnapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return fmt.Errorf("invalid sequence snapshot: %v", err)
+	}
+	if snap.Version != sequenceSnapshotVersion {
+		return fmt.Errorf("unsupported sequence snapshot version %d", snap.Version)
+	}
+	n := r.restoreSequences(&snap, time.Now())
+	log.Infof("restored %d sequence(s) from snapshot taken at %s", n, snap.Timestamp.Format(time.RFC3339))
+	return nil
+}
+
+// ScheduleSequenceSnapshots periodically snapshots the
+// sequence state until the rules engine is closed.
+func (r *Rules) ScheduleSequenceSnapshots() {
+	if r.config.Filters.Sequences.StateFile == "" {
+		return
+	}
+	interval := r.config.Filters.Sequences.SnapshotInterval
+	if interval <= 0 {
+		interval = defaultSequenceSnapshotInterval
+	}
+	go func() {
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				if err := r.SnapshotSequences(); err != nil {
+					sequenceSnapshotErrors.Add(1)
+					log.Warnf("unable to snapshot sequence state: %v", err)
+				}
+			case <-r.quit:
+				return
+			}
+		}
+	}()
+}
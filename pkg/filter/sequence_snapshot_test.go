@@ -0,0 +1,149 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceSnapshot(t *testing.T) {
+	c := newConfig("_fixtures/sequence_rule_snapshot.yml")
+	c.Filters.Sequences.StateFile = filepath.Join(t.TempDir(), "state", "sequences.json")
+
+	kevt1 := &kevent.Kevent{
+		Type:      ktypes.CreateProcess,
+		Timestamp: time.Now(),
+		Name:      "CreateProcess",
+		Tid:       2484,
+		PID:       859,
+		PS: &types.PS{
+			PID:  859,
+			Name: "cmd.exe",
+			Exe:  "C:\\Windows\\system32\\cmd.exe",
+		},
+		Kparams: kevent.Kparams{
+			kparams.ProcessID: {Name: kparams.ProcessID, Type: kparams.Uint32, Value: uint32(4143)},
+		},
+		Metadata: make(map[kevent.MetadataKey]any),
+	}
+
+	kevt2 := &kevent.Kevent{
+		Type:      ktypes.CreateFile,
+		Timestamp: time.Now().Add(time.Millisecond),
+		Name:      "CreateFile",
+		Tid:       2484,
+		PID:       859,
+		Category:  ktypes.File,
+		PS: &types.PS{
+			PID:  859,
+			Name: "cmd.exe",
+			Exe:  "C:\\Windows\\system32\\cmd.exe",
+		},
+		Kparams: kevent.Kparams{
+			kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Temp\\dropper.exe"},
+		},
+		Metadata: make(map[kevent.MetadataKey]any),
+	}
+
+	rules := NewRules(c)
+	require.NoError(t, rules.Compile())
+	require.False(t, rules.Fire(kevt1))
+	require.NoError(t, rules.Close())
+
+	// the sequence is restored in the freshly compiled
+	// rules engine and fires on the downstream event
+	restored := NewRules(c)
+	require.NoError(t, restored.Compile())
+	require.NoError(t, restored.RestoreSequences())
+
+	ss := restored.filterGroups[ktypes.CreateFile.Hash()][0].filters[0].ss
+	ss.mu.Lock()
+	assert.False(t, ss.isInitialState())
+	assert.True(t, ss.matchedRules[1])
+	require.Len(t, ss.partials[1], 1)
+	partial := ss.partials[1][0]
+	assert.Equal(t, kevt1.SequenceBy(), partial.SequenceBy())
+	assert.Equal(t, kevt1.Timestamp.UnixNano(), partial.Timestamp.UnixNano())
+	require.NotNil(t, partial.PS)
+	assert.Equal(t, "cmd.exe", partial.PS.Name)
+	assert.Contains(t, ss.spanDeadlines, ss.currentState())
+	ss.mu.Unlock()
+
+	require.True(t, restored.Fire(kevt2))
+
+	// sequences whose deadlines elapsed are discarded
+	snap := rules.snapshotSequences(time.Now())
+	require.Len(t, snap.Sequences, 1)
+	expired := NewRules(c)
+	require.NoError(t, expired.Compile())
+	assert.Equal(t, 0, expired.restoreSequences(snap, time.Now().Add(time.Hour*2)))
+	ss = expired.filterGroups[ktypes.CreateFile.Hash()][0].filters[0].ss
+	assert.True(t, ss.isInitialState())
+	assert.Len(t, ss.partials, 0)
+	require.False(t, expired.Fire(kevt2))
+
+	// sequences of modified rules are discarded
+	snap.Sequences[0].ExprHash++
+	modified := NewRules(c)
+	require.NoError(t, modified.Compile())
+	assert.Equal(t, 0, modified.restoreSequences(snap, time.Now()))
+}
+
+func TestSequenceSnapshotUnsupportedVersion(t *testing.T) {
+	c := newConfig("_fixtures/sequence_rule_snapshot.yml")
+	c.Filters.Sequences.StateFile = filepath.Join(t.TempDir(), "sequences.json")
+	require.NoError(t, os.WriteFile(c.Filters.Sequences.StateFile, []byte(`{"version":99,"sequences":[]}`), 0600))
+
+	rules := NewRules(c)
+	require.NoError(t, rules.Compile())
+	require.EqualError(t, rules.RestoreSequences(), "unsupported sequence snapshot version 99")
+}
+
+func TestSequenceJoin(t *testing.T) {
+	var tests = []any{
+		"C:\\Temp\\dropper.exe",
+		uint8(6),
+		uint16(443),
+		uint32(859),
+		uint64(0xffffffffffff),
+		uint(2),
+		-1,
+		net.ParseIP("10.0.0.1"),
+	}
+
+	for _, tt := range tests {
+		j := newSequenceJoin(tt)
+		require.NotNil(t, j)
+		v, err := j.value()
+		require.NoError(t, err)
+		assert.True(t, compareSeqJoin(tt, v), j.Type)
+	}
+	assert.Nil(t, newSequenceJoin(nil))
+}
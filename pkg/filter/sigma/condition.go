@@ -0,0 +1,274 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sigma
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/util/wildcard"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// conditionParser translates the Sigma detection condition
+// into the filter expression. Detection identifiers are
+// replaced by the expressions produced from the search
+// identifiers. The grammar is:
+//
+//	expr   := term { "or" term }
+//	term   := factor { "and" factor }
+//	factor := "not" factor | "(" expr ")" | quant | ident
+//	quant  := ( "1" | "all" ) "of" ( "them" | pattern )
+//
+// The filter parser negates the whole remainder of the expression
+// that follows the `and not` operator, so negated factors are moved
+// to the end of each conjunction and merged into a single negation.
+type conditionParser struct {
+	tokens []string
+	pos    int
+	// idents contains translated search identifiers
+	idents map[string]string
+	// anchor is the expression that precedes the negation
+	// in conjunctions consisting only of negated factors
+	anchor string
+}
+
+// tokenize splits the condition into identifiers, keywords and parentheses.
+func tokenize(cond string) []string {
+	tokens := make([]string, 0)
+	var sb strings.Builder
+	flush := func() {
+		if sb.Len() > 0 {
+			tokens = append(tokens, sb.String())
+			sb.Reset()
+		}
+	}
+	for _, c := range cond {
+		switch {
+		case unicode.IsSpace(c):
+			flush()
+		case c == '(' || c == ')' || c == '|':
+			flush()
+			tokens = append(tokens, string(c))
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+func translateCondition(cond string, idents map[string]string, anchor string) (string, error) {
+	p := &conditionParser{tokens: tokenize(cond), idents: idents, anchor: anchor}
+	if len(p.tokens) == 0 {
+		return "", fmt.Errorf("empty condition")
+	}
+	expr, _, err := p.parseExpr()
+	if err != nil {
+		return "", err
+	}
+	if tok := p.peek(); tok != "" {
+		if tok == "|" {
+			return "", fmt.Errorf("aggregation expressions are not supported")
+		}
+		return "", fmt.Errorf("unexpected %q in condition", tok)
+	}
+	return expr, nil
+}
+
+func (p *conditionParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *conditionParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+// parseExpr parses the disjunction of terms. The returned flag
+// indicates whether the expression ends with the negation.
+func (p *conditionParser) parseExpr() (string, bool, error) {
+	expr, negated, err := p.parseTerm()
+	if err != nil {
+		return "", false, err
+	}
+	terms := []string{expr}
+	negations := []bool{negated}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		expr, negated, err := p.parseTerm()
+		if err != nil {
+			return "", false, err
+		}
+		terms = append(terms, expr)
+		negations = append(negations, negated)
+	}
+	if len(terms) == 1 {
+		return terms[0], negations[0], nil
+	}
+	for i, term := range terms {
+		if negations[i] {
+			terms[i] = paren(term)
+		}
+	}
+	return join(terms, "or"), false, nil
+}
+
+// parseTerm parses the conjunction of factors. Negated factors
+// are combined into a single negation at the end of the term.
+func (p *conditionParser) parseTerm() (string, bool, error) {
+	var factors, negated []string
+	for {
+		expr, neg, err := p.parseFactor()
+		if err != nil {
+			return "", false, err
+		}
+		if neg {
+			negated = append(negated, paren(expr))
+		} else {
+			factors = append(factors, expr)
+		}
+		if !strings.EqualFold(p.peek(), "and") {
+			break
+		}
+		p.next()
+	}
+	if len(negated) == 0 {
+		return join(factors, "and"), false, nil
+	}
+	if len(factors) == 0 {
+		factors = append(factors, p.anchor)
+	}
+	return join(factors, "and") + " and not " + paren(join(negated, "or")), true, nil
+}
+
+// parseFactor parses the single factor. The returned flag indicates
+// the factor is negated, in which case the expression is the operand
+// of the negation.
+func (p *conditionParser) parseFactor() (string, bool, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return "", false, fmt.Errorf("unexpected end of condition")
+	case strings.EqualFold(tok, "not"):
+		expr, negated, err := p.parseFactor()
+		if err != nil {
+			return "", false, err
+		}
+		return expr, !negated, nil
+	case tok == "(":
+		expr, _, err := p.parseExpr()
+		if err != nil {
+			return "", false, err
+		}
+		if p.next() != ")" {
+			return "", false, fmt.Errorf("expected ')' in condition")
+		}
+		return paren(expr), false, nil
+	case (tok == "1" || strings.EqualFold(tok, "all") || strings.EqualFold(tok, "any")) &&
+		strings.EqualFold(p.peek(), "of"):
+		p.next()
+		pattern := p.next()
+		if pattern == "" {
+			return "", false, fmt.Errorf("expected search identifier pattern after %q", tok+" of")
+		}
+		idents := p.match(pattern)
+		if len(idents) == 0 {
+			return "", false, fmt.Errorf("%q doesn't match any search identifier", pattern)
+		}
+		exprs := make([]string, len(idents))
+		for i, ident := range idents {
+			exprs[i] = p.idents[ident]
+		}
+		return combine(exprs, strings.EqualFold(tok, "all")), false, nil
+	case tok == ")" || tok == "|" || strings.EqualFold(tok, "and") || strings.EqualFold(tok, "or"):
+		return "", false, fmt.Errorf("unexpected %q in condition", tok)
+	}
+	expr, ok := p.idents[tok]
+	if !ok {
+		return "", false, fmt.Errorf("undefined search identifier %q", tok)
+	}
+	return expr, false, nil
+}
+
+// match returns sorted search identifiers matching the pattern. The
+// `them` keyword matches all identifiers not starting with underscore.
+func (p *conditionParser) match(pattern string) []string {
+	idents := make([]string, 0)
+	for ident := range p.idents {
+		if strings.EqualFold(pattern, "them") {
+			if !strings.HasPrefix(ident, "_") {
+				idents = append(idents, ident)
+			}
+			continue
+		}
+		if wildcard.Match(pattern, ident) {
+			idents = append(idents, ident)
+		}
+	}
+	sort.Strings(idents)
+	return idents
+}
+
+// join combines expressions with the logical operator.
+func join(exprs []string, op string) string {
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+	return strings.Join(exprs, " "+op+" ")
+}
+
+// paren wraps the expression in parenthesis unless it is already wrapped.
+func paren(expr string) string {
+	if isWrapped(expr) {
+		return expr
+	}
+	return "(" + expr + ")"
+}
+
+// isWrapped determines whether the opening parenthesis
+// at the start of the expression is closed at its end.
+func isWrapped(expr string) bool {
+	if !strings.HasPrefix(expr, "(") || !strings.HasSuffix(expr, ")") {
+		return false
+	}
+	depth := 0
+	var quoted bool
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == '\\' && quoted:
+			i++
+		case c == '\'':
+			quoted = !quoted
+		case c == '(' && !quoted:
+			depth++
+		case c == ')' && !quoted:
+			depth--
+			if depth == 0 && i < len(expr)-1 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
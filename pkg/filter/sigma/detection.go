@@ -0,0 +1,321 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sigma
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"gopkg.in/yaml.v3"
+	"strconv"
+	"strings"
+)
+
+const (
+	modContains   = "contains"
+	modStartswith = "startswith"
+	modEndswith   = "endswith"
+	modRe         = "re"
+	modAll        = "all"
+	modCIDR       = "cidr"
+)
+
+// translator produces filter expressions from Sigma search identifiers.
+type translator struct {
+	category string
+	src      logsource
+	// unsupported accumulates constructs that can't be translated
+	unsupported []string
+}
+
+func (t *translator) unsupportedf(format string, args ...interface{}) {
+	t.unsupported = append(t.unsupported, fmt.Sprintf(format, args...))
+}
+
+// translateSearch translates the search identifier. Maps are translated
+// to the conjunction of field conditions, while lists of maps produce
+// the disjunction of map expressions.
+func (t *translator) translateSearch(name string, n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return t.translateMap(name, n)
+	case yaml.SequenceNode:
+		exprs := make([]string, 0, len(n.Content))
+		for _, item := range n.Content {
+			if item.Kind != yaml.MappingNode {
+				t.unsupportedf("%s: keyword searches are not supported", name)
+				return ""
+			}
+			exprs = append(exprs, t.translateMap(name, item))
+		}
+		return combine(exprs, false)
+	default:
+		t.unsupportedf("%s: unexpected search identifier definition", name)
+		return ""
+	}
+}
+
+func (t *translator) translateMap(name string, n *yaml.Node) string {
+	exprs := make([]string, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i].Value, n.Content[i+1]
+		expr := t.translateField(name, key, val)
+		if expr != "" {
+			exprs = append(exprs, expr)
+		}
+	}
+	return combine(exprs, true)
+}
+
+// translateField produces the expression for the field and its modifiers,
+// e.g. CommandLine|contains|all.
+func (t *translator) translateField(name, key string, n *yaml.Node) string {
+	segments := strings.Split(key, "|")
+	sigmaField, mods := segments[0], segments[1:]
+
+	values, ok := t.values(n)
+	if !ok {
+		t.unsupportedf("%s: %s has unsupported value", name, key)
+		return ""
+	}
+
+	var all bool
+	var op string
+	for _, mod := range mods {
+		switch mod {
+		case modContains, modStartswith, modEndswith, modRe, modCIDR:
+			if op != "" {
+				t.unsupportedf("%s: %s combines %q and %q modifiers", name, key, op, mod)
+				return ""
+			}
+			op = mod
+		case modAll:
+			all = true
+		default:
+			t.unsupportedf("%s: %q modifier in %s is not supported", name, mod, key)
+			return ""
+		}
+	}
+
+	// Initiated field designates the direction
+	// of the network connection, so we map it
+	// to the event type instead of the field
+	if sigmaField == "Initiated" && t.category == "network_connection" {
+		if len(values) != 1 || op != "" {
+			t.unsupportedf("%s: %s has unsupported value", name, key)
+			return ""
+		}
+		if strings.EqualFold(values[0], "true") {
+			return "kevt.name = 'Connect'"
+		}
+		return "kevt.name = 'Accept'"
+	}
+
+	field, ok := t.src.fields[sigmaField]
+	if !ok {
+		t.unsupportedf("%s: %s field has no equivalent", name, sigmaField)
+		return ""
+	}
+	if field == fields.RegistryKeyName {
+		for i, v := range values {
+			values[i] = expandRegistryRoot(v)
+		}
+	}
+
+	switch op {
+	case modRe:
+		exprs := make([]string, len(values))
+		for i, v := range values {
+			exprs[i] = fmt.Sprintf("regex(%s, %s)", field, quote(v))
+		}
+		return combine(exprs, all)
+	case modCIDR:
+		if all {
+			exprs := make([]string, len(values))
+			for i, v := range values {
+				exprs[i] = fmt.Sprintf("cidr_contains(%s, %s)", field, quote(v))
+			}
+			return combine(exprs, true)
+		}
+		cidrs := make([]string, len(values))
+		for i, v := range values {
+			cidrs[i] = quote(v)
+		}
+		return fmt.Sprintf("cidr_contains(%s, %s)", field, strings.Join(cidrs, ", "))
+	}
+
+	if bits := numericFieldBits(field); bits > 0 {
+		nums := make([]string, len(values))
+		for i, v := range values {
+			if _, err := strconv.ParseUint(v, 10, bits); op != "" || err != nil {
+				t.unsupportedf("%s: %s expects numeric values", name, key)
+				return ""
+			}
+			nums[i] = v
+		}
+		if len(nums) == 1 || all {
+			exprs := make([]string, len(nums))
+			for i, n := range nums {
+				exprs[i] = fmt.Sprintf("%s = %s", field, n)
+			}
+			return combine(exprs, all)
+		}
+		return fmt.Sprintf("%s in (%s)", field, strings.Join(nums, ", "))
+	}
+
+	// plain values are compared for equality
+	// unless they contain wildcards in which
+	// case we resort to the matches operator
+	exprs := make([]string, 0, len(values))
+	equals := make([]string, 0, len(values))
+	for _, v := range values {
+		s, wildcards, escaped := unescapeWildcards(v)
+		if wildcards && escaped {
+			t.unsupportedf("%s: %s mixes wildcards and escaped wildcards", name, key)
+			return ""
+		}
+		switch {
+		case wildcards:
+			pattern := s
+			switch op {
+			case modContains:
+				pattern = "*" + pattern + "*"
+			case modStartswith:
+				pattern += "*"
+			case modEndswith:
+				pattern = "*" + pattern
+			}
+			exprs = append(exprs, fmt.Sprintf("%s imatches %s", field, quote(pattern)))
+		case op == modContains:
+			exprs = append(exprs, fmt.Sprintf("%s icontains %s", field, quote(s)))
+		case op == modStartswith:
+			exprs = append(exprs, fmt.Sprintf("%s istartswith %s", field, quote(s)))
+		case op == modEndswith:
+			exprs = append(exprs, fmt.Sprintf("%s iendswith %s", field, quote(s)))
+		default:
+			equals = append(equals, quote(s))
+		}
+	}
+
+	switch {
+	case len(equals) == 1 || len(equals) > 1 && all:
+		// Sigma string comparisons are case-insensitive
+		for _, e := range equals {
+			exprs = append(exprs, fmt.Sprintf("%s ~= %s", field, e))
+		}
+	case len(equals) > 1:
+		exprs = append(exprs, fmt.Sprintf("%s iin (%s)", field, strings.Join(equals, ", ")))
+	}
+
+	return combine(exprs, all)
+}
+
+// values collects scalar values of the field. Null values and
+// nested structures are not supported.
+func (t *translator) values(n *yaml.Node) ([]string, bool) {
+	scalar := func(n *yaml.Node) (string, bool) {
+		if n.Kind != yaml.ScalarNode || n.Tag == "!!null" {
+			return "", false
+		}
+		return n.Value, true
+	}
+	switch n.Kind {
+	case yaml.ScalarNode:
+		v, ok := scalar(n)
+		if !ok {
+			return nil, false
+		}
+		return []string{v}, true
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(n.Content))
+		for _, item := range n.Content {
+			v, ok := scalar(item)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, v)
+		}
+		return values, len(values) > 0
+	default:
+		return nil, false
+	}
+}
+
+// combine joins the expressions with the conjunction if the `all`
+// modifier is present or the disjunction otherwise.
+func combine(exprs []string, all bool) string {
+	if len(exprs) == 0 {
+		return ""
+	}
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+	if all {
+		return paren(join(exprs, "and"))
+	}
+	return paren(join(exprs, "or"))
+}
+
+// numericFieldBits returns the bit size of the numeric field
+// value, or zero if the field isn't numeric.
+func numericFieldBits(f fields.Field) int {
+	switch f {
+	case fields.PsPid, fields.PsSiblingPid:
+		return 32
+	case fields.NetDport, fields.NetSport:
+		return 16
+	default:
+		return 0
+	}
+}
+
+// unescapeWildcards removes Sigma escape sequences and reports
+// whether the value contains unescaped or escaped wildcard characters.
+func unescapeWildcards(s string) (string, bool, bool) {
+	var sb strings.Builder
+	var wildcards, escaped bool
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isWildcard(s[i+1]):
+			escaped = true
+			sb.WriteByte(s[i+1])
+			i++
+		case c == '\\' && i+2 < len(s) && s[i+1] == '\\' && isWildcard(s[i+2]):
+			// escaped backslash followed by the wildcard
+			sb.WriteByte(c)
+			i++
+		case isWildcard(c):
+			wildcards = true
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), wildcards, escaped
+}
+
+// quote produces the filter string literal by escaping
+// backslashes and single quotes.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "'", "\\'")
+	return "'" + s + "'"
+}
+
+func isWildcard(c byte) bool { return c == '*' || c == '?' }
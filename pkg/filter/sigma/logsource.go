@@ -0,0 +1,169 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sigma
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"strings"
+)
+
+// logsource describes how the Sigma log source category
+// maps to Fibratus events. The condition restricts the
+// rule to the events of the category, while the fields
+// map translates Sigma (Sysmon) field names to filter
+// fields.
+type logsource struct {
+	condition string
+	fields    map[string]fields.Field
+}
+
+// processFields are Sysmon fields that refer to the process
+// generating the event in most of the log source categories.
+var processFields = map[string]fields.Field{
+	"Image":       fields.PsExe,
+	"CommandLine": fields.PsComm,
+	"ProcessId":   fields.PsPid,
+}
+
+// registryFields are fields shared by registry log source categories.
+var registryFields = map[string]fields.Field{
+	"TargetObject": fields.RegistryKeyName,
+	"Details":      fields.RegistryValue,
+}
+
+var logsources = map[string]logsource{
+	"process_creation": {
+		condition: "kevt.name = 'CreateProcess'",
+		// the process generating the CreateProcess
+		// event is the parent process, while the sibling
+		// process represents the spawned process
+		fields: map[string]fields.Field{
+			"Image":             fields.PsSiblingExe,
+			"CommandLine":       fields.PsSiblingComm,
+			"ProcessId":         fields.PsSiblingPid,
+			"ParentImage":       fields.PsExe,
+			"ParentCommandLine": fields.PsComm,
+			"ParentProcessId":   fields.PsPid,
+		},
+	},
+	"process_access": {
+		condition: "kevt.name = 'OpenProcess'",
+		fields: map[string]fields.Field{
+			"SourceImage":     fields.PsExe,
+			"SourceProcessId": fields.PsPid,
+			"TargetImage":     fields.PsSiblingExe,
+			"TargetProcessId": fields.PsSiblingPid,
+		},
+	},
+	"network_connection": {
+		condition: "kevt.name in ('Connect', 'Accept')",
+		fields: withProcessFields(map[string]fields.Field{
+			"DestinationIp":       fields.NetDIP,
+			"DestinationPort":     fields.NetDport,
+			"DestinationHostname": fields.NetDIPNames,
+			"SourceIp":            fields.NetSIP,
+			"SourcePort":          fields.NetSport,
+			"SourceHostname":      fields.NetSIPNames,
+			"Protocol":            fields.NetL4Proto,
+		}),
+	},
+	"file_event": {
+		condition: "kevt.name = 'CreateFile' and file.operation = 'create'",
+		fields:    withProcessFields(map[string]fields.Field{"TargetFilename": fields.FileName}),
+	},
+	"file_access": {
+		condition: "kevt.name = 'CreateFile' and file.operation = 'open'",
+		fields:    withProcessFields(map[string]fields.Field{"TargetFilename": fields.FileName}),
+	},
+	"file_delete": {
+		condition: "kevt.name = 'DeleteFile'",
+		fields:    withProcessFields(map[string]fields.Field{"TargetFilename": fields.FileName}),
+	},
+	"image_load": {
+		condition: "kevt.name = 'LoadImage'",
+		fields:    withProcessFields(map[string]fields.Field{"ImageLoaded": fields.ImageName}),
+	},
+	"registry_add": {
+		condition: "kevt.name = 'RegCreateKey'",
+		fields:    withProcessFields(registryFields),
+	},
+	"registry_set": {
+		condition: "kevt.name = 'RegSetValue'",
+		fields:    withProcessFields(registryFields),
+	},
+	"registry_delete": {
+		condition: "kevt.name in ('RegDeleteKey', 'RegDeleteValue')",
+		fields:    withProcessFields(registryFields),
+	},
+	"registry_event": {
+		condition: "kevt.category = 'registry'",
+		fields:    withProcessFields(registryFields),
+	},
+}
+
+func withProcessFields(m map[string]fields.Field) map[string]fields.Field {
+	fields := make(map[string]fields.Field, len(m)+len(processFields))
+	for k, v := range processFields {
+		fields[k] = v
+	}
+	for k, v := range m {
+		fields[k] = v
+	}
+	return fields
+}
+
+// registryRoots maps abbreviated registry root keys used
+// in Sigma rules to the root key names Fibratus produces.
+var registryRoots = map[string]string{
+	"HKLM": "HKEY_LOCAL_MACHINE",
+	"HKU":  "HKEY_USERS",
+	"HKCU": "HKEY_CURRENT_USER",
+	"HKCR": "HKEY_CLASSES_ROOT",
+}
+
+// expandRegistryRoot replaces the abbreviated root key at the
+// start of the registry path with the full root key name.
+func expandRegistryRoot(s string) string {
+	n := strings.Index(s, "\\")
+	if n < 0 {
+		return s
+	}
+	if root, ok := registryRoots[strings.ToUpper(s[:n])]; ok {
+		return root + s[n:]
+	}
+	return s
+}
+
+// tactics maps Sigma ATT&CK tactic tags to tactic identifiers and names.
+var tactics = map[string]struct{ id, name string }{
+	"reconnaissance":       {"TA0043", "Reconnaissance"},
+	"resource_development": {"TA0042", "Resource Development"},
+	"initial_access":       {"TA0001", "Initial Access"},
+	"execution":            {"TA0002", "Execution"},
+	"persistence":          {"TA0003", "Persistence"},
+	"privilege_escalation": {"TA0004", "Privilege Escalation"},
+	"defense_evasion":      {"TA0005", "Defense Evasion"},
+	"credential_access":    {"TA0006", "Credential Access"},
+	"discovery":            {"TA0007", "Discovery"},
+	"lateral_movement":     {"TA0008", "Lateral Movement"},
+	"collection":           {"TA0009", "Collection"},
+	"command_and_control":  {"TA0011", "Command and Control"},
+	"exfiltration":         {"TA0010", "Exfiltration"},
+	"impact":               {"TA0040", "Impact"},
+}
@@ -0,0 +1,321 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sigma translates Sigma rules into Fibratus rule groups.
+// Each Sigma rule produces a single group with one rule whose condition
+// is derived from the log source category and the detection block.
+package sigma
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rule represents the Sigma rule document.
+type Rule struct {
+	Title       string    `yaml:"title"`
+	ID          string    `yaml:"id"`
+	Status      string    `yaml:"status"`
+	Description string    `yaml:"description"`
+	References  []string  `yaml:"references"`
+	Tags        []string  `yaml:"tags"`
+	Level       string    `yaml:"level"`
+	Logsource   Logsource `yaml:"logsource"`
+	Detection   yaml.Node `yaml:"detection"`
+	// Action is set in rule collections that
+	// declare global or repeated documents
+	Action string `yaml:"action"`
+}
+
+// Logsource describes the data source of the Sigma rule.
+type Logsource struct {
+	Category string `yaml:"category"`
+	Product  string `yaml:"product"`
+	Service  string `yaml:"service"`
+}
+
+// Translation is the outcome of translating a single Sigma rule.
+type Translation struct {
+	// Resource is the file the rule was loaded from.
+	Resource string
+	Rule     *Rule
+	// Group is the translated rule group. It is nil if
+	// the rule contains constructs that can't be translated.
+	Group *config.FilterGroup
+	// Unsupported describes the constructs that prevented the translation.
+	Unsupported []string
+}
+
+// Translated determines if the Sigma rule was translated.
+func (t Translation) Translated() bool { return t.Group != nil }
+
+// ParseRules decodes all Sigma rule documents from the YAML payload.
+func ParseRules(b []byte) ([]*Rule, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	rules := make([]*Rule, 0)
+	for {
+		var rule Rule
+		err := dec.Decode(&rule)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Sigma rule: %v", err)
+		}
+		rules = append(rules, &rule)
+	}
+	return rules, nil
+}
+
+// TranslateFile parses Sigma rules from the file and translates each of them.
+func TranslateFile(path string) ([]Translation, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := ParseRules(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	translations := make([]Translation, 0, len(rules))
+	for _, rule := range rules {
+		t := Translate(rule)
+		t.Resource = path
+		translations = append(translations, t)
+	}
+	return translations, nil
+}
+
+// Translate converts the Sigma rule into the rule group. All constructs
+// that can't be mapped to the filter expression are reported, in which
+// case the group is not produced, since dropping any part of the detection
+// would change the rule semantics.
+func Translate(rule *Rule) Translation {
+	t := Translation{Rule: rule}
+	if rule.Action != "" {
+		t.Unsupported = append(t.Unsupported, fmt.Sprintf("%q rule collection action is not supported", rule.Action))
+		return t
+	}
+	if rule.Title == "" {
+		t.Unsupported = append(t.Unsupported, "rule has no title")
+		return t
+	}
+	if rule.Logsource.Product != "" && rule.Logsource.Product != "windows" {
+		t.Unsupported = append(t.Unsupported, fmt.Sprintf("%q log source product is not supported", rule.Logsource.Product))
+		return t
+	}
+	src, ok := logsources[rule.Logsource.Category]
+	if !ok {
+		logsource := rule.Logsource.Category
+		if logsource == "" {
+			logsource = rule.Logsource.Service
+		}
+		t.Unsupported = append(t.Unsupported, fmt.Sprintf("%q log source is not supported", logsource))
+		return t
+	}
+
+	cond, unsupported := translateDetection(rule.Logsource.Category, src, &rule.Detection)
+	if len(unsupported) > 0 {
+		t.Unsupported = unsupported
+		return t
+	}
+
+	enabled := true
+	t.Group = &config.FilterGroup{
+		Name:        rule.Title,
+		Description: strings.TrimSpace(rule.Description),
+		Enabled:     &enabled,
+		Policy:      config.IncludePolicy,
+		Relation:    config.OrRelation,
+		Tags:        rule.Tags,
+		Labels:      attackLabels(rule.Tags),
+		Rules: []*config.FilterConfig{
+			{
+				Name:      rule.Title,
+				Condition: src.condition + " and " + paren(cond),
+				Action:    action(rule),
+				Labels:    ruleLabels(rule),
+			},
+		},
+	}
+	return t
+}
+
+// translateDetection translates search identifiers and combines
+// them according to the detection condition.
+func translateDetection(category string, src logsource, detection *yaml.Node) (string, []string) {
+	if detection.Kind != yaml.MappingNode {
+		return "", []string{"detection block is missing"}
+	}
+	t := &translator{category: category, src: src}
+	idents := make(map[string]string)
+	var conds []string
+	for i := 0; i+1 < len(detection.Content); i += 2 {
+		key, val := detection.Content[i].Value, detection.Content[i+1]
+		if key == "condition" {
+			switch val.Kind {
+			case yaml.ScalarNode:
+				conds = append(conds, val.Value)
+			case yaml.SequenceNode:
+				for _, n := range val.Content {
+					conds = append(conds, n.Value)
+				}
+			}
+			continue
+		}
+		if key == "timeframe" {
+			t.unsupportedf("timeframe is not supported")
+			continue
+		}
+		idents[key] = t.translateSearch(key, val)
+	}
+	if len(conds) == 0 {
+		t.unsupportedf("detection condition is missing")
+	}
+	if len(t.unsupported) > 0 {
+		return "", t.unsupported
+	}
+
+	// multiple conditions are alternatives
+	exprs := make([]string, 0, len(conds))
+	for _, c := range conds {
+		expr, err := translateCondition(c, idents, src.condition)
+		if err != nil {
+			t.unsupportedf("condition %q: %v", c, err)
+			continue
+		}
+		exprs = append(exprs, paren(expr))
+	}
+	if len(t.unsupported) > 0 {
+		return "", t.unsupported
+	}
+	return join(exprs, "or"), nil
+}
+
+var (
+	techniqueRegexp    = regexp.MustCompile(`^t\d{4}$`)
+	subtechniqueRegexp = regexp.MustCompile(`^t\d{4}\.\d{3}$`)
+)
+
+// attackLabels produces MITRE ATT&CK group labels from Sigma
+// tags. If the rule is tagged with multiple tactics or techniques,
+// the first one is used.
+func attackLabels(tags []string) map[string]string {
+	labels := make(map[string]string)
+	set := func(k, v string) {
+		if _, ok := labels[k]; !ok {
+			labels[k] = v
+		}
+	}
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, "attack.") {
+			continue
+		}
+		name := strings.ReplaceAll(strings.TrimPrefix(tag, "attack."), "-", "_")
+		switch {
+		case subtechniqueRegexp.MatchString(name):
+			id := strings.ToUpper(name)
+			set("subtechnique.id", id)
+			set("subtechnique.ref", "https://attack.mitre.org/techniques/"+strings.ReplaceAll(id, ".", "/")+"/")
+			set("technique.id", id[:5])
+			set("technique.ref", "https://attack.mitre.org/techniques/"+id[:5]+"/")
+		case techniqueRegexp.MatchString(name):
+			id := strings.ToUpper(name)
+			set("technique.id", id)
+			set("technique.ref", "https://attack.mitre.org/techniques/"+id+"/")
+		default:
+			tactic, ok := tactics[name]
+			if !ok {
+				continue
+			}
+			set("tactic.id", tactic.id)
+			set("tactic.name", tactic.name)
+			set("tactic.ref", "https://attack.mitre.org/tactics/"+tactic.id+"/")
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// ruleLabels keeps the Sigma rule identity in rule labels.
+func ruleLabels(rule *Rule) map[string]string {
+	labels := map[string]string{"sigma.title": rule.Title}
+	if rule.ID != "" {
+		labels["sigma.id"] = rule.ID
+	}
+	if rule.Level != "" {
+		labels["sigma.level"] = rule.Level
+	}
+	return labels
+}
+
+// action builds the rule action that emits the alert with the severity
+// derived from the Sigma rule level.
+func action(rule *Rule) string {
+	severity := "medium"
+	switch rule.Level {
+	case "informational", "low":
+		severity = "low"
+	case "high", "critical":
+		severity = "critical"
+	}
+	text := strings.Join(strings.Fields(rule.Description), " ")
+	if text == "" {
+		text = rule.Title
+	}
+	return fmt.Sprintf("{{ emit . %s %s %s }}", strconv.Quote(rule.Title), strconv.Quote(text), strconv.Quote(severity))
+}
+
+// Report summarizes the translation outcome. Each line
+// either references the translated rule or describes
+// the constructs that prevented the translation.
+func Report(translations []Translation) string {
+	var sb strings.Builder
+	sorted := make([]Translation, len(translations))
+	copy(sorted, translations)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Translated() && !sorted[j].Translated() })
+	var translated int
+	for _, t := range sorted {
+		title := t.Rule.Title
+		if t.Rule.ID != "" {
+			title += " (" + t.Rule.ID + ")"
+		}
+		if t.Translated() {
+			translated++
+			sb.WriteString(fmt.Sprintf("OK    %s: %s\n", t.Resource, title))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("SKIP  %s: %s\n", t.Resource, title))
+		for _, u := range t.Unsupported {
+			sb.WriteString("      " + u + "\n")
+		}
+	}
+	sb.WriteString(fmt.Sprintf("\n%d translated, %d skipped\n", translated, len(translations)-translated))
+	return sb.String()
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sigma
+
+import (
+	"testing"
+
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateFile(t *testing.T) {
+	translations, err := TranslateFile("_fixtures/proc_creation_win_susp_cmd.yml")
+	require.NoError(t, err)
+	require.Len(t, translations, 2)
+
+	tr := translations[0]
+	require.True(t, tr.Translated())
+	group := tr.Group
+	assert.Equal(t, "Suspicious Command Shell Spawned By Office Application", group.Name)
+	assert.Equal(t, config.IncludePolicy, group.Policy)
+	assert.Equal(t, map[string]string{
+		"tactic.id":        "TA0002",
+		"tactic.name":      "Execution",
+		"tactic.ref":       "https://attack.mitre.org/tactics/TA0002/",
+		"technique.id":     "T1059",
+		"technique.ref":    "https://attack.mitre.org/techniques/T1059/",
+		"subtechnique.id":  "T1059.001",
+		"subtechnique.ref": "https://attack.mitre.org/techniques/T1059/001/",
+	}, group.Labels)
+
+	require.Len(t, group.Rules, 1)
+	rule := group.Rules[0]
+	assert.Equal(t, `kevt.name = 'CreateProcess' and (((ps.sibling.exe iendswith '\\cmd.exe' or (ps.sibling.exe iendswith '\\powershell.exe' and (ps.sibling.comm icontains '-nop' and ps.sibling.comm icontains 'IEX'))) and (ps.exe iendswith '\\WINWORD.EXE' or ps.exe iendswith '\\EXCEL.EXE')) and not (ps.sibling.comm ~= 'C:\\Windows\\system32\\cmd.exe /c echo \'ok\''))`, rule.Condition)
+	assert.Equal(t, `{{ emit . "Suspicious Command Shell Spawned By Office Application" "Detects a command shell or script interpreter spawned by the Microsoft Office application." "critical" }}`, rule.Action)
+	assert.Equal(t, map[string]string{
+		"sigma.title": "Suspicious Command Shell Spawned By Office Application",
+		"sigma.id":    "438025f9-5856-4663-83f7-52f878a70a50",
+		"sigma.level": "high",
+	}, rule.Labels)
+
+	// negated factors are moved to the end of the conjunction
+	// and anchored to the log source condition if no positive
+	// factors are present
+	tr = translations[1]
+	require.True(t, tr.Translated())
+	assert.Equal(t, `kevt.name = 'LoadImage' and ((image.name iendswith '\\dbghelp.dll' and not ((image.name istartswith 'C:\\Program Files\\' or image.name istartswith 'C:\\Program Files (x86)\\') or image.name istartswith 'C:\\Windows\\')) or (kevt.name = 'LoadImage' and not (image.name iendswith '\\dbghelp.dll')))`, tr.Group.Rules[0].Condition)
+
+	for _, tr := range translations {
+		_, err := ql.NewParser(tr.Group.Rules[0].Condition).ParseExpr()
+		require.NoError(t, err)
+	}
+}
+
+func TestTranslateModifiers(t *testing.T) {
+	var tests = []struct {
+		file string
+		cond string
+	}{
+		{
+			"_fixtures/net_connection_win_susp.yml",
+			`kevt.name in ('Connect', 'Accept') and (kevt.name = 'Connect' and cidr_contains(net.dip, '10.0.0.0/8', '192.168.0.0/16') and net.dport in (445, 139))`,
+		},
+		{
+			"_fixtures/registry_set_run_key.yml",
+			`kevt.name = 'RegSetValue' and ((registry.key.name istartswith 'HKEY_LOCAL_MACHINE\\SOFTWARE\\Microsoft\\Windows\\CurrentVersion\\Run' and regex(registry.value, '.*\\\\AppData\\\\.*\\.exe')) or ps.exe imatches '*\\Temp\\*')`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			translations, err := TranslateFile(tt.file)
+			require.NoError(t, err)
+			require.True(t, translations[0].Translated())
+			cond := translations[0].Group.Rules[0].Condition
+			assert.Equal(t, tt.cond, cond)
+			_, err = ql.NewParser(cond).ParseExpr()
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestTranslateUnsupported(t *testing.T) {
+	var tests = []struct {
+		rule        string
+		unsupported []string
+	}{
+		{
+			`
+title: Security Log
+logsource:
+  product: windows
+  service: security
+detection:
+  selection:
+    EventID: 4625
+  condition: selection
+`,
+			[]string{`"security" log source is not supported`},
+		},
+		{
+			`
+title: Linux Process
+logsource:
+  product: linux
+  category: process_creation
+detection:
+  selection:
+    Image: /bin/sh
+  condition: selection
+`,
+			[]string{`"linux" log source product is not supported`},
+		},
+		{
+			`
+title: Unsupported Constructs
+logsource:
+  product: windows
+  category: process_creation
+detection:
+  selection:
+    CommandLine|base64offset|contains: 'IEX'
+    OriginalFileName: 'cmd.exe'
+  keywords:
+    - 'mimikatz'
+  condition: selection
+`,
+			[]string{
+				`selection: "base64offset" modifier in CommandLine|base64offset|contains is not supported`,
+				`selection: OriginalFileName field has no equivalent`,
+				`keywords: keyword searches are not supported`,
+			},
+		},
+		{
+			`
+title: Aggregation
+logsource:
+  product: windows
+  category: process_creation
+detection:
+  selection:
+    Image|endswith: '\whoami.exe'
+  condition: selection | count() by ParentImage > 5
+`,
+			[]string{`condition "selection | count() by ParentImage > 5": aggregation expressions are not supported`},
+		},
+		{
+			`
+title: Undefined Identifier
+logsource:
+  product: windows
+  category: process_creation
+detection:
+  selection:
+    Image|endswith: '\whoami.exe'
+  condition: selection and filter
+`,
+			[]string{`condition "selection and filter": undefined search identifier "filter"`},
+		},
+		{
+			`
+title: Mixed Wildcards
+logsource:
+  product: windows
+  category: file_event
+detection:
+  selection:
+    TargetFilename: '*\file\*.txt'
+  condition: selection
+`,
+			[]string{`selection: TargetFilename mixes wildcards and escaped wildcards`},
+		},
+	}
+
+	for _, tt := range tests {
+		rules, err := ParseRules([]byte(tt.rule))
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		tr := Translate(rules[0])
+		assert.False(t, tr.Translated(), rules[0].Title)
+		assert.Equal(t, tt.unsupported, tr.Unsupported, rules[0].Title)
+	}
+}
+
+func TestReport(t *testing.T) {
+	translations, err := TranslateFile("_fixtures/net_connection_win_susp.yml")
+	require.NoError(t, err)
+
+	report := Report(translations)
+	assert.Contains(t, report, "OK    _fixtures/net_connection_win_susp.yml: Outbound Connection To Private Network (8cb1a3b1-e4d1-4a1c-a41f-0c3a5a1f1c01)\n")
+	assert.Contains(t, report, "SKIP  _fixtures/net_connection_win_susp.yml: Unsupported Keyword Search\n      \"security\" log source is not supported\n")
+	assert.Contains(t, report, "\n1 translated, 1 skipped\n")
+}
+
+func TestTranslateNumericFields(t *testing.T) {
+	rules, err := ParseRules([]byte(`
+title: Large Process Identifier
+logsource:
+  product: windows
+  category: process_creation
+detection:
+  selection:
+    ParentProcessId: 4194300
+  condition: selection
+`))
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	tr := Translate(rules[0])
+	require.True(t, tr.Translated(), tr.Unsupported)
+	assert.Equal(t, `kevt.name = 'CreateProcess' and (ps.pid = 4194300)`, tr.Group.Rules[0].Condition)
+	_, err = ql.NewParser(tr.Group.Rules[0].Condition).ParseExpr()
+	require.NoError(t, err)
+
+	rules, err = ParseRules([]byte(`
+title: Port Out Of Range
+logsource:
+  product: windows
+  category: network_connection
+detection:
+  selection:
+    DestinationPort: 70000
+  condition: selection
+`))
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	tr = Translate(rules[0])
+	assert.False(t, tr.Translated())
+	assert.Equal(t, []string{`selection: DestinationPort expects numeric values`}, tr.Unsupported)
+}
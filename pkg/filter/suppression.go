@@ -0,0 +1,189 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"expvar"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSuppressionWindows determines the maximum number of distinct
+// dedup keys tracked by the rule. Matches with new keys are not
+// deduplicated once the limit is reached.
+const maxSuppressionWindows = 10000
+
+var (
+	suppressedMatches = expvar.NewMap("filter.suppressed.matches")
+	collapsedAlerts   = expvar.NewMap("filter.collapsed.alerts")
+)
+
+// ruleMatch contains the events that fired the rule
+// along with aggregate values of threshold rules.
+type ruleMatch struct {
+	kevt  *kevent.Kevent
+	kevts map[uint16]*kevent.Kevent
+	aggs  map[string]float64
+}
+
+// events returns events involved in the match ordered by timestamp.
+func (m ruleMatch) events() []*kevent.Kevent {
+	if m.kevt != nil {
+		return []*kevent.Kevent{m.kevt}
+	}
+	events := mapValues(m.kevts)
+	sort.Slice(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+	return events
+}
+
+// timestamp returns the timestamp of the most recent event in the match.
+func (m ruleMatch) timestamp() time.Time {
+	events := m.events()
+	if len(events) == 0 {
+		return time.Now()
+	}
+	return events[len(events)-1].Timestamp
+}
+
+// suppressionState collapses repeated matches of the rule. Each distinct
+// combination of dedup field values opens a window when the rule matches
+// for the first time. The rule action is executed for the first matches
+// in the window until the alert budget is exhausted. Subsequent matches
+// are suppressed, and when the window elapses, the action is executed
+// once more with the most recent suppressed match and the number of all
+// matches that occurred in the window.
+type suppressionState struct {
+	name   string
+	config config.Suppression
+	// valuer maps the values of the dedup fields
+	valuer  *filter
+	windows map[string]*suppressionWindow
+	// flush executes the rule action for the collapsed matches
+	flush func(m ruleMatch, occurrences config.Occurrences)
+
+	mu sync.Mutex
+}
+
+type suppressionWindow struct {
+	occurrences config.Occurrences
+	alerts      int
+	// last is the most recent suppressed match
+	last  *ruleMatch
+	timer *time.Timer
+}
+
+func newSuppressionState(name string, sup config.Suppression, c *config.Config) (*suppressionState, error) {
+	valuer := New("", c).(*filter)
+	for _, name := range sup.By {
+		field := fields.Lookup(name)
+		if field.IsEmpty() {
+			return nil, fmt.Errorf("%q is not a valid suppression field", name)
+		}
+		valuer.addField(field)
+	}
+	valuer.initAccessors()
+	return &suppressionState{
+		name:    name,
+		config:  sup,
+		valuer:  valuer,
+		windows: make(map[string]*suppressionWindow),
+	}, nil
+}
+
+// add registers the rule match in the window identified by the dedup key.
+// It returns the occurrences of the rule in the window and true if the rule
+// action should be executed, or false if the match is suppressed.
+func (s *suppressionState) add(m ruleMatch) (config.Occurrences, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.key(m)
+	ts := m.timestamp()
+	w, ok := s.windows[key]
+	if !ok {
+		occurrences := config.Occurrences{Count: 1, FirstSeen: ts, LastSeen: ts}
+		if len(s.windows) >= maxSuppressionWindows {
+			return occurrences, true
+		}
+		w = &suppressionWindow{occurrences: occurrences, alerts: 1}
+		w.timer = time.AfterFunc(s.config.Window, func() { s.expire(key, w) })
+		s.windows[key] = w
+		return occurrences, true
+	}
+	w.occurrences.Count++
+	w.occurrences.LastSeen = ts
+	if w.alerts < s.config.Alerts() {
+		w.alerts++
+		return w.occurrences, true
+	}
+	w.last = &m
+	suppressedMatches.Add(s.name, 1)
+	log.Debugf("rule [%s] match suppressed. %d occurrence(s) since %s", s.name, w.occurrences.Count, w.occurrences.FirstSeen)
+	return w.occurrences, false
+}
+
+// expire closes the window and executes the rule
+// action if any match was suppressed in the window.
+func (s *suppressionState) expire(key string, w *suppressionWindow) {
+	s.mu.Lock()
+	if s.windows[key] == w {
+		delete(s.windows, key)
+	}
+	flush := s.flush
+	s.mu.Unlock()
+	if w.last == nil || flush == nil {
+		return
+	}
+	collapsedAlerts.Add(s.name, 1)
+	flush(*w.last, w.occurrences)
+}
+
+// equals determines if both states have the same suppression settings.
+func (s *suppressionState) equals(other *suppressionState) bool {
+	if s.config.Window != other.config.Window || s.config.Alerts() != other.config.Alerts() {
+		return false
+	}
+	return strings.Join(s.config.By, ",") == strings.Join(other.config.By, ",")
+}
+
+// key builds the dedup key from the values of the dedup fields. The
+// value of each field is taken from the earliest event that has it.
+func (s *suppressionState) key(m ruleMatch) string {
+	if len(s.config.By) == 0 {
+		return ""
+	}
+	events := m.events()
+	var sb strings.Builder
+	for _, field := range s.valuer.fields {
+		for _, kevt := range events {
+			if v, ok := s.valuer.mapValuer(kevt)[field.String()]; ok {
+				sb.WriteString(normalizeAggregationValue(v))
+				break
+			}
+		}
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
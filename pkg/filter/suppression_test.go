@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuppressionWindow(t *testing.T) {
+	rules := NewRules(newConfig("_fixtures/suppression_rule.yml"))
+	var (
+		mu      sync.Mutex
+		matches []config.Occurrences
+		kevts   []*kevent.Kevent
+	)
+	rules.match = func(kevt *kevent.Kevent, _ map[uint16]*kevent.Kevent, group config.FilterGroup, filter *config.FilterConfig, aggs map[string]float64, occurrences config.Occurrences) error {
+		mu.Lock()
+		defer mu.Unlock()
+		matches = append(matches, occurrences)
+		kevts = append(kevts, kevt)
+		return nil
+	}
+	require.NoError(t, rules.Compile())
+
+	now := time.Now()
+	newEvent := func(exe, filename string, offset time.Duration) *kevent.Kevent {
+		return &kevent.Kevent{
+			Type:      ktypes.CreateFile,
+			Timestamp: now.Add(offset),
+			Name:      "CreateFile",
+			Tid:       2484,
+			PID:       859,
+			Category:  ktypes.File,
+			PS: &types.PS{
+				Name: "cmd.exe",
+				Exe:  exe,
+			},
+			Kparams: kevent.Kparams{
+				kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: filename},
+			},
+			Metadata: make(map[kevent.MetadataKey]any),
+		}
+	}
+
+	// the first two matches run the action, while
+	// subsequent matches with the same executable
+	// are suppressed
+	for i := 0; i < 5; i++ {
+		require.True(t, rules.Fire(newEvent("C:\\Windows\\system32\\cmd.exe", "C:\\Temp\\dropper.exe", time.Duration(i)*time.Millisecond)))
+	}
+	last := newEvent("C:\\Windows\\system32\\CMD.exe", "C:\\Temp\\dropper.exe", time.Millisecond*5)
+	require.True(t, rules.Fire(last))
+	// matches with different dedup key are not suppressed
+	require.True(t, rules.Fire(newEvent("C:\\Windows\\explorer.exe", "C:\\Temp\\setup.exe", time.Millisecond*6)))
+
+	mu.Lock()
+	require.Len(t, matches, 3)
+	assert.Equal(t, config.Occurrences{Count: 1, FirstSeen: now, LastSeen: now}, matches[0])
+	assert.Equal(t, config.Occurrences{Count: 2, FirstSeen: now, LastSeen: now.Add(time.Millisecond)}, matches[1])
+	assert.Equal(t, 1, matches[2].Count)
+	mu.Unlock()
+
+	// when the window elapses, suppressed matches
+	// are collapsed into a single alert
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(matches) == 4
+	}, time.Second*2, time.Millisecond*50)
+
+	mu.Lock()
+	assert.Equal(t, config.Occurrences{Count: 6, FirstSeen: now, LastSeen: now.Add(time.Millisecond * 5)}, matches[3])
+	assert.Equal(t, last, kevts[3])
+	mu.Unlock()
+	assert.Equal(t, "4", suppressedMatches.Get("Executable dropped in temp directory").String())
+
+	// the window is closed, so the next match runs the action
+	require.True(t, rules.Fire(newEvent("C:\\Windows\\system32\\cmd.exe", "C:\\Temp\\dropper.exe", time.Second)))
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, matches, 5)
+	assert.Equal(t, 1, matches[4].Count)
+}
+
+func TestSuppressionInvalidField(t *testing.T) {
+	_, err := newSuppressionState("Executable dropped in temp directory", config.Suppression{By: []string{"ps.exename"}, Window: time.Minute}, newConfig())
+	require.EqualError(t, err, `"ps.exename" is not a valid suppression field`)
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kcap
+
+import (
+	"errors"
+	"expvar"
+	"sync/atomic"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/handle"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/ps"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrRecordingInProgress is returned when the recording is requested while
+// another recording is still active.
+var ErrRecordingInProgress = errors.New("capture recording already in progress")
+
+// droppedRecordingKevents counts events that didn't fit in the recording buffer
+var droppedRecordingKevents = expvar.NewInt("kcap.recording.dropped.kevents")
+
+// recording represents an active background capture.
+type recording struct {
+	kevts chan *kevent.Kevent
+	errs  chan error
+	done  chan struct{}
+}
+
+// Recorder writes the live event stream to the capture file for a bounded
+// period of time. Events are fed to the recorder through the Tap method.
+// Only one recording can be active at any given time.
+type Recorder struct {
+	psnap  ps.Snapshotter
+	hsnap  handle.Snapshotter
+	active atomic.Pointer[recording]
+}
+
+// NewRecorder creates a new event stream recorder.
+func NewRecorder(psnap ps.Snapshotter, hsnap handle.Snapshotter) *Recorder {
+	return &Recorder{psnap: psnap, hsnap: hsnap}
+}
+
+// Record starts writing events to the capture file. The recording
+// stops and the capture file is closed once the duration elapses.
+func (r *Recorder) Record(filename string, d time.Duration) error {
+	rec := &recording{
+		kevts: make(chan *kevent.Kevent, 1000),
+		errs:  make(chan error, 100),
+		done:  make(chan struct{}),
+	}
+	if !r.active.CompareAndSwap(nil, rec) {
+		return ErrRecordingInProgress
+	}
+	w, err := NewWriter(filename, r.psnap, r.hsnap)
+	if err != nil {
+		r.active.Store(nil)
+		return err
+	}
+	errs := w.Write(rec.kevts, rec.errs)
+	go func() {
+		for {
+			select {
+			case err := <-errs:
+				log.Warnf("capture recording error: %v", err)
+			case <-rec.done:
+				return
+			}
+		}
+	}()
+	time.AfterFunc(d, func() {
+		r.active.Store(nil)
+		// the writer might be blocked on reporting the error,
+		// so errors are drained until the writer is closed
+		if err := w.Close(); err != nil {
+			log.Warnf("couldn't close %s capture: %v", filename, err)
+		}
+		close(rec.done)
+	})
+	log.Infof("recording events to %s for %v", filename, d)
+	return nil
+}
+
+// Tap feeds the event to the active recording. The event is cloned, since
+// the writer returns events to the pool, while the original event continues
+// its journey to outputs. Events are dropped if the recording lags behind.
+func (r *Recorder) Tap(kevt *kevent.Kevent) {
+	rec := r.active.Load()
+	if rec == nil {
+		return
+	}
+	clone, err := kevent.NewFromKcap(kevt.MarshalRaw())
+	if err != nil {
+		return
+	}
+	select {
+	case rec.kevts <- clone:
+	default:
+		droppedRecordingKevents.Add(1)
+	}
+}
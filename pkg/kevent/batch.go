@@ -18,6 +18,13 @@
 
 package kevent
 
+import (
+	"fmt"
+
+	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/rabbitstack/fibratus/pkg/util/bytes"
+)
+
 // Batch contains a sequence of kernel events.
 type Batch struct {
 	Events []*Kevent
@@ -56,3 +63,70 @@ func (b *Batch) MarshalJSON() []byte {
 	buf = append(buf, ']')
 	return buf
 }
+
+// MarshalRaw produces a byte stream of the batch suitable for writing to disk.
+// Each event is followed by its process state, so the events recovered from
+// the stream carry the same process state as the original events.
+func (b *Batch) MarshalRaw() []byte {
+	buf := make([]byte, 0)
+	buf = append(buf, bytes.WriteUint32(uint32(len(b.Events)))...)
+	for _, kevt := range b.Events {
+		raw := kevt.MarshalRaw()
+		buf = append(buf, bytes.WriteUint32(uint32(len(raw)))...)
+		buf = append(buf, raw...)
+		if kevt.PS == nil {
+			buf = append(buf, bytes.WriteUint32(0)...)
+			continue
+		}
+		ps := kevt.PS.Marshal()
+		buf = append(buf, bytes.WriteUint32(uint32(len(ps)))...)
+		buf = append(buf, ps...)
+	}
+	return buf
+}
+
+// NewBatchFromRaw recovers the batch of events from the byte stream
+// produced by the MarshalRaw method.
+func NewBatchFromRaw(buf []byte) (*Batch, error) {
+	if len(buf) < 4 {
+		return nil, fmt.Errorf("expected at least 4 bytes but got %d bytes", len(buf))
+	}
+	n := bytes.ReadUint32(buf)
+	evts := make([]*Kevent, 0, n)
+	off := uint32(4)
+	next := func() ([]byte, error) {
+		if uint32(len(buf)) < off+4 {
+			return nil, fmt.Errorf("truncated batch at offset %d", off)
+		}
+		l := bytes.ReadUint32(buf[off:])
+		off += 4
+		if uint32(len(buf)) < off+l {
+			return nil, fmt.Errorf("truncated batch at offset %d", off)
+		}
+		b := buf[off : off+l]
+		off += l
+		return b, nil
+	}
+	for i := uint32(0); i < n; i++ {
+		raw, err := next()
+		if err != nil {
+			return nil, err
+		}
+		kevt, err := NewFromKcap(raw)
+		if err != nil {
+			return nil, err
+		}
+		ps, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if len(ps) > 0 {
+			kevt.PS = &pstypes.PS{}
+			if err := kevt.PS.Unmarshal(ps); err != nil {
+				return nil, err
+			}
+		}
+		evts = append(evts, kevt)
+	}
+	return NewBatch(evts...), nil
+}
@@ -250,3 +250,66 @@ func TestBatchMarshalJSON(t *testing.T) {
 	assert.Equal(t, uint32(459), kevts[1].PID)
 	assert.Equal(t, uint32(829), kevts[2].PID)
 }
+
+func TestBatchMarshalRaw(t *testing.T) {
+	kevt := &Kevent{
+		Type:        ktypes.CreateFile,
+		Tid:         2484,
+		PID:         859,
+		CPU:         1,
+		Seq:         2,
+		Name:        "CreateFile",
+		Timestamp:   time.Now(),
+		Category:    ktypes.File,
+		Host:        "archrabbit",
+		Description: "Creates or opens a new file, directory, I/O device, pipe, console",
+		Kparams: Kparams{
+			kparams.FileName:      {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "\\Device\\HarddiskVolume2\\Windows\\system32\\user32.dll"},
+			kparams.FileOperation: {Name: kparams.FileOperation, Type: kparams.AnsiString, Value: "open"},
+		},
+		Metadata: map[MetadataKey]any{"foo": "bar"},
+		PS: &pstypes.PS{
+			PID:       859,
+			Ppid:      6304,
+			Name:      "firefox.exe",
+			Exe:       `C:\Program Files\Mozilla Firefox\firefox.exe`,
+			Comm:      `C:\Program Files\Mozilla Firefox\firefox.exe -contentproc`,
+			Cwd:       `C:\Program Files\Mozilla Firefox\`,
+			SID:       "archrabbit\\SYSTEM",
+			SessionID: 4,
+		},
+	}
+	kevt1 := &Kevent{
+		Type:        ktypes.CreateFile,
+		Tid:         2484,
+		PID:         459,
+		CPU:         1,
+		Seq:         3,
+		Name:        "CreateFile",
+		Timestamp:   time.Now(),
+		Category:    ktypes.File,
+		Host:        "archrabbit",
+		Description: "Creates or opens a new file, directory, I/O device, pipe, console",
+		Kparams: Kparams{
+			kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Windows\\notepad.exe"},
+		},
+		Metadata: map[MetadataKey]any{},
+	}
+
+	b, err := NewBatchFromRaw(NewBatch(kevt, kevt1).MarshalRaw())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), b.Len())
+
+	assert.Equal(t, uint32(859), b.Events[0].PID)
+	assert.Equal(t, uint64(3), b.Events[1].Seq)
+	filename, err := b.Events[0].Kparams.GetString(kparams.FileName)
+	require.NoError(t, err)
+	assert.Equal(t, "\\Device\\HarddiskVolume2\\Windows\\system32\\user32.dll", filename)
+	require.NotNil(t, b.Events[0].PS)
+	assert.Equal(t, "firefox.exe", b.Events[0].PS.Name)
+	assert.Equal(t, uint32(6304), b.Events[0].PS.Ppid)
+	require.Nil(t, b.Events[1].PS)
+
+	_, err = NewBatchFromRaw(NewBatch(kevt).MarshalRaw()[:20])
+	require.Error(t, err)
+}
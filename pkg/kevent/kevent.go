@@ -200,6 +200,24 @@ func NewFromKcap(buf []byte) (*Kevent, error) {
 	return kevt, nil
 }
 
+// Copy returns a copy of the event that can be mutated and released
+// independently of the original event. Parameters and metadata are
+// copied, while the process state is shared between both events.
+func (kevt *Kevent) Copy() *Kevent {
+	c := pool.Get().(*Kevent)
+	*c = *kevt
+	c.Kparams = make(Kparams, len(kevt.Kparams))
+	for name, kpar := range kevt.Kparams {
+		kp := *kpar
+		c.Kparams[name] = &kp
+	}
+	c.Metadata = make(Metadata, len(kevt.Metadata))
+	for k, v := range kevt.Metadata {
+		c.Metadata[k] = v
+	}
+	return c
+}
+
 // AddMeta appends a key/value pair to event's metadata.
 func (kevt *Kevent) AddMeta(k MetadataKey, v any) {
 	kevt.Metadata[k] = v
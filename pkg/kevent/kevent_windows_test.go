@@ -85,3 +85,29 @@ func TestKeventSummary(t *testing.T) {
 	kevt.PS = nil
 	require.Equal(t, "process with <code>859</code> id opened a file <code>C:\\Windows\\system32\\user32.dll</code>", kevt.Summary())
 }
+
+func TestKeventCopy(t *testing.T) {
+	kevt := &Kevent{
+		Type:     ktypes.CreateFile,
+		Name:     "CreateFile",
+		PID:      1023,
+		Category: ktypes.File,
+		Kparams: Kparams{
+			kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Windows\\system32\\kernel32.dll"},
+		},
+		Metadata: map[MetadataKey]any{"foo": "bar"},
+		PS:       &pstypes.PS{Name: "cmd.exe"},
+	}
+
+	c := kevt.Copy()
+	assert.Equal(t, kevt.Name, c.Name)
+	assert.Equal(t, kevt.PS, c.PS)
+
+	c.Kparams.Remove(kparams.FileName)
+	c.AddMeta("key", "value")
+	c.Release()
+
+	assert.True(t, kevt.Kparams.Contains(kparams.FileName))
+	assert.Len(t, kevt.Metadata, 1)
+	assert.Equal(t, uint32(1023), kevt.PID)
+}
@@ -106,6 +106,15 @@ type Consumer interface {
 	// each incoming event. If the callback function is set up, the events
 	// channel doesn't receive any inbound events.
 	SetEventCallback(EventCallbackFunc)
+	// SetEventTap registers a function that observes every event that
+	// survived the drop checks, regardless of the rule outcome. Taps are
+	// used for recording the event stream in the background.
+	SetEventTap(func(*kevent.Kevent))
+	// ReloadRules recompiles the detection rules and replaces the active rule set.
+	// The active rule set is left intact if any of the rules fails to compile.
+	ReloadRules() error
+	// ProfileRules returns evaluation statistics of the detection rules.
+	ProfileRules() ([]filter.RuleProfile, error)
 }
 
 type kstreamConsumer struct {
@@ -122,11 +131,12 @@ type kstreamConsumer struct {
 	sequencer        *kevent.Sequencer // event sequence manager
 
 	filter filter.Filter
-	rules  filter.Rules
+	rules  *filter.Rules
 
 	capture bool // capture determines whether the event capture is triggered
 
-	eventCallback EventCallbackFunc // called on each incoming event
+	eventCallback EventCallbackFunc    // called on each incoming event
+	eventTap      func(*kevent.Kevent) // observes each incoming event
 }
 
 func (k *kstreamConsumer) addTraceHandle(traceHandle etw.TraceHandle) {
@@ -154,6 +164,12 @@ func NewConsumer(ktraceController KtraceController, psnap ps.Snapshotter, hsnap
 // SetFilter initializes the filter that's applied on the kernel events.
 func (k *kstreamConsumer) SetFilter(filter filter.Filter) { k.filter = filter }
 
+// ReloadRules recompiles the detection rules and replaces the active rule set.
+func (k *kstreamConsumer) ReloadRules() error { return k.rules.Reload() }
+
+// ProfileRules returns evaluation statistics of the detection rules.
+func (k *kstreamConsumer) ProfileRules() ([]filter.RuleProfile, error) { return k.rules.Profiles() }
+
 // OpenKstream initializes the kernel event stream by setting the event record callback and instructing it
 // to consume events from log buffers. This operation can fail if opening the kernel logger session results
 // in an invalid trace handler. Errors returned by `ProcessTrace` are sent to the channel since this function
@@ -163,6 +179,13 @@ func (k *kstreamConsumer) OpenKstream(traces map[string]TraceSession) error {
 	if err != nil {
 		return err
 	}
+	if err := k.rules.RestoreSequences(); err != nil {
+		log.Warnf("unable to restore sequence state: %v", err)
+	}
+	k.rules.ScheduleSequenceSnapshots()
+	if err := k.rules.Watch(); err != nil {
+		log.Warnf("unable to watch rule sources: %v", err)
+	}
 	for _, trace := range traces {
 		err := k.openKstream(trace.Name)
 		if err != nil {
@@ -233,6 +256,9 @@ func (k *kstreamConsumer) CloseKstream() error {
 	if err := k.sequencer.Close(); err != nil {
 		log.Warn(err)
 	}
+	if err := k.rules.Close(); err != nil {
+		log.Warnf("unable to snapshot sequence state: %v", err)
+	}
 
 	return k.interceptorChain.Close()
 }
@@ -252,6 +278,11 @@ func (k *kstreamConsumer) SetEventCallback(f EventCallbackFunc) {
 	k.eventCallback = f
 }
 
+// SetEventTap sets the function that observes inbound events.
+func (k *kstreamConsumer) SetEventTap(f func(*kevent.Kevent)) {
+	k.eventTap = f
+}
+
 // bufferStatsCallback is periodically triggered by ETW subsystem for the purpose of reporting
 // buffer statistics, such as the number of buffers processed.
 func (k *kstreamConsumer) bufferStatsCallback(logfile *etw.EventTraceLogfile) uintptr {
@@ -410,7 +441,11 @@ func (k *kstreamConsumer) processKevent(evt *etw.EventRecord) error {
 	// run rules. In case of rule groups with sequence policy
 	// the last event matching the group is forwarded to the
 	// outputs
-	if rulesFired := k.rules.Fire(kevt); !rulesFired {
+	rulesFired := k.rules.Fire(kevt)
+	if k.eventTap != nil {
+		k.eventTap(kevt)
+	}
+	if !rulesFired {
 		return nil
 	}
 	if k.eventCallback != nil {
@@ -436,7 +471,11 @@ func (k *kstreamConsumer) enqueueKevent(kevt *kevent.Kevent) error {
 		kevt.Release()
 		return nil
 	}
-	if rulesFired := k.rules.Fire(kevt); !rulesFired {
+	rulesFired := k.rules.Fire(kevt)
+	if k.eventTap != nil {
+		k.eventTap(kevt)
+	}
+	if !rulesFired {
 		return nil
 	}
 	// increment sequence
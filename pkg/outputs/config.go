@@ -20,6 +20,7 @@ package outputs
 
 import (
 	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers"
 	"github.com/spf13/pflag"
 )
 
@@ -27,6 +28,15 @@ import (
 type Config struct {
 	Type   Type
 	Output interface{}
+	// Name uniquely identifies the output. Outputs declared
+	// in the output section are named after the output type.
+	Name string
+	// Filter is the filter expression that selects events
+	// routed to the output. All events are routed if empty.
+	Filter string
+	// Transformers contains the transformers that are applied
+	// only to the events routed to the output.
+	Transformers []transformers.Config
 }
 
 // TLSConfig stores the client TLS parameters.
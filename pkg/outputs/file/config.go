@@ -0,0 +1,115 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/rabbitstack/fibratus/pkg/util/log/rotate"
+	"github.com/spf13/pflag"
+)
+
+const (
+	fileEnabled         = "output.file.enabled"
+	filePath            = "output.file.path"
+	fileName            = "output.file.name"
+	fileSerializer      = "output.file.serializer"
+	fileTemplate        = "output.file.template"
+	fileMaxSize         = "output.file.max-size"
+	fileInterval        = "output.file.interval"
+	fileCompression     = "output.file.compression"
+	fileMaxAge          = "output.file.max-age"
+	fileMaxFiles        = "output.file.max-files"
+	fileSplitByCategory = "output.file.split-by-category"
+)
+
+// Config contains the options that influence the behaviour of the file output.
+type Config struct {
+	// Enabled indicates if the file output is enabled.
+	Enabled bool `mapstructure:"enabled"`
+	// Path is the directory where event files are stored.
+	Path string `mapstructure:"path"`
+	// Name is the base name of event files.
+	Name string `mapstructure:"name"`
+	// Serializer indicates the serializer for events. Each event is written on a separate line.
+	Serializer outputs.Serializer `mapstructure:"serializer"`
+	// Template renders events with the given template instead of the serializer.
+	Template string `mapstructure:"template"`
+	// MaxSize is the maximum size in megabytes of the file before it gets rotated.
+	MaxSize int `mapstructure:"max-size"`
+	// Interval specifies the interval for the time-based rotation.
+	Interval time.Duration `mapstructure:"interval"`
+	// Compression is the codec for compressing rotated files.
+	Compression string `mapstructure:"compression"`
+	// MaxAge is the maximum number of days to retain rotated files.
+	MaxAge int `mapstructure:"max-age"`
+	// MaxFiles is the maximum number of rotated files to retain.
+	MaxFiles int `mapstructure:"max-files"`
+	// SplitByCategory writes events of each category to a separate file.
+	SplitByCategory bool `mapstructure:"split-by-category"`
+}
+
+// AddFlags registers persistent flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.Bool(fileEnabled, false, "Indicates if the file output is enabled")
+	flags.String(filePath, filepath.Join(os.Getenv("PROGRAMFILES"), "fibratus", "events"), "Specifies the directory where event files are stored")
+	flags.String(fileName, "fibratus", "Specifies the base name of event files")
+	flags.String(fileSerializer, string(outputs.JSON), "Indicates the event serializer type")
+	flags.String(fileTemplate, "", "Renders events with the given template instead of the serializer")
+	flags.Int(fileMaxSize, 100, "Specifies the maximum size in megabytes of the file before it gets rotated. Size-based rotation is disabled if zero")
+	flags.Duration(fileInterval, 0, "Specifies the interval for the time-based rotation, e.g. 1h or 24h. Time-based rotation is disabled by default")
+	flags.String(fileCompression, string(rotate.Gzip), "Specifies the codec for compressing rotated files. Possible values are none, gzip, and zstd")
+	flags.Int(fileMaxAge, 0, "Specifies the maximum number of days to retain rotated files. By default, rotated files are not removed due to their age")
+	flags.Int(fileMaxFiles, 10, "Specifies the maximum number of rotated files to retain")
+	flags.Bool(fileSplitByCategory, false, "Indicates if events of each category are written to a separate file")
+}
+
+func (c Config) validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if c.Template == "" && c.Serializer != outputs.JSON {
+		return fmt.Errorf("invalid serializer: %s", c.Serializer)
+	}
+	switch rotate.Compression(c.Compression) {
+	case rotate.None, rotate.Gzip, rotate.Zstd:
+	default:
+		return fmt.Errorf("invalid compression: %s", c.Compression)
+	}
+	return nil
+}
+
+// writerConfig returns the rotating writer config for the given file name.
+func (c Config) writerConfig(name string) rotate.WriterConfig {
+	return rotate.WriterConfig{
+		Filename:    filepath.Join(c.Path, name),
+		MaxSize:     int64(c.MaxSize) * 1024 * 1024,
+		Interval:    c.Interval,
+		Compression: rotate.Compression(c.Compression),
+		MaxAge:      time.Duration(c.MaxAge) * 24 * time.Hour,
+		MaxFiles:    c.MaxFiles,
+	}
+}
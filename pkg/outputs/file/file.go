@@ -0,0 +1,160 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"os"
+
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/rabbitstack/fibratus/pkg/util/log/rotate"
+	"github.com/rabbitstack/fibratus/pkg/util/multierror"
+)
+
+var (
+	// fileErrors counts events that failed to be written
+	fileErrors = expvar.NewInt("output.file.publish.errors")
+	// fileEvents counts the total number of written events
+	fileEvents = expvar.NewInt("output.file.publish.events")
+)
+
+type file struct {
+	config    Config
+	formatter *kevent.Formatter
+	ext       string
+	// writers contains the rotating writer for each category if events are
+	// split by category. Otherwise, all events are written by the single
+	// writer stored under the empty category
+	writers map[ktypes.Category]*rotate.Writer
+}
+
+func init() {
+	outputs.Register(outputs.File, initFile)
+}
+
+func initFile(config outputs.Config) (outputs.OutputGroup, error) {
+	cfg, ok := config.Output.(Config)
+	if !ok {
+		return outputs.Fail(outputs.ErrInvalidConfig(outputs.File, config.Output))
+	}
+	f, err := newFile(cfg)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+	return outputs.Success(f), nil
+}
+
+func newFile(config Config) (*file, error) {
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid file output config: %v", err)
+	}
+	f := &file{
+		config:  config,
+		ext:     ".json",
+		writers: make(map[ktypes.Category]*rotate.Writer),
+	}
+	if config.Template != "" {
+		var err error
+		f.formatter, err = kevent.NewFormatter(config.Template)
+		if err != nil {
+			return nil, err
+		}
+		f.ext = ".log"
+	}
+	return f, nil
+}
+
+func (f *file) Connect() error {
+	return os.MkdirAll(f.config.Path, os.ModePerm)
+}
+
+func (f *file) Close() error {
+	errs := make([]error, 0)
+	for cat, w := range f.writers {
+		if err := w.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(f.writers, cat)
+	}
+	if len(errs) > 0 {
+		return multierror.Wrap(errs...)
+	}
+	return nil
+}
+
+func (f *file) Publish(batch *kevent.Batch) error {
+	defer batch.Release()
+
+	// group serialized events by the target file, so
+	// the batch is written with a single call per file
+	bufs := make(map[ktypes.Category]*bytes.Buffer)
+	counts := make(map[ktypes.Category]int64)
+	for _, kevt := range batch.Events {
+		var cat ktypes.Category
+		if f.config.SplitByCategory {
+			cat = kevt.Category
+		}
+		buf, ok := bufs[cat]
+		if !ok {
+			buf = &bytes.Buffer{}
+			bufs[cat] = buf
+		}
+		if f.formatter != nil {
+			buf.Write(f.formatter.Format(kevt))
+		} else {
+			buf.Write(kevt.MarshalJSON())
+		}
+		buf.WriteByte('\n')
+		counts[cat]++
+	}
+
+	errs := make([]error, 0)
+	for cat, buf := range bufs {
+		if _, err := f.writer(cat).Write(buf.Bytes()); err != nil {
+			fileErrors.Add(counts[cat])
+			errs = append(errs, err)
+			continue
+		}
+		fileEvents.Add(counts[cat])
+	}
+	if len(errs) > 0 {
+		return multierror.Wrap(errs...)
+	}
+	return nil
+}
+
+// writer returns the rotating writer for the given category. The writer file
+// name is composed of the base name and the category, e.g. fibratus-file.json.
+func (f *file) writer(cat ktypes.Category) *rotate.Writer {
+	w, ok := f.writers[cat]
+	if ok {
+		return w
+	}
+	name := f.config.Name
+	if cat != "" {
+		name += "-" + string(cat)
+	}
+	w = rotate.NewWriter(f.config.writerConfig(name + f.ext))
+	f.writers[cat] = w
+	return w
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newConfig(dir string) Config {
+	return Config{
+		Path:        dir,
+		Name:        "fibratus",
+		Serializer:  outputs.JSON,
+		MaxSize:     100,
+		Compression: "gzip",
+		MaxFiles:    10,
+	}
+}
+
+// readLines reads NDJSON events from the file.
+func readLines(t *testing.T, path string) []map[string]any {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var r = bufio.NewScanner(f)
+	if filepath.Ext(path) == ".gz" {
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		r = bufio.NewScanner(gz)
+	}
+	evts := make([]map[string]any, 0)
+	for r.Scan() {
+		var evt map[string]any
+		require.NoError(t, json.Unmarshal(r.Bytes(), &evt))
+		evts = append(evts, evt)
+	}
+	require.NoError(t, r.Err())
+	return evts
+}
+
+func listFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	files := make([]string, 0, len(entries))
+	for _, e := range entries {
+		files = append(files, e.Name())
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestPublishFile(t *testing.T) {
+	dir := t.TempDir()
+	f, err := newFile(newConfig(dir))
+	require.NoError(t, err)
+	require.NoError(t, f.Connect())
+
+	require.NoError(t, f.Publish(getBatch()))
+	require.NoError(t, f.Publish(getBatch()))
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, []string{"fibratus.json"}, listFiles(t, dir))
+	evts := readLines(t, filepath.Join(dir, "fibratus.json"))
+	require.Len(t, evts, 6)
+	assert.Equal(t, "CreateFile", evts[0]["name"])
+	assert.Equal(t, "CreateProcess", evts[5]["name"])
+}
+
+func TestPublishFileSplitByCategory(t *testing.T) {
+	dir := t.TempDir()
+	config := newConfig(dir)
+	config.SplitByCategory = true
+	f, err := newFile(config)
+	require.NoError(t, err)
+	require.NoError(t, f.Connect())
+
+	require.NoError(t, f.Publish(getBatch()))
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, []string{"fibratus-file.json", "fibratus-process.json"}, listFiles(t, dir))
+	assert.Len(t, readLines(t, filepath.Join(dir, "fibratus-file.json")), 2)
+	assert.Len(t, readLines(t, filepath.Join(dir, "fibratus-process.json")), 1)
+}
+
+func TestPublishFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	config := newConfig(dir)
+	config.Interval = time.Hour
+	f, err := newFile(config)
+	require.NoError(t, err)
+	require.NoError(t, f.Connect())
+
+	require.NoError(t, f.Publish(getBatch()))
+	require.NoError(t, f.writers[""].Rotate())
+	require.NoError(t, f.Publish(getBatch()))
+	require.NoError(t, f.Close())
+
+	files := listFiles(t, dir)
+	require.Len(t, files, 2)
+	assert.Regexp(t, `^fibratus-\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.\d{3}\.json\.gz$`, files[0])
+	assert.Equal(t, "fibratus.json", files[1])
+	assert.Len(t, readLines(t, filepath.Join(dir, files[0])), 3)
+	assert.Len(t, readLines(t, filepath.Join(dir, files[1])), 3)
+}
+
+func TestFileConfig(t *testing.T) {
+	config := newConfig(t.TempDir())
+	config.Compression = "lzma"
+	_, err := newFile(config)
+	require.EqualError(t, err, "invalid file output config: invalid compression: lzma")
+
+	config = newConfig(t.TempDir())
+	config.Name = ""
+	_, err = newFile(config)
+	require.EqualError(t, err, "invalid file output config: name is required")
+
+	config = newConfig(t.TempDir())
+	config.MaxSize = 5
+	config.MaxAge = 7
+	c := config.writerConfig("fibratus.json")
+	assert.Equal(t, filepath.Join(config.Path, "fibratus.json"), c.Filename)
+	assert.Equal(t, int64(5*1024*1024), c.MaxSize)
+	assert.Equal(t, time.Hour*24*7, c.MaxAge)
+}
+
+func getBatch() *kevent.Batch {
+	newKevent := func(pid uint32, typ ktypes.Ktype, name, cat string) *kevent.Kevent {
+		return &kevent.Kevent{
+			Type:      typ,
+			Tid:       2484,
+			PID:       pid,
+			CPU:       1,
+			Seq:       2,
+			Name:      name,
+			Timestamp: time.Now(),
+			Category:  ktypes.Category(cat),
+			Host:      "archrabbit",
+			Kparams: kevent.Kparams{
+				kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "\\Device\\HarddiskVolume2\\Windows\\system32\\user32.dll"},
+			},
+			Metadata: map[kevent.MetadataKey]any{"foo": "bar"},
+			PS: &pstypes.PS{
+				PID:  pid,
+				Ppid: 6304,
+				Name: "firefox.exe",
+				Exe:  `C:\Program Files\Mozilla Firefox\firefox.exe`,
+			},
+		}
+	}
+	return kevent.NewBatch(
+		newKevent(859, ktypes.CreateFile, "CreateFile", "file"),
+		newKevent(459, ktypes.CreateFile, "CreateFile", "file"),
+		newKevent(829, ktypes.CreateProcess, "CreateProcess", "process"),
+	)
+}
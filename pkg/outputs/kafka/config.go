@@ -0,0 +1,230 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/rabbitstack/fibratus/pkg/util/tls"
+	"github.com/spf13/pflag"
+	"github.com/xdg-go/scram"
+)
+
+const (
+	kafkaEnabled       = "output.kafka.enabled"
+	kafkaBrokers       = "output.kafka.brokers"
+	kafkaTopic         = "output.kafka.topic"
+	kafkaPartitionKey  = "output.kafka.partition-key"
+	kafkaClientID      = "output.kafka.client-id"
+	kafkaVersion       = "output.kafka.version"
+	kafkaTimeout       = "output.kafka.timeout"
+	kafkaAcks          = "output.kafka.acks"
+	kafkaCompression   = "output.kafka.compression"
+	kafkaBatchSize     = "output.kafka.batch-size"
+	kafkaBatchBytes    = "output.kafka.batch-bytes"
+	kafkaLinger        = "output.kafka.linger"
+	kafkaMaxRetries    = "output.kafka.max-retries"
+	kafkaSASLMechanism = "output.kafka.sasl-mechanism"
+	kafkaUsername      = "output.kafka.username"
+	kafkaPassword      = "output.kafka.password"
+)
+
+// Config contains the tweaks that influence the behaviour of the Kafka output.
+type Config struct {
+	outputs.TLSConfig
+	// Enabled indicates if the Kafka output is enabled.
+	Enabled bool `mapstructure:"enabled"`
+	// Brokers contains the list of bootstrap broker addresses.
+	Brokers []string `mapstructure:"brokers"`
+	// Topic is the name of the topic where events are produced. The topic may
+	// contain event field placeholders, e.g. fibratus-{{ .Category }}.
+	Topic string `mapstructure:"topic"`
+	// PartitionKey is the template of the message key that determines the partition
+	// the event is produced to, e.g. {{ .Host }}. Events are spread across partitions
+	// randomly if the partition key is empty.
+	PartitionKey string `mapstructure:"partition-key"`
+	// ClientID is the client identifier sent to brokers.
+	ClientID string `mapstructure:"client-id"`
+	// Version is the Kafka protocol version used to communicate with brokers.
+	Version string `mapstructure:"version"`
+	// Timeout specifies the connection and produce request timeout.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Acks determines the number of acknowledgments the leader must receive before
+	// the produce request is considered complete. Possible values are none, leader, and all.
+	Acks string `mapstructure:"acks"`
+	// Compression is the compression codec of message batches. Possible values are
+	// none, gzip, snappy, lz4, and zstd.
+	Compression string `mapstructure:"compression"`
+	// BatchSize is the maximum number of messages buffered before they are sent to the broker.
+	BatchSize int `mapstructure:"batch-size"`
+	// BatchBytes is the maximum number of bytes buffered before messages are sent to the broker.
+	BatchBytes int `mapstructure:"batch-bytes"`
+	// Linger is the maximum time to wait for the batch to fill up before it is sent to the broker.
+	Linger time.Duration `mapstructure:"linger"`
+	// MaxRetries is the number of times the producer retries sending messages.
+	MaxRetries int `mapstructure:"max-retries"`
+	// SASLMechanism is the SASL authentication mechanism. Possible values are
+	// plain, scram-sha-256, and scram-sha-512. SASL is disabled if empty.
+	SASLMechanism string `mapstructure:"sasl-mechanism"`
+	// Username is the SASL username.
+	Username string `mapstructure:"username"`
+	// Password is the SASL password.
+	Password string `mapstructure:"password"`
+	// Headers contains a list of headers that are added to each message.
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// AddFlags registers persistent flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.Bool(kafkaEnabled, false, "Indicates if the Kafka output is enabled")
+	flags.StringSlice(kafkaBrokers, []string{"localhost:9092"}, "Contains the list of bootstrap broker addresses")
+	flags.String(kafkaTopic, "fibratus", "Specifies the topic where events are produced. It may contain event field placeholders")
+	flags.String(kafkaPartitionKey, "", "Specifies the message key template that determines the partition the event is produced to")
+	flags.String(kafkaClientID, "fibratus", "Specifies the client identifier sent to brokers")
+	flags.String(kafkaVersion, "2.1.0", "Specifies the Kafka protocol version used to communicate with brokers")
+	flags.Duration(kafkaTimeout, time.Second*10, "Specifies the connection and produce request timeout")
+	flags.String(kafkaAcks, "leader", "Determines the number of acknowledgments required for the produce request to complete. Possible values are none, leader, and all")
+	flags.String(kafkaCompression, "none", "Specifies the compression codec of message batches. Possible values are none, gzip, snappy, lz4, and zstd")
+	flags.Int(kafkaBatchSize, 500, "Specifies the maximum number of messages buffered before they are sent to the broker")
+	flags.Int(kafkaBatchBytes, 1024*1024, "Specifies the maximum number of bytes buffered before messages are sent to the broker")
+	flags.Duration(kafkaLinger, time.Millisecond*100, "Specifies the maximum time to wait for the batch to fill up before it is sent to the broker")
+	flags.Int(kafkaMaxRetries, 3, "Specifies the number of times the producer retries sending messages")
+	flags.String(kafkaSASLMechanism, "", "Specifies the SASL authentication mechanism. Possible values are plain, scram-sha-256, and scram-sha-512")
+	flags.String(kafkaUsername, "", "The username for the SASL authentication")
+	flags.String(kafkaPassword, "", "The password for the SASL authentication")
+	outputs.AddTLSFlags(flags, outputs.Kafka)
+}
+
+// saramaConfig builds the producer configuration.
+func (c Config) saramaConfig() (*sarama.Config, error) {
+	config := sarama.NewConfig()
+	if c.ClientID != "" {
+		config.ClientID = c.ClientID
+	}
+
+	if c.Version != "" {
+		version, err := sarama.ParseKafkaVersion(c.Version)
+		if err != nil {
+			return nil, err
+		}
+		config.Version = version
+	}
+
+	if c.Timeout > 0 {
+		config.Net.DialTimeout = c.Timeout
+		config.Net.ReadTimeout = c.Timeout
+		config.Net.WriteTimeout = c.Timeout
+		config.Producer.Timeout = c.Timeout
+	}
+
+	switch strings.ToLower(c.Acks) {
+	case "none":
+		config.Producer.RequiredAcks = sarama.NoResponse
+	case "", "leader":
+		config.Producer.RequiredAcks = sarama.WaitForLocal
+	case "all":
+		config.Producer.RequiredAcks = sarama.WaitForAll
+	default:
+		return nil, fmt.Errorf("invalid acks: %s", c.Acks)
+	}
+
+	switch strings.ToLower(c.Compression) {
+	case "", "none":
+		config.Producer.Compression = sarama.CompressionNone
+	case "gzip":
+		config.Producer.Compression = sarama.CompressionGZIP
+	case "snappy":
+		config.Producer.Compression = sarama.CompressionSnappy
+	case "lz4":
+		config.Producer.Compression = sarama.CompressionLZ4
+	case "zstd":
+		config.Producer.Compression = sarama.CompressionZSTD
+	default:
+		return nil, fmt.Errorf("invalid compression codec: %s", c.Compression)
+	}
+
+	config.Producer.Flush.Messages = c.BatchSize
+	config.Producer.Flush.Bytes = c.BatchBytes
+	config.Producer.Flush.Frequency = c.Linger
+	config.Producer.Retry.Max = c.MaxRetries
+	// required by the sync producer
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+
+	if c.PartitionKey != "" {
+		config.Producer.Partitioner = sarama.NewHashPartitioner
+	} else {
+		config.Producer.Partitioner = sarama.NewRandomPartitioner
+	}
+
+	tlsConfig, err := tls.MakeConfig(c.TLSCert, c.TLSKey, c.TLSCA, c.TLSInsecureSkipVerify)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS config: %v", err)
+	}
+	if tlsConfig != nil {
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+
+	if c.SASLMechanism != "" {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.User = c.Username
+		config.Net.SASL.Password = c.Password
+		switch strings.ToLower(c.SASLMechanism) {
+		case "plain":
+			config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		case "scram-sha-256":
+			config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hashGenerator: scram.SHA256} }
+		case "scram-sha-512":
+			config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hashGenerator: scram.SHA512} }
+		default:
+			return nil, fmt.Errorf("invalid SASL mechanism: %s", c.SASLMechanism)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// scramClient implements the SCRAM authentication conversation.
+type scramClient struct {
+	hashGenerator scram.HashGeneratorFcn
+	conv          *scram.ClientConversation
+}
+
+func (c *scramClient) Begin(username, password, authzID string) error {
+	client, err := c.hashGenerator.NewClient(username, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conv = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) { return c.conv.Step(challenge) }
+
+func (c *scramClient) Done() bool { return c.conv.Done() }
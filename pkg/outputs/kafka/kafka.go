@@ -0,0 +1,168 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// kafkaErrors counts messages that failed to be produced
+	kafkaErrors = expvar.NewInt("output.kafka.publish.errors")
+	// kafkaMessages counts the total number of produced messages
+	kafkaMessages = expvar.NewInt("output.kafka.publish.messages")
+)
+
+type kafka struct {
+	config   Config
+	sconfig  *sarama.Config
+	producer sarama.SyncProducer
+	// topic and key are nil if the topic or the
+	// partition key don't contain any placeholders
+	topic *kevent.Formatter
+	key   *kevent.Formatter
+}
+
+func init() {
+	outputs.Register(outputs.Kafka, initKafka)
+}
+
+func initKafka(config outputs.Config) (outputs.OutputGroup, error) {
+	cfg, ok := config.Output.(Config)
+	if !ok {
+		return outputs.Fail(outputs.ErrInvalidConfig(outputs.Kafka, config.Output))
+	}
+	k, err := newKafka(cfg)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+	return outputs.Success(k), nil
+}
+
+func newKafka(config Config) (*kafka, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("at least one broker is required")
+	}
+	if config.Topic == "" {
+		return nil, errors.New("topic is required")
+	}
+	sconfig, err := config.saramaConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid Kafka config: %v", err)
+	}
+	k := &kafka{config: config, sconfig: sconfig}
+	k.topic, err = newFormatter(config.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Kafka topic: %v", err)
+	}
+	k.key, err = newFormatter(config.PartitionKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Kafka partition key: %v", err)
+	}
+	return k, nil
+}
+
+// newFormatter creates the event formatter if the
+// template contains any field placeholders.
+func newFormatter(tmpl string) (*kevent.Formatter, error) {
+	if !strings.Contains(tmpl, "{{") {
+		return nil, nil
+	}
+	return kevent.NewFormatter(tmpl)
+}
+
+func (k *kafka) Connect() error {
+	producer, err := sarama.NewSyncProducer(k.config.Brokers, k.sconfig)
+	if err != nil {
+		return err
+	}
+	k.producer = producer
+	log.Infof("established connection to Kafka brokers %s", strings.Join(k.config.Brokers, ","))
+	return nil
+}
+
+func (k *kafka) Close() error {
+	if k.producer == nil {
+		return nil
+	}
+	return k.producer.Close()
+}
+
+func (k *kafka) Publish(batch *kevent.Batch) error {
+	msgs := k.messages(batch)
+	defer batch.Release()
+
+	if len(msgs) == 0 {
+		return nil
+	}
+	if k.producer == nil {
+		return errors.New("producer is not connected")
+	}
+
+	err := k.producer.SendMessages(msgs)
+	if err != nil {
+		var perrs sarama.ProducerErrors
+		if errors.As(err, &perrs) {
+			kafkaErrors.Add(int64(len(perrs)))
+			kafkaMessages.Add(int64(len(msgs) - len(perrs)))
+		} else {
+			kafkaErrors.Add(int64(len(msgs)))
+		}
+		return err
+	}
+
+	kafkaMessages.Add(int64(len(msgs)))
+
+	return nil
+}
+
+// messages produces a message for each event in the batch.
+func (k *kafka) messages(batch *kevent.Batch) []*sarama.ProducerMessage {
+	headers := make([]sarama.RecordHeader, 0, len(k.config.Headers))
+	for key, value := range k.config.Headers {
+		headers = append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+	}
+	msgs := make([]*sarama.ProducerMessage, 0, len(batch.Events))
+	for _, kevt := range batch.Events {
+		msg := &sarama.ProducerMessage{
+			Topic:     k.config.Topic,
+			Value:     sarama.ByteEncoder(kevt.MarshalJSON()),
+			Headers:   headers,
+			Timestamp: kevt.Timestamp,
+		}
+		if k.topic != nil {
+			msg.Topic = string(k.topic.Format(kevt))
+		}
+		if k.key != nil {
+			msg.Key = sarama.ByteEncoder(k.key.Format(kevt))
+		} else if k.config.PartitionKey != "" {
+			msg.Key = sarama.StringEncoder(k.config.PartitionKey)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBroker starts the in-process broker that speaks the Kafka protocol and
+// acknowledges produce requests for the topics of the test batch.
+func newBroker(t *testing.T, produce *sarama.MockProduceResponse) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 1)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("fibratus-file", 0, broker.BrokerID()).
+			SetLeader("fibratus-file", 1, broker.BrokerID()).
+			SetLeader("fibratus-process", 0, broker.BrokerID()),
+		"ProduceRequest": produce,
+	})
+	return broker
+}
+
+func TestPublishKafkaOutput(t *testing.T) {
+	for _, compression := range []string{"none", "gzip", "snappy", "lz4", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			broker := newBroker(t, sarama.NewMockProduceResponse(t))
+			defer broker.Close()
+
+			k, err := newKafka(Config{
+				Brokers:      []string{broker.Addr()},
+				Topic:        "fibratus-{{ .Category }}",
+				PartitionKey: "{{ .Host }}",
+				Version:      "2.1.0",
+				Timeout:      time.Second * 5,
+				Acks:         "all",
+				Compression:  compression,
+				BatchSize:    100,
+				Linger:       time.Millisecond * 10,
+			})
+			require.NoError(t, err)
+			require.NoError(t, k.Connect())
+			defer k.Close()
+
+			require.NoError(t, k.Publish(getBatch()))
+
+			topics := make(map[string]bool)
+			for _, rr := range broker.History() {
+				req, ok := rr.Request.(*sarama.ProduceRequest)
+				if !ok {
+					continue
+				}
+				assert.Equal(t, sarama.WaitForAll, req.RequiredAcks)
+				res := rr.Response.(*sarama.ProduceResponse)
+				for topic := range res.Blocks {
+					topics[topic] = true
+				}
+			}
+			assert.Equal(t, map[string]bool{"fibratus-file": true, "fibratus-process": true}, topics)
+		})
+	}
+}
+
+func TestPublishKafkaOutputError(t *testing.T) {
+	produce := sarama.NewMockProduceResponse(t).SetError("fibratus-file", 0, sarama.ErrInvalidMessage)
+	broker := newBroker(t, produce)
+	defer broker.Close()
+
+	k, err := newKafka(Config{
+		Brokers: []string{broker.Addr()},
+		Topic:   "fibratus-file",
+		Version: "2.1.0",
+		Timeout: time.Second * 5,
+		Acks:    "leader",
+	})
+	require.NoError(t, err)
+	require.NoError(t, k.Connect())
+	defer k.Close()
+
+	require.Error(t, k.Publish(getBatch()))
+}
+
+func TestKafkaMessages(t *testing.T) {
+	k, err := newKafka(Config{
+		Brokers:      []string{"localhost:9092"},
+		Topic:        "fibratus-{{ .Category }}",
+		PartitionKey: "{{ .Host }}-{{ .Pid }}",
+		Headers:      map[string]string{"env": "dev"},
+	})
+	require.NoError(t, err)
+
+	msgs := k.messages(getBatch())
+	require.Len(t, msgs, 3)
+
+	assert.Equal(t, "fibratus-file", msgs[0].Topic)
+	assert.Equal(t, "fibratus-process", msgs[2].Topic)
+
+	key, err := msgs[0].Key.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, "archrabbit-859", string(key))
+
+	require.Len(t, msgs[0].Headers, 1)
+	assert.Equal(t, "env", string(msgs[0].Headers[0].Key))
+
+	value, err := msgs[0].Value.Encode()
+	require.NoError(t, err)
+	var kevt kevent.Kevent
+	require.NoError(t, json.Unmarshal(value, &kevt))
+	assert.Equal(t, uint32(859), kevt.PID)
+
+	// static topic and no partition key
+	k, err = newKafka(Config{Brokers: []string{"localhost:9092"}, Topic: "events"})
+	require.NoError(t, err)
+	msgs = k.messages(getBatch())
+	assert.Equal(t, "events", msgs[2].Topic)
+	assert.Nil(t, msgs[2].Key)
+}
+
+func TestKafkaConfig(t *testing.T) {
+	var tests = []struct {
+		c   Config
+		err bool
+	}{
+		{Config{Brokers: []string{"localhost:9092"}, Topic: "fibratus"}, false},
+		{Config{Brokers: []string{"localhost:9092"}, Topic: "fibratus", Acks: "two"}, true},
+		{Config{Brokers: []string{"localhost:9092"}, Topic: "fibratus", Compression: "brotli"}, true},
+		{Config{Brokers: []string{"localhost:9092"}, Topic: "fibratus", Version: "x.y"}, true},
+		{Config{Brokers: []string{"localhost:9092"}, Topic: "fibratus", SASLMechanism: "gssapi", Username: "fibratus", Password: "secret"}, true},
+		{Config{Brokers: []string{"localhost:9092"}, Topic: "fibratus", SASLMechanism: "plain", Username: "fibratus", Password: "secret"}, false},
+		{Config{Brokers: []string{"localhost:9092"}, Topic: "fibratus", SASLMechanism: "scram-sha-512", Username: "fibratus", Password: "secret"}, false},
+		{Config{Brokers: []string{"localhost:9092"}, Topic: "fibratus-{{ .Unknown }}"}, true},
+		{Config{Brokers: []string{"localhost:9092"}}, true},
+		{Config{Topic: "fibratus"}, true},
+	}
+
+	for i, tt := range tests {
+		_, err := newKafka(tt.c)
+		if tt.err {
+			assert.Error(t, err, i)
+		} else {
+			assert.NoError(t, err, i)
+		}
+	}
+
+	k, err := newKafka(Config{Brokers: []string{"localhost:9092"}, Topic: "fibratus", SASLMechanism: "scram-sha-256", Username: "fibratus", Password: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA256), k.sconfig.Net.SASL.Mechanism)
+	require.NotNil(t, k.sconfig.Net.SASL.SCRAMClientGeneratorFunc)
+	scram := k.sconfig.Net.SASL.SCRAMClientGeneratorFunc()
+	require.NoError(t, scram.Begin("fibratus", "secret", ""))
+	first, err := scram.Step("")
+	require.NoError(t, err)
+	assert.Contains(t, first, "n=fibratus")
+}
+
+func getBatch() *kevent.Batch {
+	newKevent := func(pid uint32, typ ktypes.Ktype, name, cat string) *kevent.Kevent {
+		return &kevent.Kevent{
+			Type:      typ,
+			Tid:       2484,
+			PID:       pid,
+			CPU:       1,
+			Seq:       2,
+			Name:      name,
+			Timestamp: time.Now(),
+			Category:  ktypes.Category(cat),
+			Host:      "archrabbit",
+			Kparams: kevent.Kparams{
+				kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "\\Device\\HarddiskVolume2\\Windows\\system32\\user32.dll"},
+			},
+			Metadata: map[kevent.MetadataKey]any{"foo": "bar"},
+			PS: &pstypes.PS{
+				PID:  pid,
+				Ppid: 6304,
+				Name: "firefox.exe",
+				Exe:  `C:\Program Files\Mozilla Firefox\firefox.exe`,
+			},
+		}
+	}
+	return kevent.NewBatch(
+		newKevent(859, ktypes.CreateFile, "CreateFile", "file"),
+		newKevent(459, ktypes.CreateFile, "CreateFile", "file"),
+		newKevent(829, ktypes.CreateProcess, "CreateProcess", "process"),
+	)
+}
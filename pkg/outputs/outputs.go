@@ -51,6 +51,12 @@ const (
 	Eventlog
 	// Null is the null output.
 	Null
+	// Kafka denotes the Kafka output.
+	Kafka
+	// Syslog denotes the syslog output.
+	Syslog
+	// File denotes the file output.
+	File
 	// Unknown is an undefined output type.
 	Unknown
 )
@@ -70,6 +76,12 @@ func (t Type) String() string {
 		return "eventlog"
 	case Null:
 		return "null"
+	case Kafka:
+		return "kafka"
+	case Syslog:
+		return "syslog"
+	case File:
+		return "file"
 	default:
 		return "unknown"
 	}
@@ -90,6 +102,12 @@ func TypeFromString(s string) Type {
 		return Eventlog
 	case "null":
 		return Null
+	case "kafka":
+		return Kafka
+	case "syslog":
+		return Syslog
+	case "file":
+		return File
 	default:
 		return Unknown
 	}
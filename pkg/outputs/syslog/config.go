@@ -0,0 +1,162 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/spf13/pflag"
+)
+
+const (
+	syslogEnabled     = "output.syslog.enabled"
+	syslogNetwork     = "output.syslog.network"
+	syslogAddress     = "output.syslog.address"
+	syslogTimeout     = "output.syslog.timeout"
+	syslogProtocol    = "output.syslog.protocol"
+	syslogFraming     = "output.syslog.framing"
+	syslogFormat      = "output.syslog.format"
+	syslogFacility    = "output.syslog.facility"
+	syslogAppName     = "output.syslog.app-name"
+	syslogHostname    = "output.syslog.hostname"
+	syslogSeverityKey = "output.syslog.severity-key"
+)
+
+const (
+	// rfc5424 is the modern syslog protocol
+	rfc5424 = "rfc5424"
+	// rfc3164 is the legacy BSD syslog protocol
+	rfc3164 = "rfc3164"
+
+	// octetCounting frames messages by prepending the message length
+	octetCounting = "octet-counting"
+	// nonTransparent frames messages by appending the LF character
+	nonTransparent = "non-transparent"
+
+	// jsonFormat renders the message body as JSON
+	jsonFormat = "json"
+	// cefFormat renders the message body in ArcSight Common Event Format
+	cefFormat = "cef"
+	// leefFormat renders the message body in QRadar Log Event Extended Format
+	leefFormat = "leef"
+)
+
+// facilities maps facility names to facility codes
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// Config contains the tweaks that influence the behaviour of the syslog output.
+type Config struct {
+	outputs.TLSConfig
+	// Enabled indicates if the syslog output is enabled.
+	Enabled bool `mapstructure:"enabled"`
+	// Network is the transport for sending messages. Possible values are udp, tcp, and tls.
+	Network string `mapstructure:"network"`
+	// Address is the address of the syslog server.
+	Address string `mapstructure:"address"`
+	// Timeout specifies the connection and write timeout.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Protocol determines the format of the syslog header. Possible values are rfc5424 and rfc3164.
+	Protocol string `mapstructure:"protocol"`
+	// Framing determines how messages are delimited in TCP and TLS streams. Possible
+	// values are octet-counting and non-transparent.
+	Framing string `mapstructure:"framing"`
+	// Format is the format of the message body. Possible values are json, cef, and leef.
+	Format string `mapstructure:"format"`
+	// Facility is the syslog facility name.
+	Facility string `mapstructure:"facility"`
+	// AppName is the application name in the syslog header.
+	AppName string `mapstructure:"app-name"`
+	// Hostname overrides the event host name in the syslog header.
+	Hostname string `mapstructure:"hostname"`
+	// SeverityKey is the metadata key that holds the severity of the event.
+	SeverityKey string `mapstructure:"severity-key"`
+	// Extensions contains additional CEF/LEEF extension keys. Values may contain event
+	// field placeholders.
+	Extensions map[string]string `mapstructure:"extensions"`
+}
+
+// AddFlags registers persistent flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.Bool(syslogEnabled, false, "Indicates if the syslog output is enabled")
+	flags.String(syslogNetwork, "udp", "Specifies the transport for sending messages. Possible values are udp, tcp, and tls")
+	flags.String(syslogAddress, "localhost:514", "Specifies the address of the syslog server")
+	flags.Duration(syslogTimeout, time.Second*5, "Specifies the connection and write timeout")
+	flags.String(syslogProtocol, rfc5424, "Determines the format of the syslog header. Possible values are rfc5424 and rfc3164")
+	flags.String(syslogFraming, octetCounting, "Determines how messages are delimited in TCP and TLS streams. Possible values are octet-counting and non-transparent")
+	flags.String(syslogFormat, jsonFormat, "Specifies the format of the message body. Possible values are json, cef, and leef")
+	flags.String(syslogFacility, "local0", "Specifies the syslog facility")
+	flags.String(syslogAppName, "fibratus", "Specifies the application name in the syslog header")
+	flags.String(syslogHostname, "", "Overrides the event host name in the syslog header")
+	flags.String(syslogSeverityKey, "severity", "Specifies the metadata key that holds the severity of the event")
+	outputs.AddTLSFlags(flags, outputs.Syslog)
+}
+
+func (c Config) validate() error {
+	switch c.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return fmt.Errorf("invalid network: %s", c.Network)
+	}
+	if c.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	switch c.Protocol {
+	case rfc5424, rfc3164:
+	default:
+		return fmt.Errorf("invalid protocol: %s", c.Protocol)
+	}
+	switch c.Framing {
+	case octetCounting, nonTransparent:
+	default:
+		return fmt.Errorf("invalid framing: %s", c.Framing)
+	}
+	switch c.Format {
+	case jsonFormat, cefFormat, leefFormat:
+	default:
+		return fmt.Errorf("invalid format: %s", c.Format)
+	}
+	if _, ok := facilities[strings.ToLower(c.Facility)]; !ok {
+		return fmt.Errorf("invalid facility: %s", c.Facility)
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/util/version"
+)
+
+const (
+	vendor  = "Fibratus"
+	product = "Fibratus"
+
+	// leefTimeFormat is the Java date format of the LEEF devTime key
+	leefTimeFormat = "yyyy-MM-dd'T'HH:mm:ss.SSSZ"
+)
+
+var (
+	cefHeaderEscaper  = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	cefExtEscaper     = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+	leefHeaderEscaper = strings.NewReplacer(`|`, `\|`)
+	leefExtEscaper    = strings.NewReplacer("\t", `\t`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+)
+
+// ext is the CEF/LEEF extension key/value pair
+type ext struct {
+	key   string
+	value string
+}
+
+// template is the custom extension with the optional formatter
+type template struct {
+	key   string
+	value string
+	f     *kevent.Formatter
+}
+
+// formatter renders events as syslog messages.
+type formatter struct {
+	config    Config
+	facility  int
+	procID    string
+	templates []template
+}
+
+func newFormatter(config Config) (*formatter, error) {
+	f := &formatter{
+		config:    config,
+		facility:  facilities[strings.ToLower(config.Facility)],
+		procID:    strconv.Itoa(os.Getpid()),
+		templates: make([]template, 0, len(config.Extensions)),
+	}
+	for key, value := range config.Extensions {
+		t := template{key: key, value: value}
+		if strings.Contains(value, "{{") {
+			var err error
+			t.f, err = kevent.NewFormatter(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		f.templates = append(f.templates, t)
+	}
+	sort.Slice(f.templates, func(i, j int) bool { return f.templates[i].key < f.templates[j].key })
+	return f, nil
+}
+
+// format produces the syslog message of the event.
+func (f *formatter) format(kevt *kevent.Kevent) []byte {
+	sev := f.severity(kevt)
+
+	var body []byte
+	switch f.config.Format {
+	case cefFormat:
+		body = f.cef(kevt, sev)
+	case leefFormat:
+		body = f.leef(kevt, sev)
+	default:
+		body = kevt.MarshalJSON()
+	}
+
+	hostname := f.config.Hostname
+	if hostname == "" {
+		hostname = kevt.Host
+	}
+	if hostname == "" {
+		hostname = "-"
+	}
+	pri := "<" + strconv.Itoa(f.facility*8+syslogSeverity(sev)) + ">"
+
+	var b bytes.Buffer
+	b.WriteString(pri)
+	switch f.config.Protocol {
+	case rfc3164:
+		// <PRI>TIMESTAMP HOSTNAME TAG[PID]: MSG
+		b.WriteString(kevt.Timestamp.Format("Jan _2 15:04:05"))
+		b.WriteByte(' ')
+		b.WriteString(hostname)
+		b.WriteByte(' ')
+		b.WriteString(f.config.AppName)
+		b.WriteString("[" + f.procID + "]: ")
+	default:
+		// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+		b.WriteString("1 ")
+		b.WriteString(kevt.Timestamp.Format("2006-01-02T15:04:05.000000Z07:00"))
+		b.WriteByte(' ')
+		b.WriteString(hostname)
+		b.WriteByte(' ')
+		b.WriteString(nilValue(f.config.AppName))
+		b.WriteByte(' ')
+		b.WriteString(f.procID)
+		b.WriteByte(' ')
+		b.WriteString(nilValue(kevt.Name))
+		b.WriteString(" - ")
+	}
+	b.Write(body)
+	return b.Bytes()
+}
+
+// cef renders the event in Common Event Format:
+//
+// CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+func (f *formatter) cef(kevt *kevent.Kevent, sev int) []byte {
+	name := kevt.Description
+	if rule := meta(kevt, kevent.RuleNameKey); rule != "" {
+		name = rule
+	}
+	if name == "" {
+		name = kevt.Name
+	}
+
+	exts := []ext{
+		{"rt", strconv.FormatInt(kevt.Timestamp.UnixMilli(), 10)},
+		{"dvchost", kevt.Host},
+		{"externalId", strconv.FormatUint(kevt.Seq, 10)},
+		{"cat", string(kevt.Category)},
+		{"act", kevt.Name},
+		{"msg", kevt.Description},
+		{"spid", strconv.FormatUint(uint64(kevt.PID), 10)},
+	}
+	if ps := kevt.PS; ps != nil {
+		exts = append(exts,
+			ext{"sproc", ps.Name},
+			ext{"suser", ps.SID},
+			ext{"cs3Label", "CommandLine"},
+			ext{"cs3", ps.Comm},
+			ext{"cs4Label", "Executable"},
+			ext{"cs4", ps.Exe},
+		)
+	}
+	if file := kparam(kevt, kparams.FileName); file != "" {
+		exts = append(exts, ext{"filePath", file}, ext{"fname", file[strings.LastIndexAny(file, `\/`)+1:]})
+	}
+	exts = append(exts,
+		ext{"src", kparam(kevt, kparams.NetSIP)},
+		ext{"dst", kparam(kevt, kparams.NetDIP)},
+		ext{"spt", kparam(kevt, kparams.NetSport)},
+		ext{"dpt", kparam(kevt, kparams.NetDport)},
+	)
+	if rule := meta(kevt, kevent.RuleNameKey); rule != "" {
+		exts = append(exts,
+			ext{"cs1Label", "Rule"},
+			ext{"cs1", rule},
+			ext{"cs2Label", "RuleGroup"},
+			ext{"cs2", meta(kevt, kevent.RuleGroupKey)},
+		)
+	}
+	exts = append(exts, f.extensions(kevt)...)
+
+	var b bytes.Buffer
+	b.WriteString("CEF:0|")
+	b.WriteString(cefHeaderEscaper.Replace(vendor) + "|")
+	b.WriteString(cefHeaderEscaper.Replace(product) + "|")
+	b.WriteString(cefHeaderEscaper.Replace(version.Get()) + "|")
+	b.WriteString(cefHeaderEscaper.Replace(kevt.Name) + "|")
+	b.WriteString(cefHeaderEscaper.Replace(name) + "|")
+	b.WriteString(strconv.Itoa(sev) + "|")
+	first := true
+	for _, e := range exts {
+		if e.value == "" {
+			continue
+		}
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		b.WriteString(e.key + "=" + cefExtEscaper.Replace(e.value))
+	}
+	return b.Bytes()
+}
+
+// leef renders the event in Log Event Extended Format:
+//
+// LEEF:Version|Vendor|Product|Version|EventID|Extension
+func (f *formatter) leef(kevt *kevent.Kevent, sev int) []byte {
+	exts := []ext{
+		{"devTime", kevt.Timestamp.Format("2006-01-02T15:04:05.000-0700")},
+		{"devTimeFormat", leefTimeFormat},
+		{"sev", strconv.Itoa(sev)},
+		{"cat", string(kevt.Category)},
+		{"identHostName", kevt.Host},
+		{"seq", strconv.FormatUint(kevt.Seq, 10)},
+		{"desc", kevt.Description},
+		{"pid", strconv.FormatUint(uint64(kevt.PID), 10)},
+	}
+	if ps := kevt.PS; ps != nil {
+		exts = append(exts,
+			ext{"proc", ps.Name},
+			ext{"usrName", ps.SID},
+			ext{"cmdline", ps.Comm},
+			ext{"exe", ps.Exe},
+		)
+	}
+	exts = append(exts,
+		ext{"filePath", kparam(kevt, kparams.FileName)},
+		ext{"src", kparam(kevt, kparams.NetSIP)},
+		ext{"dst", kparam(kevt, kparams.NetDIP)},
+		ext{"srcPort", kparam(kevt, kparams.NetSport)},
+		ext{"dstPort", kparam(kevt, kparams.NetDport)},
+		ext{"ruleName", meta(kevt, kevent.RuleNameKey)},
+		ext{"ruleGroup", meta(kevt, kevent.RuleGroupKey)},
+	)
+	exts = append(exts, f.extensions(kevt)...)
+
+	var b bytes.Buffer
+	b.WriteString("LEEF:1.0|")
+	b.WriteString(leefHeaderEscaper.Replace(vendor) + "|")
+	b.WriteString(leefHeaderEscaper.Replace(product) + "|")
+	b.WriteString(leefHeaderEscaper.Replace(version.Get()) + "|")
+	b.WriteString(leefHeaderEscaper.Replace(kevt.Name) + "|")
+	first := true
+	for _, e := range exts {
+		if e.value == "" {
+			continue
+		}
+		if !first {
+			b.WriteByte('\t')
+		}
+		first = false
+		b.WriteString(e.key + "=" + leefExtEscaper.Replace(e.value))
+	}
+	return b.Bytes()
+}
+
+// extensions renders the custom extensions.
+func (f *formatter) extensions(kevt *kevent.Kevent) []ext {
+	exts := make([]ext, 0, len(f.templates))
+	for _, t := range f.templates {
+		value := t.value
+		if t.f != nil {
+			value = string(t.f.Format(kevt))
+		}
+		exts = append(exts, ext{t.key, value})
+	}
+	return exts
+}
+
+// severity returns the event severity in the 0-10 range. The severity
+// is resolved from the metadata, which usually comes from the labels
+// of the group that contains the triggered rule. Alerts without the
+// severity default to the medium severity, the same as emitted alerts.
+// Other events are informational.
+func (f *formatter) severity(kevt *kevent.Kevent) int {
+	s := meta(kevt, kevent.MetadataKey(f.config.SeverityKey))
+	switch strings.ToLower(s) {
+	case "":
+		if meta(kevt, kevent.RuleNameKey) != "" {
+			return 5
+		}
+		return 1
+	case "info", "informational":
+		return 1
+	case "low":
+		return 3
+	case "medium":
+		return 5
+	case "high":
+		return 8
+	case "critical":
+		return 10
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 1
+	}
+	if n > 10 {
+		return 10
+	}
+	return n
+}
+
+// syslogSeverity maps the event severity to the syslog severity code.
+func syslogSeverity(sev int) int {
+	switch {
+	case sev >= 9:
+		return 2 // critical
+	case sev >= 7:
+		return 3 // error
+	case sev >= 4:
+		return 4 // warning
+	case sev >= 2:
+		return 5 // notice
+	default:
+		return 6 // informational
+	}
+}
+
+func meta(kevt *kevent.Kevent, key kevent.MetadataKey) string {
+	v, ok := kevt.Metadata[key]
+	if !ok || v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func kparam(kevt *kevent.Kevent, name string) string {
+	kpar, ok := kevt.Kparams[name]
+	if !ok {
+		return ""
+	}
+	return kpar.String()
+}
+
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newConfig(format, protocol string) Config {
+	return Config{
+		Network:     "udp",
+		Address:     "localhost:514",
+		Protocol:    protocol,
+		Framing:     octetCounting,
+		Format:      format,
+		Facility:    "local0",
+		AppName:     "fibratus",
+		SeverityKey: "severity",
+	}
+}
+
+func newAlert() *kevent.Kevent {
+	return &kevent.Kevent{
+		Type:        ktypes.CreateFile,
+		Tid:         2484,
+		PID:         859,
+		Seq:         12,
+		Name:        "CreateFile",
+		Timestamp:   time.Date(2022, 3, 14, 10, 5, 12, 345000000, time.UTC),
+		Category:    ktypes.Category("file"),
+		Description: "Creates or opens a file",
+		Host:        "archrabbit",
+		Kparams: kevent.Kparams{
+			kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: `C:\Windows\system32\user32.dll`},
+			kparams.NetSIP:   {Name: kparams.NetSIP, Type: kparams.IPv4, Value: net.ParseIP("10.0.0.1")},
+			kparams.NetSport: {Name: kparams.NetSport, Type: kparams.Uint16, Value: uint16(4444)},
+		},
+		Metadata: kevent.Metadata{
+			kevent.RuleNameKey:  "Suspicious DLL | load",
+			kevent.RuleGroupKey: "Defense evasion",
+			"severity":          "high",
+		},
+		PS: &pstypes.PS{
+			PID:  859,
+			Ppid: 6304,
+			Name: "firefox.exe",
+			Exe:  `C:\Program Files\Mozilla Firefox\firefox.exe`,
+			Comm: `"C:\Program Files\Mozilla Firefox\firefox.exe" -contentproc=tab`,
+			SID:  `archrabbit\nedo`,
+		},
+	}
+}
+
+func TestRFC5424Header(t *testing.T) {
+	f, err := newFormatter(newConfig(jsonFormat, rfc5424))
+	require.NoError(t, err)
+
+	msg := string(f.format(newAlert()))
+	// local0 (16) * 8 + error (3)
+	prefix := "<131>1 2022-03-14T10:05:12.345000Z archrabbit fibratus " + strconv.Itoa(os.Getpid()) + " CreateFile - {"
+	assert.True(t, strings.HasPrefix(msg, prefix), msg)
+	assert.Contains(t, msg, `"seq":12`)
+}
+
+func TestRFC3164Header(t *testing.T) {
+	config := newConfig(jsonFormat, rfc3164)
+	config.Facility = "auth"
+	config.Hostname = "collector"
+	f, err := newFormatter(config)
+	require.NoError(t, err)
+
+	kevt := newAlert()
+	kevt.Metadata = kevent.Metadata{}
+	msg := string(f.format(kevt))
+	// auth (4) * 8 + informational (6)
+	prefix := "<38>Mar 14 10:05:12 collector fibratus[" + strconv.Itoa(os.Getpid()) + "]: {"
+	assert.True(t, strings.HasPrefix(msg, prefix), msg)
+}
+
+func TestCEF(t *testing.T) {
+	config := newConfig(cefFormat, rfc5424)
+	config.Extensions = map[string]string{
+		"deviceExternalId": "{{ .Host }}",
+		"cs6Label":         "Tenant",
+		"cs6":              "acme",
+	}
+	f, err := newFormatter(config)
+	require.NoError(t, err)
+
+	msg := string(f.cef(newAlert(), 8))
+	assert.True(t, strings.HasPrefix(msg, `CEF:0|Fibratus|Fibratus|`), msg)
+	assert.Contains(t, msg, `|CreateFile|Suspicious DLL \| load|8|`)
+
+	exts := []string{
+		"rt=1647252312345",
+		"dvchost=archrabbit",
+		"externalId=12",
+		"cat=file",
+		"act=CreateFile",
+		"spid=859",
+		"sproc=firefox.exe",
+		`suser=archrabbit\\nedo`,
+		`cs3="C:\\Program Files\\Mozilla Firefox\\firefox.exe" -contentproc\=tab`,
+		`filePath=C:\\Windows\\system32\\user32.dll`,
+		"fname=user32.dll",
+		"src=10.0.0.1",
+		"spt=4444",
+		"cs1Label=Rule cs1=Suspicious DLL | load",
+		"cs2Label=RuleGroup cs2=Defense evasion",
+		"cs6=acme cs6Label=Tenant",
+		"deviceExternalId=archrabbit",
+	}
+	for _, ext := range exts {
+		assert.Contains(t, msg, ext)
+	}
+	// empty extensions are omitted
+	assert.NotContains(t, msg, "dst=")
+}
+
+func TestLEEF(t *testing.T) {
+	f, err := newFormatter(newConfig(leefFormat, rfc5424))
+	require.NoError(t, err)
+
+	msg := string(f.leef(newAlert(), 8))
+	parts := strings.SplitN(msg, "|", 6)
+	require.Len(t, parts, 6)
+	assert.Equal(t, "LEEF:1.0", parts[0])
+	assert.Equal(t, "CreateFile", parts[4])
+
+	exts := make(map[string]string)
+	for _, kv := range strings.Split(parts[5], "\t") {
+		k, v, ok := strings.Cut(kv, "=")
+		require.True(t, ok, kv)
+		exts[k] = v
+	}
+	assert.Equal(t, "2022-03-14T10:05:12.345+0000", exts["devTime"])
+	assert.Equal(t, leefTimeFormat, exts["devTimeFormat"])
+	assert.Equal(t, "8", exts["sev"])
+	assert.Equal(t, "file", exts["cat"])
+	assert.Equal(t, `archrabbit\nedo`, exts["usrName"])
+	assert.Equal(t, "10.0.0.1", exts["src"])
+	assert.Equal(t, "4444", exts["srcPort"])
+	assert.Equal(t, "Suspicious DLL | load", exts["ruleName"])
+	assert.Equal(t, "Defense evasion", exts["ruleGroup"])
+	assert.NotContains(t, exts, "dst")
+}
+
+func TestSeverity(t *testing.T) {
+	f, err := newFormatter(newConfig(cefFormat, rfc5424))
+	require.NoError(t, err)
+
+	var tests = []struct {
+		meta      kevent.Metadata
+		sev       int
+		syslogSev int
+	}{
+		{kevent.Metadata{}, 1, 6},
+		{kevent.Metadata{kevent.RuleNameKey: "Suspicious DLL load"}, 5, 4},
+		{kevent.Metadata{"severity": "medium"}, 5, 4},
+		{kevent.Metadata{"severity": "High"}, 8, 3},
+		{kevent.Metadata{"severity": "critical"}, 10, 2},
+		{kevent.Metadata{"severity": "7"}, 7, 3},
+		{kevent.Metadata{"severity": 42}, 10, 2},
+		{kevent.Metadata{"severity": "unknown"}, 1, 6},
+	}
+
+	for i, tt := range tests {
+		kevt := newAlert()
+		kevt.Metadata = tt.meta
+		sev := f.severity(kevt)
+		assert.Equal(t, tt.sev, sev, i)
+		assert.Equal(t, tt.syslogSev, syslogSeverity(sev), i)
+	}
+}
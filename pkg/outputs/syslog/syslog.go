@@ -0,0 +1,177 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"expvar"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	tlsutil "github.com/rabbitstack/fibratus/pkg/util/tls"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// syslogErrors counts messages that failed to be sent
+	syslogErrors = expvar.NewInt("output.syslog.publish.errors")
+	// syslogMessages counts the total number of sent messages
+	syslogMessages = expvar.NewInt("output.syslog.publish.messages")
+)
+
+type syslog struct {
+	config    Config
+	conn      net.Conn
+	formatter *formatter
+}
+
+func init() {
+	outputs.Register(outputs.Syslog, initSyslog)
+}
+
+func initSyslog(config outputs.Config) (outputs.OutputGroup, error) {
+	cfg, ok := config.Output.(Config)
+	if !ok {
+		return outputs.Fail(outputs.ErrInvalidConfig(outputs.Syslog, config.Output))
+	}
+	s, err := newSyslog(cfg)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+	return outputs.Success(s), nil
+}
+
+func newSyslog(config Config) (*syslog, error) {
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid syslog config: %v", err)
+	}
+	f, err := newFormatter(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid syslog extensions: %v", err)
+	}
+	return &syslog{config: config, formatter: f}, nil
+}
+
+func (s *syslog) Connect() error {
+	conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	log.Infof("established %s connection to syslog server %s", s.config.Network, s.config.Address)
+	return nil
+}
+
+func (s *syslog) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+	if s.config.Network != "tls" {
+		return dialer.Dial(s.config.Network, s.config.Address)
+	}
+	config, err := tlsutil.MakeConfig(s.config.TLSCert, s.config.TLSKey, s.config.TLSCA, s.config.TLSInsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &tls.Config{InsecureSkipVerify: s.config.TLSInsecureSkipVerify} //nolint:gosec
+	}
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(s.config.Address)
+		if err == nil {
+			config.ServerName = host
+		}
+	}
+	return tls.DialWithDialer(dialer, "tcp", s.config.Address, config)
+}
+
+func (s *syslog) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+func (s *syslog) Publish(batch *kevent.Batch) error {
+	defer batch.Release()
+	if batch.Len() == 0 {
+		return nil
+	}
+
+	// reestablish the connection if the previous write failed
+	if s.conn == nil {
+		if err := s.Connect(); err != nil {
+			syslogErrors.Add(batch.Len())
+			return err
+		}
+	}
+
+	if s.config.Network == "udp" {
+		// each datagram carries a single message
+		for n, kevt := range batch.Events {
+			if err := s.write(s.formatter.format(kevt)); err != nil {
+				syslogErrors.Add(batch.Len() - int64(n))
+				return err
+			}
+			syslogMessages.Add(1)
+		}
+		return nil
+	}
+
+	var b bytes.Buffer
+	for _, kevt := range batch.Events {
+		msg := s.formatter.format(kevt)
+		switch s.config.Framing {
+		case nonTransparent:
+			b.Write(msg)
+			b.WriteByte('\n')
+		default:
+			b.WriteString(strconv.Itoa(len(msg)))
+			b.WriteByte(' ')
+			b.Write(msg)
+		}
+	}
+	if err := s.write(b.Bytes()); err != nil {
+		syslogErrors.Add(batch.Len())
+		return err
+	}
+	syslogMessages.Add(batch.Len())
+
+	return nil
+}
+
+// write sends the buffer to the syslog server. The connection
+// is discarded on failure, so the next publish redials the server.
+func (s *syslog) write(b []byte) error {
+	if s.config.Timeout > 0 {
+		if err := s.conn.SetWriteDeadline(time.Now().Add(s.config.Timeout)); err != nil {
+			return err
+		}
+	}
+	_, err := s.conn.Write(b)
+	if err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("unable to write to syslog server %s: %v", s.config.Address, err)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBatch() *kevent.Batch {
+	evts := make([]*kevent.Kevent, 3)
+	for i := range evts {
+		evts[i] = newAlert()
+		evts[i].Seq = uint64(i + 1)
+	}
+	return kevent.NewBatch(evts...)
+}
+
+func TestPublishUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	config := newConfig(cefFormat, rfc5424)
+	config.Address = conn.LocalAddr().String()
+	s, err := newSyslog(config)
+	require.NoError(t, err)
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	require.NoError(t, s.Publish(newBatch()))
+
+	buf := make([]byte, 65536)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second*5)))
+	for i := 1; i <= 3; i++ {
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		msg := string(buf[:n])
+		assert.True(t, strings.HasPrefix(msg, "<131>1 "), msg)
+		assert.Contains(t, msg, "CEF:0|")
+		assert.Contains(t, msg, "externalId="+strconv.Itoa(i))
+	}
+}
+
+func TestPublishTCPOctetCounting(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	config := newConfig(leefFormat, rfc5424)
+	config.Network = "tcp"
+	config.Address = l.Addr().String()
+	config.Timeout = time.Second * 5
+	s, err := newSyslog(config)
+	require.NoError(t, err)
+
+	msgs := make(chan []string, 1)
+	go func() { msgs <- readFrames(t, l, 3, octetCounting) }()
+
+	require.NoError(t, s.Connect())
+	defer s.Close()
+	require.NoError(t, s.Publish(newBatch()))
+
+	frames := <-msgs
+	require.Len(t, frames, 3)
+	for i, msg := range frames {
+		assert.True(t, strings.HasPrefix(msg, "<131>1 "), msg)
+		assert.Contains(t, msg, "LEEF:1.0|")
+		assert.Contains(t, msg, "seq="+strconv.Itoa(i+1))
+	}
+}
+
+func TestPublishTLS(t *testing.T) {
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{newCertificate(t)}, MinVersion: tls.VersionTLS12})
+	require.NoError(t, err)
+	defer l.Close()
+
+	config := newConfig(jsonFormat, rfc3164)
+	config.Network = "tls"
+	config.Framing = nonTransparent
+	config.Address = l.Addr().String()
+	config.Timeout = time.Second * 5
+	config.TLSInsecureSkipVerify = true
+	s, err := newSyslog(config)
+	require.NoError(t, err)
+
+	msgs := make(chan []string, 1)
+	go func() { msgs <- readFrames(t, l, 3, nonTransparent) }()
+
+	require.NoError(t, s.Connect())
+	defer s.Close()
+	require.NoError(t, s.Publish(newBatch()))
+
+	frames := <-msgs
+	require.Len(t, frames, 3)
+	for i, msg := range frames {
+		assert.True(t, strings.HasPrefix(msg, "<131>Mar 14 10:05:12 archrabbit fibratus["), msg)
+		assert.Contains(t, msg, `"seq":`+strconv.Itoa(i+1))
+	}
+}
+
+func TestPublishReconnect(t *testing.T) {
+	config := newConfig(jsonFormat, rfc5424)
+	config.Network = "tcp"
+	config.Address = "127.0.0.1:1"
+	config.Timeout = time.Second
+	s, err := newSyslog(config)
+	require.NoError(t, err)
+
+	require.Error(t, s.Publish(newBatch()))
+	assert.Nil(t, s.conn)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	config := newConfig(jsonFormat, rfc5424)
+	config.Format = "xml"
+	_, err := newSyslog(config)
+	require.EqualError(t, err, "invalid syslog config: invalid format: xml")
+
+	config = newConfig(jsonFormat, rfc5424)
+	config.Facility = "local9"
+	_, err = newSyslog(config)
+	require.EqualError(t, err, "invalid syslog config: invalid facility: local9")
+}
+
+// readFrames accepts a single connection and reads n framed messages.
+func readFrames(t *testing.T, l net.Listener, n int, framing string) []string {
+	conn, err := l.Accept()
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+
+	r := bufio.NewReader(conn)
+	frames := make([]string, 0, n)
+	for len(frames) < n {
+		if framing == nonTransparent {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Error(err)
+				return frames
+			}
+			frames = append(frames, strings.TrimSuffix(line, "\n"))
+			continue
+		}
+		size, err := r.ReadString(' ')
+		if err != nil {
+			t.Error(err)
+			return frames
+		}
+		l, err := strconv.Atoi(strings.TrimSuffix(size, " "))
+		if err != nil {
+			t.Error(err)
+			return frames
+		}
+		buf := make([]byte, l)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			t.Error(err)
+			return frames
+		}
+		frames = append(frames, string(buf))
+	}
+	return frames
+}
+
+// newCertificate generates the self-signed server certificate.
+func newCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
@@ -89,6 +89,10 @@ func (pe *PE) Marshal() []byte {
 		b = append(b, v...)
 	}
 
+	// imphash
+	b = append(b, bytes.WriteUint16(uint16(len(pe.Imphash)))...)
+	b = append(b, pe.Imphash...)
+
 	return b
 }
 
@@ -208,6 +212,18 @@ func (pe *PE) Unmarshal(b []byte) error {
 		}
 	}
 
+	offset += roffset
+
+	// read imphash. Captures produced by older
+	// versions end with version resources
+	if len(b) < 22+int(offset) {
+		return nil
+	}
+	l = bytes.ReadUint16(b[20+offset:])
+	if l > 0 && len(b) >= 22+int(offset)+int(l) {
+		pe.Imphash = string(b[22+offset : 22+offset+uint32(l)])
+	}
+
 	return nil
 }
 
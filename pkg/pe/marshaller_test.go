@@ -44,6 +44,7 @@ func TestMetadataMarshal(t *testing.T) {
 		Symbols:          []string{"SelectObject", "GetTextFaceW", "EnumFontsW", "TextOutW", "GetProcessHeap"},
 		Imports:          []string{"GDI32.dll", "USER32.dll", "msvcrt.dll", "api-ms-win-core-libraryloader-l1-2-0.dl"},
 		VersionResources: map[string]string{"CompanyName": "Microsoft Corporation", "FileDescription": "Notepad", "FileVersion": "10.0.18362.693"},
+		Imphash:          "28e7cf1bf0a237602e55689d52623b82",
 	}
 
 	b := pe.Marshal()
@@ -83,4 +84,12 @@ func TestMetadataMarshal(t *testing.T) {
 	assert.Equal(t, "10.0.18362.693", newPE.VersionResources["FileVersion"])
 	assert.Equal(t, "Microsoft Corporation", newPE.VersionResources["CompanyName"])
 	assert.Equal(t, "Notepad", newPE.VersionResources["FileDescription"])
+
+	assert.Equal(t, "28e7cf1bf0a237602e55689d52623b82", newPE.Imphash)
+
+	// the byte stream without the trailing imphash
+	oldPE := &PE{VersionResources: make(map[string]string)}
+	require.NoError(t, oldPE.Unmarshal(b[:len(b)-len(pe.Imphash)-2]))
+	assert.Len(t, oldPE.VersionResources, 3)
+	assert.Empty(t, oldPE.Imphash)
 }
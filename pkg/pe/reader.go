@@ -32,6 +32,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/rabbitstack/fibratus/pkg/util/hashers"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/text/encoding/unicode"
 )
@@ -144,6 +145,7 @@ func (r *reader) Read(filename string) (*PE, error) {
 				p.addImport(lib)
 				p.addSymbol(symbol)
 			}
+			p.Imphash = hashers.Imphash(symbols)
 		}(&wg)
 	}
 
@@ -61,6 +61,8 @@ type PE struct {
 	Symbols []string `json:"symbols"`
 	// Imports contains the imported libraries.
 	Imports []string `json:"imports"`
+	// Imphash is the hash of the imported functions.
+	Imphash string `json:"imphash"`
 	// VersionResources holds the version resources
 	VersionResources map[string]string `json:"resources"`
 }
@@ -76,6 +78,7 @@ func (pe PE) String() string {
 		 Sections: %v
 		 Symbols: %v
 		 Imports: %v
+		 Imphash: %s
          Version resources: %v
 		`,
 		pe.NumberOfSections,
@@ -86,6 +89,7 @@ func (pe PE) String() string {
 		pe.Sections,
 		pe.Symbols,
 		pe.Imports,
+		pe.Imphash,
 		pe.VersionResources,
 	)
 }
@@ -122,6 +122,7 @@ func (s *snapshotter) WriteFromKcap(kevt *kevent.Kevent) error {
 			s.procs[pid] = ps
 		} else {
 			s.procs[pid] = pstypes.FromKevent(unwrapParams(pid, kevt))
+			s.procs[pid].StartTime, _ = kevt.Kparams.GetTime(kparams.StartTime)
 		}
 		ppid, err := kevt.Kparams.GetPpid()
 		if err != nil {
@@ -182,6 +183,7 @@ func (s *snapshotter) Write(kevt *kevent.Kevent) error {
 		}
 
 		ps := pstypes.FromKevent(unwrapParams(pid, kevt))
+		ps.StartTime, _ = kevt.Kparams.GetTime(kparams.StartTime)
 
 		// enumerate process handles
 		ps.Handles, err = s.handleSnap.FindHandles(pid)
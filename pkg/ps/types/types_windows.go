@@ -23,6 +23,7 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/util/cmdline"
 	"path/filepath"
 	"sync"
+	"time"
 
 	htypes "github.com/rabbitstack/fibratus/pkg/handle/types"
 	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
@@ -54,6 +55,8 @@ type PS struct {
 	Args []string `json:"args"`
 	// SessionID is the unique identifier for the current session.
 	SessionID uint8 `json:"session"`
+	// StartTime is the time at which the process was started.
+	StartTime time.Time `json:"started"`
 	// Envs contains process' environment variables indexed by env variable name.
 	Envs map[string]string `json:"envs"`
 	// Threads contains all the threads running in the address space of this process.
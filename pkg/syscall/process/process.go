@@ -44,6 +44,8 @@ var (
 	getProcessTimes           = kernel32.NewProc("GetProcessTimes")
 	getProcessIDOfThread      = kernel32.NewProc("GetProcessIdOfThread")
 	getExitCodeProcess        = kernel32.NewProc("GetExitCodeProcess")
+	ntSuspendProcess          = native.NewProc("NtSuspendProcess")
+	ntResumeProcess           = native.NewProc("NtResumeProcess")
 )
 
 const procStatusStillActive = 259
@@ -256,3 +258,21 @@ func IsAlive(handle handle.Handle) bool {
 	}
 	return exitCode == procStatusStillActive
 }
+
+// Suspend suspends all threads of the process. The handle must have the SuspendResume access right.
+func Suspend(handle handle.Handle) error {
+	errno, _, _ := ntSuspendProcess.Call(uintptr(handle))
+	if winerrno.Errno(errno) != winerrno.Success {
+		return fmt.Errorf("NtSuspendProcess failed with status code 0x%X", errno)
+	}
+	return nil
+}
+
+// Resume resumes all threads of the suspended process. The handle must have the SuspendResume access right.
+func Resume(handle handle.Handle) error {
+	errno, _, _ := ntResumeProcess.Call(uintptr(handle))
+	if winerrno.Errno(errno) != winerrno.Success {
+		return fmt.Errorf("NtResumeProcess failed with status code 0x%X", errno)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hashers
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"strings"
+)
+
+// Imphash computes the import hash of the executable from the list of imported
+// functions. Each import is given either in the library.function form, such as
+// kernel32.dll.CreateFileW, or in the function:library form produced by the
+// debug/pe package. Library names are lowercased and stripped of the dll, ocx,
+// and sys extensions, and the resulting library.function strings are joined by
+// commas in the import order before computing the MD5 hash. An empty string is
+// returned if there are no valid imports.
+func Imphash(imports []string) string {
+	entries := make([]string, 0, len(imports))
+	for _, imp := range imports {
+		var lib, fn string
+		if n := strings.LastIndex(imp, ":"); n > 0 {
+			fn, lib = imp[:n], imp[n+1:]
+		} else if n := strings.LastIndex(imp, "."); n > 0 {
+			lib, fn = imp[:n], imp[n+1:]
+		}
+		if lib == "" || fn == "" {
+			continue
+		}
+		lib = strings.ToLower(lib)
+		if n := strings.LastIndex(lib, "."); n > 0 {
+			switch lib[n+1:] {
+			case "dll", "ocx", "sys":
+				lib = lib[:n]
+			}
+		}
+		entries = append(entries, lib+"."+strings.ToLower(fn))
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	hash := md5.Sum([]byte(strings.Join(entries, ",")))
+	return hex.EncodeToString(hash[:])
+}
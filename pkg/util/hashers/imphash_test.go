@@ -0,0 +1,32 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hashers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImphash(t *testing.T) {
+	assert.Equal(t, "28e7cf1bf0a237602e55689d52623b82", Imphash([]string{"CreateFileW:KERNEL32.dll", "CloseHandle:KERNEL32.dll", "MessageBoxA:USER32.dll"}))
+	assert.Equal(t, "28e7cf1bf0a237602e55689d52623b82", Imphash([]string{"kernel32.dll.CreateFileW", "kernel32.CloseHandle", "user32.dll.MessageBoxA"}))
+	assert.NotEqual(t, "28e7cf1bf0a237602e55689d52623b82", Imphash([]string{"kernel32.dll.CloseHandle", "kernel32.dll.CreateFileW", "user32.dll.MessageBoxA"}))
+	assert.Empty(t, Imphash([]string{"CreateFileW", ""}))
+}
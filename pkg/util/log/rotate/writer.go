@@ -0,0 +1,403 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+)
+
+// Compression is the codec for compressing rotated files.
+type Compression string
+
+const (
+	// None leaves rotated files uncompressed.
+	None Compression = "none"
+	// Gzip compresses rotated files with gzip.
+	Gzip Compression = "gzip"
+	// Zstd compresses rotated files with Zstandard.
+	Zstd Compression = "zstd"
+)
+
+// ext returns the file extension of compressed files.
+func (c Compression) ext() string {
+	switch c {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// backupTimeFormat is the layout of the timestamp encoded in rotated file names
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+var errWriterClosed = errors.New("writer is closed")
+
+// WriterConfig determines when the file is rotated and how long rotated files are kept.
+type WriterConfig struct {
+	// Filename is the path of the file being written. Rotated files are stored in the same
+	// directory and their names are suffixed with the rotation timestamp.
+	Filename string
+	// MaxSize is the maximum size of the file in bytes before it gets rotated. Size-based
+	// rotation is disabled if zero.
+	MaxSize int64
+	// Interval rotates the file on the interval boundary. Time-based rotation is disabled
+	// if zero.
+	Interval time.Duration
+	// Compression is the codec for compressing rotated files.
+	Compression Compression
+	// MaxAge is the maximum age of rotated files based on the timestamp encoded in their
+	// names. By default, rotated files are not removed due to their age.
+	MaxAge time.Duration
+	// MaxFiles is the maximum number of rotated files to retain. By default, all rotated
+	// files are retained.
+	MaxFiles int
+}
+
+// Writer is the io.WriteCloser that rotates the underlying file by size and/or time. Rotated
+// files are compressed and removed according to the retention policy in the background.
+type Writer struct {
+	mu     sync.Mutex
+	config WriterConfig
+	file   *os.File
+	size   int64
+	// opened is the time the current file was opened or last modified before the writer started
+	opened time.Time
+	closed bool
+
+	mill chan struct{}
+	wg   sync.WaitGroup
+
+	now func() time.Time
+}
+
+// NewWriter creates the rotating writer. The file is created lazily on the first write.
+func NewWriter(config WriterConfig) *Writer {
+	return newWriter(config, time.Now)
+}
+
+func newWriter(config WriterConfig, now func() time.Time) *Writer {
+	w := &Writer{
+		config: config,
+		mill:   make(chan struct{}, 1),
+		now:    now,
+	}
+	w.wg.Add(1)
+	go w.millRun()
+	// compress and clean up files left over from previous runs
+	w.mill <- struct{}{}
+	return w
+}
+
+// Write writes the buffer to the file. The file is rotated before writing if the write
+// would exceed the maximum file size or the rotation interval has elapsed.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, errWriterClosed
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it by appending the rotation timestamp, and
+// opens a new file.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return errWriterClosed
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	return w.rotate()
+}
+
+// Close closes the file and waits for pending compressions to complete.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	close(w.mill)
+	w.mu.Unlock()
+	w.wg.Wait()
+	return err
+}
+
+func (w *Writer) shouldRotate(n int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.config.MaxSize > 0 && w.size+n > w.config.MaxSize {
+		return true
+	}
+	if w.config.Interval > 0 && !w.now().Truncate(w.config.Interval).Equal(w.opened.Truncate(w.config.Interval)) {
+		return true
+	}
+	return false
+}
+
+// open opens the existing file in append mode or creates a new file.
+func (w *Writer) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.config.Filename), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.config.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.file = f
+	w.size = fi.Size()
+	w.opened = w.now()
+	if w.size > 0 {
+		w.opened = fi.ModTime()
+	}
+	return nil
+}
+
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	if w.size > 0 {
+		if err := os.Rename(w.config.Filename, w.backupName()); err != nil {
+			return err
+		}
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	select {
+	case w.mill <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// backupName returns the unique name of the rotated file, e.g. events-2022-03-14T10-05-12.345.json.
+func (w *Writer) backupName() string {
+	dir, prefix, ext := w.parts()
+	t := w.now().UTC()
+	for {
+		name := filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+		_, err := os.Stat(name)
+		_, errc := os.Stat(name + w.config.Compression.ext())
+		if os.IsNotExist(err) && os.IsNotExist(errc) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// parts splits the file name into the directory, the prefix of rotated files, and the extension.
+func (w *Writer) parts() (string, string, string) {
+	dir, name := filepath.Split(w.config.Filename)
+	ext := filepath.Ext(name)
+	return dir, strings.TrimSuffix(name, ext) + "-", ext
+}
+
+func (w *Writer) millRun() {
+	defer w.wg.Done()
+	for range w.mill {
+		if err := w.millRunOnce(); err != nil {
+			log.Warnf("unable to process rotated files of %s: %v", w.config.Filename, err)
+		}
+	}
+}
+
+// backup represents the rotated file
+type backup struct {
+	path      string
+	timestamp time.Time
+	// compressed indicates if the file is compressed with the configured codec
+	compressed bool
+}
+
+// millRunOnce compresses rotated files and removes files that exceed the retention policy.
+func (w *Writer) millRunOnce() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	// backups are sorted from the newest to the oldest
+	remove := make(map[string]bool)
+	if w.config.MaxFiles > 0 && len(backups) > w.config.MaxFiles {
+		for _, b := range backups[w.config.MaxFiles:] {
+			remove[b.path] = true
+		}
+	}
+	if w.config.MaxAge > 0 {
+		cutoff := w.now().Add(-w.config.MaxAge)
+		for _, b := range backups {
+			if b.timestamp.Before(cutoff) {
+				remove[b.path] = true
+			}
+		}
+	}
+
+	for _, b := range backups {
+		if remove[b.path] {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if b.compressed || w.config.Compression.ext() == "" {
+			continue
+		}
+		if err := compress(b.path, w.config.Compression); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// backups returns rotated files sorted by the timestamp encoded in their names, from the newest
+// to the oldest.
+func (w *Writer) backups() ([]backup, error) {
+	dir, prefix, ext := w.parts()
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	backups := make([]backup, 0)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(e.Name(), prefix)
+		compressed := false
+		if cext := w.config.Compression.ext(); cext != "" && strings.HasSuffix(name, ext+cext) {
+			name, compressed = strings.TrimSuffix(name, ext+cext), true
+		} else if strings.HasSuffix(name, ext) {
+			name = strings.TrimSuffix(name, ext)
+		} else {
+			continue
+		}
+		// the timestamp parsing skips rotated files with different
+		// prefixes, e.g. events-file-<timestamp> for the events file
+		ts, err := time.Parse(backupTimeFormat, name)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, e.Name()), timestamp: ts, compressed: compressed})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].timestamp.After(backups[j].timestamp) })
+	return backups, nil
+}
+
+// compress compresses the file and removes the source file.
+func compress(src string, c Compression) (err error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dst := src + c.ext()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(dst)
+		}
+	}()
+
+	var zw io.WriteCloser
+	switch c {
+	case Gzip:
+		zw = gzip.NewWriter(out)
+	case Zstd:
+		zw, err = zstd.NewWriter(out)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown compression: %s", c)
+	}
+	if _, err = io.Copy(zw, f); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	_ = f.Close()
+	return os.Remove(src)
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2021-2022 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rotate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is the fake clock that is advanced manually
+type clock struct {
+	sync.Mutex
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newClock() *clock {
+	return &clock{t: time.Date(2022, 3, 14, 10, 5, 12, 0, time.UTC)}
+}
+
+func listFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	files := make([]string, 0, len(entries))
+	for _, e := range entries {
+		files = append(files, e.Name())
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestWriterRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	c := newClock()
+	w := newWriter(WriterConfig{Filename: filepath.Join(dir, "events.json"), MaxSize: 10, Compression: None}, c.now)
+
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("event\n"))
+		require.NoError(t, err)
+		c.advance(time.Second)
+	}
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, []string{
+		"events-2022-03-14T10-05-13.000.json",
+		"events-2022-03-14T10-05-14.000.json",
+		"events.json",
+	}, listFiles(t, dir))
+	b, err := os.ReadFile(filepath.Join(dir, "events.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "event\n", string(b))
+}
+
+func TestWriterRotateByInterval(t *testing.T) {
+	dir := t.TempDir()
+	c := newClock()
+	w := newWriter(WriterConfig{Filename: filepath.Join(dir, "events.json"), Interval: time.Hour, Compression: None}, c.now)
+
+	_, err := w.Write([]byte("event\n"))
+	require.NoError(t, err)
+	c.advance(time.Minute * 30)
+	_, err = w.Write([]byte("event\n"))
+	require.NoError(t, err)
+	// crosses the hour boundary
+	c.advance(time.Minute * 30)
+	_, err = w.Write([]byte("event\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, []string{"events-2022-03-14T11-05-12.000.json", "events.json"}, listFiles(t, dir))
+	b, err := os.ReadFile(filepath.Join(dir, "events-2022-03-14T11-05-12.000.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "event\nevent\n", string(b))
+}
+
+func TestWriterCompression(t *testing.T) {
+	var tests = []struct {
+		compression Compression
+		ext         string
+		reader      func(io.Reader) (io.Reader, error)
+	}{
+		{Gzip, ".gz", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{Zstd, ".zst", func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.compression), func(t *testing.T) {
+			dir := t.TempDir()
+			c := newClock()
+			w := newWriter(WriterConfig{Filename: filepath.Join(dir, "events.json"), Compression: tt.compression}, c.now)
+
+			_, err := w.Write([]byte("event\n"))
+			require.NoError(t, err)
+			require.NoError(t, w.Rotate())
+			require.NoError(t, w.Close())
+
+			backup := "events-2022-03-14T10-05-12.000.json" + tt.ext
+			assert.Equal(t, []string{backup, "events.json"}, listFiles(t, dir))
+
+			f, err := os.Open(filepath.Join(dir, backup))
+			require.NoError(t, err)
+			defer f.Close()
+			r, err := tt.reader(f)
+			require.NoError(t, err)
+			var b bytes.Buffer
+			_, err = io.Copy(&b, r)
+			require.NoError(t, err)
+			assert.Equal(t, "event\n", b.String())
+		})
+	}
+}
+
+func TestWriterRetention(t *testing.T) {
+	dir := t.TempDir()
+	c := newClock()
+	w := newWriter(WriterConfig{Filename: filepath.Join(dir, "events.json"), Compression: Gzip, MaxFiles: 3, MaxAge: time.Hour * 2}, c.now)
+
+	// rotated files of other writers are left intact
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "events-file-2022-03-14T10-05-12.000.json"), []byte("event\n"), 0644))
+
+	for i := 0; i < 5; i++ {
+		_, err := w.Write([]byte("event\n"))
+		require.NoError(t, err)
+		require.NoError(t, w.Rotate())
+		c.advance(time.Hour)
+	}
+	// the empty file is not rotated, but retention is enforced again
+	require.NoError(t, w.Rotate())
+	require.NoError(t, w.Close())
+
+	// only three files are retained, and the file rotated
+	// at 12:05 is older than two hours at 15:05
+	assert.Equal(t, []string{
+		"events-2022-03-14T13-05-12.000.json.gz",
+		"events-2022-03-14T14-05-12.000.json.gz",
+		"events-file-2022-03-14T10-05-12.000.json",
+		"events.json",
+	}, listFiles(t, dir))
+}
+
+func TestWriterClosed(t *testing.T) {
+	w := NewWriter(WriterConfig{Filename: filepath.Join(t.TempDir(), "events.json")})
+	require.NoError(t, w.Close())
+	_, err := w.Write([]byte("event\n"))
+	require.Error(t, err)
+	require.NoError(t, w.Close())
+}